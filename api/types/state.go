@@ -31,7 +31,15 @@ const (
 	ConfigurationDestroying              ConfigurationState = "Destroying"
 	ConfigurationApplyFailed             ConfigurationState = "ApplyFailed"
 	ConfigurationDestroyFailed           ConfigurationState = "DestroyFailed"
+	ConfigurationDestroyed               ConfigurationState = "Destroyed"
 	ConfigurationReloading               ConfigurationState = "ConfigurationReloading"
+	ConfigurationValidationFailed        ConfigurationState = "ValidationFailed"
+	ConfigurationWaitingForDependencies  ConfigurationState = "WaitingForDependencies"
+	InvalidRegion                        ConfigurationState = "InvalidRegion"
+	ConfigurationPendingApproval         ConfigurationState = "PendingApproval"
+	ConfigurationVerificationFailed      ConfigurationState = "VerificationFailed"
+	ClusterNotReady                      ConfigurationState = "ClusterNotReady"
+	ConfigurationDeletionBlocked         ConfigurationState = "DeletionBlocked"
 )
 
 // ProviderState is the type for Provider state
@@ -43,3 +51,30 @@ const (
 	// ProviderIsInitializing marks the state of a Provider is initializing
 	ProviderIsInitializing ProviderState = "initializing"
 )
+
+// A FailureReason is the machine-readable reason why a Configuration failed, which tools can branch on
+type FailureReason string
+
+// Reasons a Configuration failed.
+const (
+	// FailureReasonInvalidConfiguration means the spec or the Terraform configuration is invalid, like an HCL error
+	FailureReasonInvalidConfiguration FailureReason = "InvalidConfiguration"
+	// FailureReasonCredentialError means the credentials of the provider are not available, or rejected by the cloud
+	FailureReasonCredentialError FailureReason = "CredentialError"
+	// FailureReasonThrottled means the requests were throttled by the cloud API
+	FailureReasonThrottled FailureReason = "Throttled"
+	// FailureReasonQuotaExceeded means a quota or limit of the cloud account is exceeded
+	FailureReasonQuotaExceeded FailureReason = "QuotaExceeded"
+	// FailureReasonResourceAlreadyExists means a resource to create already exists in the cloud, but not in the state
+	FailureReasonResourceAlreadyExists FailureReason = "ResourceAlreadyExists"
+	// FailureReasonDependencyViolation means a resource to destroy is still used by others
+	FailureReasonDependencyViolation FailureReason = "DependencyViolation"
+	// FailureReasonImportFailed means a resource in spec.imports failed to be imported
+	FailureReasonImportFailed FailureReason = "ImportFailed"
+	// FailureReasonTimeout means the Job was stopped after its deadline, like when Terraform hung
+	FailureReasonTimeout FailureReason = "Timeout"
+	// FailureReasonUntrustedSource means the remote git repo or the OCI artifact isn't signed by a trusted key
+	FailureReasonUntrustedSource FailureReason = "UntrustedSource"
+	// FailureReasonUnknown means the failure isn't recognized, whose details are in the message
+	FailureReasonUnknown FailureReason = "Unknown"
+)
@@ -17,4 +17,27 @@ const (
 	ConfigurationHCL ConfigurationType = "HCL"
 	// ConfigurationRemote means HCL stores in a remote git repository
 	ConfigurationRemote ConfigurationType = "Remote"
+	// ConfigurationOCI means HCL stores in an OCI artifact
+	ConfigurationOCI ConfigurationType = "OCI"
+)
+
+// EngineType is the type of the binary which executes a Terraform Configuration
+type EngineType string
+
+const (
+	// TerraformEngine executes a Configuration by HashiCorp Terraform
+	TerraformEngine EngineType = "terraform"
+	// OpenTofuEngine executes a Configuration by OpenTofu
+	OpenTofuEngine EngineType = "tofu"
+)
+
+// OutputsSource is where the outputs of a Configuration are read from
+type OutputsSource string
+
+const (
+	// OutputsFromState parses the outputs from the Terraform state
+	OutputsFromState OutputsSource = "state"
+	// OutputsFromTerraformOutput runs `terraform output -json` in the apply Job, and reads the outputs from the
+	// termination message of its pod
+	OutputsFromTerraformOutput OutputsSource = "terraformOutput"
 )
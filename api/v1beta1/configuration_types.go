@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	state "github.com/oam-dev/terraform-controller/api/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
@@ -28,12 +30,26 @@ import (
 type ConfigurationSpec struct {
 	// JSON is the Terraform JSON syntax configuration
 	JSON string `json:"JSON,omitempty"`
-	// HCL is the Terraform HCL type configuration
+	// HCL is the Terraform HCL type configuration. The state is kept when it's changed, so that the resources whose
+	// addresses are changed with `moved` blocks are moved rather than destroyed and re-created.
 	HCL string `json:"hcl,omitempty"`
 
 	// Remote is a git repo which contains hcl files. Currently, only public git repos are supported.
 	Remote string `json:"remote,omitempty"`
 
+	// Path is the directory of the configuration in the Remote git repo, relative to its root. Defaults to the root.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// OCI is an OCI artifact which contains hcl files, which is pulled instead of cloning a git repo
+	// +optional
+	OCI *OCISource `json:"oci,omitempty"`
+
+	// Verify verifies the signature of the remote git repo or the OCI artifact before the configuration is run, which
+	// fails with the state VerificationFailed if it isn't signed by a trusted key
+	// +optional
+	Verify *SourceVerification `json:"verify,omitempty"`
+
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Variable *runtime.RawExtension `json:"variable,omitempty"`
 
@@ -47,43 +63,780 @@ type ConfigurationSpec struct {
 	// be written. Connection details frequently include the endpoint, username,
 	// and password required to connect to the managed resource.
 	// +optional
-	WriteConnectionSecretToReference *types.SecretReference `json:"writeConnectionSecretToRef,omitempty"`
+	WriteConnectionSecretToReference *ConnectionSecretReference `json:"writeConnectionSecretToRef,omitempty"`
+
+	// WriteOutputsToConfigMapReference specifies the namespace and name of a ConfigMap to which the non-sensitive
+	// outputs, like a VPC ID, should be written, which is convenient to be consumed by other controllers.
+	// +optional
+	WriteOutputsToConfigMapReference *ConfigMapReference `json:"writeOutputsToConfigMapRef,omitempty"`
 
-	// ProviderReference specifies the reference to Provider
+	// ProviderReference specifies the reference to Provider, which could be in any namespace. Its namespace defaults
+	// to `default` rather than the namespace of the Configuration. Defaults to the default Provider of the controller
+	// in the namespace `default`, which is set by its flag --default-provider, or the Provider default/default.
 	ProviderReference *types.Reference `json:"providerRef,omitempty"`
+
+	// Region overrides the region of the Provider for the Configuration, like us-west-2 of AWS. It's supported by the
+	// Providers of alibaba, aws and gcp.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// AliasedProviders are the Providers besides ProviderReference, for the configurations which need more than one,
+	// like two regions of a cloud. The credentials of each are passed as the variables named after its alias and the
+	// environment variables of the credentials in lower case, e.g. `west_aws_access_key_id`, which the configuration
+	// declares and sets to its aliased provider block.
+	// +optional
+	AliasedProviders []AliasedProviderReference `json:"aliasedProviders,omitempty"`
+
+	// OutputsFrom is where the outputs are read from. `state`, the default, parses the Terraform state, while
+	// `terraformOutput` runs `terraform output -json` in the apply Job, which doesn't depend on the state format but
+	// limits the outputs to 4KiB. The outputs of the custom backend always come from `terraform output -json`.
+	// +kubebuilder:validation:Enum=state;terraformOutput
+	// +optional
+	OutputsFrom state.OutputsSource `json:"outputsFrom,omitempty"`
+
+	// Engine is the binary to run the configuration, `terraform` or `tofu`(OpenTofu). Defaults to `terraform`.
+	// +kubebuilder:validation:Enum=terraform;tofu
+	// +optional
+	Engine state.EngineType `json:"engine,omitempty"`
+
+	// Validate runs `terraform validate` in a short-lived Job before the configuration is applied, so that syntax or
+	// module errors are reported before a full apply is attempted.
+	// +optional
+	Validate bool `json:"validate,omitempty"`
+
+	// RequireApproval makes an apply wait for its plan to be approved. The plan is computed by a Job, whose hash is
+	// in status.plan.hash, and applied only after the annotation `terraform.core.oam.dev/approved` is set to the hash.
+	// The saved plan is applied as it is when the controller has a volume of the plans, or else the changes are
+	// planned again and applied only if the plan is still the approved one.
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+
+	// PostApplyHooks are containers which run in order in a Job after the configuration is applied successfully, like
+	// registering the cloud resources to a CMDB. The non-sensitive outputs are injected as environment variables
+	// `TF_OUTPUT_{name}`, while the sensitive ones can be read from the connection secret.
+	// +optional
+	PostApplyHooks []Hook `json:"postApplyHooks,omitempty"`
+
+	// Imports adopt the resources which already exist in the cloud. They're imported into the state by the apply Job
+	// before the apply, while the ones already in the state are skipped.
+	// +optional
+	Imports []ResourceImport `json:"imports,omitempty"`
+
+	// PodAnnotations are added to the pods of the Jobs which run Terraform. They are merged with the default
+	// annotation `sidecar.istio.io/inject: "false"`, which could be overridden, e.g. to opt out of another service mesh.
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// SubResourceLabels are added to the sub-resources created for the Configuration, like the Jobs, their pods and the
+	// input ConfigMap, e.g. to attribute the cloud cost to teams.
+	// +optional
+	SubResourceLabels map[string]string `json:"subResourceLabels,omitempty"`
+
+	// SubResourceAnnotations are added to the sub-resources created for the Configuration, like the Jobs and the input
+	// ConfigMap.
+	// +optional
+	SubResourceAnnotations map[string]string `json:"subResourceAnnotations,omitempty"`
+
+	// RetainFailedJobLogs snapshots the logs of a failed Job into the ConfigMap `<job name>-failed-logs` in the
+	// controller namespace before the Job is deleted to be re-created, so that the logs of failures which don't
+	// reproduce are kept for debugging. The ConfigMap is replaced by the next failure.
+	// +optional
+	RetainFailedJobLogs bool `json:"retainFailedJobLogs,omitempty"`
+
+	// ApplyTimeout is how long the apply Job could run before the Configuration fails as timed out. The Job isn't
+	// stopped, and the Configuration becomes available if it succeeds later. Defaults to 1h.
+	// +optional
+	ApplyTimeout *metav1.Duration `json:"applyTimeout,omitempty"`
+
+	// DestroyTimeout is how long the destroy of the Configuration could take before the controller escalates. Defaults
+	// to 1h.
+	// +optional
+	DestroyTimeout *metav1.Duration `json:"destroyTimeout,omitempty"`
+
+	// JobDeadline is how long a Job of the Configuration could run before it's stopped by Kubernetes, which is the
+	// activeDeadlineSeconds of the Job, so that a hung Terraform doesn't run forever. The Configuration fails as timed
+	// out, and the Job is kept until it's deleted, or re-created for a change of the configuration or the variables.
+	// No deadline by default.
+	// +optional
+	JobDeadline *metav1.Duration `json:"jobDeadline,omitempty"`
+
+	// JobRestartPolicy is the restart policy of the pods of the apply and destroy Jobs. OnFailure, the default,
+	// restarts Terraform in the same pod. Never keeps the failed pods for inspection, and the Job retries by new pods
+	// until it fails after 6 retries.
+	// +kubebuilder:validation:Enum=OnFailure;Never
+	// +optional
+	JobRestartPolicy corev1.RestartPolicy `json:"jobRestartPolicy,omitempty"`
+
+	// LogLevel is the log level of Terraform in the executor, which is set as TF_LOG. Terraform doesn't log by
+	// default. DEBUG and TRACE log the requests and responses of the providers, which could carry credentials and
+	// sensitive values, to the logs of the Jobs and the retained logs of the failed ones, while the logs of Terraform
+	// are left out of the status.
+	// +kubebuilder:validation:Enum=TRACE;DEBUG;INFO;WARN;ERROR
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// DestroyStages destroy the resources in order before the rest of them, each stage by a destroy Job with the
+	// -target options of its resources, which is followed by the destroy of all the resources left. It helps when the
+	// resources fail to be destroyed at once, e.g. due to the dependencies unknown to Terraform or the rate limits of
+	// the cloud APIs.
+	// +optional
+	DestroyStages []DestroyStage `json:"destroyStages,omitempty"`
+
+	// DependsOn are the Configurations which must be available before this one is applied
+	// +optional
+	DependsOn []ConfigurationReference `json:"dependsOn,omitempty"`
+
+	// VariableFrom sets variables to the outputs of other Configurations, which are depended on implicitly. They take
+	// precedence over the same variables in Variable.
+	// +optional
+	VariableFrom []VariableFromOutput `json:"variableFrom,omitempty"`
+
+	// VarFiles are the .tfvars or .tfvars.json files in the ConfigMaps or Secrets in the namespace of the Configuration,
+	// which are passed to Terraform by -var-file in order. They take precedence over the same variables in Variable and
+	// VariableFrom, and the Configuration is applied again when they're changed.
+	// +optional
+	VarFiles []VarFileSource `json:"varFiles,omitempty"`
+
+	// Destroy destroys the cloud resources while keeping the Configuration. They are applied again once it's set back
+	// to false.
+	// +optional
+	Destroy bool `json:"destroy,omitempty"`
+
+	// ForceDelete cleans up the sub-resources and removes the finalizer of the Configuration if the destroy doesn't
+	// succeed within DestroyTimeout. The cloud resources may be left behind.
+	// +optional
+	ForceDelete bool `json:"forceDelete,omitempty"`
+
+	// ServiceAccountName is the ServiceAccount which the Terraform Jobs and post-apply hooks run as, for example, to
+	// integrate with cloud IAM. It must exist in the execution namespace, and be allowed to get, list, create,
+	// update and delete Secrets, and get, create, update and delete Leases there, to read and write the Terraform
+	// state. Defaults to the ServiceAccount created by the chart.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// ApplyInterval re-runs the apply periodically after the previous one succeeded, to correct the drift of the cloud
+	// resources. The apply only runs when the configuration is changed if it's not set.
+	// +optional
+	ApplyInterval *metav1.Duration `json:"applyInterval,omitempty"`
+
+	// SkipRefresh plans and applies with -refresh=false, which doesn't read the cloud resources before the apply, to
+	// speed up the apply of large states and save the API calls to the cloud. The changes are then planned against the
+	// stored state: the drift of the cloud resources isn't corrected, including by spec.applyInterval, and the outputs
+	// reflect the stored state rather than the live one. The refresh annotation still refreshes the state. It applies
+	// to the Jobs created afterwards.
+	// +optional
+	SkipRefresh bool `json:"skipRefresh,omitempty"`
+
+	// Volumes are the extra Secrets or ConfigMaps mounted into the Terraform executor, like a CA bundle or a
+	// kubeconfig for the kubernetes provider. They must be in the namespace of the Configuration, and are copied to
+	// the namespace of the Jobs, from where they're mounted.
+	// +optional
+	Volumes []ExecutorVolume `json:"volumes,omitempty"`
+
+	// Entrypoint wraps the command of the Terraform executor, which is passed to it as the arguments, like
+	// `["sh", "/opt/hooks/wrapper.sh"]` of a script mounted by spec.volumes which sets up a credentials helper or runs
+	// the hooks before Terraform. The wrapper should run the command by `exec "$@"`, so that Terraform is stopped
+	// gracefully when the pod is evicted, and exit with its exit code. It applies to the Jobs created afterwards.
+	// +optional
+	Entrypoint []string `json:"entrypoint,omitempty"`
+
+	// Env are the environment variables of the Terraform executor and the init containers, which are passed as they
+	// are rather than as Terraform variables, like AWS_PROFILE or the settings of the providers. They can't be the ones
+	// set by the controller, like the credentials of the Providers, or start with TF_VAR_, which are set by
+	// spec.variable.
+	// +optional
+	Env []EnvVar `json:"env,omitempty"`
+
+	// WorkingVolume configures the emptyDir volumes where the Terraform executor works and keeps the backend
+	// configuration, overriding the defaults of the controller. It applies to the Jobs created afterwards.
+	// +optional
+	WorkingVolume *WorkingVolume `json:"workingVolume,omitempty"`
+
+	// Cluster is the workload cluster which the Terraform Jobs, the input ConfigMaps and the kubernetes backend state
+	// are in, rather than the cluster of the controller. The connection Secret and the outputs ConfigMap are still
+	// written next to the Configuration.
+	// +optional
+	Cluster *ClusterReference `json:"cluster,omitempty"`
+}
+
+// ClusterReference is a workload cluster which the sub-resources of a Configuration are created in
+type ClusterReference struct {
+	// KubeconfigSecretRef is the key of the Secret in the namespace of the Configuration which keeps the kubeconfig of
+	// the cluster. The Secret must be kept until the Configuration is deleted, as the destroy Job runs in the cluster.
+	// The credentials and the certificates must be inline, as the exec plugins, the auth providers and the files aren't
+	// allowed.
+	KubeconfigSecretRef corev1.SecretKeySelector `json:"kubeconfigSecretRef"`
+
+	// Namespace is the namespace in the cluster where the sub-resources are created, which takes the place of the
+	// execution namespace. It must exist along with the ServiceAccount of the Jobs, and can't be changed afterwards.
+	Namespace string `json:"namespace"`
 }
 
 // ConfigurationStatus defines the observed state of Configuration
 type ConfigurationStatus struct {
 	Apply   ConfigurationApplyStatus   `json:"apply,omitempty"`
 	Destroy ConfigurationDestroyStatus `json:"destroy,omitempty"`
+
+	// Summary tells the state of the Configuration in one line, like "Available (us-west-2), 3 outputs, last applied
+	// at 2022-01-02T15:04:05Z", which is updated along with the state of the apply or destroy
+	// +optional
+	Summary string `json:"summary,omitempty"`
+
+	// OutputsCount is the number of the outputs of the Configuration when it's available
+	// +optional
+	OutputsCount int `json:"outputsCount,omitempty"`
+
+	// RemoteGitCommit is the commit of the remote git repo which is being applied or has been applied when spec.remote
+	// is set
+	// +optional
+	RemoteGitCommit string `json:"remoteGitCommit,omitempty"`
+
+	// ExecutionNamespace is the namespace of the Terraform Jobs, the state and the other sub-resources, which is
+	// resolved the first time the Configuration is reconciled
+	// +optional
+	ExecutionNamespace string `json:"executionNamespace,omitempty"`
+
+	// Region is the effective region of the Provider, which is spec.region if it's set, or the region of the Provider
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Plan summarizes the changes of the latest apply or destroy
+	// +optional
+	Plan *PlanSummary `json:"plan,omitempty"`
+
+	// Progress is how many of the planned changes the running apply Job has made, which is cleared once it finishes
+	// +optional
+	Progress *ApplyProgress `json:"progress,omitempty"`
+
+	// LastApplied records the latest successful apply, which tells whether the cloud resources are up to date after
+	// the apply Job is cleaned up
+	// +optional
+	LastApplied *AppliedRecord `json:"lastApplied,omitempty"`
+
+	// LastSuccessfulOutputs are the outputs of the latest successful apply, which are kept while the following applies
+	// are running or failing. The values of sensitive outputs are redacted as in apply.outputs.
+	// +optional
+	LastSuccessfulOutputs map[string]Property `json:"lastSuccessfulOutputs,omitempty"`
+
+	// LastSuccessfulOutputsTime is when LastSuccessfulOutputs were read
+	// +optional
+	LastSuccessfulOutputsTime *metav1.Time `json:"lastSuccessfulOutputsTime,omitempty"`
+
+	// DesiredInputs are the hashes of the current inputs. Changes are pending when they differ from the ones of
+	// LastApplied.
+	// +optional
+	DesiredInputs *InputsHashes `json:"desiredInputs,omitempty"`
+
+	// StateRemoval records the latest removal of resources from the state requested by the state-rm annotation
+	// +optional
+	StateRemoval *StateRemovalRecord `json:"stateRemoval,omitempty"`
+
+	// Refresh records the latest refresh of the state and the outputs requested by the refresh annotation
+	// +optional
+	Refresh *RefreshRecord `json:"refresh,omitempty"`
+
+	// CredentialsPendingSince is when the Provider or the Secret of its credentials was found missing, which is waited
+	// for in a grace period before the Configuration turns ProviderNotReady
+	// +optional
+	CredentialsPendingSince *metav1.Time `json:"credentialsPendingSince,omitempty"`
+
+	// InputsChange records the latest change of the variables which made a Job re-run
+	// +optional
+	InputsChange *InputsChangeRecord `json:"inputsChange,omitempty"`
+
+	// Backend is the backend which the state is stored in. When spec.backend is changed, the state is migrated from it
+	// to the new backend before the next apply or destroy, and it's switched once the migration succeeds.
+	// +optional
+	Backend *Backend `json:"backend,omitempty"`
+
+	// StateMigration records the latest migration of the state between backends
+	// +optional
+	StateMigration *StateMigrationRecord `json:"stateMigration,omitempty"`
+
+	// Conditions are the latest observations of the Configuration, following the Kubernetes conditions convention
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// PlanSummary is how many resources a plan adds, changes and destroys, which is parsed from the output of Terraform
+type PlanSummary struct {
+	// Add is the number of resources to add
+	Add int `json:"add"`
+	// Change is the number of resources to change
+	Change int `json:"change"`
+	// Destroy is the number of resources to destroy
+	Destroy int `json:"destroy"`
+	// NoChanges is true when the infrastructure already matches the configuration
+	// +optional
+	NoChanges bool `json:"noChanges,omitempty"`
+	// Hash of the plan waiting for approval when spec.requireApproval is set
+	// +optional
+	Hash string `json:"hash,omitempty"`
+}
+
+// ApplyProgress is the progress of the running apply Job, which is parsed from its logs
+type ApplyProgress struct {
+	// Completed is the number of the resources which have been created, changed or destroyed
+	Completed int `json:"completed"`
+	// Total is the number of the resources which the plan creates, changes or destroys
+	Total int `json:"total"`
+	// UpdateTime is when the progress was observed to change
+	UpdateTime metav1.Time `json:"updateTime"`
+}
+
+// ConditionType is the type of a Condition
+type ConditionType string
+
+const (
+	// ConditionReady is True only when the configuration is applied and the outputs are generated, which enables
+	// `kubectl wait --for=condition=Ready`
+	ConditionReady ConditionType = "Ready"
+	// ConditionApplied is the condition of applying the configuration
+	ConditionApplied ConditionType = "Applied"
+	// ConditionDestroyed is the condition of destroying the configuration
+	ConditionDestroyed ConditionType = "Destroyed"
+	// ConditionPaused is the condition of whether the reconciliation of the configuration is paused
+	ConditionPaused ConditionType = "Paused"
+	// ConditionReconcileTimedOut is True when the latest reconciliation of the configuration didn't finish in time,
+	// like when its backend is slow or unreachable
+	ConditionReconcileTimedOut ConditionType = "ReconcileTimedOut"
+	// ConditionDestroyTimedOut is True when the destroy of the deleted configuration didn't succeed in
+	// spec.destroyTimeout, whose reason tells whether it's force deleted
+	ConditionDestroyTimedOut ConditionType = "DestroyTimedOut"
+)
+
+// Condition is an observation of the Configuration
+type Condition struct {
+	// Type of the condition
+	Type ConditionType `json:"type"`
+	// Status of the condition, one of True, False or Unknown
+	Status corev1.ConditionStatus `json:"status"`
+	// Reason is the state of the Configuration when the condition transitioned
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is the human readable detail of the transition
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is the last time the status or the reason of the condition changed
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// SetCondition sets the condition of the same type. LastTransitionTime is kept unless the status or the reason of the
+// condition changes.
+func (s *ConfigurationStatus) SetCondition(c Condition) {
+	for i, existing := range s.Conditions {
+		if existing.Type != c.Type {
+			continue
+		}
+		if existing.Status == c.Status && existing.Reason == c.Reason {
+			c.LastTransitionTime = existing.LastTransitionTime
+		}
+		s.Conditions[i] = c
+		return
+	}
+	s.Conditions = append(s.Conditions, c)
+}
+
+// GetCondition returns the condition of the type, or nil if it's not set
+func (s *ConfigurationStatus) GetCondition(t ConditionType) *Condition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
 }
 
 // ConfigurationApplyStatus is the status for Configuration apply
 type ConfigurationApplyStatus struct {
 	State   state.ConfigurationState `json:"state,omitempty"`
 	Message string                   `json:"message,omitempty"`
-	Outputs map[string]Property      `json:"outputs,omitempty"`
+	// Reason classifies the failure when the state is a failed one
+	// +kubebuilder:validation:Enum=InvalidConfiguration;CredentialError;Throttled;QuotaExceeded;ResourceAlreadyExists;DependencyViolation;ImportFailed;Unknown
+	// +optional
+	Reason  state.FailureReason `json:"reason,omitempty"`
+	Outputs map[string]Property `json:"outputs,omitempty"`
+	// JobTimes tells when the latest apply Job ran
+	JobTimes `json:",inline"`
+	// LastAppliedTime is when the latest successful apply completed
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+}
+
+// JobTimes tells when the latest Job of the apply or destroy ran
+type JobTimes struct {
+	// StartTime is when the Job started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when the Job completed successfully, which is unset while it's running
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Duration is how long the Job took to complete
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// AppliedRecord records a successful apply
+type AppliedRecord struct {
+	// Time is when the apply Job completed
+	Time metav1.Time `json:"time"`
+	// InputsHashes are the hashes of the inputs which were applied
+	InputsHashes `json:",inline"`
+}
+
+// InputsHashes are the hashes of the inputs of an apply, which tell whether and what changed between two applies
+type InputsHashes struct {
+	// InputsHash is the hash of all the inputs
+	InputsHash string `json:"inputsHash"`
+	// ConfigurationHash is the hash of the rendered configuration, along with the commit of the remote git repo
+	// +optional
+	ConfigurationHash string `json:"configurationHash,omitempty"`
+	// VariablesHash is the hash of the variables
+	// +optional
+	VariablesHash string `json:"variablesHash,omitempty"`
+	// CredentialsHash is the hash of the credentials of the provider, along with the other environment variables like
+	// the ones of the backend
+	// +optional
+	CredentialsHash string `json:"credentialsHash,omitempty"`
+}
+
+// StateRemovalRecord records a removal of resources from the state
+type StateRemovalRecord struct {
+	// Addresses are the addresses of the resources which are removed from the state
+	Addresses []string `json:"addresses"`
+	// Time is when the removal finished
+	Time metav1.Time `json:"time"`
+	// Succeeded tells whether the removal succeeded
+	Succeeded bool `json:"succeeded"`
+	// Message is the error when the removal failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RefreshRecord records a refresh of the state and the outputs
+type RefreshRecord struct {
+	// Time is when the refresh finished
+	Time metav1.Time `json:"time"`
+	// Succeeded tells whether the refresh succeeded
+	Succeeded bool `json:"succeeded"`
+	// Message is the error when the refresh failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DestroyStage is a stage of the destroy, which destroys the resources along with the ones depending on them
+type DestroyStage struct {
+	// Targets are the addresses of the resources, like `alicloud_vpc.main` or `module.network`
+	// +kubebuilder:validation:MinItems=1
+	Targets []string `json:"targets"`
+}
+
+// StateMigrationRecord records a migration of the state from one backend to another
+type StateMigrationRecord struct {
+	// From is the type of the backend which the state is migrated from
+	From string `json:"from"`
+	// To is the type of the backend which the state is migrated to
+	To string `json:"to"`
+	// Time is when the migration finished
+	Time metav1.Time `json:"time"`
+	// Succeeded tells whether the migration succeeded
+	Succeeded bool `json:"succeeded"`
+	// Message is the error when the migration failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// InputsChangeRecord records the variables which changed since a Job was created. Only their names are recorded, as
+// the values may be sensitive.
+type InputsChangeRecord struct {
+	// Job is the name of the Job which is re-created for the change
+	Job string `json:"job"`
+	// Time is when the change was detected
+	Time metav1.Time `json:"time"`
+	// Added are the names of the variables which are added
+	// +optional
+	Added []string `json:"added,omitempty"`
+	// Removed are the names of the variables which are removed
+	// +optional
+	Removed []string `json:"removed,omitempty"`
+	// Changed are the names of the variables whose values are changed
+	// +optional
+	Changed []string `json:"changed,omitempty"`
 }
 
 // ConfigurationDestroyStatus is the status for Configuration destroy
 type ConfigurationDestroyStatus struct {
 	State   state.ConfigurationState `json:"state,omitempty"`
 	Message string                   `json:"message,omitempty"`
+	// Reason classifies the failure when the state is a failed one
+	// +kubebuilder:validation:Enum=InvalidConfiguration;CredentialError;Throttled;QuotaExceeded;ResourceAlreadyExists;DependencyViolation;ImportFailed;Unknown
+	// +optional
+	Reason state.FailureReason `json:"reason,omitempty"`
+	// JobTimes tells when the latest destroy Job ran
+	JobTimes `json:",inline"`
+	// CompletedStages is how many of spec.destroyStages have been destroyed, which is reset once the Configuration is
+	// applied again
+	// +optional
+	CompletedStages int `json:"completedStages,omitempty"`
 }
 
 // Property is the property for an output
 type Property struct {
+	// Value is the value of the output. Values of complex types, like list, map and object, are encoded in JSON.
 	Value string `json:"value,omitempty"`
-	Type  string `json:"type,omitempty"`
+	// Type is the Terraform type of the output, like `string` or `["list","string"]` for complex types.
+	Type string `json:"type,omitempty"`
+	// Sensitive marks the output as sensitive in Terraform. The value of a sensitive output is only written to the
+	// connection secret, and is redacted in the status.
+	Sensitive bool `json:"sensitive,omitempty"`
 }
 
-// Backend stores the state in a Kubernetes secret with locking done using a Lease resource.
+// ConnectionSecretReference is the Secret which the outputs are written to
+type ConnectionSecretReference struct {
+	types.SecretReference `json:",inline"`
+
+	// Keys are the outputs written to the Secret, like the endpoint and the password. All the outputs are written if
+	// it's not set. The outputs not written still appear in the status, where the sensitive ones are redacted.
+	// +optional
+	Keys []string `json:"keys,omitempty"`
+
+	// KeyMapping renames the outputs to the keys of the Secret expected by the consumers, like password to
+	// DB_PASSWORD. The outputs not mapped are written as they're named.
+	// +optional
+	KeyMapping map[string]string `json:"keyMapping,omitempty"`
+}
+
+// SecretKey returns the key of the Secret which an output is written to
+func (r *ConnectionSecretReference) SecretKey(output string) string {
+	if key, ok := r.KeyMapping[output]; ok {
+		return key
+	}
+	return output
+}
+
+// ConfigMapReference is a reference to a ConfigMap in an arbitrary namespace
+type ConfigMapReference struct {
+	// Name of the ConfigMap
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// OCISource is a Terraform module packaged as an OCI artifact, like by `oras push`
+type OCISource struct {
+	// Artifact is the reference of the artifact pinned by a tag or a digest, like
+	// registry.example.com/modules/vpc:v1.2.0. The configuration is applied again when it's changed, but not when the
+	// tag is moved to another artifact, so pin it by a digest to be sure what's applied.
+	Artifact string `json:"artifact"`
+
+	// Path is the directory of the configuration in the artifact, relative to its root. Defaults to the root.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// PullSecretName is a Secret of the type kubernetes.io/dockerconfigjson to authenticate to the registry, like an
+	// imagePullSecret. It must be in the execution namespace.
+	// +optional
+	PullSecretName string `json:"pullSecretName,omitempty"`
+}
+
+// SourceVerification is the trust policy of the remote git repo or the OCI artifact
+type SourceVerification struct {
+	// TrustedKeysSecretName is a Secret in the execution namespace with the trusted keys. The key `allowed_signers`
+	// lists the SSH keys trusted to sign the commits of the remote git repo in the format of ssh-keygen, and the key
+	// `cosign.pub` is the public key trusted to sign the OCI artifact by cosign. An OCI artifact to verify must be pinned
+	// by a digest, so that the verified artifact is the one pulled.
+	TrustedKeysSecretName string `json:"trustedKeysSecretName"`
+}
+
+// VarFileSource is a .tfvars or .tfvars.json file in a ConfigMap or a Secret, only one of them could be set. The key
+// of the file must have one of the suffixes, which tells Terraform how to parse it.
+type VarFileSource struct {
+	// ConfigMapKeyRef selects the file in a ConfigMap
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// SecretKeyRef selects the file in a Secret
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// ExecutorVolume is a Secret or ConfigMap mounted into the Terraform executor, only one of them could be set
+type ExecutorVolume struct {
+	// Name of the volume, which can't be the same as the volumes reserved by the controller
+	Name string `json:"name"`
+	// MountPath is the absolute path in the executor container where the volume is mounted read-only
+	MountPath string `json:"mountPath"`
+	// SubPath is the path in the volume to mount, instead of its root
+	// +optional
+	SubPath string `json:"subPath,omitempty"`
+	// Secret to mount
+	// +optional
+	Secret *corev1.SecretVolumeSource `json:"secret,omitempty"`
+	// ConfigMap to mount
+	// +optional
+	ConfigMap *corev1.ConfigMapVolumeSource `json:"configMap,omitempty"`
+}
+
+// EnvVar is an environment variable of the Terraform Jobs
+type EnvVar struct {
+	// Name of the environment variable
+	Name string `json:"name"`
+	// Value of the environment variable
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+// WorkingVolume configures the emptyDir volumes of the Terraform executor
+type WorkingVolume struct {
+	// Medium of the volumes. Memory makes them backed by tmpfs, whose usage counts against the memory limit of the
+	// executor. The storage of the node is used by default.
+	// +kubebuilder:validation:Enum="";Memory
+	// +optional
+	Medium corev1.StorageMedium `json:"medium,omitempty"`
+	// SizeLimit is the maximum size of each volume, beyond which the executor Pod is evicted
+	// +optional
+	SizeLimit *resource.Quantity `json:"sizeLimit,omitempty"`
+}
+
+// AliasedProviderReference references a Provider by an alias
+type AliasedProviderReference struct {
+	// Alias of the Provider, which prefixes the variables of its credentials
+	Alias string `json:"alias"`
+	// Name of the Provider
+	Name string `json:"name"`
+	// Namespace of the Provider, which defaults to `default` like ProviderReference
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Region overrides the region of the Provider, like spec.region does for ProviderReference
+	// +optional
+	Region string `json:"region,omitempty"`
+}
+
+// ConfigurationReference references a Configuration
+type ConfigurationReference struct {
+	// Name of the Configuration
+	Name string `json:"name"`
+	// Namespace of the Configuration, which is the namespace of the referencing Configuration by default
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ResourceImport imports an existing cloud resource into the state
+type ResourceImport struct {
+	// Address is the address of the resource in the configuration, like `alicloud_vpc.main`
+	Address string `json:"address"`
+	// ID is the ID of the resource in the cloud
+	ID string `json:"id"`
+}
+
+// VariableFromOutput sets a variable to an output of another Configuration
+type VariableFromOutput struct {
+	// Configuration is the name of the Configuration which produces the output
+	Configuration string `json:"configuration"`
+	// Namespace of the Configuration, which can only be the namespace of the referencing Configuration, as the
+	// outputs of the Configurations in other namespaces can't be read
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Output is the name of the output. The value of a sensitive output is read from the connection secret of the
+	// Configuration, which must be set.
+	Output string `json:"output"`
+	// Var is the name of the variable
+	Var string `json:"var"`
+}
+
+// Hook is a container which runs after the configuration is applied
+type Hook struct {
+	// Name of the hook container
+	Name string `json:"name"`
+	// Image of the hook container
+	Image string `json:"image"`
+	// Command is the entrypoint array of the hook container
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments to the entrypoint
+	// +optional
+	Args []string `json:"args,omitempty"`
+}
+
+// Backend is where the Terraform state is stored. By default, it stores the state in a Kubernetes secret with locking
+// done using a Lease resource.
 type Backend struct {
 	// SecretSuffix used when creating secrets. Secrets will be named in the format: tfstate-{workspace}-{secretSuffix}
 	SecretSuffix string `json:"secretSuffix,omitempty"`
 	// InClusterConfig Used to authenticate to the cluster from inside a pod. Only `true` is allowed
 	InClusterConfig bool `json:"inClusterConfig,omitempty"`
+	// Encryption encrypts the state stored by the kubernetes backend. It relies on the state encryption of OpenTofu, so
+	// it requires the `tofu` engine, and the outputs are collected with `tofu output -json` as the controller can't
+	// read the encrypted state.
+	// +optional
+	Encryption *StateEncryption `json:"encryption,omitempty"`
+	// BackendType is the type of the backend, which is `kubernetes` by default
+	// +kubebuilder:validation:Enum=kubernetes;azurerm;http;custom
+	// +optional
+	BackendType string `json:"backendType,omitempty"`
+	// AzureRM stores the state in a blob of an Azure Storage Account container, which is required when BackendType
+	// is `azurerm`
+	// +optional
+	AzureRM *AzureRMBackend `json:"azurerm,omitempty"`
+	// HTTP stores the state with a REST client, which is required when BackendType is `http`
+	// +optional
+	HTTP *HTTPBackend `json:"http,omitempty"`
+	// Custom is a raw backend block, like `backend "s3" { ... }`, which is injected into the `terraform` block
+	// verbatim when BackendType is `custom`. The state is managed externally and not read by the controller, so the
+	// outputs are collected by the apply Job with `terraform output -json`, which are limited to 4KiB.
+	// +optional
+	Custom string `json:"custom,omitempty"`
+}
+
+// StateEncryption encrypts the state with AES-GCM, with a key derived from a passphrase by PBKDF2. The existing
+// unencrypted state is still readable, and is encrypted when it's written next time.
+type StateEncryption struct {
+	// PassphraseSecretRef references the key of a Secret storing the passphrase, which has at least 16 characters.
+	// The Secret is in the namespace of the Configuration if the namespace isn't set.
+	PassphraseSecretRef types.SecretKeySelector `json:"passphraseSecretRef"`
+}
+
+// AzureRMBackend stores the state as a blob in an Azure Storage Account container. It's accessed with the access key
+// of the storage account, which is `armAccessKey` in the credentials of the Azure Provider, or the managed identity
+// if UseMSI is true.
+type AzureRMBackend struct {
+	// StorageAccountName is the name of the storage account
+	StorageAccountName string `json:"storageAccountName"`
+	// ContainerName is the name of the container in the storage account
+	ContainerName string `json:"containerName"`
+	// Key is the name of the blob storing the state, which is {namespace}-{name}.tfstate by default
+	// +optional
+	Key string `json:"key,omitempty"`
+	// UseMSI authenticates with the managed identity rather than the access key
+	// +optional
+	UseMSI bool `json:"useMSI,omitempty"`
+}
+
+// HTTPBackend stores the state with a generic HTTP state server, like the GitLab-managed Terraform state
+type HTTPBackend struct {
+	// Address is the URL of the state
+	Address string `json:"address"`
+	// LockAddress is the URL to lock the state, and locking is disabled if not set
+	// +optional
+	LockAddress string `json:"lockAddress,omitempty"`
+	// UnlockAddress is the URL to unlock the state
+	// +optional
+	UnlockAddress string `json:"unlockAddress,omitempty"`
+	// LockMethod is the HTTP method of locking, which is LOCK by default
+	// +optional
+	LockMethod string `json:"lockMethod,omitempty"`
+	// UnlockMethod is the HTTP method of unlocking, which is UNLOCK by default
+	// +optional
+	UnlockMethod string `json:"unlockMethod,omitempty"`
+	// CredentialsSecretRef references the Secret with the keys `username` and `password` for the basic authentication.
+	// The Secret is in the namespace of the Configuration if the namespace isn't set.
+	// +optional
+	CredentialsSecretRef *types.SecretReference `json:"credentialsSecretRef,omitempty"`
+	// DeleteOnCleanUp deletes the state with the DELETE method after the cloud resources are destroyed
+	// +optional
+	DeleteOnCleanUp bool `json:"deleteOnCleanUp,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -91,7 +844,12 @@ type Backend struct {
 // Configuration is the Schema for the configurations API
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="STATE",type="string",JSONPath=".status.apply.state"
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="PROVIDER",type="string",JSONPath=".spec.providerRef.name",priority=1
+// +kubebuilder:printcolumn:name="LAST-APPLIED",type="date",JSONPath=".status.apply.lastAppliedTime",priority=1
+// +kubebuilder:printcolumn:name="REGION",type="string",JSONPath=".status.region",priority=1
+// +kubebuilder:printcolumn:name="SUMMARY",type="string",JSONPath=".status.summary",priority=1
 type Configuration struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -35,27 +35,70 @@ type ProviderSpec struct {
 	Credentials ProviderCredentials `json:"credentials"`
 }
 
+// CredentialsSourceSecretStore indicates that a provider should acquire credentials from a secret store outside of
+// Kubernetes, like HashiCorp Vault.
+const CredentialsSourceSecretStore crossplanetypes.CredentialsSource = "SecretStore"
+
 // ProviderCredentials required to authenticate.
 type ProviderCredentials struct {
-	// Source of the provider credentials.
-	// +kubebuilder:validation:Enum=None;Secret;InjectedIdentity;Environment;Filesystem
+	// Source of the provider credentials. The credentials of the sources InjectedIdentity and None aren't injected into
+	// the Terraform Jobs, which rely on the identity of their pods, like IRSA of AWS or Workload Identity of GCP.
+	// +kubebuilder:validation:Enum=None;Secret;InjectedIdentity;Environment;Filesystem;SecretStore
 	Source crossplanetypes.CredentialsSource `json:"source"`
 
 	// A SecretRef is a reference to a secret key that contains the credentials
 	// that must be used to connect to the provider.
 	// +optional
 	SecretRef *crossplanetypes.SecretKeySelector `json:"secretRef,omitempty"`
+
+	// SecretStoreRef is a reference to a secret in a secret store that contains the credentials, when the source is
+	// SecretStore. The secret is in the same format as the key of SecretRef.
+	// +optional
+	SecretStoreRef *SecretStoreReference `json:"secretStoreRef,omitempty"`
+}
+
+// SecretStoreType is the type of a secret store
+type SecretStoreType string
+
+const (
+	// SecretStoreVault is HashiCorp Vault
+	SecretStoreVault SecretStoreType = "Vault"
+	// SecretStoreAWSSecretsManager is AWS Secrets Manager
+	SecretStoreAWSSecretsManager SecretStoreType = "AWSSecretsManager"
+)
+
+// SecretStoreReference is a reference to a secret in a secret store outside of Kubernetes. The secret stores are
+// configured by the flags of the controller, like the address of Vault, so that a Provider only chooses the secret.
+type SecretStoreReference struct {
+	// Type of the secret store, like Vault or AWSSecretsManager
+	Type SecretStoreType `json:"type"`
+
+	// Path of the secret, like secret/data/terraform/aws of a KV secrets engine of Vault, or the name or the ARN of a
+	// secret of AWS Secrets Manager
+	Path string `json:"path"`
+
+	// Key of the secret whose value is the credentials. The whole secret is the credentials if it's not set.
+	// +optional
+	Key string `json:"key,omitempty"`
 }
 
 // ProviderStatus defines the observed state of Provider.
 type ProviderStatus struct {
 	State   types.ProviderState `json:"state,omitempty"`
 	Message string              `json:"message,omitempty"`
+	// Identity is who the credentials authenticate as, like the ARN of an AWS user or role, which is verified by the
+	// cloud when the verification of the credentials is enabled for the controller
+	// +optional
+	Identity string `json:"identity,omitempty"`
+	// VerifiedTime is when the credentials were verified by the cloud
+	// +optional
+	VerifiedTime *metav1.Time `json:"verifiedTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="STATE",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="IDENTITY",type="string",JSONPath=".status.identity",priority=1
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Provider is the Schema for the providers API.
@@ -22,12 +22,92 @@ package v1beta1
 
 import (
 	crossplane_runtime "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AliasedProviderReference) DeepCopyInto(out *AliasedProviderReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AliasedProviderReference.
+func (in *AliasedProviderReference) DeepCopy() *AliasedProviderReference {
+	if in == nil {
+		return nil
+	}
+	out := new(AliasedProviderReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedRecord) DeepCopyInto(out *AppliedRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	out.InputsHashes = in.InputsHashes
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedRecord.
+func (in *AppliedRecord) DeepCopy() *AppliedRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplyProgress) DeepCopyInto(out *ApplyProgress) {
+	*out = *in
+	in.UpdateTime.DeepCopyInto(&out.UpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplyProgress.
+func (in *ApplyProgress) DeepCopy() *ApplyProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplyProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureRMBackend) DeepCopyInto(out *AzureRMBackend) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureRMBackend.
+func (in *AzureRMBackend) DeepCopy() *AzureRMBackend {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureRMBackend)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Backend) DeepCopyInto(out *Backend) {
 	*out = *in
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(StateEncryption)
+		**out = **in
+	}
+	if in.AzureRM != nil {
+		in, out := &in.AzureRM, &out.AzureRM
+		*out = new(AzureRMBackend)
+		**out = **in
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPBackend)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Backend.
@@ -40,6 +120,53 @@ func (in *Backend) DeepCopy() *Backend {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterReference) DeepCopyInto(out *ClusterReference) {
+	*out = *in
+	in.KubeconfigSecretRef.DeepCopyInto(&out.KubeconfigSecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterReference.
+func (in *ClusterReference) DeepCopy() *ClusterReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
+	if in == nil {
+		return nil
+	}
+	out := new(Condition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapReference.
+func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Configuration) DeepCopyInto(out *Configuration) {
 	*out = *in
@@ -77,6 +204,11 @@ func (in *ConfigurationApplyStatus) DeepCopyInto(out *ConfigurationApplyStatus)
 			(*out)[key] = val
 		}
 	}
+	in.JobTimes.DeepCopyInto(&out.JobTimes)
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationApplyStatus.
@@ -92,6 +224,7 @@ func (in *ConfigurationApplyStatus) DeepCopy() *ConfigurationApplyStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigurationDestroyStatus) DeepCopyInto(out *ConfigurationDestroyStatus) {
 	*out = *in
+	in.JobTimes.DeepCopyInto(&out.JobTimes)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationDestroyStatus.
@@ -136,9 +269,34 @@ func (in *ConfigurationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigurationReference) DeepCopyInto(out *ConfigurationReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationReference.
+func (in *ConfigurationReference) DeepCopy() *ConfigurationReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigurationReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigurationSpec) DeepCopyInto(out *ConfigurationSpec) {
 	*out = *in
+	if in.OCI != nil {
+		in, out := &in.OCI, &out.OCI
+		*out = new(OCISource)
+		**out = **in
+	}
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = new(SourceVerification)
+		**out = **in
+	}
 	if in.Variable != nil {
 		in, out := &in.Variable, &out.Variable
 		*out = new(runtime.RawExtension)
@@ -147,11 +305,16 @@ func (in *ConfigurationSpec) DeepCopyInto(out *ConfigurationSpec) {
 	if in.Backend != nil {
 		in, out := &in.Backend, &out.Backend
 		*out = new(Backend)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.WriteConnectionSecretToReference != nil {
 		in, out := &in.WriteConnectionSecretToReference, &out.WriteConnectionSecretToReference
-		*out = new(crossplane_runtime.SecretReference)
+		*out = new(ConnectionSecretReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WriteOutputsToConfigMapReference != nil {
+		in, out := &in.WriteOutputsToConfigMapReference, &out.WriteOutputsToConfigMapReference
+		*out = new(ConfigMapReference)
 		**out = **in
 	}
 	if in.ProviderReference != nil {
@@ -159,6 +322,115 @@ func (in *ConfigurationSpec) DeepCopyInto(out *ConfigurationSpec) {
 		*out = new(crossplane_runtime.Reference)
 		**out = **in
 	}
+	if in.AliasedProviders != nil {
+		in, out := &in.AliasedProviders, &out.AliasedProviders
+		*out = make([]AliasedProviderReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostApplyHooks != nil {
+		in, out := &in.PostApplyHooks, &out.PostApplyHooks
+		*out = make([]Hook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Imports != nil {
+		in, out := &in.Imports, &out.Imports
+		*out = make([]ResourceImport, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SubResourceLabels != nil {
+		in, out := &in.SubResourceLabels, &out.SubResourceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SubResourceAnnotations != nil {
+		in, out := &in.SubResourceAnnotations, &out.SubResourceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ApplyTimeout != nil {
+		in, out := &in.ApplyTimeout, &out.ApplyTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DestroyTimeout != nil {
+		in, out := &in.DestroyTimeout, &out.DestroyTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.JobDeadline != nil {
+		in, out := &in.JobDeadline, &out.JobDeadline
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DestroyStages != nil {
+		in, out := &in.DestroyStages, &out.DestroyStages
+		*out = make([]DestroyStage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]ConfigurationReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.VariableFrom != nil {
+		in, out := &in.VariableFrom, &out.VariableFrom
+		*out = make([]VariableFromOutput, len(*in))
+		copy(*out, *in)
+	}
+	if in.VarFiles != nil {
+		in, out := &in.VarFiles, &out.VarFiles
+		*out = make([]VarFileSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ApplyInterval != nil {
+		in, out := &in.ApplyInterval, &out.ApplyInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]ExecutorVolume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Entrypoint != nil {
+		in, out := &in.Entrypoint, &out.Entrypoint
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]EnvVar, len(*in))
+		copy(*out, *in)
+	}
+	if in.WorkingVolume != nil {
+		in, out := &in.WorkingVolume, &out.WorkingVolume
+		*out = new(WorkingVolume)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cluster != nil {
+		in, out := &in.Cluster, &out.Cluster
+		*out = new(ClusterReference)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationSpec.
@@ -175,7 +447,74 @@ func (in *ConfigurationSpec) DeepCopy() *ConfigurationSpec {
 func (in *ConfigurationStatus) DeepCopyInto(out *ConfigurationStatus) {
 	*out = *in
 	in.Apply.DeepCopyInto(&out.Apply)
-	out.Destroy = in.Destroy
+	in.Destroy.DeepCopyInto(&out.Destroy)
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(PlanSummary)
+		**out = **in
+	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(ApplyProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastApplied != nil {
+		in, out := &in.LastApplied, &out.LastApplied
+		*out = new(AppliedRecord)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastSuccessfulOutputs != nil {
+		in, out := &in.LastSuccessfulOutputs, &out.LastSuccessfulOutputs
+		*out = make(map[string]Property, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastSuccessfulOutputsTime != nil {
+		in, out := &in.LastSuccessfulOutputsTime, &out.LastSuccessfulOutputsTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DesiredInputs != nil {
+		in, out := &in.DesiredInputs, &out.DesiredInputs
+		*out = new(InputsHashes)
+		**out = **in
+	}
+	if in.StateRemoval != nil {
+		in, out := &in.StateRemoval, &out.StateRemoval
+		*out = new(StateRemovalRecord)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Refresh != nil {
+		in, out := &in.Refresh, &out.Refresh
+		*out = new(RefreshRecord)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CredentialsPendingSince != nil {
+		in, out := &in.CredentialsPendingSince, &out.CredentialsPendingSince
+		*out = (*in).DeepCopy()
+	}
+	if in.InputsChange != nil {
+		in, out := &in.InputsChange, &out.InputsChange
+		*out = new(InputsChangeRecord)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Backend != nil {
+		in, out := &in.Backend, &out.Backend
+		*out = new(Backend)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StateMigration != nil {
+		in, out := &in.StateMigration, &out.StateMigration
+		*out = new(StateMigrationRecord)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationStatus.
@@ -188,6 +527,243 @@ func (in *ConfigurationStatus) DeepCopy() *ConfigurationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionSecretReference) DeepCopyInto(out *ConnectionSecretReference) {
+	*out = *in
+	out.SecretReference = in.SecretReference
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeyMapping != nil {
+		in, out := &in.KeyMapping, &out.KeyMapping
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionSecretReference.
+func (in *ConnectionSecretReference) DeepCopy() *ConnectionSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DestroyStage) DeepCopyInto(out *DestroyStage) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DestroyStage.
+func (in *DestroyStage) DeepCopy() *DestroyStage {
+	if in == nil {
+		return nil
+	}
+	out := new(DestroyStage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvVar) DeepCopyInto(out *EnvVar) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVar.
+func (in *EnvVar) DeepCopy() *EnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutorVolume) DeepCopyInto(out *ExecutorVolume) {
+	*out = *in
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(corev1.SecretVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(corev1.ConfigMapVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutorVolume.
+func (in *ExecutorVolume) DeepCopy() *ExecutorVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutorVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPBackend) DeepCopyInto(out *HTTPBackend) {
+	*out = *in
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(crossplane_runtime.SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPBackend.
+func (in *HTTPBackend) DeepCopy() *HTTPBackend {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPBackend)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hook) DeepCopyInto(out *Hook) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hook.
+func (in *Hook) DeepCopy() *Hook {
+	if in == nil {
+		return nil
+	}
+	out := new(Hook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InputsChangeRecord) DeepCopyInto(out *InputsChangeRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Added != nil {
+		in, out := &in.Added, &out.Added
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Removed != nil {
+		in, out := &in.Removed, &out.Removed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Changed != nil {
+		in, out := &in.Changed, &out.Changed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InputsChangeRecord.
+func (in *InputsChangeRecord) DeepCopy() *InputsChangeRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(InputsChangeRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InputsHashes) DeepCopyInto(out *InputsHashes) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InputsHashes.
+func (in *InputsHashes) DeepCopy() *InputsHashes {
+	if in == nil {
+		return nil
+	}
+	out := new(InputsHashes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobTimes) DeepCopyInto(out *JobTimes) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobTimes.
+func (in *JobTimes) DeepCopy() *JobTimes {
+	if in == nil {
+		return nil
+	}
+	out := new(JobTimes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCISource) DeepCopyInto(out *OCISource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCISource.
+func (in *OCISource) DeepCopy() *OCISource {
+	if in == nil {
+		return nil
+	}
+	out := new(OCISource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanSummary) DeepCopyInto(out *PlanSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanSummary.
+func (in *PlanSummary) DeepCopy() *PlanSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Property) DeepCopyInto(out *Property) {
 	*out = *in
@@ -209,7 +785,7 @@ func (in *Provider) DeepCopyInto(out *Provider) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Provider.
@@ -238,6 +814,11 @@ func (in *ProviderCredentials) DeepCopyInto(out *ProviderCredentials) {
 		*out = new(crossplane_runtime.SecretKeySelector)
 		**out = **in
 	}
+	if in.SecretStoreRef != nil {
+		in, out := &in.SecretStoreRef, &out.SecretStoreRef
+		*out = new(SecretStoreReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderCredentials.
@@ -301,6 +882,10 @@ func (in *ProviderSpec) DeepCopy() *ProviderSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProviderStatus) DeepCopyInto(out *ProviderStatus) {
 	*out = *in
+	if in.VerifiedTime != nil {
+		in, out := &in.VerifiedTime, &out.VerifiedTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderStatus.
@@ -312,3 +897,177 @@ func (in *ProviderStatus) DeepCopy() *ProviderStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RefreshRecord) DeepCopyInto(out *RefreshRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RefreshRecord.
+func (in *RefreshRecord) DeepCopy() *RefreshRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(RefreshRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceImport) DeepCopyInto(out *ResourceImport) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceImport.
+func (in *ResourceImport) DeepCopy() *ResourceImport {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceImport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretStoreReference) DeepCopyInto(out *SecretStoreReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretStoreReference.
+func (in *SecretStoreReference) DeepCopy() *SecretStoreReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretStoreReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SourceVerification) DeepCopyInto(out *SourceVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourceVerification.
+func (in *SourceVerification) DeepCopy() *SourceVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(SourceVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateEncryption) DeepCopyInto(out *StateEncryption) {
+	*out = *in
+	out.PassphraseSecretRef = in.PassphraseSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateEncryption.
+func (in *StateEncryption) DeepCopy() *StateEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(StateEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateMigrationRecord) DeepCopyInto(out *StateMigrationRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMigrationRecord.
+func (in *StateMigrationRecord) DeepCopy() *StateMigrationRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(StateMigrationRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateRemovalRecord) DeepCopyInto(out *StateRemovalRecord) {
+	*out = *in
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateRemovalRecord.
+func (in *StateRemovalRecord) DeepCopy() *StateRemovalRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(StateRemovalRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VarFileSource) DeepCopyInto(out *VarFileSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VarFileSource.
+func (in *VarFileSource) DeepCopy() *VarFileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(VarFileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VariableFromOutput) DeepCopyInto(out *VariableFromOutput) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VariableFromOutput.
+func (in *VariableFromOutput) DeepCopy() *VariableFromOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(VariableFromOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkingVolume) DeepCopyInto(out *WorkingVolume) {
+	*out = *in
+	if in.SizeLimit != nil {
+		in, out := &in.SizeLimit, &out.SizeLimit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkingVolume.
+func (in *WorkingVolume) DeepCopy() *WorkingVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkingVolume)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The runner binary runs the steps of the Terraform Jobs composed by the controller, so that the executor image
+// doesn't need a shell. It's shipped in the image of the controller, and installed to the Jobs by an init container.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/oam-dev/terraform-controller/controllers/runner"
+)
+
+const usage = `Usage:
+  runner install <path>   copies the runner binary to the path
+  runner run <steps>      runs the steps in JSON`
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "install":
+		err = runner.Install(os.Args[2])
+	case "run":
+		var steps []runner.Step
+		if err = json.Unmarshal([]byte(os.Args[2]), &steps); err != nil {
+			break
+		}
+		// the runner is PID 1 of the container, which receives SIGTERM when the pod is evicted
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+		err = (&runner.Runner{Stdout: os.Stdout, Stderr: os.Stderr, Signals: signals}).Run(steps)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		// a failed command has logged its own error
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(runner.ExitCode(err))
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+const (
+	// envARMAccessKey is the access key of the storage account in the credentials of the Azure Provider, which the
+	// azurerm backend of Terraform picks up from the environment variable of the same name
+	envARMAccessKey = "ARM_ACCESS_KEY"
+
+	azureStorageAPIVersion = "2020-04-08"
+	azureStorageResource   = "https://storage.azure.com/"
+	azureMSITokenEndpoint  = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+var azureHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// azureRMBackend stores the state as a blob in an Azure Storage Account container. For detailed information, please
+// refer to https://www.terraform.io/language/settings/backends/azurerm
+type azureRMBackend struct {
+	conf        *v1beta1.AzureRMBackend
+	credentials map[string]string
+	// endpoint and msiEndpoint are only overridden in tests
+	endpoint    string
+	msiEndpoint string
+}
+
+func (b *azureRMBackend) HCL() (string, error) {
+	var sb strings.Builder
+	sb.WriteString("\nterraform {\n  backend \"azurerm\" {\n")
+	fmt.Fprintf(&sb, "    storage_account_name = %q\n", b.conf.StorageAccountName)
+	fmt.Fprintf(&sb, "    container_name       = %q\n", b.conf.ContainerName)
+	fmt.Fprintf(&sb, "    key                  = %q\n", b.conf.Key)
+	if b.conf.UseMSI {
+		sb.WriteString("    use_msi              = true\n")
+		sb.WriteString("    use_azuread_auth     = true\n")
+	}
+	sb.WriteString("  }\n}\n")
+	return sb.String(), nil
+}
+
+func (b *azureRMBackend) Envs(_ context.Context) (map[string]string, error) {
+	return nil, nil
+}
+
+func (b *azureRMBackend) GetTFStateJSON(ctx context.Context) ([]byte, error) {
+	resp, err := b.do(ctx, http.MethodGet)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get Terraform state blob")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read Terraform state blob")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to get Terraform state blob %s/%s: %s", b.conf.ContainerName, b.conf.Key, resp.Status)
+	}
+	return data, nil
+}
+
+func (b *azureRMBackend) CleanUp(ctx context.Context) error {
+	resp, err := b.do(ctx, http.MethodDelete)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete Terraform state blob")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNotFound {
+		return errors.Errorf("failed to delete Terraform state blob %s/%s: %s", b.conf.ContainerName, b.conf.Key, resp.Status)
+	}
+	return nil
+}
+
+// do sends a request to the state blob, authorized with the access key of the storage account, or the token of the
+// managed identity
+func (b *azureRMBackend) do(ctx context.Context, method string) (*http.Response, error) {
+	endpoint := b.endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", b.conf.StorageAccountName)
+	}
+	path := (&url.URL{Path: "/" + b.conf.ContainerName + "/" + b.conf.Key}).EscapedPath()
+	req, err := http.NewRequest(method, endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureStorageAPIVersion)
+
+	switch {
+	case b.conf.UseMSI:
+		token, err := b.msiToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case b.credentials[envARMAccessKey] != "":
+		signature, err := signSharedKey(req, b.conf.StorageAccountName, path, b.credentials[envARMAccessKey])
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", b.conf.StorageAccountName, signature))
+	default:
+		return nil, errors.New("neither armAccessKey is set in the credentials of the Provider nor useMSI is enabled")
+	}
+	return azureHTTPClient.Do(req)
+}
+
+// signSharedKey signs a request without body or query parameters with the access key of the storage account. For
+// detailed information, please refer to
+// https://docs.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func signSharedKey(req *http.Request, account, escapedPath, accessKey string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(accessKey)
+	if err != nil {
+		return "", errors.Wrap(err, "the access key of the storage account is not base64 encoded")
+	}
+
+	var msHeaders []string
+	for name := range req.Header {
+		if name = strings.ToLower(name); strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name)
+		}
+	}
+	sort.Strings(msHeaders)
+	var canonicalizedHeaders strings.Builder
+	for _, name := range msHeaders {
+		fmt.Fprintf(&canonicalizedHeaders, "%s:%s\n", name, req.Header.Get(name))
+	}
+
+	// VERB, then Content-Encoding, Content-Language, Content-Length, Content-MD5, Content-Type, Date,
+	// If-Modified-Since, If-Match, If-None-Match, If-Unmodified-Since and Range, which are all empty
+	stringToSign := req.Method + strings.Repeat("\n", 12) + canonicalizedHeaders.String() + "/" + account + escapedPath
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(stringToSign)) //nolint:errcheck
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// msiToken gets a token to access the storage from the managed identity of the controller
+func (b *azureRMBackend) msiToken(ctx context.Context) (string, error) {
+	endpoint := b.msiEndpoint
+	if endpoint == "" {
+		endpoint = azureMSITokenEndpoint
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint+"?api-version=2018-02-01&resource="+url.QueryEscape(azureStorageResource), nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Metadata", "true")
+	resp, err := azureHTTPClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the token of the managed identity")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("failed to get the token of the managed identity: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", errors.Wrap(err, "failed to decode the token of the managed identity")
+	}
+	return token.AccessToken, nil
+}
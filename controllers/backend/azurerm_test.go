@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestAzureRMBackendHCL(t *testing.T) {
+	conf := &v1beta1.Backend{
+		BackendType: TypeAzureRM,
+		AzureRM:     &v1beta1.AzureRMBackend{StorageAccountName: "tfstate", ContainerName: "states", Key: "default-oss.tfstate", UseMSI: true},
+	}
+	b, err := New(nil, conf, "vela-system", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hcl, err := b.HCL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`backend "azurerm"`, `storage_account_name = "tfstate"`, `key                  = "default-oss.tfstate"`, "use_msi"} {
+		if !strings.Contains(hcl, expected) {
+			t.Errorf("expected %q in the backend block:\n%s", expected, hcl)
+		}
+	}
+
+	if _, err := New(nil, &v1beta1.Backend{BackendType: TypeAzureRM}, "vela-system", nil); err == nil {
+		t.Error("expected an error without the azurerm configuration")
+	}
+}
+
+func TestAzureRMBackendState(t *testing.T) {
+	accessKey := base64.StdEncoding.EncodeToString([]byte("secret"))
+	state := `{"version": 4, "outputs": {"name": {"value": "oss", "type": "string"}}}`
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/states/default/oss.tfstate" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		signature, err := signSharedKey(r, "tfstate", r.URL.EscapedPath(), accessKey)
+		if err != nil || r.Header.Get("Authorization") != "SharedKey tfstate:"+signature {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(state)) //nolint:errcheck
+		case http.MethodDelete:
+			deleted = true
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	b := &azureRMBackend{
+		conf:        &v1beta1.AzureRMBackend{StorageAccountName: "tfstate", ContainerName: "states", Key: "default/oss.tfstate"},
+		credentials: map[string]string{envARMAccessKey: accessKey},
+		endpoint:    server.URL,
+	}
+	ctx := context.Background()
+	data, err := b.GetTFStateJSON(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != state {
+		t.Errorf("expected state %s, got %s", state, data)
+	}
+	if err := b.CleanUp(ctx); err != nil || !deleted {
+		t.Errorf("expected the state blob deleted, got %v", err)
+	}
+
+	b.credentials = map[string]string{envARMAccessKey: base64.StdEncoding.EncodeToString([]byte("wrong"))}
+	if _, err := b.GetTFStateJSON(ctx); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a forbidden error with the wrong access key, got %v", err)
+	}
+
+	b.credentials = nil
+	if _, err := b.GetTFStateJSON(ctx); err == nil || !strings.Contains(err.Error(), "armAccessKey") {
+		t.Errorf("expected an error without credentials, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+const (
+	// TypeKubernetes stores the state in a Kubernetes Secret
+	TypeKubernetes = "kubernetes"
+	// TypeAzureRM stores the state in a blob of an Azure Storage Account container
+	TypeAzureRM = "azurerm"
+	// TypeHTTP stores the state with a generic HTTP state server
+	TypeHTTP = "http"
+	// TypeCustom stores the state in a backend configured by a raw backend block, which the controller doesn't access
+	TypeCustom = "custom"
+)
+
+// ErrStateExternallyManaged means the state is managed externally and can't be read by the controller
+var ErrStateExternallyManaged = errors.New("the Terraform state is managed externally")
+
+// terraformWorkspace is the workspace of Terraform, the state of which is read
+const terraformWorkspace = "default"
+
+// Backend is where the Terraform state of a Configuration is stored
+type Backend interface {
+	// HCL renders the backend block of the Terraform configuration
+	HCL() (string, error)
+	// Envs returns the environment variables the Terraform Job needs to access the backend, besides the credentials
+	// of the Provider
+	Envs(ctx context.Context) (map[string]string, error)
+	// GetTFStateJSON gets the Terraform state in JSON
+	GetTFStateJSON(ctx context.Context) ([]byte, error)
+	// CleanUp removes the Terraform state after the cloud resources are destroyed
+	CleanUp(ctx context.Context) error
+}
+
+// New returns the Backend of a Configuration. The state of the kubernetes backend is stored in the namespace, and the
+// credentials of the Provider are used to access other backends. Rendering the HCL needs neither the client nor the
+// credentials.
+func New(k8sClient client.Client, backend *v1beta1.Backend, namespace string, credentials map[string]string) (Backend, error) {
+	if backend == nil {
+		return nil, errors.New("backend is not set")
+	}
+	switch backend.BackendType {
+	case "", TypeKubernetes:
+		return &kubernetesBackend{client: k8sClient, conf: backend, namespace: namespace}, nil
+	case TypeAzureRM:
+		if backend.AzureRM == nil {
+			return nil, errors.New("spec.backend.azurerm must be set for the azurerm backend")
+		}
+		return &azureRMBackend{conf: backend.AzureRM, credentials: credentials}, nil
+	case TypeHTTP:
+		if backend.HTTP == nil {
+			return nil, errors.New("spec.backend.http must be set for the http backend")
+		}
+		return &httpBackend{client: k8sClient, conf: backend.HTTP}, nil
+	case TypeCustom:
+		if strings.TrimSpace(backend.Custom) == "" {
+			return nil, errors.New("spec.backend.custom must be set for the custom backend")
+		}
+		return &customBackend{block: backend.Custom}, nil
+	default:
+		return nil, errors.Errorf("unsupported backend type %s", backend.BackendType)
+	}
+}
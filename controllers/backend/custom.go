@@ -0,0 +1,44 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"fmt"
+)
+
+// customBackend injects a raw backend block into the configuration. The backend is accessed with the credentials of
+// the Provider in the Job, and the controller never touches the state.
+type customBackend struct {
+	block string
+}
+
+func (b *customBackend) HCL() (string, error) {
+	return fmt.Sprintf("\nterraform {\n%s\n}\n", b.block), nil
+}
+
+func (b *customBackend) Envs(_ context.Context) (map[string]string, error) {
+	return nil, nil
+}
+
+func (b *customBackend) GetTFStateJSON(_ context.Context) ([]byte, error) {
+	return nil, ErrStateExternallyManaged
+}
+
+func (b *customBackend) CleanUp(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+const (
+	// envTFEncryption configures the state encryption of OpenTofu, which keeps the passphrase out of the configuration
+	envTFEncryption = "TF_ENCRYPTION"
+
+	// minPassphraseLength is the minimum length of the passphrase required by the pbkdf2 key provider of OpenTofu
+	minPassphraseLength = 16
+)
+
+// stateEncryptionTemplate encrypts the state with AES-GCM. The unencrypted method is the fallback, so that the state
+// written before the encryption is enabled is still readable.
+var stateEncryptionTemplate = `
+key_provider "pbkdf2" "controller" {
+  passphrase = %q
+}
+method "aes_gcm" "controller" {
+  keys = key_provider.pbkdf2.controller
+}
+method "unencrypted" "migrate" {}
+state {
+  method = method.aes_gcm.controller
+  fallback {
+    method = method.unencrypted.migrate
+  }
+}
+`
+
+// stateEncryptionEnvs returns the environment variable for OpenTofu to encrypt the state
+func stateEncryptionEnvs(ctx context.Context, k8sClient client.Client, encryption *v1beta1.StateEncryption) (map[string]string, error) {
+	ref := encryption.PassphraseSecretRef
+	var secret v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}, &secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to get the passphrase Secret %s/%s of the state encryption", ref.Namespace, ref.Name)
+	}
+	passphrase := string(secret.Data[ref.Key])
+	if len(passphrase) < minPassphraseLength {
+		return nil, errors.Errorf("the passphrase %s in Secret %s/%s must have at least %d characters", ref.Key, ref.Namespace,
+			ref.Name, minPassphraseLength)
+	}
+	// escape the template sequences, which are interpolated in HCL strings
+	passphrase = strings.NewReplacer("${", "$${", "%{", "%%{").Replace(passphrase)
+	return map[string]string{envTFEncryption: fmt.Sprintf(stateEncryptionTemplate, passphrase)}, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+const (
+	// envTFHTTPUsername and envTFHTTPPassword are the credentials the http backend of Terraform picks up
+	envTFHTTPUsername = "TF_HTTP_USERNAME"
+	envTFHTTPPassword = "TF_HTTP_PASSWORD"
+
+	httpCredentialsUsernameKey = "username"
+	httpCredentialsPasswordKey = "password"
+)
+
+var httpStateClient = &http.Client{Timeout: 30 * time.Second}
+
+// httpBackend stores the state with a REST client. For detailed information, please refer to
+// https://www.terraform.io/language/settings/backends/http
+type httpBackend struct {
+	client client.Client
+	conf   *v1beta1.HTTPBackend
+}
+
+func (b *httpBackend) HCL() (string, error) {
+	var sb strings.Builder
+	sb.WriteString("\nterraform {\n  backend \"http\" {\n")
+	fmt.Fprintf(&sb, "    address        = %q\n", b.conf.Address)
+	if b.conf.LockAddress != "" {
+		fmt.Fprintf(&sb, "    lock_address   = %q\n", b.conf.LockAddress)
+	}
+	if b.conf.UnlockAddress != "" {
+		fmt.Fprintf(&sb, "    unlock_address = %q\n", b.conf.UnlockAddress)
+	}
+	if b.conf.LockMethod != "" {
+		fmt.Fprintf(&sb, "    lock_method    = %q\n", b.conf.LockMethod)
+	}
+	if b.conf.UnlockMethod != "" {
+		fmt.Fprintf(&sb, "    unlock_method  = %q\n", b.conf.UnlockMethod)
+	}
+	sb.WriteString("  }\n}\n")
+	return sb.String(), nil
+}
+
+// Envs passes the credentials to the Job as environment variables, so that they're not stored in the configuration
+func (b *httpBackend) Envs(ctx context.Context) (map[string]string, error) {
+	username, password, err := b.getCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if username == "" && password == "" {
+		return nil, nil
+	}
+	return map[string]string{envTFHTTPUsername: username, envTFHTTPPassword: password}, nil
+}
+
+func (b *httpBackend) getCredentials(ctx context.Context) (string, string, error) {
+	ref := b.conf.CredentialsSecretRef
+	if ref == nil {
+		return "", "", nil
+	}
+	var secret v1.Secret
+	if err := b.client.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}, &secret); err != nil {
+		return "", "", errors.Wrapf(err, "failed to get the credentials Secret %s/%s of the http backend", ref.Namespace, ref.Name)
+	}
+	return string(secret.Data[httpCredentialsUsernameKey]), string(secret.Data[httpCredentialsPasswordKey]), nil
+}
+
+func (b *httpBackend) GetTFStateJSON(ctx context.Context) ([]byte, error) {
+	resp, err := b.do(ctx, http.MethodGet)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get Terraform state")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read Terraform state")
+	}
+	// The state server responds with no content or not found if the state doesn't exist yet
+	if resp.StatusCode != http.StatusOK || len(data) == 0 {
+		return nil, errors.Errorf("failed to get Terraform state from %s: %s", b.conf.Address, resp.Status)
+	}
+	return data, nil
+}
+
+func (b *httpBackend) CleanUp(ctx context.Context) error {
+	if !b.conf.DeleteOnCleanUp {
+		return nil
+	}
+	resp, err := b.do(ctx, http.MethodDelete)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete Terraform state")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode >= http.StatusMultipleChoices && resp.StatusCode != http.StatusNotFound {
+		return errors.Errorf("failed to delete Terraform state from %s: %s", b.conf.Address, resp.Status)
+	}
+	return nil
+}
+
+func (b *httpBackend) do(ctx context.Context, method string) (*http.Response, error) {
+	req, err := http.NewRequest(method, b.conf.Address, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	username, password, err := b.getCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+	return httpStateClient.Do(req)
+}
@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestHTTPBackend(t *testing.T) {
+	ctx := context.Background()
+	state := `{"version": 4, "outputs": {}}`
+	var deleted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "gitlab-ci-token" || password != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(state)) //nolint:errcheck
+		case http.MethodDelete:
+			deleted = true
+		}
+	}))
+	defer server.Close()
+
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gitlab-state", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("gitlab-ci-token"), "password": []byte("token")},
+	}
+	conf := &v1beta1.Backend{
+		BackendType: TypeHTTP,
+		HTTP: &v1beta1.HTTPBackend{
+			Address:              server.URL,
+			LockAddress:          server.URL + "/lock",
+			LockMethod:           "POST",
+			CredentialsSecretRef: &crossplane.SecretReference{Name: "gitlab-state", Namespace: "default"},
+		},
+	}
+	b, err := New(fake.NewFakeClientWithScheme(s, secret), conf, "vela-system", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hcl, err := b.HCL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(hcl, `backend "http"`) || !strings.Contains(hcl, `lock_method    = "POST"`) || strings.Contains(hcl, "token") {
+		t.Errorf("unexpected backend block:\n%s", hcl)
+	}
+
+	envs, err := b.Envs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{envTFHTTPUsername: "gitlab-ci-token", envTFHTTPPassword: "token"}
+	if !reflect.DeepEqual(envs, expected) {
+		t.Errorf("expected envs %v, got %v", expected, envs)
+	}
+
+	data, err := b.GetTFStateJSON(ctx)
+	if err != nil || string(data) != state {
+		t.Fatalf("expected state %s, got %s, %v", state, data, err)
+	}
+
+	// The state is kept by default
+	if err := b.CleanUp(ctx); err != nil || deleted {
+		t.Fatalf("expected the state kept, got deleted %v, %v", deleted, err)
+	}
+	conf.HTTP.DeleteOnCleanUp = true
+	if err := b.CleanUp(ctx); err != nil || !deleted {
+		t.Fatalf("expected the state deleted, got deleted %v, %v", deleted, err)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/util"
+)
+
+// TerraformStateNameInSecret is the key name to store Terraform state
+const TerraformStateNameInSecret = "tfstate"
+
+// gzipMagic is the header of the gzip compressed data
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// kubernetesBackend stores the state in a Secret. For detailed information, please refer to
+// https://www.terraform.io/docs/language/settings/backends/kubernetes.html#configuration-variables
+type kubernetesBackend struct {
+	client    client.Client
+	conf      *v1beta1.Backend
+	namespace string
+}
+
+func (b *kubernetesBackend) HCL() (string, error) {
+	return util.RenderTemplate(b.conf, b.namespace)
+}
+
+// secretName returns the name of the state Secret, which is in the format: tfstate-{workspace}-{secret_suffix}
+func (b *kubernetesBackend) secretName() string {
+	return fmt.Sprintf("tfstate-%s-%s", terraformWorkspace, b.conf.SecretSuffix)
+}
+
+func (b *kubernetesBackend) Envs(ctx context.Context) (map[string]string, error) {
+	if b.conf.Encryption == nil {
+		return nil, nil
+	}
+	return stateEncryptionEnvs(ctx, b.client, b.conf.Encryption)
+}
+
+func (b *kubernetesBackend) GetTFStateJSON(ctx context.Context) ([]byte, error) {
+	var s v1.Secret
+	if err := b.client.Get(ctx, client.ObjectKey{Name: b.secretName(), Namespace: b.namespace}, &s); err != nil {
+		return nil, errors.Wrap(err, "terraform state file backend secret is not generated")
+	}
+	tfStateData, ok := s.Data[TerraformStateNameInSecret]
+	if !ok {
+		return nil, fmt.Errorf("failed to get %s from Terraform State secret %s", TerraformStateNameInSecret, s.Name)
+	}
+	// The state is gzip compressed by Terraform, while the uncompressed state is still accepted
+	if !bytes.HasPrefix(tfStateData, gzipMagic) {
+		return tfStateData, nil
+	}
+	tfStateJSON, err := util.DecompressTerraformStateSecret(string(tfStateData))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress state secret data")
+	}
+	return tfStateJSON, nil
+}
+
+func (b *kubernetesBackend) CleanUp(ctx context.Context) error {
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: b.secretName(), Namespace: b.namespace}}
+	if err := b.client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete Terraform state secret %s", secret.Name)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/util"
+)
+
+func TestKubernetesBackendGetTFStateJSON(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	state := `{"version": 4, "outputs": {}}`
+	compressed, err := util.CompressData(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"compressed": compressed, "uncompressed": []byte(state)} {
+		t.Run(name, func(t *testing.T) {
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-oss", Namespace: "vela-system"},
+				Data:       map[string][]byte{TerraformStateNameInSecret: data},
+			}
+			b, err := New(fake.NewFakeClientWithScheme(s, secret), &v1beta1.Backend{SecretSuffix: "oss"}, "vela-system", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := b.GetTFStateJSON(ctx)
+			if err != nil || string(got) != state {
+				t.Errorf("expected state %s, got %s, %v", state, got, err)
+			}
+		})
+	}
+}
+
+func TestKubernetesBackendEncryption(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "state-key", Namespace: "default"},
+		Data:       map[string][]byte{"passphrase": []byte("correct-horse-${battery}"), "short": []byte("staple")},
+	}
+	conf := &v1beta1.Backend{
+		SecretSuffix: "oss",
+		Encryption: &v1beta1.StateEncryption{PassphraseSecretRef: crossplane.SecretKeySelector{
+			SecretReference: crossplane.SecretReference{Name: "state-key", Namespace: "default"},
+			Key:             "passphrase",
+		}},
+	}
+	b, err := New(fake.NewFakeClientWithScheme(s, secret), conf, "vela-system", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envs, err := b.Envs(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if encryption := envs[envTFEncryption]; !strings.Contains(encryption, `passphrase = "correct-horse-$${battery}"`) {
+		t.Errorf("expected the escaped passphrase in the encryption configuration, got %s", encryption)
+	}
+
+	conf.Encryption.PassphraseSecretRef.Key = "short"
+	if _, err := b.Envs(ctx); err == nil || !strings.Contains(err.Error(), "at least 16 characters") {
+		t.Errorf("expected an error about the short passphrase, got %v", err)
+	}
+}
@@ -1,51 +1,487 @@
 package configuration
 
 import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 
 	"github.com/oam-dev/terraform-controller/api/types"
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
 	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/backend"
 	"github.com/oam-dev/terraform-controller/controllers/util"
 )
 
+const (
+	// CompressedFileSuffix is the suffix of the gzip compressed file stored in ConfigMap BinaryData
+	CompressedFileSuffix = ".gz"
+	// RemoteGitCommitAnnotation records the commit of the remote git repo in the input ConfigMap
+	RemoteGitCommitAnnotation = "terraform.core.oam.dev/remote-git-commit"
+	// RemoteGitPathAnnotation records the directory of the configuration in the remote git repo in the input ConfigMap
+	RemoteGitPathAnnotation = "terraform.core.oam.dev/remote-git-path"
+	// VarFilesHashAnnotation records the hash of the var files in the input ConfigMap
+	VarFilesHashAnnotation = "terraform.core.oam.dev/var-files-hash"
+	// OCIArtifactAnnotation records the OCI artifact in the input ConfigMap
+	OCIArtifactAnnotation = "terraform.core.oam.dev/oci-artifact"
+	// OCIPathAnnotation records the directory of the configuration in the OCI artifact in the input ConfigMap
+	OCIPathAnnotation = "terraform.core.oam.dev/oci-path"
+	// DefaultApplyTimeout is how long the apply Job could run before the Configuration fails as timed out
+	DefaultApplyTimeout = time.Hour
+	// DefaultDestroyTimeout is how long the destroy could take before the controller escalates
+	DefaultDestroyTimeout = time.Hour
+)
+
+// SetDefaultProvider references the Provider of the name in the default namespace when a Configuration doesn't reference
+// any, which is the default Provider of the controller. A Provider referenced by the Configuration takes precedence
+// over it, and it over the Provider "default", which SetDefaults falls back to. An empty name is left to SetDefaults.
+func SetDefaultProvider(configuration *v1beta1.Configuration, name string) {
+	if configuration.Spec.ProviderReference == nil && name != "" {
+		configuration.Spec.ProviderReference = &crossplane.Reference{Name: name, Namespace: util.ProviderDefaultNamespace}
+	}
+}
+
+// SetDefaults sets the default values of a Configuration, which are persisted by the mutating webhook, and applied
+// in memory when reconciling a Configuration created without the webhook
+func SetDefaults(configuration *v1beta1.Configuration) {
+	if configuration.Spec.Engine == "" {
+		configuration.Spec.Engine = types.TerraformEngine
+	}
+	if configuration.Spec.ProviderReference == nil {
+		configuration.Spec.ProviderReference = &crossplane.Reference{
+			Name:      util.ProviderDefaultName,
+			Namespace: util.ProviderDefaultNamespace,
+		}
+	}
+	// The namespace of the Provider is independent of the Configuration
+	if configuration.Spec.ProviderReference.Namespace == "" {
+		configuration.Spec.ProviderReference.Namespace = util.ProviderDefaultNamespace
+	}
+	for i := range configuration.Spec.AliasedProviders {
+		if configuration.Spec.AliasedProviders[i].Namespace == "" {
+			configuration.Spec.AliasedProviders[i].Namespace = util.ProviderDefaultNamespace
+		}
+	}
+	setBackendDefaults(configuration)
+	if configuration.Spec.ApplyTimeout == nil {
+		configuration.Spec.ApplyTimeout = &metav1.Duration{Duration: DefaultApplyTimeout}
+	}
+	if configuration.Spec.DestroyTimeout == nil {
+		configuration.Spec.DestroyTimeout = &metav1.Duration{Duration: DefaultDestroyTimeout}
+	}
+}
+
+func setBackendDefaults(configuration *v1beta1.Configuration) {
+	if configuration.Spec.Backend == nil {
+		configuration.Spec.Backend = &v1beta1.Backend{}
+	}
+	if configuration.Spec.Backend.BackendType == backend.TypeCustom {
+		return
+	}
+	if configuration.Spec.Backend.BackendType == backend.TypeHTTP {
+		if h := configuration.Spec.Backend.HTTP; h != nil && h.CredentialsSecretRef != nil && h.CredentialsSecretRef.Namespace == "" {
+			h.CredentialsSecretRef.Namespace = configuration.Namespace
+		}
+		return
+	}
+	if configuration.Spec.Backend.BackendType == backend.TypeAzureRM {
+		// The name is unknown yet when a Configuration is created with generateName
+		if azurerm := configuration.Spec.Backend.AzureRM; azurerm != nil && azurerm.Key == "" && configuration.Name != "" {
+			azurerm.Key = fmt.Sprintf("%s-%s.tfstate", configuration.Namespace, configuration.Name)
+		}
+		return
+	}
+	if configuration.Spec.Backend.SecretSuffix == "" {
+		configuration.Spec.Backend.SecretSuffix = configuration.Name
+	}
+	if encryption := configuration.Spec.Backend.Encryption; encryption != nil && encryption.PassphraseSecretRef.Namespace == "" {
+		encryption.PassphraseSecretRef.Namespace = configuration.Namespace
+	}
+	configuration.Spec.Backend.InClusterConfig = true
+}
+
 // ValidConfigurationObject will validate a Configuration
 func ValidConfigurationObject(configuration *v1beta1.Configuration) (types.ConfigurationType, error) {
 	json := configuration.Spec.JSON
 	hcl := configuration.Spec.HCL
 	remote := configuration.Spec.Remote
+	oci := configuration.Spec.OCI
+	var sources int
+	for _, set := range []bool{json != "", hcl != "", remote != "", oci != nil} {
+		if set {
+			sources++
+		}
+	}
 	switch {
-	case json == "" && hcl == "" && remote == "":
-		return "", errors.New("spec.JSON, spec.HCL or spec.Remote should be set")
-	case json != "" && hcl != "", json != "" && remote != "", hcl != "" && remote != "":
-		return "", errors.New("spec.JSON, spec.HCL and/or spec.Remote cloud not be set at the same time")
+	case sources == 0:
+		return "", errors.New("spec.JSON, spec.HCL, spec.Remote or spec.OCI should be set")
+	case sources > 1:
+		return "", errors.New("spec.JSON, spec.HCL, spec.Remote and/or spec.OCI cloud not be set at the same time")
 	case json != "":
 		return types.ConfigurationJSON, nil
 	case hcl != "":
 		return types.ConfigurationHCL, nil
 	case remote != "":
 		return types.ConfigurationRemote, nil
+	case oci != nil:
+		return types.ConfigurationOCI, nil
 	}
 	return "", nil
 }
 
-// RenderConfiguration will compose the Terraform configuration with hcl/json and backend
-func RenderConfiguration(configuration *v1beta1.Configuration, controllerNamespace string, configurationType types.ConfigurationType) (string, error) {
-	if configuration.Spec.Backend != nil {
-		if configuration.Spec.Backend.SecretSuffix == "" {
-			configuration.Spec.Backend.SecretSuffix = configuration.Name
+// ValidateConfiguration validates the spec of a Configuration, which is used by the validating webhook to reject an
+// invalid Configuration before it's reconciled
+func ValidateConfiguration(configuration *v1beta1.Configuration) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if _, err := ValidConfigurationObject(configuration); err != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath, err.Error()))
+	}
+
+	if _, err := util.RawExtension2Map(configuration.Spec.Variable); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("variable"), string(configuration.Spec.Variable.Raw), err.Error()))
+	}
+
+	if remote := configuration.Spec.Remote; remote != "" {
+		if err := ValidateRemoteGit(remote); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("remote"), remote, err.Error()))
+		}
+	}
+
+	if p := configuration.Spec.Path; p != "" {
+		pathPath := specPath.Child("path")
+		if configuration.Spec.Remote == "" {
+			allErrs = append(allErrs, field.Forbidden(pathPath, "only works with spec.remote"))
+		}
+		allErrs = append(allErrs, validateRelativePath(pathPath, p, "repo")...)
+	}
+
+	if oci := configuration.Spec.OCI; oci != nil {
+		ociPath := specPath.Child("oci")
+		if err := ValidateOCIArtifact(oci.Artifact); err != nil {
+			allErrs = append(allErrs, field.Invalid(ociPath.Child("artifact"), oci.Artifact, err.Error()))
+		} else if err := ValidateVerifiedOCIArtifact(oci.Artifact); err != nil && configuration.Spec.Verify != nil {
+			allErrs = append(allErrs, field.Invalid(ociPath.Child("artifact"), oci.Artifact, err.Error()))
+		}
+		allErrs = append(allErrs, validateRelativePath(ociPath.Child("path"), oci.Path, "artifact")...)
+		if name := oci.PullSecretName; name != "" {
+			for _, msg := range validation.IsDNS1123Subdomain(name) {
+				allErrs = append(allErrs, field.Invalid(ociPath.Child("pullSecretName"), name, msg))
+			}
+		}
+	}
+
+	for i, source := range configuration.Spec.VarFiles {
+		varFilePath := specPath.Child("varFiles").Index(i)
+		var key string
+		switch {
+		case (source.ConfigMapKeyRef == nil) == (source.SecretKeyRef == nil):
+			allErrs = append(allErrs, field.Invalid(varFilePath, "", "exactly one of configMapKeyRef and secretKeyRef must be set"))
+			continue
+		case source.ConfigMapKeyRef != nil:
+			key, varFilePath = source.ConfigMapKeyRef.Key, varFilePath.Child("configMapKeyRef", "key")
+		default:
+			key, varFilePath = source.SecretKeyRef.Key, varFilePath.Child("secretKeyRef", "key")
+		}
+		if !IsVarFile(key) {
+			allErrs = append(allErrs, field.Invalid(varFilePath, key, "must have the suffix .tfvars or .tfvars.json"))
+		}
+		for _, msg := range validation.IsConfigMapKey(key) {
+			allErrs = append(allErrs, field.Invalid(varFilePath, key, msg))
+		}
+	}
+
+	if verify := configuration.Spec.Verify; verify != nil {
+		verifyPath := specPath.Child("verify")
+		if configuration.Spec.Remote == "" && configuration.Spec.OCI == nil {
+			allErrs = append(allErrs, field.Forbidden(verifyPath, "only works with spec.remote or spec.oci"))
 		}
-		configuration.Spec.Backend.InClusterConfig = true
-	} else {
-		configuration.Spec.Backend = &v1beta1.Backend{
-			SecretSuffix:    configuration.Name,
-			InClusterConfig: true,
+		if name := verify.TrustedKeysSecretName; name == "" {
+			allErrs = append(allErrs, field.Required(verifyPath.Child("trustedKeysSecretName"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(name) {
+				allErrs = append(allErrs, field.Invalid(verifyPath.Child("trustedKeysSecretName"), name, msg))
+			}
 		}
 	}
-	backendTF, err := util.RenderTemplate(configuration.Spec.Backend, controllerNamespace)
+
+	if cluster := configuration.Spec.Cluster; cluster != nil {
+		clusterPath := specPath.Child("cluster")
+		refPath := clusterPath.Child("kubeconfigSecretRef")
+		if name := cluster.KubeconfigSecretRef.Name; name == "" {
+			allErrs = append(allErrs, field.Required(refPath.Child("name"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(name) {
+				allErrs = append(allErrs, field.Invalid(refPath.Child("name"), name, msg))
+			}
+		}
+		if key := cluster.KubeconfigSecretRef.Key; key == "" {
+			allErrs = append(allErrs, field.Required(refPath.Child("key"), ""))
+		} else {
+			for _, msg := range validation.IsConfigMapKey(key) {
+				allErrs = append(allErrs, field.Invalid(refPath.Child("key"), key, msg))
+			}
+		}
+		if ns := cluster.Namespace; ns == "" {
+			allErrs = append(allErrs, field.Required(clusterPath.Child("namespace"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(ns) {
+				allErrs = append(allErrs, field.Invalid(clusterPath.Child("namespace"), ns, msg))
+			}
+		}
+	}
+
+	if b := configuration.Spec.Backend; b != nil && b.SecretSuffix != "" {
+		// The state is stored in the Secret tfstate-{workspace}-{secretSuffix}
+		for _, msg := range validation.IsDNS1123Subdomain("tfstate-default-" + b.SecretSuffix) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("backend", "secretSuffix"), b.SecretSuffix, msg))
+		}
+	}
+	if b := configuration.Spec.Backend; b != nil && b.Encryption != nil {
+		encryptionPath := specPath.Child("backend", "encryption")
+		if b.BackendType != "" && b.BackendType != backend.TypeKubernetes {
+			allErrs = append(allErrs, field.Forbidden(encryptionPath, "only the kubernetes backend supports the encryption"))
+		}
+		if configuration.Spec.Engine != types.OpenTofuEngine {
+			allErrs = append(allErrs, field.Forbidden(encryptionPath, "the encryption requires the tofu engine"))
+		}
+		if ref := b.Encryption.PassphraseSecretRef; ref.Name == "" || ref.Key == "" {
+			allErrs = append(allErrs, field.Required(encryptionPath.Child("passphraseSecretRef"), "name and key must be set"))
+		}
+	}
+	if b := configuration.Spec.Backend; b != nil && b.BackendType == backend.TypeAzureRM {
+		azurermPath := specPath.Child("backend", "azurerm")
+		if b.AzureRM == nil {
+			allErrs = append(allErrs, field.Required(azurermPath, "must be set for the azurerm backend"))
+		} else {
+			if b.AzureRM.StorageAccountName == "" {
+				allErrs = append(allErrs, field.Required(azurermPath.Child("storageAccountName"), ""))
+			}
+			if b.AzureRM.ContainerName == "" {
+				allErrs = append(allErrs, field.Required(azurermPath.Child("containerName"), ""))
+			}
+		}
+	}
+	if b := configuration.Spec.Backend; b != nil && b.BackendType == backend.TypeCustom && strings.TrimSpace(b.Custom) == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("backend", "custom"), "must be set for the custom backend"))
+	}
+	if b := configuration.Spec.Backend; b != nil && b.BackendType == backend.TypeHTTP {
+		httpPath := specPath.Child("backend", "http")
+		if b.HTTP == nil {
+			allErrs = append(allErrs, field.Required(httpPath, "must be set for the http backend"))
+		} else {
+			if u, err := url.Parse(b.HTTP.Address); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				allErrs = append(allErrs, field.Invalid(httpPath.Child("address"), b.HTTP.Address, "must be an http(s) URL"))
+			}
+			if b.HTTP.CredentialsSecretRef != nil && b.HTTP.CredentialsSecretRef.Name == "" {
+				allErrs = append(allErrs, field.Required(httpPath.Child("credentialsSecretRef", "name"), ""))
+			}
+		}
+	}
+	if v := configuration.Spec.WorkingVolume; v != nil && v.SizeLimit != nil && v.SizeLimit.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("workingVolume", "sizeLimit"), v.SizeLimit.String(), "must be positive"))
+	}
+
+	hookNames := make(map[string]bool)
+	for i, hook := range configuration.Spec.PostApplyHooks {
+		hookPath := specPath.Child("postApplyHooks").Index(i)
+		switch {
+		case hook.Name == "":
+			allErrs = append(allErrs, field.Required(hookPath.Child("name"), ""))
+		case hookNames[hook.Name]:
+			allErrs = append(allErrs, field.Duplicate(hookPath.Child("name"), hook.Name))
+		default:
+			// The hooks run as containers named after them
+			for _, msg := range validation.IsDNS1123Label(hook.Name) {
+				allErrs = append(allErrs, field.Invalid(hookPath.Child("name"), hook.Name, msg))
+			}
+		}
+		hookNames[hook.Name] = true
+		if hook.Image == "" {
+			allErrs = append(allErrs, field.Required(hookPath.Child("image"), ""))
+		}
+	}
+
+	for i, dep := range configuration.Spec.DependsOn {
+		depPath := specPath.Child("dependsOn").Index(i)
+		if dep.Name == "" {
+			allErrs = append(allErrs, field.Required(depPath.Child("name"), ""))
+		}
+		if dep.Name == configuration.Name && (dep.Namespace == "" || dep.Namespace == configuration.Namespace) {
+			allErrs = append(allErrs, field.Invalid(depPath, dep.Name, "a Configuration can't depend on itself"))
+		}
+	}
+
+	aliases := make(map[string]bool)
+	for i, ref := range configuration.Spec.AliasedProviders {
+		providerPath := specPath.Child("aliasedProviders").Index(i)
+		switch {
+		case ref.Alias == "":
+			allErrs = append(allErrs, field.Required(providerPath.Child("alias"), ""))
+		case !providerAliasRegexp.MatchString(ref.Alias):
+			allErrs = append(allErrs, field.Invalid(providerPath.Child("alias"), ref.Alias,
+				"must consist of lower case letters, digits and '_', and start with a letter"))
+		case aliases[ref.Alias]:
+			allErrs = append(allErrs, field.Duplicate(providerPath.Child("alias"), ref.Alias))
+		}
+		aliases[ref.Alias] = true
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(providerPath.Child("name"), ""))
+		}
+	}
+
+	addresses := make(map[string]bool)
+	for i, imp := range configuration.Spec.Imports {
+		importPath := specPath.Child("imports").Index(i)
+		if imp.Address == "" {
+			allErrs = append(allErrs, field.Required(importPath.Child("address"), ""))
+		} else if addresses[imp.Address] {
+			allErrs = append(allErrs, field.Duplicate(importPath.Child("address"), imp.Address))
+		}
+		addresses[imp.Address] = true
+		if imp.ID == "" {
+			allErrs = append(allErrs, field.Required(importPath.Child("id"), ""))
+		}
+	}
+
+	for i, stage := range configuration.Spec.DestroyStages {
+		stagePath := specPath.Child("destroyStages").Index(i)
+		if len(stage.Targets) == 0 {
+			allErrs = append(allErrs, field.Required(stagePath.Child("targets"), ""))
+		}
+		for j, target := range stage.Targets {
+			if target == "" || strings.HasPrefix(target, "-") {
+				allErrs = append(allErrs, field.Invalid(stagePath.Child("targets").Index(j), target,
+					"must be the address of a resource or a module"))
+			}
+		}
+	}
+
+	vars := make(map[string]bool)
+	for i, ref := range configuration.Spec.VariableFrom {
+		refPath := specPath.Child("variableFrom").Index(i)
+		if ref.Configuration == "" {
+			allErrs = append(allErrs, field.Required(refPath.Child("configuration"), ""))
+		}
+		if ref.Namespace != "" && ref.Namespace != configuration.Namespace {
+			allErrs = append(allErrs, field.Invalid(refPath.Child("namespace"), ref.Namespace,
+				"must be the namespace of the Configuration"))
+		} else if ref.Configuration == configuration.Name {
+			allErrs = append(allErrs, field.Invalid(refPath, ref.Configuration, "a Configuration can't depend on itself"))
+		}
+		if ref.Output == "" {
+			allErrs = append(allErrs, field.Required(refPath.Child("output"), ""))
+		}
+		if ref.Var == "" {
+			allErrs = append(allErrs, field.Required(refPath.Child("var"), ""))
+		} else if vars[ref.Var] {
+			allErrs = append(allErrs, field.Duplicate(refPath.Child("var"), ref.Var))
+		}
+		vars[ref.Var] = true
+	}
+
+	if ref := configuration.Spec.WriteConnectionSecretToReference; ref != nil {
+		keys := make(map[string]bool)
+		for i, key := range ref.Keys {
+			keyPath := specPath.Child("writeConnectionSecretToRef", "keys").Index(i)
+			switch {
+			case key == "":
+				allErrs = append(allErrs, field.Required(keyPath, ""))
+			case keys[key]:
+				allErrs = append(allErrs, field.Duplicate(keyPath, key))
+			}
+			keys[key] = true
+		}
+		mappingPath := specPath.Child("writeConnectionSecretToRef", "keyMapping")
+		outputs := make([]string, 0, len(ref.KeyMapping))
+		for output := range ref.KeyMapping {
+			outputs = append(outputs, output)
+		}
+		// sorted so that the same error is reported for the same collision
+		sort.Strings(outputs)
+		targets := make(map[string]string)
+		for _, output := range outputs {
+			key := ref.KeyMapping[output]
+			for _, msg := range validation.IsConfigMapKey(key) {
+				allErrs = append(allErrs, field.Invalid(mappingPath.Key(output), key, msg))
+			}
+			if len(ref.Keys) > 0 && !keys[output] {
+				allErrs = append(allErrs, field.Invalid(mappingPath.Key(output), key, "the output is not one of the keys"))
+			}
+			_, renamed := ref.KeyMapping[key]
+			if previous, ok := targets[key]; ok {
+				allErrs = append(allErrs, field.Invalid(mappingPath.Key(output), key,
+					fmt.Sprintf("collides with the key of output %s", previous)))
+			} else if keys[key] && !renamed && key != output {
+				allErrs = append(allErrs, field.Invalid(mappingPath.Key(output), key,
+					fmt.Sprintf("collides with the key of output %s", key)))
+			}
+			targets[key] = output
+		}
+	}
+
+	if name := configuration.Spec.ServiceAccountName; name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("serviceAccountName"), name, msg))
+		}
+	}
+
+	if configuration.Spec.ApplyInterval != nil && configuration.Spec.ApplyInterval.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("applyInterval"), configuration.Spec.ApplyInterval.Duration.String(),
+			"must be positive"))
+	}
+
+	if configuration.Spec.ApplyTimeout != nil && configuration.Spec.ApplyTimeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("applyTimeout"), configuration.Spec.ApplyTimeout.Duration.String(),
+			"must be positive"))
+	}
+
+	if configuration.Spec.DestroyTimeout != nil && configuration.Spec.DestroyTimeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("destroyTimeout"), configuration.Spec.DestroyTimeout.Duration.String(),
+			"must be positive"))
+	}
+
+	// activeDeadlineSeconds is in seconds, which must be at least 1
+	if configuration.Spec.JobDeadline != nil && configuration.Spec.JobDeadline.Duration < time.Second {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("jobDeadline"), configuration.Spec.JobDeadline.Duration.String(),
+			"must be at least 1s"))
+	}
+
+	if entrypoint := configuration.Spec.Entrypoint; len(entrypoint) > 0 && strings.TrimSpace(entrypoint[0]) == "" {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("entrypoint").Index(0), entrypoint[0], "the executable must not be empty"))
+	}
+
+	switch configuration.Spec.JobRestartPolicy {
+	case "", v1.RestartPolicyOnFailure, v1.RestartPolicyNever:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("jobRestartPolicy"), configuration.Spec.JobRestartPolicy,
+			[]string{string(v1.RestartPolicyOnFailure), string(v1.RestartPolicyNever)}))
+	}
+
+	return allErrs.ToAggregate()
+}
+
+// RenderConfiguration will compose the Terraform configuration with hcl/json and backend
+func RenderConfiguration(configuration *v1beta1.Configuration, controllerNamespace string, configurationType types.ConfigurationType) (string, error) {
+	setBackendDefaults(configuration)
+	b, err := backend.New(nil, configuration.Spec.Backend, controllerNamespace, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to prepare Terraform backend configuration")
+	}
+	backendTF, err := b.HCL()
 	if err != nil {
 		return "", errors.Wrap(err, "failed to prepare Terraform backend configuration")
 	}
@@ -57,13 +493,81 @@ func RenderConfiguration(configuration *v1beta1.Configuration, controllerNamespa
 		completedConfiguration := configuration.Spec.HCL
 		completedConfiguration += "\n" + backendTF
 		return completedConfiguration, nil
-	case types.ConfigurationRemote:
+	case types.ConfigurationRemote, types.ConfigurationOCI:
 		return backendTF, nil
 	default:
 		return "", errors.New("Unsupported Configuration Type")
 	}
 }
 
+// providerAliasRegexp matches the alias of a Provider, which prefixes the names of Terraform variables
+var providerAliasRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// scpLikeGitRegexp matches the scp-like address of a git repo, like git@github.com:oam-dev/terraform-controller.git
+var scpLikeGitRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^:]`)
+
+// ValidateRemoteGit only allows the http(s), ssh and git URLs, or the scp-like addresses of git repos. Other
+// transports, like ext:: which runs commands, and values which git could take as options are rejected.
+func ValidateRemoteGit(remote string) error {
+	if strings.HasPrefix(remote, "-") || strings.IndexFunc(remote, func(r rune) bool { return r <= ' ' || r == 0x7f }) >= 0 {
+		return errors.New("must not start with '-' or contain whitespaces or control characters")
+	}
+	if scpLikeGitRegexp.MatchString(remote) {
+		return nil
+	}
+	u, err := url.Parse(remote)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "ssh", "git":
+		if u.Host == "" {
+			return errors.New("must have a host")
+		}
+		return nil
+	default:
+		return errors.New("must be a http(s), ssh or git URL, or an scp-like address like git@github.com:org/repo.git")
+	}
+}
+
+// validateRelativePath validates a directory which must stay in the root of a repo or an artifact
+func validateRelativePath(fldPath *field.Path, p, root string) field.ErrorList {
+	var allErrs field.ErrorList
+	if path.IsAbs(p) {
+		allErrs = append(allErrs, field.Invalid(fldPath, p, fmt.Sprintf("must be relative to the root of the %s", root)))
+	}
+	for _, elem := range strings.Split(p, "/") {
+		if elem == ".." {
+			allErrs = append(allErrs, field.Invalid(fldPath, p, "must not contain '..'"))
+			break
+		}
+	}
+	return allErrs
+}
+
+// ociArtifactRegexp matches the reference of an OCI artifact pinned by a tag or a digest, like
+// registry.example.com:5000/modules/vpc:v1.2.0 or registry.example.com/modules/vpc@sha256:<digest>
+var ociArtifactRegexp = regexp.MustCompile(
+	`^[A-Za-z0-9][A-Za-z0-9.-]*(:[0-9]+)?(/[a-z0-9]+([._-]+[a-z0-9]+)*)+(:[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}|@sha256:[a-f0-9]{64})$`)
+
+// ValidateOCIArtifact only allows the reference of an OCI artifact in a registry pinned by a tag or a digest, which
+// the artifact is pulled by, and which can't be taken as an option of oras
+func ValidateOCIArtifact(artifact string) error {
+	if !ociArtifactRegexp.MatchString(artifact) {
+		return errors.New("must be a reference pinned by a tag or a digest, like registry.example.com/modules/vpc:v1.2.0")
+	}
+	return nil
+}
+
+// ValidateVerifiedOCIArtifact only allows an OCI artifact pinned by a digest to be verified, as the tag could be moved
+// to another artifact after the verification
+func ValidateVerifiedOCIArtifact(artifact string) error {
+	if !strings.Contains(artifact, "@sha256:") {
+		return errors.New("must be pinned by a digest to be verified")
+	}
+	return nil
+}
+
 // CheckWhetherConfigurationChanges will check whether configuration is changed
 func CheckWhetherConfigurationChanges(configurationType types.ConfigurationType, cm *v1.ConfigMap, completedConfiguration string) (bool, error) {
 	var configurationChanged bool
@@ -72,9 +576,13 @@ func CheckWhetherConfigurationChanges(configurationType types.ConfigurationType,
 		return configurationChanged, nil
 	case types.ConfigurationHCL:
 		if cm != nil {
-			configurationChanged = cm.Data[types.TerraformHCLConfigurationName] != completedConfiguration
+			storedConfiguration, err := GetConfigurationFromConfigMap(cm, types.TerraformHCLConfigurationName)
+			if err != nil {
+				return false, err
+			}
+			configurationChanged = storedConfiguration != completedConfiguration
 			if configurationChanged {
-				klog.InfoS("Configuration HCL changed", "ConfigMap", cm.Data[types.TerraformHCLConfigurationName],
+				klog.InfoS("Configuration HCL changed", "ConfigMap", storedConfiguration,
 					"RenderedCompletedConfiguration", completedConfiguration)
 			}
 		} else {
@@ -83,13 +591,81 @@ func CheckWhetherConfigurationChanges(configurationType types.ConfigurationType,
 		}
 
 		return configurationChanged, nil
-	case types.ConfigurationRemote:
+	case types.ConfigurationRemote, types.ConfigurationOCI:
 		return cm.Name == "", nil
 	}
 
 	return configurationChanged, errors.New("unknown issue")
 }
 
+// CheckWhetherRemoteGitPathChanges checks whether the directory of the configuration in the remote git repo differs
+// from the one recorded in the input ConfigMap
+func CheckWhetherRemoteGitPathChanges(cm *v1.ConfigMap, path string) bool {
+	if cm.Name == "" {
+		return false
+	}
+	changed := cm.Annotations[RemoteGitPathAnnotation] != path
+	if changed {
+		klog.InfoS("Path of the remote git repo changed", "ConfigMap", cm.Name, "Path", cm.Annotations[RemoteGitPathAnnotation],
+			"LatestPath", path)
+	}
+	return changed
+}
+
+// CheckWhetherRemoteGitChanges checks whether the latest commit of the remote git repo differs from the one recorded
+// in the input ConfigMap
+func CheckWhetherRemoteGitChanges(cm *v1.ConfigMap, latestCommit string) bool {
+	if latestCommit == "" {
+		return false
+	}
+	changed := cm.Annotations[RemoteGitCommitAnnotation] != latestCommit
+	if changed {
+		klog.InfoS("Remote git repo changed", "ConfigMap", cm.Name, "Commit", cm.Annotations[RemoteGitCommitAnnotation],
+			"LatestCommit", latestCommit)
+	}
+	return changed
+}
+
+// CheckWhetherVarFilesChange checks whether the var files differ from the ones recorded in the input ConfigMap by
+// their hash, which is empty without any var files
+func CheckWhetherVarFilesChange(cm *v1.ConfigMap, hash string) bool {
+	if cm.Name == "" {
+		return false
+	}
+	changed := cm.Annotations[VarFilesHashAnnotation] != hash
+	if changed {
+		klog.InfoS("Var files changed", "ConfigMap", cm.Name, "Hash", cm.Annotations[VarFilesHashAnnotation], "LatestHash", hash)
+	}
+	return changed
+}
+
+// CheckWhetherOCIArtifactChanges checks whether the OCI artifact or the directory of the configuration in it differs
+// from the one recorded in the input ConfigMap
+func CheckWhetherOCIArtifactChanges(cm *v1.ConfigMap, oci *v1beta1.OCISource) bool {
+	if cm.Name == "" {
+		return false
+	}
+	changed := cm.Annotations[OCIArtifactAnnotation] != oci.Artifact || cm.Annotations[OCIPathAnnotation] != oci.Path
+	if changed {
+		klog.InfoS("OCI artifact changed", "ConfigMap", cm.Name, "Artifact", cm.Annotations[OCIArtifactAnnotation],
+			"Path", cm.Annotations[OCIPathAnnotation], "LatestArtifact", oci.Artifact, "LatestPath", oci.Path)
+	}
+	return changed
+}
+
+// GetConfigurationFromConfigMap gets the configuration file stored in the ConfigMap, which is compressed into
+// BinaryData with a `.gz` suffix when it's too large for a ConfigMap
+func GetConfigurationFromConfigMap(cm *v1.ConfigMap, name string) (string, error) {
+	if compressed, ok := cm.BinaryData[name+CompressedFileSuffix]; ok {
+		data, err := util.DecompressTerraformStateSecret(string(compressed))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to decompress %s in ConfigMap %s", name, cm.Name)
+		}
+		return string(data), nil
+	}
+	return cm.Data[name], nil
+}
+
 // CompareTwoContainerEnvs compares two slices of v1.EnvVar
 func CompareTwoContainerEnvs(s1 []v1.EnvVar, s2 []v1.EnvVar) bool {
 	less := func(env1 v1.EnvVar, env2 v1.EnvVar) bool {
@@ -97,3 +673,32 @@ func CompareTwoContainerEnvs(s1 []v1.EnvVar, s2 []v1.EnvVar) bool {
 	}
 	return cmp.Diff(s1, s2, cmpopts.SortSlices(less)) == ""
 }
+
+// DiffContainerEnvs tells the names of the environment variables which are added, removed and changed from previous
+// to current. The values aren't returned since they may be sensitive.
+func DiffContainerEnvs(previous []v1.EnvVar, current []v1.EnvVar) (added, removed, changed []string) {
+	previousEnvs := make(map[string]v1.EnvVar, len(previous))
+	for _, env := range previous {
+		previousEnvs[env.Name] = env
+	}
+	currentEnvs := make(map[string]v1.EnvVar, len(current))
+	for _, env := range current {
+		currentEnvs[env.Name] = env
+		p, ok := previousEnvs[env.Name]
+		switch {
+		case !ok:
+			added = append(added, env.Name)
+		case !cmp.Equal(p, env):
+			changed = append(changed, env.Name)
+		}
+	}
+	for name := range previousEnvs {
+		if _, ok := currentEnvs[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
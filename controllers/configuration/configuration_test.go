@@ -1 +1,489 @@
 package configuration
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/util"
+)
+
+func TestGetConfigurationFromConfigMap(t *testing.T) {
+	hcl := `resource "random_id" "server" {}`
+	compressed, err := util.CompressData(hcl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		cm      *v1.ConfigMap
+		want    string
+		wantErr bool
+	}{
+		"plain data": {
+			cm:   &v1.ConfigMap{Data: map[string]string{types.TerraformHCLConfigurationName: hcl}},
+			want: hcl,
+		},
+		"compressed data": {
+			cm:   &v1.ConfigMap{BinaryData: map[string][]byte{types.TerraformHCLConfigurationName + CompressedFileSuffix: compressed}},
+			want: hcl,
+		},
+		"corrupted compressed data": {
+			cm:      &v1.ConfigMap{BinaryData: map[string][]byte{types.TerraformHCLConfigurationName + CompressedFileSuffix: []byte("abc")}},
+			wantErr: true,
+		},
+		"missing data": {
+			cm: &v1.ConfigMap{},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			got, err := GetConfigurationFromConfigMap(tc.cm, types.TerraformHCLConfigurationName)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRenderConfigurationWithCustomBackend(t *testing.T) {
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{
+			HCL: `resource "random_id" "server" {}`,
+			Backend: &v1beta1.Backend{
+				BackendType: "custom",
+				Custom:      `backend "s3" { bucket = "tfstate" }`,
+			},
+		},
+	}
+	rendered, err := RenderConfiguration(configuration, "vela-system", types.ConfigurationHCL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "resource \"random_id\" \"server\" {}\n\nterraform {\nbackend \"s3\" { bucket = \"tfstate\" }\n}\n"
+	if rendered != expected {
+		t.Errorf("expected configuration %q, got %q", expected, rendered)
+	}
+	// The kubernetes backend isn't defaulted for the custom backend
+	if configuration.Spec.Backend.SecretSuffix != "" {
+		t.Errorf("expected no secretSuffix, got %s", configuration.Spec.Backend.SecretSuffix)
+	}
+
+	configuration.Spec.Backend.Custom = ""
+	if err := ValidateConfiguration(configuration); err == nil || !strings.Contains(err.Error(), "spec.backend.custom") {
+		t.Errorf("expected an error about spec.backend.custom, got %v", err)
+	}
+}
+
+func TestValidateConfigurationPath(t *testing.T) {
+	testcases := map[string]struct {
+		remote string
+		path   string
+		errMsg string
+	}{
+		"nested path": {
+			remote: "https://github.com/kubevela-contrib/terraform-modules.git",
+			path:   "alibaba/vswitch with space",
+		},
+		"escaping the repo": {
+			remote: "https://github.com/kubevela-contrib/terraform-modules.git",
+			path:   "alibaba/../../etc",
+			errMsg: "must not contain '..'",
+		},
+		"absolute path": {
+			remote: "https://github.com/kubevela-contrib/terraform-modules.git",
+			path:   "/alibaba",
+			errMsg: "must be relative",
+		},
+		"without remote": {
+			path:   "alibaba",
+			errMsg: "only works with spec.remote",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{Remote: tc.remote, Path: tc.path}}
+			if tc.remote == "" {
+				configuration.Spec.HCL = `resource "random_id" "server" {}`
+			}
+			err := ValidateConfiguration(configuration)
+			if tc.errMsg == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.errMsg)) {
+				t.Errorf("expected an error about %s, got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfigurationAliasedProviders(t *testing.T) {
+	configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+		HCL:              `resource "random_id" "server" {}`,
+		AliasedProviders: []v1beta1.AliasedProviderReference{{Alias: "west", Name: "aws-west"}, {Alias: "east_1", Name: "aws-east"}},
+	}}
+	if err := ValidateConfiguration(configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configuration.Spec.AliasedProviders = append(configuration.Spec.AliasedProviders,
+		v1beta1.AliasedProviderReference{Alias: "west", Name: "aws-west-2"}, v1beta1.AliasedProviderReference{Alias: "East", Name: "aws"})
+	err := ValidateConfiguration(configuration)
+	if err == nil || !strings.Contains(err.Error(), "Duplicate value: \"west\"") || !strings.Contains(err.Error(), "must consist of lower case") {
+		t.Errorf("expected errors about the duplicate and the invalid aliases, got %v", err)
+	}
+}
+
+func TestValidateConfigurationConnectionSecretKeys(t *testing.T) {
+	configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+		HCL: `resource "random_id" "server" {}`,
+		WriteConnectionSecretToReference: &v1beta1.ConnectionSecretReference{
+			SecretReference: crossplane.SecretReference{Name: "db-conn"},
+			Keys:            []string{"endpoint", "password", "endpoint", ""},
+		},
+	}}
+	err := ValidateConfiguration(configuration)
+	if err == nil || !strings.Contains(err.Error(), `keys[2]: Duplicate value: "endpoint"`) || !strings.Contains(err.Error(), "keys[3]: Required value") {
+		t.Errorf("expected errors about the duplicate and the empty keys, got %v", err)
+	}
+}
+
+func TestValidateConfigurationConnectionSecretKeyMapping(t *testing.T) {
+	configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+		HCL: `resource "random_id" "server" {}`,
+		WriteConnectionSecretToReference: &v1beta1.ConnectionSecretReference{
+			SecretReference: crossplane.SecretReference{Name: "db-conn"},
+			Keys:            []string{"endpoint", "password"},
+			KeyMapping:      map[string]string{"endpoint": "password", "port": "DB_PORT", "password": "bad key"},
+		},
+	}}
+	err := ValidateConfiguration(configuration)
+	if err == nil {
+		t.Fatal("expected errors about the key mapping")
+	}
+	for _, msg := range []string{
+		`keyMapping[port]: Invalid value: "DB_PORT": the output is not one of the keys`,
+		`keyMapping[password]: Invalid value: "bad key"`,
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %v", msg, err)
+		}
+	}
+
+	configuration.Spec.WriteConnectionSecretToReference.KeyMapping = map[string]string{"endpoint": "DB_HOST", "password": "DB_HOST"}
+	err = ValidateConfiguration(configuration)
+	if err == nil || !strings.Contains(err.Error(), "collides with the key of output endpoint") {
+		t.Errorf("expected an error about the colliding keys, got %v", err)
+	}
+}
+
+func TestValidateConfigurationOCI(t *testing.T) {
+	testcases := map[string]struct {
+		oci    v1beta1.OCISource
+		errMsg string
+	}{
+		"pinned by a tag": {
+			oci: v1beta1.OCISource{Artifact: "registry.example.com:5000/modules/vpc:v1.2.0", Path: "aws", PullSecretName: "creds"},
+		},
+		"pinned by a digest": {
+			oci: v1beta1.OCISource{Artifact: "ghcr.io/org/vpc@sha256:" + strings.Repeat("a", 64)},
+		},
+		"not pinned": {
+			oci:    v1beta1.OCISource{Artifact: "registry.example.com/modules/vpc"},
+			errMsg: "must be a reference pinned by a tag or a digest",
+		},
+		"taken as an option": {
+			oci:    v1beta1.OCISource{Artifact: "--insecure registry.example.com/modules/vpc:v1"},
+			errMsg: "must be a reference pinned by a tag or a digest",
+		},
+		"escaping the artifact": {
+			oci:    v1beta1.OCISource{Artifact: "registry.example.com/modules/vpc:v1", Path: "../etc"},
+			errMsg: "must not contain '..'",
+		},
+		"invalid pull secret": {
+			oci:    v1beta1.OCISource{Artifact: "registry.example.com/modules/vpc:v1", PullSecretName: "Creds"},
+			errMsg: "spec.oci.pullSecretName",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			oci := tc.oci
+			err := ValidateConfiguration(&v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{OCI: &oci}})
+			if tc.errMsg == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.errMsg)) {
+				t.Errorf("expected an error about %s, got %v", tc.errMsg, err)
+			}
+		})
+	}
+
+	configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+		Remote: "https://github.com/org/repo.git",
+		OCI:    &v1beta1.OCISource{Artifact: "registry.example.com/modules/vpc:v1"},
+	}}
+	if _, err := ValidConfigurationObject(configuration); err == nil {
+		t.Error("expected an error about the sources set at the same time")
+	}
+}
+
+func TestValidateConfigurationVerify(t *testing.T) {
+	verify := &v1beta1.SourceVerification{TrustedKeysSecretName: "trusted-keys"}
+	testcases := map[string]struct {
+		spec   v1beta1.ConfigurationSpec
+		errMsg string
+	}{
+		"remote git": {
+			spec: v1beta1.ConfigurationSpec{Remote: "https://github.com/org/repo.git", Verify: verify},
+		},
+		"OCI artifact pinned by a digest": {
+			spec: v1beta1.ConfigurationSpec{Verify: verify,
+				OCI: &v1beta1.OCISource{Artifact: "registry.example.com/modules/vpc@sha256:" + strings.Repeat("a", 64)}},
+		},
+		"OCI artifact pinned by a tag": {
+			spec:   v1beta1.ConfigurationSpec{Verify: verify, OCI: &v1beta1.OCISource{Artifact: "registry.example.com/modules/vpc:v1"}},
+			errMsg: "must be pinned by a digest to be verified",
+		},
+		"inline configuration": {
+			spec:   v1beta1.ConfigurationSpec{HCL: `resource "random_id" "server" {}`, Verify: verify},
+			errMsg: "only works with spec.remote or spec.oci",
+		},
+		"without the trusted keys": {
+			spec:   v1beta1.ConfigurationSpec{Remote: "https://github.com/org/repo.git", Verify: &v1beta1.SourceVerification{}},
+			errMsg: "spec.verify.trustedKeysSecretName: Required value",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateConfiguration(&v1beta1.Configuration{Spec: tc.spec})
+			if tc.errMsg == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.errMsg)) {
+				t.Errorf("expected an error about %s, got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestCheckWhetherOCIArtifactChanges(t *testing.T) {
+	oci := &v1beta1.OCISource{Artifact: "registry.example.com/modules/vpc:v1.2.0", Path: "aws"}
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "vpc-tf-input", Annotations: map[string]string{
+		OCIArtifactAnnotation: "registry.example.com/modules/vpc:v1.2.0",
+		OCIPathAnnotation:     "aws",
+	}}}
+	if CheckWhetherOCIArtifactChanges(cm, oci) {
+		t.Error("expected the same artifact unchanged")
+	}
+	oci.Artifact = "registry.example.com/modules/vpc:v1.3.0"
+	if !CheckWhetherOCIArtifactChanges(cm, oci) {
+		t.Error("expected the artifact changed")
+	}
+	if CheckWhetherOCIArtifactChanges(&v1.ConfigMap{}, oci) {
+		t.Error("expected no change without the input ConfigMap, which is created as a new configuration")
+	}
+}
+
+func TestValidateConfigurationVarFiles(t *testing.T) {
+	configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+		HCL: `resource "random_id" "server" {}`,
+		VarFiles: []v1beta1.VarFileSource{
+			{ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "vars"}, Key: "prod.tfvars"}},
+			{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "vars"}, Key: "prod.json"}},
+			{},
+		},
+	}}
+	err := ValidateConfiguration(configuration)
+	if err == nil {
+		t.Fatal("expected errors about the var files")
+	}
+	for _, msg := range []string{
+		`spec.varFiles[1].secretKeyRef.key: Invalid value: "prod.json": must have the suffix .tfvars or .tfvars.json`,
+		"spec.varFiles[2]: Invalid value: \"\": exactly one of configMapKeyRef and secretKeyRef must be set",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %v", msg, err)
+		}
+	}
+	if strings.Contains(err.Error(), "spec.varFiles[0]") {
+		t.Errorf("expected the first var file valid, got %v", err)
+	}
+}
+
+func TestValidateConfigurationCluster(t *testing.T) {
+	configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+		HCL: `resource "random_id" "server" {}`,
+		Cluster: &v1beta1.ClusterReference{
+			KubeconfigSecretRef: v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "prod-kubeconfig"}},
+			Namespace:           "Terraform",
+		},
+	}}
+	err := ValidateConfiguration(configuration)
+	if err == nil {
+		t.Fatal("expected errors about the cluster")
+	}
+	for _, msg := range []string{"spec.cluster.kubeconfigSecretRef.key: Required value", "spec.cluster.namespace: Invalid value"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %v", msg, err)
+		}
+	}
+	if strings.Contains(err.Error(), "spec.cluster.kubeconfigSecretRef.name") {
+		t.Errorf("expected the name of the Secret valid, got %v", err)
+	}
+
+	configuration.Spec.Cluster.KubeconfigSecretRef.Key = "kubeconfig"
+	configuration.Spec.Cluster.Namespace = "terraform"
+	if err := ValidateConfiguration(configuration); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfigurationDestroyStages(t *testing.T) {
+	configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+		HCL:           `resource "random_id" "server" {}`,
+		DestroyStages: []v1beta1.DestroyStage{{Targets: []string{"random_id.server", "-lock=true"}}, {}},
+	}}
+	err := ValidateConfiguration(configuration)
+	if err == nil {
+		t.Fatal("expected errors about the destroy stages")
+	}
+	for _, msg := range []string{
+		`spec.destroyStages[0].targets[1]: Invalid value: "-lock=true"`,
+		"spec.destroyStages[1].targets: Required value",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %v", msg, err)
+		}
+	}
+	if strings.Contains(err.Error(), "spec.destroyStages[0].targets[0]") {
+		t.Errorf("expected the first target valid, got %v", err)
+	}
+}
+
+func TestValidateRemoteGit(t *testing.T) {
+	valid := []string{
+		"https://github.com/kubevela-contrib/terraform-modules.git",
+		"ssh://git@github.com/kubevela-contrib/terraform-modules.git",
+		"git@github.com:kubevela-contrib/terraform-modules.git",
+	}
+	for _, remote := range valid {
+		if err := ValidateRemoteGit(remote); err != nil {
+			t.Errorf("expected %s valid, got %v", remote, err)
+		}
+	}
+	invalid := []string{
+		"--upload-pack=touch /tmp/pwned",
+		"ext::sh -c touch% /tmp/pwned",
+		"https://github.com/org/repo.git; touch /tmp/pwned",
+		"file:///etc",
+		"https://",
+	}
+	for _, remote := range invalid {
+		if err := ValidateRemoteGit(remote); err == nil {
+			t.Errorf("expected %s invalid", remote)
+		}
+	}
+}
+
+func TestValidateConfigurationJobDeadline(t *testing.T) {
+	configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+		HCL:              `resource "random_id" "server" {}`,
+		JobDeadline:      &metav1.Duration{Duration: 30 * time.Minute},
+		JobRestartPolicy: v1.RestartPolicyNever,
+	}}
+	if err := ValidateConfiguration(configuration); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+
+	configuration.Spec.JobDeadline.Duration = 500 * time.Millisecond
+	configuration.Spec.JobRestartPolicy = v1.RestartPolicyAlways
+	err := ValidateConfiguration(configuration)
+	if err == nil {
+		t.Fatal("expected errors about the deadline and the restart policy")
+	}
+	for _, msg := range []string{
+		`spec.jobDeadline: Invalid value: "500ms"`,
+		`spec.jobRestartPolicy: Unsupported value: "Always"`,
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %v", msg, err)
+		}
+	}
+}
+
+func TestValidateConfigurationVariableFrom(t *testing.T) {
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+		Spec: v1beta1.ConfigurationSpec{
+			HCL: `resource "random_id" "server" {}`,
+			VariableFrom: []v1beta1.VariableFromOutput{
+				{Configuration: "net", Output: "vpc_id", Var: "vpc_id"},
+				{Configuration: "net", Namespace: "team-a", Output: "subnet_id", Var: "subnet_id"},
+			},
+		},
+	}
+	if err := ValidateConfiguration(configuration); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+
+	configuration.Spec.VariableFrom[1].Namespace = "team-b"
+	err := ValidateConfiguration(configuration)
+	if err == nil || !strings.Contains(err.Error(), `spec.variableFrom[1].namespace: Invalid value: "team-b"`) {
+		t.Errorf("expected an error about the namespace of another team, got %v", err)
+	}
+}
+
+func TestValidateConfigurationEntrypoint(t *testing.T) {
+	configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+		HCL:        `resource "random_id" "server" {}`,
+		Entrypoint: []string{"sh", "/opt/hooks/wrapper.sh"},
+	}}
+	if err := ValidateConfiguration(configuration); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+	configuration.Spec.Entrypoint = []string{" ", "/opt/hooks/wrapper.sh"}
+	if err := ValidateConfiguration(configuration); err == nil || !strings.Contains(err.Error(), "spec.entrypoint[0]") {
+		t.Errorf("expected an error about the empty executable, got %v", err)
+	}
+}
+
+func TestSetDefaultProvider(t *testing.T) {
+	testcases := map[string]struct {
+		ref             *crossplane.Reference
+		defaultProvider string
+		want            crossplane.Reference
+	}{
+		"referenced": {
+			ref:             &crossplane.Reference{Name: "aws", Namespace: "prod"},
+			defaultProvider: "alibaba",
+			want:            crossplane.Reference{Name: "aws", Namespace: "prod"},
+		},
+		"default of the controller": {
+			defaultProvider: "alibaba",
+			want:            crossplane.Reference{Name: "alibaba", Namespace: "default"},
+		},
+		"no default of the controller": {
+			want: crossplane.Reference{Name: "default", Namespace: "default"},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{ProviderReference: tc.ref}}
+			SetDefaultProvider(configuration, tc.defaultProvider)
+			SetDefaults(configuration)
+			if got := *configuration.Spec.ProviderReference; got != tc.want {
+				t.Errorf("expected the Provider %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
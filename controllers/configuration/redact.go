@@ -0,0 +1,166 @@
+package configuration
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+// RedactedValue replaces the sensitive values in the redacted configuration
+const RedactedValue = "(redacted)"
+
+var (
+	// sensitiveNameRegexp matches the names of the attributes and blocks whose values are taken as credentials, like
+	// access_key, secret_key, password and token of the backends and providers
+	sensitiveNameRegexp = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|access_key|private_key|api_key|sas_key|client_certificate)`)
+	// hclAttributeRegexp matches an attribute of HCL and captures its name and value
+	hclAttributeRegexp = regexp.MustCompile(`^(\s*"?([A-Za-z0-9_.-]+)"?\s*=\s*)(.*)$`)
+	// hclBlockRegexp matches the header of a block of HCL, like `variable "db_password" {`
+	hclBlockRegexp = regexp.MustCompile(`^\s*[A-Za-z0-9_-]+((\s+"?[A-Za-z0-9_.-]+"?)*)\s*\{\s*$`)
+	// oneLineBlockRegexp matches a block of HCL in one line, like `variable "db_password" { default = "..." }`
+	oneLineBlockRegexp = regexp.MustCompile(`^(\s*[A-Za-z0-9_-]+((\s+"?[A-Za-z0-9_.-]+"?)*)\s*\{).*(\}\s*)$`)
+	// inlineAttributeRegexp matches the attributes in one line, like the ones of a one-line block or map
+	inlineAttributeRegexp = regexp.MustCompile(`([A-Za-z0-9_.-]+"?\s*[=:]\s*)("[^"]*"|[^\s,}]+)`)
+	// heredocRegexp matches the start of a heredoc and captures its terminator
+	heredocRegexp = regexp.MustCompile(`^<<-?\s*([A-Za-z0-9_]+)\s*$`)
+	// urlUserInfoRegexp matches the user info of a URL, which could carry a password
+	urlUserInfoRegexp = regexp.MustCompile(`://[^/\s"'@]+@`)
+)
+
+// RedactConfiguration returns a copy of the rendered configuration with the sensitive values redacted, which is safe
+// to be inspected. The credentials of Providers and backends are passed to the Jobs by environment variables, so
+// only the values written in the configuration, like a custom backend, need to be redacted.
+func RedactConfiguration(configurationType types.ConfigurationType, configuration string) string {
+	if configurationType == types.ConfigurationJSON {
+		var value interface{}
+		if err := json.Unmarshal([]byte(configuration), &value); err == nil {
+			if redacted, err := json.MarshalIndent(redactJSON(value, false), "", "  "); err == nil {
+				return string(redacted)
+			}
+		}
+	}
+	return redactHCL(configuration)
+}
+
+func redactJSON(value interface{}, sensitive bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = redactJSON(item, sensitive || sensitiveNameRegexp.MatchString(k))
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSON(item, sensitive)
+		}
+		return v
+	case string:
+		if sensitive {
+			return RedactedValue
+		}
+		return redactURLUserInfo(v)
+	default:
+		if sensitive && v != nil {
+			return RedactedValue
+		}
+		return v
+	}
+}
+
+// redactHCL redacts HCL line by line, as the configuration isn't guaranteed to be parsed, like the one with a syntax
+// error which is being debugged. The values of the sensitive attributes, and all the values in a sensitive block,
+// like `variable "db_password" { default = "..." }`, are redacted.
+func redactHCL(configuration string) string {
+	lines := strings.Split(configuration, "\n")
+	var (
+		// the depths of the braces which the sensitive blocks are closed at
+		sensitiveBlocks []int
+		depth           int
+		// the terminator of the heredoc being skipped, whose lines are redacted if it's sensitive
+		heredoc          string
+		heredocSensitive bool
+	)
+	for i, line := range lines {
+		if heredoc != "" {
+			if strings.TrimSpace(line) == heredoc {
+				heredoc = ""
+				continue
+			}
+			if heredocSensitive {
+				lines[i] = RedactedValue
+			} else {
+				lines[i] = redactURLUserInfo(line)
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		if m := hclBlockRegexp.FindStringSubmatch(line); m != nil {
+			depth++
+			if len(sensitiveBlocks) > 0 || sensitiveNameRegexp.MatchString(m[1]) {
+				sensitiveBlocks = append(sensitiveBlocks, depth)
+			}
+			continue
+		}
+		if m := hclAttributeRegexp.FindStringSubmatch(line); m != nil {
+			sensitive := len(sensitiveBlocks) > 0 || sensitiveNameRegexp.MatchString(m[2])
+			value := strings.TrimSpace(m[3])
+			if h := heredocRegexp.FindStringSubmatch(value); h != nil {
+				heredoc, heredocSensitive = h[1], sensitive
+				continue
+			}
+			// a map or list spanning lines is redacted as a sensitive block
+			if nested := nesting(value); nested > 0 {
+				depth += nested
+				if sensitive {
+					sensitiveBlocks = append(sensitiveBlocks, depth)
+				}
+				continue
+			}
+			if sensitive && value != "" {
+				lines[i] = m[1] + `"` + RedactedValue + `"`
+			} else {
+				lines[i] = redactLine(line)
+			}
+			continue
+		}
+		if m := oneLineBlockRegexp.FindStringSubmatch(line); m != nil && (len(sensitiveBlocks) > 0 || sensitiveNameRegexp.MatchString(m[2])) {
+			lines[i] = m[1] + " " + RedactedValue + " " + m[4]
+		} else if len(sensitiveBlocks) > 0 && nesting(line) == 0 && trimmed != "" {
+			lines[i] = RedactedValue
+		} else {
+			lines[i] = redactLine(line)
+		}
+		depth += nesting(line)
+		for len(sensitiveBlocks) > 0 && depth < sensitiveBlocks[len(sensitiveBlocks)-1] {
+			sensitiveBlocks = sensitiveBlocks[:len(sensitiveBlocks)-1]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// nesting returns how many braces and brackets a line opens, or closes if it's negative
+func nesting(line string) int {
+	return strings.Count(line, "{") + strings.Count(line, "[") - strings.Count(line, "}") - strings.Count(line, "]")
+}
+
+// redactLine redacts the sensitive attributes in one line, and the user info of the URLs
+func redactLine(line string) string {
+	line = inlineAttributeRegexp.ReplaceAllStringFunc(line, func(attribute string) string {
+		m := inlineAttributeRegexp.FindStringSubmatch(attribute)
+		if !sensitiveNameRegexp.MatchString(m[1]) {
+			return attribute
+		}
+		return m[1] + `"` + RedactedValue + `"`
+	})
+	return redactURLUserInfo(line)
+}
+
+func redactURLUserInfo(s string) string {
+	return urlUserInfoRegexp.ReplaceAllString(s, "://"+RedactedValue+"@")
+}
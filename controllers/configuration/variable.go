@@ -0,0 +1,206 @@
+package configuration
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+var (
+	hclVariableBlockRegexp = regexp.MustCompile(`(?m)^[ \t]*variable[ \t]+"?([\w-]+)"?[ \t]*\{`)
+	hclDefaultAttrRegexp   = regexp.MustCompile(`(?m)^[ \t]*default[ \t]*=`)
+	hclHeredocRegexp       = regexp.MustCompile(`^<<-?([A-Za-z_][\w-]*)[ \t]*\n`)
+	hclTopAttributeRegexp  = regexp.MustCompile(`(?m)^[ \t]*([A-Za-z_][\w-]*)[ \t]*=[^=]`)
+)
+
+// IsVarFile tells whether a file is a .tfvars or .tfvars.json file, which is parsed by its suffix
+func IsVarFile(name string) bool {
+	return strings.HasSuffix(name, ".tfvars") || strings.HasSuffix(name, ".tfvars.json")
+}
+
+// GetVarFileVariables gets the names of the variables set by a .tfvars or .tfvars.json file
+func GetVarFileVariables(name, content string) ([]string, error) {
+	var variables []string
+	if strings.HasSuffix(name, ".json") {
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(content), &values); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the var file %s", name)
+		}
+		for k, v := range values {
+			// a null variable isn't passed to Terraform
+			if v != nil {
+				variables = append(variables, k)
+			}
+		}
+		sort.Strings(variables)
+		return variables, nil
+	}
+
+	skeleton := hclSkeleton(content)
+	for _, loc := range hclTopAttributeRegexp.FindAllStringSubmatchIndex(skeleton, -1) {
+		// the attributes of the objects aren't variables
+		if strings.Count(skeleton[:loc[0]], "{") != strings.Count(skeleton[:loc[0]], "}") {
+			continue
+		}
+		variables = append(variables, skeleton[loc[2]:loc[3]])
+	}
+	sort.Strings(variables)
+	return variables, nil
+}
+
+// GetRequiredVariables gets the names of the variables which are declared without a default value in the configuration.
+// The variables of a remote configuration are unknown before it's cloned, so nothing is returned for it.
+func GetRequiredVariables(configurationType types.ConfigurationType, configuration string) ([]string, error) {
+	var (
+		required []string
+		err      error
+	)
+	switch configurationType {
+	case types.ConfigurationHCL:
+		required = getHCLRequiredVariables(configuration)
+	case types.ConfigurationJSON:
+		required, err = getJSONRequiredVariables(configuration)
+	}
+	sort.Strings(required)
+	return required, err
+}
+
+// CheckRequiredVariables checks all the required variables are supplied, and returns the missing ones
+func CheckRequiredVariables(configurationType types.ConfigurationType, configuration string, variables map[string]interface{}) ([]string, error) {
+	required, err := GetRequiredVariables(configurationType, configuration)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, name := range required {
+		// a null variable isn't passed to Terraform
+		if value, ok := variables[name]; !ok || value == nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+func getJSONRequiredVariables(configuration string) ([]string, error) {
+	var config struct {
+		Variable map[string]map[string]interface{} `json:"variable"`
+	}
+	if err := json.Unmarshal([]byte(configuration), &config); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the variables of the JSON configuration")
+	}
+	var required []string
+	for name, v := range config.Variable {
+		if _, ok := v["default"]; !ok {
+			required = append(required, name)
+		}
+	}
+	return required, nil
+}
+
+func getHCLRequiredVariables(configuration string) []string {
+	skeleton := hclSkeleton(configuration)
+	var required []string
+	for _, loc := range hclVariableBlockRegexp.FindAllStringSubmatchIndex(skeleton, -1) {
+		// only top level blocks declare variables
+		if strings.Count(skeleton[:loc[0]], "{") != strings.Count(skeleton[:loc[0]], "}") {
+			continue
+		}
+		name := skeleton[loc[2]:loc[3]]
+		if !hclDefaultAttrRegexp.MatchString(hclBlockAttributes(skeleton[loc[1]:])) {
+			required = append(required, name)
+		}
+	}
+	return required
+}
+
+// hclBlockAttributes returns the body of a block which starts right after its opening brace, without the nested blocks
+func hclBlockAttributes(body string) string {
+	var b strings.Builder
+	depth := 0
+	for _, c := range body {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return b.String()
+			}
+			depth--
+		default:
+			if depth == 0 {
+				b.WriteRune(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// hclSkeleton removes comments and heredocs from HCL, and blanks out the braces in strings, so that blocks could be
+// found by matching braces
+func hclSkeleton(src string) string {
+	var b strings.Builder
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '#' || (c == '/' && i+1 < len(src) && src[i+1] == '/'):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			if i < len(src) {
+				b.WriteByte('\n')
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			b.WriteString(strings.Repeat("\n", strings.Count(src[i:i+2+end], "\n")))
+			i += end + 3
+		case c == '<' && hclHeredocRegexp.MatchString(src[i:]):
+			m := hclHeredocRegexp.FindStringSubmatch(src[i:])
+			i += len(m[0])
+			// skip the lines until the closing marker
+			for i < len(src) {
+				end := strings.IndexByte(src[i:], '\n')
+				line := src[i:]
+				if end >= 0 {
+					line = src[i : i+end]
+				}
+				if strings.TrimSpace(line) == m[1] {
+					i += len(line) - 1
+					break
+				}
+				if end < 0 {
+					i = len(src)
+					break
+				}
+				i += end + 1
+			}
+			b.WriteString(`""`)
+		case c == '"':
+			b.WriteByte(c)
+			for i++; i < len(src) && src[i] != '"' && src[i] != '\n'; i++ {
+				switch src[i] {
+				case '\\':
+					b.WriteString("  ")
+					i++
+				case '{', '}':
+					b.WriteByte(' ')
+				default:
+					b.WriteByte(src[i])
+				}
+			}
+			if i < len(src) {
+				b.WriteByte(src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
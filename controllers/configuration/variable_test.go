@@ -0,0 +1,130 @@
+package configuration
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+func TestGetRequiredVariables(t *testing.T) {
+	testcases := map[string]struct {
+		configurationType types.ConfigurationType
+		configuration     string
+		required          []string
+		wantErr           bool
+	}{
+		"hcl": {
+			configurationType: types.ConfigurationHCL,
+			configuration: `
+# variable "commented" {}
+variable "bucket" {
+  description = "The name of the bucket {"
+  type        = string
+}
+
+variable acl {
+  default = "private"
+}
+
+variable "tags" {
+  type = map(string)
+  validation {
+    condition = length(var.tags) > 0
+    default   = "not an attribute of the variable"
+  }
+}
+
+/*
+variable "block_commented" {}
+*/
+resource "alicloud_oss_bucket" "bucket-acl" {
+  bucket = var.bucket
+  acl    = var.acl
+  policy = <<POLICY
+variable "in_heredoc" {}
+}
+POLICY
+}
+
+module "nested" {
+  variable "not_top_level" {}
+}
+
+variable "nullable" {
+  default = null
+}
+`,
+			required: []string{"bucket", "tags"},
+		},
+		"json": {
+			configurationType: types.ConfigurationJSON,
+			configuration:     `{"variable": {"bucket": {"type": "string"}, "acl": {"default": "private"}}, "resource": {}}`,
+			required:          []string{"bucket"},
+		},
+		"invalid json": {
+			configurationType: types.ConfigurationJSON,
+			configuration:     `{`,
+			wantErr:           true,
+		},
+		"remote": {
+			configurationType: types.ConfigurationRemote,
+			configuration:     `variable "bucket" {}`,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			required, err := GetRequiredVariables(tc.configurationType, tc.configuration)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(required, tc.required) {
+				t.Errorf("expected required variables %v, got %v", tc.required, required)
+			}
+		})
+	}
+}
+
+func TestCheckRequiredVariables(t *testing.T) {
+	hcl := `variable "bucket" {}
+variable "acl" {}
+variable "region" { default = "cn-beijing" }`
+	missing, err := CheckRequiredVariables(types.ConfigurationHCL, hcl, map[string]interface{}{"bucket": "b", "acl": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing, []string{"acl"}) {
+		t.Errorf("expected missing variables [acl], got %v", missing)
+	}
+}
+
+func TestGetVarFileVariables(t *testing.T) {
+	tfvars := `# the network
+cidr = "10.0.0.0/16"
+tags = {
+  env = "prod"
+}
+description = <<EOT
+name = not a variable
+EOT
+zones = ["a", "b"]
+`
+	variables, err := GetVarFileVariables("prod.tfvars", tfvars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"cidr", "description", "tags", "zones"}; !reflect.DeepEqual(variables, expected) {
+		t.Errorf("expected %v, got %v", expected, variables)
+	}
+
+	variables, err = GetVarFileVariables("prod.tfvars.json", `{"cidr": "10.0.0.0/16", "tags": {"env": "prod"}, "unset": null}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"cidr", "tags"}; !reflect.DeepEqual(variables, expected) {
+		t.Errorf("expected %v, got %v", expected, variables)
+	}
+	if _, err := GetVarFileVariables("prod.tfvars.json", `{`); err == nil {
+		t.Error("expected an error about the invalid JSON")
+	}
+}
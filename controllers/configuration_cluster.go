@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/terraform"
+)
+
+// clusterTimeout bounds the requests to a workload cluster, so that an unreachable one doesn't block the reconciliation
+const clusterTimeout = 30 * time.Second
+
+// clusterKey is the key of the client of the workload cluster in the context of a reconciliation
+type clusterKey struct{}
+
+// workloadCluster is a cluster which the sub-resources of Configurations are created in, connected by the kubeconfig
+// in a Secret
+type workloadCluster struct {
+	// resourceVersion is the one of the kubeconfig Secret, which tells whether the clients are out of date
+	resourceVersion string
+	client          client.Client
+	clientSet       kubernetes.Interface
+}
+
+// connectCluster returns a context which carries the clients of the workload cluster of spec.cluster, which are cached
+// until the kubeconfig Secret is changed. The context is returned as it is when spec.cluster isn't set.
+func (r *ConfigurationReconciler) connectCluster(ctx context.Context, configuration v1beta1.Configuration) (context.Context, error) {
+	cluster := configuration.Spec.Cluster
+	if cluster == nil {
+		return ctx, nil
+	}
+	ref := cluster.KubeconfigSecretRef
+	var secret v1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: configuration.Namespace}, &secret); err != nil {
+		return ctx, errors.Wrapf(err, "failed to get the kubeconfig Secret %s of the cluster", ref.Name)
+	}
+
+	key := configuration.Namespace + "/" + ref.Name + "/" + ref.Key
+	if cached, ok := r.clusters.Load(key); ok && cached.(*workloadCluster).resourceVersion == secret.ResourceVersion {
+		return withCluster(ctx, cached.(*workloadCluster)), nil
+	}
+	kubeconfig, ok := secret.Data[ref.Key]
+	if !ok {
+		return ctx, errors.Errorf("the kubeconfig %s is not found in the Secret %s", ref.Key, ref.Name)
+	}
+	c, err := newWorkloadCluster(kubeconfig, r.Scheme)
+	if err != nil {
+		return ctx, errors.Wrapf(err, "failed to connect to the cluster by the kubeconfig Secret %s", ref.Name)
+	}
+	c.resourceVersion = secret.ResourceVersion
+	r.clusters.Store(key, c)
+	return withCluster(ctx, c), nil
+}
+
+// newWorkloadCluster creates the clients of a cluster by its kubeconfig
+func newWorkloadCluster(kubeconfig []byte, scheme *runtime.Scheme) (*workloadCluster, error) {
+	config, err := restConfigFromKubeconfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	config.Timeout = clusterTimeout
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &workloadCluster{client: c, clientSet: clientSet}, nil
+}
+
+// restConfigFromKubeconfig builds the config of the current context of a kubeconfig from its inline data only. The
+// kubeconfig is written by the users, so the exec plugins and the auth providers, which run commands or read the
+// credentials of the controller, and the files, which are the ones of the controller, are refused.
+func restConfigFromKubeconfig(kubeconfig []byte) (*rest.Config, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	for name, auth := range config.AuthInfos {
+		switch {
+		case auth.Exec != nil:
+			return nil, errors.Errorf("the exec plugin of the user %s is not allowed", name)
+		case auth.AuthProvider != nil:
+			return nil, errors.Errorf("the auth provider of the user %s is not allowed", name)
+		case auth.TokenFile != "" || auth.ClientCertificate != "" || auth.ClientKey != "":
+			return nil, errors.Errorf("the files of the user %s are not allowed, which must be inline", name)
+		}
+	}
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return nil, errors.Errorf("the certificate authority file of the cluster %s is not allowed, which must be inline", name)
+		}
+	}
+
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, errors.Errorf("the current context %q is not found", config.CurrentContext)
+	}
+	cluster, ok := config.Clusters[kubeContext.Cluster]
+	if !ok || cluster.Server == "" {
+		return nil, errors.Errorf("the server of the cluster %q is not found", kubeContext.Cluster)
+	}
+	restConfig := &rest.Config{
+		Host: cluster.Server,
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure:   cluster.InsecureSkipTLSVerify,
+			ServerName: cluster.TLSServerName,
+			CAData:     cluster.CertificateAuthorityData,
+		},
+	}
+	if auth, ok := config.AuthInfos[kubeContext.AuthInfo]; ok {
+		restConfig.TLSClientConfig.CertData = auth.ClientCertificateData
+		restConfig.TLSClientConfig.KeyData = auth.ClientKeyData
+		restConfig.BearerToken = auth.Token
+		restConfig.Username = auth.Username
+		restConfig.Password = auth.Password
+		restConfig.Impersonate = rest.ImpersonationConfig{
+			UserName: auth.Impersonate,
+			Groups:   auth.ImpersonateGroups,
+			Extra:    auth.ImpersonateUserExtra,
+		}
+	}
+	return restConfig, nil
+}
+
+// withCluster returns a context whose sub-resources are in the workload cluster
+func withCluster(ctx context.Context, c *workloadCluster) context.Context {
+	return terraform.WithClientSet(context.WithValue(ctx, clusterKey{}, c.client), c.clientSet)
+}
+
+// clusterClient is the client of the cluster which the Jobs and the other sub-resources are in, which is the workload
+// cluster carried by the context if spec.cluster is set, or the cluster of the controller
+func clusterClient(ctx context.Context, k8sClient client.Client) client.Client {
+	if c, ok := ctx.Value(clusterKey{}).(client.Client); ok {
+		return c
+	}
+	return k8sClient
+}
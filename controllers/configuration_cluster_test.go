@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestConnectCluster(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-kubeconfig", Namespace: "default"},
+		Data:       map[string][]byte{"config": []byte("apiVersion: v1")},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, secret)
+	r := &ConfigurationReconciler{Client: k8sClient, Scheme: s}
+
+	configuration := v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "default", UID: "uid"}}
+	got, err := r.connectCluster(ctx, configuration)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != ctx || clusterClient(got, k8sClient) != k8sClient {
+		t.Error("expected the cluster of the controller without spec.cluster")
+	}
+
+	configuration.Spec.Cluster = &v1beta1.ClusterReference{
+		KubeconfigSecretRef: v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "missing"}, Key: "config"},
+		Namespace:           "terraform",
+	}
+	if _, err := r.connectCluster(ctx, configuration); err == nil || !strings.Contains(err.Error(), "failed to get the kubeconfig Secret missing") {
+		t.Errorf("expected an error about the missing Secret, got %v", err)
+	}
+	configuration.Spec.Cluster.KubeconfigSecretRef = v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "prod-kubeconfig"}, Key: "kubeconfig"}
+	if _, err := r.connectCluster(ctx, configuration); err == nil || !strings.Contains(err.Error(), "is not found in the Secret") {
+		t.Errorf("expected an error about the missing key, got %v", err)
+	}
+
+	// the sub-resources in another cluster can't be owned by the Configuration
+	if refs := ownerReferences(configuration, "default"); refs != nil {
+		t.Errorf("expected no owner references, got %v", refs)
+	}
+}
+
+func TestRestConfigFromKubeconfig(t *testing.T) {
+	kubeconfig := func(cluster, user string) []byte {
+		return []byte(`apiVersion: v1
+kind: Config
+current-context: prod
+contexts:
+- name: prod
+  context: {cluster: prod, user: admin}
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+` + cluster + `
+users:
+- name: admin
+  user:
+` + user)
+	}
+
+	config, err := restConfigFromKubeconfig(kubeconfig("    certificate-authority-data: Y2E=", "    token: t0ken"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Host != "https://prod.example.com:6443" || config.BearerToken != "t0ken" || string(config.CAData) != "ca" {
+		t.Errorf("expected the config from the inline data, got %v", config)
+	}
+
+	for name, tc := range map[string]struct {
+		cluster string
+		user    string
+		err     string
+	}{
+		"exec plugin": {
+			user: "    exec:\n      apiVersion: client.authentication.k8s.io/v1beta1\n      command: cat\n      args: [/etc/passwd]",
+			err:  "exec plugin",
+		},
+		"auth provider": {
+			user: "    auth-provider:\n      name: gcp",
+			err:  "auth provider",
+		},
+		"token file": {
+			user: "    tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token",
+			err:  "files of the user admin",
+		},
+		"client certificate file": {
+			user: "    client-certificate: /etc/kubernetes/pki/admin.crt\n    client-key: /etc/kubernetes/pki/admin.key",
+			err:  "files of the user admin",
+		},
+		"certificate authority file": {
+			cluster: "    certificate-authority: /etc/kubernetes/pki/ca.crt",
+			user:    "    token: t0ken",
+			err:     "certificate authority file",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := restConfigFromKubeconfig(kubeconfig(tc.cluster, tc.user)); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected an error about the %s, got %v", name, err)
+			}
+		})
+	}
+}
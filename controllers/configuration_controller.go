@@ -17,20 +17,35 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math"
 	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -39,15 +54,26 @@ import (
 	"github.com/oam-dev/terraform-controller/api/types"
 	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
 	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/backend"
 	cfgvalidator "github.com/oam-dev/terraform-controller/controllers/configuration"
+	"github.com/oam-dev/terraform-controller/controllers/runner"
 	"github.com/oam-dev/terraform-controller/controllers/terraform"
 	"github.com/oam-dev/terraform-controller/controllers/util"
 )
 
 const (
 	// TerraformImage is the Terraform image which can run `terraform init/plan/apply`
-	terraformImage     = "oamdev/docker-terraform:1.0.7"
-	terraformWorkspace = "default"
+	terraformImage = "oamdev/docker-terraform:1.0.7"
+	// openTofuImage is the OpenTofu image which can run `tofu init/plan/apply`
+	openTofuImage = "ghcr.io/opentofu/opentofu:1.6.2"
+	// orasImage is the image which pulls the configuration packaged as an OCI artifact
+	orasImage = "ghcr.io/oras-project/oras:v1.2.0"
+	// cosignImage is the image which verifies the signature of the OCI artifact
+	cosignImage = "ghcr.io/sigstore/cosign/cosign:v2.2.4"
+	// runnerImage is the image of the controller, which ships the runner binary at runnerImageBinary
+	runnerImage = "oamdev/terraform-controller:0.2.4"
+	// runnerImageBinary is the runner binary in the image of the controller
+	runnerImageBinary = "/runner"
 )
 
 const (
@@ -57,17 +83,107 @@ const (
 	InputTFConfigurationVolumeName = "tf-input-configuration"
 	// BackendVolumeName is the volume name for Terraform backend
 	BackendVolumeName = "tf-backend"
+	// PluginCacheVolumeName is the volume name for the shared plugin cache
+	PluginCacheVolumeName = "tf-plugin-cache"
+	// PluginCacheMountPath is the mount path of the shared plugin cache, which TF_PLUGIN_CACHE_DIR points to
+	PluginCacheMountPath = "/plugin-cache"
+	// PlanArtifactsVolumeName is the volume name for the saved plans which are applied once they are approved
+	PlanArtifactsVolumeName = "tf-plan-artifacts"
+	// PlanArtifactsMountPath is the mount path of the saved plans of a Configuration, which is its own directory of the
+	// volume
+	PlanArtifactsMountPath = "/plan-artifacts"
+	// CLIConfigVolumeName is the volume name for the CLI configuration of Terraform
+	CLIConfigVolumeName = "tf-cli-config"
+	// CLIConfigMountPath is the mount path of the CLI configuration of Terraform
+	CLIConfigMountPath = "/etc/terraform-cli"
+	// CLIConfigKey is the key of the CLI configuration in its ConfigMap or Secret, like the provider_installation
+	// block which installs the providers from a network mirror
+	CLIConfigKey = ".terraformrc"
+	// OCIRegistryConfigVolumeName is the volume name for the credentials of the registry of the OCI artifact
+	OCIRegistryConfigVolumeName = "oci-registry-config"
+	// OCIRegistryConfigMountPath is the mount path of the credentials of the registry of the OCI artifact
+	OCIRegistryConfigMountPath = "/etc/oras"
+	// TrustedKeysVolumeName is the volume name for the keys trusted to sign the source of the configuration
+	TrustedKeysVolumeName = "source-trusted-keys"
+	// TrustedKeysMountPath is the mount path of the keys trusted to sign the source of the configuration
+	TrustedKeysMountPath = "/etc/trusted-keys"
+	// AllowedSignersKey is the key of the SSH keys trusted to sign the commits of the remote git repo
+	AllowedSignersKey = "allowed_signers"
+	// CosignPublicKeyKey is the key of the public key trusted to sign the OCI artifact
+	CosignPublicKeyKey = "cosign.pub"
 	// InputTFConfigurationVolumeMountPath is the volume mount path for input Terraform Configuration
 	InputTFConfigurationVolumeMountPath = "/opt/tf-configuration"
 	// BackendVolumeMountPath is the volume mount path for Terraform backend
 	BackendVolumeMountPath = "/opt/tf-backend"
+	// RunnerVolumeName is the volume name for the runner binary installed to the Jobs
+	RunnerVolumeName = "tf-runner"
+	// RunnerMountPath is the mount path of the runner binary installed to the Jobs
+	RunnerMountPath = "/opt/tf-runner"
+)
+
+const (
+	// terraformExecutorContainerName is the name of the container running Terraform in the Jobs
+	terraformExecutorContainerName = "terraform-executor"
+	// terminationMessagePath is where the executor writes the outputs when the state is managed externally
+	terminationMessagePath = "/dev/termination-log"
+	// planFile is the plan waiting for approval, which is saved in the working directory
+	planFile = "tfplan"
+	// planTextFile is the text of the plan, whose hash approves it
+	planTextFile = "tfplan.txt"
+	// savedPlanTextFile is the text of the saved plan, which is hashed again before it's applied
+	savedPlanTextFile = "tfplan.saved.txt"
+	// runnerBinary is the runner binary installed to the Jobs, which runs the steps of the executor
+	runnerBinary = RunnerMountPath + "/runner"
+	// executorTerminationGracePeriodSeconds gives Terraform time to finish the in-flight requests and write the state
+	// when the pod of the apply or destroy is evicted
+	executorTerminationGracePeriodSeconds int64 = 300
 )
 
 const (
 	// TerraformStateNameInSecret is the key name to store Terraform state
-	TerraformStateNameInSecret = "tfstate"
+	TerraformStateNameInSecret = backend.TerraformStateNameInSecret
 	// TFInputConfigMapName is the CM name for Terraform Input Configuration
 	TFInputConfigMapName = "%s-tf-input"
+	// PostApplyJobName is the name of the Job which runs the post-apply hooks
+	PostApplyJobName = "%s-post-apply"
+	// OutputEnvPrefix is the prefix of the environment variables of outputs in post-apply hooks
+	OutputEnvPrefix = "TF_OUTPUT_"
+	// FailedJobLogsConfigMapName is the name of the ConfigMap which keeps the logs of a failed Job
+	FailedJobLogsConfigMapName = "%s-failed-logs"
+	// RenderedConfigMapName is the name of the ConfigMap which keeps the redacted copy of the rendered configuration
+	RenderedConfigMapName = "%s-tf-rendered"
+	// RenderedConfigurationLabel labels the ConfigMap of the rendered configuration, whose value is always redacted
+	RenderedConfigurationLabel = "terraform.core.oam.dev/rendered-configuration"
+	// FailedJobLogsKey is the key of the logs in the ConfigMap of the logs of a failed Job
+	FailedJobLogsKey = "logs"
+	// ImportsHashAnnotation records the hash of spec.imports which the apply Job imports
+	ImportsHashAnnotation = "terraform.core.oam.dev/imports-hash"
+	// ConfigurationHashAnnotation records the hash of the configuration which the apply Job applies, which tells
+	// whether the Job is out of date after it's left to finish
+	ConfigurationHashAnnotation = "terraform.core.oam.dev/configuration-hash"
+	// DestroyStageAnnotation records the index of the stage of spec.destroyStages which the destroy Job destroys, which
+	// is the number of the stages for the destroy of all the resources left
+	DestroyStageAnnotation = "terraform.core.oam.dev/destroy-stage"
+	// ApplyJobUIDAnnotation records the UID of the apply Job which a post-apply Job runs after
+	ApplyJobUIDAnnotation = "terraform.core.oam.dev/apply-job-uid"
+	// neverRestartBackoffLimit is the backoff limit of the apply and destroy Jobs whose pods aren't restarted, which
+	// is the default of Kubernetes
+	neverRestartBackoffLimit int32 = 6
+	// maxConfigMapDataSize is the max size of the data of the input ConfigMap. A ConfigMap can't exceed 1MiB, and
+	// some room is left for its metadata.
+	maxConfigMapDataSize = 1024*1024 - 16*1024
+	// reconcileTimeoutRecordTimeout is how long the timeout of a reconciliation could take to be recorded
+	reconcileTimeoutRecordTimeout = 10 * time.Second
+)
+
+// errConfigurationTooLarge means the configuration can't be stored in a ConfigMap even if it's compressed
+var errConfigurationTooLarge = errors.New("the configuration exceeds the 1MiB size limit of ConfigMap")
+
+const (
+	// LabelKeyOwnedBy is the label key of the name of the Configuration which owns a resource
+	LabelKeyOwnedBy = "terraform.core.oam.dev/owned-by"
+	// LabelKeyOwnedNamespace is the label key of the namespace of the Configuration which owns a resource
+	LabelKeyOwnedNamespace = "terraform.core.oam.dev/owned-namespace"
 )
 
 // TerraformExecutionType is the type for Terraform execution
@@ -78,12 +194,90 @@ const (
 	TerraformApply TerraformExecutionType = "apply"
 	// TerraformDestroy is the name to mark `terraform destroy`
 	TerraformDestroy TerraformExecutionType = "destroy"
+	// TerraformValidate is the name to mark `terraform validate`
+	TerraformValidate TerraformExecutionType = "validate"
+	// TerraformStateRemove is the name to mark `terraform state rm`
+	TerraformStateRemove TerraformExecutionType = "state-rm"
+	// TerraformPlan is the name to mark `terraform plan` whose approval an apply waits for
+	TerraformPlan TerraformExecutionType = "plan"
+	// TerraformRefresh is the name to mark `terraform apply -refresh-only`
+	TerraformRefresh TerraformExecutionType = "refresh"
+	// TerraformMigrateState is the name to mark `terraform init -migrate-state`
+	TerraformMigrateState TerraformExecutionType = "migrate-state"
 )
 
 const (
 	configurationFinalizer = "configuration.finalizers.terraform-controller"
+	// defaultExecutorServiceAccountName is the ServiceAccount created by the chart for the Terraform Jobs
+	defaultExecutorServiceAccountName = "tf-executor-service-account"
+)
+
+const (
+	// ReasonApplyTimeout is the event reason when the apply Job doesn't complete in time
+	ReasonApplyTimeout = "ApplyTimeout"
+	// ReasonApplyJobFailed is the event reason when the apply Job failed without the logs telling why
+	ReasonApplyJobFailed = "ApplyJobFailed"
+	// ReasonDestroyTimeout is the event reason when the destroy doesn't complete in time
+	ReasonDestroyTimeout = "DestroyTimeout"
+	// ReasonForceDeleting is the reason of the condition DestroyTimedOut when the Configuration is force deleted
+	ReasonForceDeleting = "ForceDeleting"
+	// ReasonPaused is the event reason when the Configuration is paused
+	ReasonPaused = "Paused"
+	// ReasonResumed is the event reason when the Configuration is resumed
+	ReasonResumed = "Resumed"
+	// ReasonReconcileTimedOut is the event reason when the reconciliation doesn't finish in time
+	ReasonReconcileTimedOut = "ReconcileTimedOut"
+	// ReasonReconcileFinished is the reason of the ReconcileTimedOut condition when the reconciliation finishes in time
+	// again
+	ReasonReconcileFinished = "ReconcileFinished"
+	// ReasonJobEvicted is the event reason when the pod of the apply or destroy Job is evicted
+	ReasonJobEvicted = "JobEvicted"
+	// ReasonInputsChanged is the event reason when the variables of a Job changed, which re-creates it
+	ReasonInputsChanged = "InputsChanged"
+	// ReasonDestroyed is the event reason when the cloud resources of a deleted Configuration are destroyed
+	ReasonDestroyed = "Destroyed"
+	// ReasonDeletionBlocked is the event reason when the deletion of a protected Configuration is blocked
+	ReasonDeletionBlocked = "DeletionBlocked"
 )
 
+// PauseAnnotation pauses the reconciliation of a Configuration when it's "true", so that neither apply nor destroy
+// is performed, e.g. during incident response. Removing it resumes the reconciliation.
+const PauseAnnotation = "terraform.core.oam.dev/pause"
+
+// ExecutionNamespaceAnnotation routes the Terraform Jobs, the state and the other sub-resources of a Configuration to
+// another namespace than the one of the controller, like the one of a team. It must be one of the execution
+// namespaces of the controller, and can't be changed once the Configuration is reconciled.
+const ExecutionNamespaceAnnotation = "terraform.core.oam.dev/execution-namespace"
+
+// DeletionProtectionAnnotation protects a Configuration from being deleted by accident when it's "true". The cloud
+// resources of a protected Configuration aren't destroyed after it's deleted, which keeps its finalizer and stays
+// DeletionBlocked until the annotation is removed.
+const DeletionProtectionAnnotation = "terraform.core.oam.dev/deletion-protection"
+
+// deletionBlockedRequeueInterval is how often a deleted Configuration whose deletion is blocked is checked again
+const deletionBlockedRequeueInterval = time.Minute
+
+// HoldFinalizerAnnotation holds the finalizer of a deleted Configuration after its cloud resources are destroyed, as
+// long as it's set, e.g. by the controllers which clean up the external resources like DNS records. Removing it lets
+// the Configuration go.
+const HoldFinalizerAnnotation = "terraform.core.oam.dev/hold-finalizer"
+
+// ApprovedAnnotation approves the plan whose hash is its value, which an apply waits for when spec.requireApproval is
+// set
+const ApprovedAnnotation = "terraform.core.oam.dev/approved"
+
+// defaultPodAnnotations are the annotations of the pods of Jobs. A sidecar injected by a service mesh keeps running
+// after Terraform exits, which prevents the Job from completing.
+var defaultPodAnnotations = map[string]string{
+	"sidecar.istio.io/inject": "false",
+}
+
+// notSafeToEvictAnnotations stop the cluster autoscaler from evicting the pods of the apply and destroy, which could
+// be interrupted in the middle of changing the cloud resources
+var notSafeToEvictAnnotations = map[string]string{
+	"cluster-autoscaler.kubernetes.io/safe-to-evict": "false",
+}
+
 const (
 	// MessageDestroyJobNotCompleted is the message when Configuration deletion isn't completed
 	MessageDestroyJobNotCompleted = "Configuration deletion isn't completed"
@@ -93,24 +287,113 @@ const (
 	MessageCloudResourceProvisioningAndChecking = "Cloud resources are being provisioned and provisioning status is checking..."
 	// ErrUpdateTerraformApplyJob means hitting  an issue to update Terraform apply job
 	ErrUpdateTerraformApplyJob = "Hit an issue to update Terraform apply job"
+	// MessageCloudResourceReapplying is the message when cloud resources are applied again after the apply interval
+	MessageCloudResourceReapplying = "Cloud resources are being re-applied after the apply interval"
 	// MessageCloudResourceDeployed means Cloud resources are deployed and ready to use
 	MessageCloudResourceDeployed = "Cloud resources are deployed and ready to use"
 	// MessageCloudResourceDestroying is the message when cloud resource is being destroyed
 	MessageCloudResourceDestroying = "Cloud resources is being destroyed..."
+	// MessageDestroyingStage is the message when a stage of spec.destroyStages is being destroyed
+	MessageDestroyingStage = "Cloud resources of stage %d of %d of spec.destroyStages are being destroyed..."
+	// MessageCloudResourceDestroyed is the message when cloud resources are destroyed as spec.destroy is set
+	MessageCloudResourceDestroyed = "Cloud resources are destroyed, set spec.destroy to false to apply them again"
 	// ErrProviderNotReady means provider object is not ready
 	ErrProviderNotReady = "Provider is not ready"
 	// MessageProviderReady means provider object is ready
 	MessageProviderReady = "Provider is ready"
 	// ConfigurationReloading means Configuration changed and needs reloading
 	ConfigurationReloading = "Configuration has changed and is reloading"
+	// MessageValidateJobNotCompleted is the message when the validation of the configuration isn't completed
+	MessageValidateJobNotCompleted = "Configuration is being validated"
+	// MessagePlanJobNotCompleted is the message when the plan waiting for approval isn't completed
+	MessagePlanJobNotCompleted = "The changes are being planned for approval"
+	// MessagePendingApproval is the message when the plan is waiting for approval
+	MessagePendingApproval = "The plan is waiting for approval, set the annotation " + ApprovedAnnotation + " to %s to apply it"
+	// MessagePlanExpired is the message when the saved plan wasn't applied in time, which is planned again
+	MessagePlanExpired = "The plan expired before it was applied, and is planned again for approval"
+	// MessagePlanChanged is the message when the plan changed after it was approved, which is planned again
+	MessagePlanChanged = "The plan changed after it was approved, and is planned again for approval"
+	// MessagePaused is the message when the reconciliation of the Configuration is paused
+	MessagePaused = "Reconciliation is paused by the annotation " + PauseAnnotation
+	// MessageResumed is the message when the reconciliation of the Configuration is resumed
+	MessageResumed = "Reconciliation is resumed"
+	// MessageReconcileTimedOut is the message when the reconciliation of the Configuration doesn't finish in time
+	MessageReconcileTimedOut = "Reconciliation didn't finish in %s, and is retried"
+	// MessageReconcileFinished is the message when the reconciliation of the Configuration finishes in time again
+	MessageReconcileFinished = "Reconciliation finished in time"
+	// MessageRequiredVariablesMissing is the message when some required variables are not set in spec.variable
+	MessageRequiredVariablesMissing = "Required variables are not set"
+	// MessageImportSuggestion suggests adopting the resource which exists in the cloud but not in the state
+	MessageImportSuggestion = "The resource already exists, add it to spec.imports to manage it by the Configuration"
+	// MessageFinalizerHeld is the message when the finalizer is held after the cloud resources are destroyed
+	MessageFinalizerHeld = "Cloud resources are destroyed, the finalizer is held by the annotation " + HoldFinalizerAnnotation +
+		" or the grace period of the controller"
+	// MessageDeleting is the event message when the finalizer is removed after the cloud resources are destroyed
+	MessageDeleting = "Cloud resources are destroyed, and the Configuration is being deleted"
+	// MessageDeletionBlocked is the message when the deletion of a protected Configuration is blocked
+	MessageDeletionBlocked = "The Configuration is protected from deletion, remove the annotation " + DeletionProtectionAnnotation +
+		" to destroy the cloud resources and delete it"
 )
 
 // ConfigurationReconciler reconciles a Configuration object.
 type ConfigurationReconciler struct {
 	client.Client
-	Log          logr.Logger
-	Scheme       *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// ProviderName is the default Provider in the namespace "default" of the Configurations which don't reference
+	// any, which falls back to the Provider "default" when it's empty
 	ProviderName string
+	// JobTTLSecondsAfterFinished is set to the apply and destroy Jobs, so that they are cleaned up after finishing
+	JobTTLSecondsAfterFinished *int32
+	// EnableStateSurgery allows removing resources from the state by the state-rm annotation
+	EnableStateSurgery bool
+	// WorkingVolume is the default of the emptyDir volumes of the Terraform executor, which spec.workingVolume of
+	// Configurations overrides
+	WorkingVolume v1beta1.WorkingVolume
+	// PluginCache is the volume of the plugin cache shared by the Terraform Jobs, like a PersistentVolumeClaim or a
+	// hostPath, so that the providers aren't downloaded by every init. Each namespace has its own directory of it, so
+	// the providers written by the Jobs of a namespace aren't used by the others. nil disables it.
+	PluginCache *v1.VolumeSource
+	// PlanArtifacts is the volume, like a PersistentVolumeClaim, where the plans of spec.requireApproval are saved, so
+	// that the apply Job applies the very plan which was approved rather than planning again. nil disables it.
+	PlanArtifacts *v1.VolumeSource
+	// PlanExpiry is how long a saved plan could wait to be applied, after which it's planned again, 0 disables it
+	PlanExpiry time.Duration
+	// CLIConfig is the volume of the CLI configuration of Terraform, a ConfigMap or Secret with the key CLIConfigKey,
+	// like the network mirror of the providers in an air-gapped cluster. nil disables it.
+	CLIConfig *v1.VolumeSource
+	// TerraformImage and OpenTofuImage are the images of the executor of the engines, which only need the binary of
+	// the engine, as the steps are run by the runner rather than a shell. The built-in ones are used when they are empty.
+	TerraformImage string
+	OpenTofuImage  string
+	// RunnerImage is the image shipping the runner binary, which is installed to the Jobs by an init container. It's
+	// the image of the controller, and the built-in one is used when it's empty.
+	RunnerImage string
+	// JSONLogs runs plan, apply and destroy with -json, whose machine-readable messages are parsed for the summary of
+	// the plan and the errors rather than the plain text. It needs Terraform 0.15.3 or later.
+	JSONLogs bool
+	// ApplyProgressInterval is the minimum interval to read the logs of a running apply Job for its progress, which
+	// bounds the requests to the API server however often a Configuration is reconciled. 0 disables it.
+	ApplyProgressInterval time.Duration
+	// CredentialsGracePeriod is how long a Provider or the Secret of its credentials which isn't found is waited for
+	// before the Configuration turns ProviderNotReady, as they may be created along with it. 0 disables it.
+	CredentialsGracePeriod time.Duration
+	// FinalizerGracePeriod is how long the finalizer of a deleted Configuration is held after its cloud resources are
+	// destroyed, e.g. for auditing. 0 removes it right away.
+	FinalizerGracePeriod time.Duration
+	// ExecutionNamespaces are the namespaces besides the one of the controller which the sub-resources of
+	// Configurations are allowed to be routed to by ExecutionNamespaceAnnotation
+	ExecutionNamespaces []string
+	// ReconcileTimeout is how long a reconciliation could take before its calls to the API servers and the backends
+	// are cancelled and the Configuration is requeued, so that a slow Configuration doesn't hold a worker. 0 disables
+	// it.
+	ReconcileTimeout time.Duration
+	// Health tracks the reconciliations for the health checks, which are not tracked when it's nil
+	Health *ReconcileHealth
+
+	// clusters caches the workload clusters of spec.cluster by their kubeconfig Secrets
+	clusters sync.Map
 }
 
 var controllerNamespace = os.Getenv("CONTROLLER_NAMESPACE")
@@ -122,29 +405,143 @@ type TFConfigurationMeta struct {
 	ConfigurationType     types.ConfigurationType
 	CompleteConfiguration string
 	RemoteGit             string
-	ConfigurationChanged  bool
-	ConfigurationCMName   string
-	BackendCMName         string
-	ApplyJobName          string
-	DestroyJobName        string
-	Envs                  []v1.EnvVar
-	ProviderReference     *crossplane.Reference
+	RemoteGitCommit       string
+	// RemoteGitPath is the directory of the configuration in the remote git repo
+	RemoteGitPath string
+	// OCI is the OCI artifact which the configuration is pulled from
+	OCI *v1beta1.OCISource
+	// Verify is the trust policy of the remote git repo or the OCI artifact
+	Verify *v1beta1.SourceVerification
+	// VarFiles are the names of the var files in their Secret, which are passed by -var-file in order
+	VarFiles []string
+	// VarFileVariables are the variables set by the var files
+	VarFileVariables []string
+	// VarFilesHash hashes the var files, which is empty without any var files
+	VarFilesHash string
+	varFilesData map[string][]byte
+	// executorVolumeSecrets and executorVolumeConfigMaps are the copies of the Secrets and ConfigMaps of spec.volumes
+	executorVolumeSecrets    []v1.Secret
+	executorVolumeConfigMaps []v1.ConfigMap
+	// OutputsFromJob means the state can't be read by the controller, and the outputs come from the apply Job
+	OutputsFromJob       bool
+	ConfigurationChanged bool
+	ConfigurationCMName  string
+	BackendCMName        string
+	ApplyJobName         string
+	DestroyJobName       string
+	ValidateJobName      string
+	PlanJobName          string
+	// ApprovedPlanHash is the hash of the approved plan, which is the only plan the apply Job applies
+	ApprovedPlanHash    string
+	PostApplyJobName    string
+	StateRemoveJobName  string
+	RefreshJobName      string
+	MigrateStateJobName string
+	// StateRemoveAddresses are the addresses of the resources which the state-rm Job removes from the state
+	StateRemoveAddresses []string
+	// PreviousBackendHCL is the backend block of the backend which the migrate-state Job migrates the state from
+	PreviousBackendHCL string
+	// DestroyStage is the index of the stage of spec.destroyStages which the destroy Job destroys
+	DestroyStage int
+	// DestroyTargets are the resources of the stage which the destroy Job destroys, or empty to destroy all of them
+	DestroyTargets []string
+	Envs           []v1.EnvVar
+	// Env are the environment variables of spec.env, which are passed to the containers of the Jobs as they are
+	Env               []v1.EnvVar
+	ProviderReference *crossplane.Reference
+	AliasedProviders  []v1beta1.AliasedProviderReference
+	// RegionOverride is the region of the Configuration, which overrides the one of the Provider
+	RegionOverride string
+	// Region is the effective region of the Provider, which is resolved along with its credentials
+	Region             string
+	Engine             types.EngineType
+	PodAnnotations     map[string]string
+	ServiceAccountName string
+	ExecutorVolumes    []v1beta1.ExecutorVolume
+	Imports            []v1beta1.ResourceImport
+	Labels             map[string]string
+	Annotations        map[string]string
+	OwnerReferences    []metav1.OwnerReference
+	// JobTTLSecondsAfterFinished is the TTL of the apply and destroy Jobs after they finish
+	JobTTLSecondsAfterFinished *int32
+	// JobActiveDeadlineSeconds is the deadline of the Jobs, which run without one when it's nil
+	JobActiveDeadlineSeconds *int64
+	// JobRestartPolicy is the restart policy of the pods of the apply and destroy Jobs
+	JobRestartPolicy v1.RestartPolicy
+	// Entrypoint wraps the command of the executor
+	Entrypoint []string
+	// LogLevel is TF_LOG of the executor, which isn't set when it's empty
+	LogLevel string
+	// WorkingVolume configures the emptyDir volumes of the executor
+	WorkingVolume v1beta1.WorkingVolume
+	// PluginCache is the volume of the shared plugin cache
+	PluginCache *v1.VolumeSource
+	// PluginCacheSubPath is the directory of the plugin cache mounted, which is the namespace of the Configuration
+	PluginCacheSubPath string
+	// PlanArtifacts is the volume of the saved plans
+	PlanArtifacts *v1.VolumeSource
+	// CLIConfig is the volume of the CLI configuration of Terraform
+	CLIConfig *v1.VolumeSource
+	// TerraformImage and OpenTofuImage are the images of the executor
+	TerraformImage string
+	OpenTofuImage  string
+	// RunnerImage is the image shipping the runner binary
+	RunnerImage string
+	// JSONLogs runs plan, apply and destroy with -json
+	JSONLogs bool
+	// SkipRefresh plans and applies with -refresh=false
+	SkipRefresh bool
+	// CredentialsGracePeriod is how long the credentials which aren't found are waited for
+	CredentialsGracePeriod time.Duration
 }
 
 // +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=configurations,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=configurations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=configurations/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=selfsubjectaccessreviews,verbs=create
 
 // Reconcile will reconcile periodically
 func (r *ConfigurationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	if r.ReconcileTimeout <= 0 {
+		result, err := r.reconcile(context.Background(), req)
+		r.Health.observe(err == nil, false)
+		return result, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.ReconcileTimeout)
+	defer cancel()
+	result, err := r.reconcile(ctx, req)
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	r.Health.observe(err == nil && !timedOut, timedOut)
+
+	// the timeout is recorded by a context which hasn't timed out
+	recordCtx, cancelRecord := context.WithTimeout(context.Background(), reconcileTimeoutRecordTimeout)
+	defer cancelRecord()
+	if recordErr := r.recordReconcileTimeout(recordCtx, req.NamespacedName, timedOut, err); recordErr != nil {
+		return ctrl.Result{}, recordErr
+	}
+	if timedOut {
+		return ctrl.Result{Requeue: true}, nil
+	}
+	return result, err
+}
+
+func (r *ConfigurationReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	var (
 		configuration v1beta1.Configuration
-		ctx           = context.Background()
 		meta          = &TFConfigurationMeta{
 			Namespace:           controllerNamespace,
 			Name:                req.Name,
 			ConfigurationCMName: fmt.Sprintf(TFInputConfigMapName, req.Name),
 			ApplyJobName:        req.Name + "-" + string(TerraformApply),
 			DestroyJobName:      req.Name + "-" + string(TerraformDestroy),
+			ValidateJobName:     req.Name + "-" + string(TerraformValidate),
+			PlanJobName:         req.Name + "-" + string(TerraformPlan),
+			PostApplyJobName:    fmt.Sprintf(PostApplyJobName, req.Name),
+			StateRemoveJobName:  req.Name + "-" + string(TerraformStateRemove),
+			RefreshJobName:      req.Name + "-" + string(TerraformRefresh),
+			MigrateStateJobName: req.Name + "-" + string(TerraformMigrateState),
 		}
 	)
 	klog.InfoS("reconciling Terraform Configuration...", "NamespacedName", req.NamespacedName)
@@ -156,16 +553,69 @@ func (r *ConfigurationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		}
 		return ctrl.Result{}, err
 	}
-	meta.RemoteGit = configuration.Spec.Remote
-
-	if configuration.Spec.ProviderReference != nil {
-		meta.ProviderReference = configuration.Spec.ProviderReference
-	} else {
-		meta.ProviderReference = &crossplane.Reference{
-			Name:      util.ProviderDefaultName,
-			Namespace: util.ProviderDefaultNamespace,
+	if paused, err := r.reconcilePause(ctx, &configuration); paused || err != nil {
+		return ctrl.Result{}, err
+	}
+	// the sub-resources are created in the workload cluster carried by the context when spec.cluster is set
+	clusterCtx, err := r.connectCluster(ctx, configuration)
+	if err != nil {
+		if updateErr := updateStatus(ctx, r.Client, configuration, types.ClusterNotReady, err.Error()); updateErr != nil {
+			return ctrl.Result{}, updateErr
+		}
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, err
+	}
+	ctx = clusterCtx
+	namespace, err := r.resolveExecutionNamespace(ctx, &configuration)
+	if err != nil {
+		if updateErr := updateStatus(ctx, r.Client, configuration, types.ConfigurationStaticChecking, err.Error()); updateErr != nil {
+			return ctrl.Result{}, updateErr
 		}
+		return ctrl.Result{}, err
+	}
+	meta.Namespace = namespace
+	cfgvalidator.SetDefaultProvider(&configuration, r.ProviderName)
+	cfgvalidator.SetDefaults(&configuration)
+	meta.RemoteGit = configuration.Spec.Remote
+	meta.RemoteGitPath = configuration.Spec.Path
+	meta.OCI = configuration.Spec.OCI
+	meta.Verify = configuration.Spec.Verify
+	meta.Engine = configuration.Spec.Engine
+	meta.PodAnnotations = mergeMaps(defaultPodAnnotations, configuration.Spec.PodAnnotations)
+	meta.ExecutorVolumes = configuration.Spec.Volumes
+	meta.Env = specEnv(configuration)
+	meta.Imports = configuration.Spec.Imports
+	meta.OutputsFromJob = outputsFromJob(configuration)
+	meta.ServiceAccountName = configuration.Spec.ServiceAccountName
+	if meta.ServiceAccountName == "" {
+		meta.ServiceAccountName = defaultExecutorServiceAccountName
+	}
+	// owner labels identify the sub-resources of the Configuration, and can't be overridden
+	meta.Labels = mergeMaps(configuration.Spec.SubResourceLabels, ownerLabels(configuration))
+	meta.Annotations = configuration.Spec.SubResourceAnnotations
+	meta.OwnerReferences = ownerReferences(configuration, meta.Namespace)
+	meta.JobTTLSecondsAfterFinished = r.JobTTLSecondsAfterFinished
+	if deadline := configuration.Spec.JobDeadline; deadline != nil {
+		seconds := int64(deadline.Duration / time.Second)
+		meta.JobActiveDeadlineSeconds = &seconds
 	}
+	meta.JobRestartPolicy = configuration.Spec.JobRestartPolicy
+	meta.Entrypoint = configuration.Spec.Entrypoint
+	meta.LogLevel = configuration.Spec.LogLevel
+	meta.WorkingVolume = workingVolume(r.WorkingVolume, configuration.Spec.WorkingVolume)
+	meta.PluginCache = r.PluginCache
+	meta.PluginCacheSubPath = configuration.Namespace
+	meta.PlanArtifacts = r.PlanArtifacts
+	meta.CLIConfig = r.CLIConfig
+	meta.TerraformImage = r.TerraformImage
+	meta.OpenTofuImage = r.OpenTofuImage
+	meta.RunnerImage = r.RunnerImage
+	meta.JSONLogs = r.JSONLogs
+	meta.SkipRefresh = configuration.Spec.SkipRefresh
+	meta.CredentialsGracePeriod = r.CredentialsGracePeriod
+
+	meta.ProviderReference = configuration.Spec.ProviderReference
+	meta.AliasedProviders = configuration.Spec.AliasedProviders
+	meta.RegionOverride = configuration.Spec.Region
 
 	// add finalizer
 	if configuration.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -177,16 +627,36 @@ func (r *ConfigurationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		}
 	}
 
+	// the cloud resources are destroyed and the sub-resources are cleaned up, so only the finalizer is left
+	if !configuration.DeletionTimestamp.IsZero() && configuration.Status.Destroy.State == types.ConfigurationDestroyed {
+		return r.removeFinalizer(ctx, &configuration)
+	}
+	if !configuration.DeletionTimestamp.IsZero() && configuration.Annotations[DeletionProtectionAnnotation] == "true" {
+		return r.blockDeletion(ctx, configuration)
+	}
+
 	// pre-check Configuration
 	if err := r.preCheck(ctx, &configuration, meta); err != nil {
 		return ctrl.Result{}, err
 	}
+	// the state is migrated to the new backend before it's applied or destroyed
+	if migrating, err := r.reconcileStateMigration(ctx, &configuration, meta); err != nil || migrating {
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, err
+	}
 
 	if !configuration.ObjectMeta.DeletionTimestamp.IsZero() {
 		// terraform destroy
 		klog.InfoS("performing Configuration Destroy", "Namespace", req.Namespace, "Name", req.Name, "JobName", meta.DestroyJobName)
 
-		if err := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.DestroyJobName); err != nil {
+		summary, err := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.DestroyJobName)
+		if recordErr := r.recordPlanSummary(ctx, &configuration, summary); recordErr != nil {
+			return ctrl.Result{}, recordErr
+		}
+		if errors.Is(err, terraform.ErrPodEvicted) {
+			if err := r.recordEviction(ctx, configuration, meta.DestroyJobName, types.ConfigurationDestroying, err); err != nil {
+				return ctrl.Result{}, err
+			}
+		} else if err != nil {
 			klog.ErrorS(err, "Terraform destroy failed")
 			if updateErr := updateStatus(ctx, r.Client, configuration, types.ConfigurationDestroyFailed, err.Error()); updateErr != nil {
 				return ctrl.Result{}, err
@@ -194,40 +664,185 @@ func (r *ConfigurationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		}
 
 		if err := r.terraformDestroy(ctx, configuration, meta); err != nil {
-			if err.Error() == MessageDestroyJobNotCompleted {
-				return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+			forceDeleted, escalateErr := r.escalateDestroyTimeout(ctx, configuration, meta, err)
+			if escalateErr != nil {
+				return ctrl.Result{RequeueAfter: 3 * time.Second}, escalateErr
 			}
-			return ctrl.Result{RequeueAfter: 3 * time.Second}, errors.Wrap(err, "continue reconciling to destroy cloud resource")
-		}
-		if controllerutil.ContainsFinalizer(&configuration, configurationFinalizer) {
-			controllerutil.RemoveFinalizer(&configuration, configurationFinalizer)
-			if err := r.Update(ctx, &configuration); err != nil {
-				return ctrl.Result{RequeueAfter: 3 * time.Second}, errors.Wrap(err, "failed to remove finalizer")
+			if !forceDeleted {
+				if err.Error() == MessageDestroyJobNotCompleted {
+					return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+				}
+				return ctrl.Result{RequeueAfter: 3 * time.Second}, errors.Wrap(err, "continue reconciling to destroy cloud resource")
 			}
 		}
-		return ctrl.Result{}, nil
+		return r.removeFinalizer(ctx, &configuration)
+	}
+
+	if removing, err := r.reconcileStateRemoval(ctx, &configuration, meta); err != nil || removing {
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, err
+	}
+	if refreshing, err := r.reconcileRefresh(ctx, &configuration, meta); err != nil || refreshing {
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, err
+	}
+	if configuration.Spec.Destroy {
+		return r.destroyWithoutDeletion(ctx, configuration, meta)
+	}
+	if destroying, err := r.resumeAfterDestroy(ctx, &configuration, meta); err != nil || destroying {
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, err
+	}
+	if waiting, err := r.waitForDependencies(ctx, &configuration); err != nil || waiting {
+		return ctrl.Result{RequeueAfter: dependencyRequeueInterval}, err
 	}
 
 	// Terraform apply (create or update)
 	klog.InfoS("performing Terraform Apply (cloud resource create/update)", "Namespace", req.Namespace, "Name", req.Name)
-	if configuration.Spec.ProviderReference != nil {
-		r.ProviderName = configuration.Spec.ProviderReference.Name
+	summary, err := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.ApplyJobName)
+	if recordErr := r.recordPlanSummary(ctx, &configuration, summary); recordErr != nil {
+		return ctrl.Result{}, recordErr
 	}
-	if err := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.ApplyJobName); err != nil {
+	if errors.Is(err, terraform.ErrPodEvicted) {
+		if err := r.recordEviction(ctx, configuration, meta.ApplyJobName, types.ConfigurationProvisioningAndChecking, err); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil && configuration.Spec.RequireApproval && (strings.Contains(err.Error(), terraform.PlanChangedMessage) ||
+		strings.Contains(err.Error(), terraform.StalePlanMessage)) {
+		// the apply Job refused to apply the changes which weren't approved, or the saved plan which is stale, which
+		// are planned again
+		for _, jobName := range []string{meta.ApplyJobName, meta.PlanJobName} {
+			if err := deleteJob(ctx, clusterClient(ctx, r.Client), jobName, meta.Namespace); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		if err := updateStatus(ctx, r.Client, configuration, types.ConfigurationPendingApproval, MessagePlanChanged); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+	} else if err != nil {
 		klog.ErrorS(err, "Terraform apply failed")
-		if updateErr := updateStatus(ctx, r.Client, configuration, types.ConfigurationApplyFailed, err.Error()); updateErr != nil {
+		if updateErr := updateStatus(ctx, r.Client, configuration, applyFailedState(err.Error()), err.Error()); updateErr != nil {
 			return ctrl.Result{}, err
 		}
 	}
 	if err := r.terraformApply(ctx, req.Namespace, configuration, meta); err != nil {
-		if err.Error() == MessageApplyJobNotCompleted {
+		if err.Error() == MessageApplyJobNotCompleted || err.Error() == MessageValidateJobNotCompleted ||
+			err.Error() == MessagePlanJobNotCompleted {
+			return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+		}
+		if errors.Is(err, errPendingApproval) {
+			// setting the annotation triggers another reconciliation
+			return ctrl.Result{}, nil
+		}
+		if errors.Is(err, errCredentialsPending) {
 			return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
 		}
 		return ctrl.Result{RequeueAfter: 3 * time.Second}, errors.Wrap(err, "failed to create/update cloud resource")
 	}
+	if requeueAfter, err := r.recordApplyProgress(ctx, req.NamespacedName, meta); err != nil {
+		klog.ErrorS(err, "failed to record the progress of the apply", "Namespace", req.Namespace, "Name", req.Name)
+	} else if requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	if configuration.Spec.ApplyInterval != nil {
+		requeueAfter, err := r.reapplyAfterInterval(ctx, configuration, meta)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: 3 * time.Second}, errors.Wrap(err, "failed to re-apply cloud resource")
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
+// recordEviction shows in the status that the pod of a Job was evicted. It isn't a failure, as the Job runs another
+// pod from scratch, which is waited for.
+func (r *ConfigurationReconciler) recordEviction(ctx context.Context, configuration v1beta1.Configuration, jobName string,
+	state types.ConfigurationState, evictErr error) error {
+	message := fmt.Sprintf("The pod of Job %s was evicted, waiting for it to be run again: %s", jobName, evictErr.Error())
+	currentState, currentMessage := configuration.Status.Apply.State, configuration.Status.Apply.Message
+	if !configuration.DeletionTimestamp.IsZero() {
+		currentState, currentMessage = configuration.Status.Destroy.State, configuration.Status.Destroy.Message
+	}
+	if currentState == state && currentMessage == message {
+		return nil
+	}
+	klog.InfoS(message, "Namespace", configuration.Namespace, "Name", configuration.Name)
+	r.Recorder.Event(&configuration, v1.EventTypeWarning, ReasonJobEvicted, message)
+	return updateStatus(ctx, r.Client, configuration, state, message)
+}
+
+// reapplyAfterInterval deletes the apply Job when spec.applyInterval has passed since it succeeded, so that the apply
+// runs again. It returns when to check again. A Job which is still running, or whose post-apply hooks are, is never
+// deleted, so the runs don't overlap.
+func (r *ConfigurationReconciler) reapplyAfterInterval(ctx context.Context, configuration v1beta1.Configuration,
+	meta *TFConfigurationMeta) (time.Duration, error) {
+	interval := configuration.Spec.ApplyInterval.Duration
+	cluster := clusterClient(ctx, r.Client)
+	var applyJob batchv1.Job
+	if err := cluster.Get(ctx, client.ObjectKey{Name: meta.ApplyJobName, Namespace: meta.Namespace}, &applyJob); err != nil {
+		if kerrors.IsNotFound(err) {
+			return r.reapplyAfterIntervalSinceLastApplied(ctx, configuration, meta)
+		}
+		return 0, err
+	}
+	if applyJob.Status.Succeeded != int32(1) || applyJob.Status.CompletionTime == nil || !applyJob.DeletionTimestamp.IsZero() ||
+		configuration.Status.Apply.State != types.Available {
+		return 3 * time.Second, nil
+	}
+	if len(configuration.Spec.PostApplyHooks) > 0 {
+		var postApplyJob batchv1.Job
+		if err := cluster.Get(ctx, client.ObjectKey{Name: meta.PostApplyJobName, Namespace: meta.Namespace}, &postApplyJob); err != nil {
+			if kerrors.IsNotFound(err) {
+				return 3 * time.Second, nil
+			}
+			return 0, err
+		}
+		if postApplyJob.Annotations[ApplyJobUIDAnnotation] != string(applyJob.UID) ||
+			(postApplyJob.Status.Succeeded == 0 && postApplyJob.Status.Failed == 0) {
+			return 3 * time.Second, nil
+		}
+	}
+
+	if elapsed := time.Since(applyJob.Status.CompletionTime.Time); elapsed < interval {
+		return interval - elapsed, nil
+	}
+	klog.InfoS("re-applying after the apply interval", "Namespace", configuration.Namespace, "Name", configuration.Name,
+		"ApplyInterval", interval)
+	if err := updateStatus(ctx, r.Client, configuration, types.ConfigurationProvisioningAndChecking, MessageCloudResourceReapplying); err != nil {
+		return 0, err
+	}
+	if err := cluster.Delete(ctx, &applyJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		return 0, client.IgnoreNotFound(err)
+	}
+	return 3 * time.Second, nil
+}
+
+// reapplyAfterIntervalSinceLastApplied re-applies when spec.applyInterval has passed since the latest successful
+// apply, whose Job has been cleaned up. Marking the Configuration as not available lets the apply Job be created again.
+func (r *ConfigurationReconciler) reapplyAfterIntervalSinceLastApplied(ctx context.Context, configuration v1beta1.Configuration,
+	meta *TFConfigurationMeta) (time.Duration, error) {
+	interval := configuration.Spec.ApplyInterval.Duration
+	lastApplied := configuration.Status.LastApplied
+	if lastApplied == nil || configuration.Status.Apply.State != types.Available {
+		return 3 * time.Second, nil
+	}
+	if len(configuration.Spec.PostApplyHooks) > 0 {
+		var postApplyJob batchv1.Job
+		if err := clusterClient(ctx, r.Client).Get(ctx, client.ObjectKey{Name: meta.PostApplyJobName, Namespace: meta.Namespace}, &postApplyJob); client.IgnoreNotFound(err) != nil {
+			return 0, err
+		} else if err == nil && postApplyJob.Status.Succeeded == 0 && postApplyJob.Status.Failed == 0 {
+			return 3 * time.Second, nil
+		}
+	}
+	if elapsed := time.Since(lastApplied.Time.Time); elapsed < interval {
+		return interval - elapsed, nil
+	}
+	klog.InfoS("re-applying after the apply interval", "Namespace", configuration.Namespace, "Name", configuration.Name,
+		"ApplyInterval", interval)
+	if err := updateStatus(ctx, r.Client, configuration, types.ConfigurationProvisioningAndChecking, MessageCloudResourceReapplying); err != nil {
+		return 0, err
+	}
+	return 3 * time.Second, nil
+}
+
 func (r *ConfigurationReconciler) terraformApply(ctx context.Context, namespace string, configuration v1beta1.Configuration, meta *TFConfigurationMeta) error {
 	klog.InfoS("terraform apply job", "Namespace", namespace, "Name", meta.ApplyJobName)
 
@@ -236,217 +851,1696 @@ func (r *ConfigurationReconciler) terraformApply(ctx context.Context, namespace
 		tfExecutionJob batchv1.Job
 	)
 
+	// the times and the inputs are recorded ahead of updating the status, which is done on a copy of the Configuration
+	err := clusterClient(ctx, k8sClient).Get(ctx, client.ObjectKey{Name: meta.ApplyJobName, Namespace: meta.Namespace}, &tfExecutionJob)
+	if err == nil {
+		if err := recordJobTimes(ctx, k8sClient, &configuration, TerraformApply, tfExecutionJob); err != nil {
+			return err
+		}
+		if err := r.detectStuckApply(ctx, &configuration, tfExecutionJob); err != nil {
+			return err
+		}
+	} else if kerrors.IsNotFound(err) && configuration.Status.Apply.State == types.ConfigurationProvisioningAndChecking {
+		// the Job could have been deleted by someone cleaning up the execution namespace
+		klog.InfoS("the apply Job is missing while provisioning, re-creating it", "Namespace", meta.Namespace,
+			"Name", meta.ApplyJobName)
+	}
+	if err := meta.recordDesiredInputs(ctx, k8sClient, &configuration); err != nil {
+		return err
+	}
+
 	// start provisioning and check the status of the provision
 	if configuration.Status.Apply.State != types.Available && configuration.Status.Apply.State != types.ProviderNotReady &&
-		configuration.Status.Apply.State != types.ConfigurationApplyFailed {
+		configuration.Status.Apply.State != types.ConfigurationApplyFailed &&
+		configuration.Status.Apply.State != types.ConfigurationValidationFailed &&
+		configuration.Status.Apply.State != types.ConfigurationVerificationFailed &&
+		configuration.Status.Apply.State != types.ConfigurationPendingApproval {
 		if err := updateStatus(ctx, k8sClient, configuration, types.ConfigurationProvisioningAndChecking, MessageCloudResourceProvisioningAndChecking); err != nil {
 			return err
 		}
 	}
 
-	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.ApplyJobName, Namespace: controllerNamespace}, &tfExecutionJob); err != nil {
-		if kerrors.IsNotFound(err) {
-			return meta.assembleAndTriggerJob(ctx, k8sClient, &configuration, TerraformApply)
+	if kerrors.IsNotFound(err) {
+		// the succeeded apply Job could have been cleaned up after ttlSecondsAfterFinished
+		upToDate, err := meta.isAppliedUpToDate(ctx, k8sClient, configuration)
+		if err != nil || upToDate {
+			return err
+		}
+	}
+
+	if configuration.Spec.Validate {
+		if err := r.terraformValidate(ctx, configuration, meta); err != nil {
+			return err
+		}
+	}
+
+	if kerrors.IsNotFound(err) {
+		if configuration.Spec.RequireApproval {
+			if err := r.waitForApproval(ctx, configuration, meta); err != nil {
+				return err
+			}
+			if err := updateStatus(ctx, k8sClient, configuration, types.ConfigurationProvisioningAndChecking,
+				MessageCloudResourceProvisioningAndChecking); err != nil {
+				return err
+			}
 		}
+		return meta.assembleAndTriggerJob(ctx, k8sClient, &configuration, TerraformApply)
 	}
 
-	if err := meta.updateTerraformJobIfNeeded(ctx, k8sClient, configuration, tfExecutionJob, meta.ConfigurationChanged); err != nil {
+	recreated, err := meta.updateTerraformJobIfNeeded(ctx, k8sClient, r.Recorder, &configuration, tfExecutionJob, meta.ConfigurationChanged)
+	if err != nil {
 		klog.ErrorS(err, ErrUpdateTerraformApplyJob, "Name", meta.ApplyJobName)
 		return errors.Wrap(err, ErrUpdateTerraformApplyJob)
 	}
+	if recreated {
+		// the result of the out-of-date Job isn't recorded, and the apply is started over
+		return nil
+	}
 
+	if tfExecutionJob.Status.Succeeded == int32(1) {
+		if err := meta.recordLastApplied(ctx, k8sClient, &configuration, tfExecutionJob); err != nil {
+			return err
+		}
+		// the applied plan is done with, and the next apply waits for the approval of another one
+		if err := deleteJob(ctx, clusterClient(ctx, k8sClient), meta.PlanJobName, meta.Namespace); err != nil {
+			return err
+		}
+	}
 	if tfExecutionJob.Status.Succeeded == int32(1) && configuration.Status.Apply.State != types.Available {
 		if err := updateStatus(ctx, k8sClient, configuration, types.Available, MessageCloudResourceDeployed); err != nil {
 			return err
 		}
 	}
+
+	// post-apply hooks run when the outputs have been recorded in the status
+	if tfExecutionJob.Status.Succeeded == int32(1) && configuration.Status.Apply.State == types.Available &&
+		len(configuration.Spec.PostApplyHooks) > 0 {
+		return meta.triggerPostApplyHooks(ctx, clusterClient(ctx, k8sClient), configuration, tfExecutionJob)
+	}
 	return nil
 }
 
-func (r *ConfigurationReconciler) terraformDestroy(ctx context.Context, configuration v1beta1.Configuration, meta *TFConfigurationMeta) error {
-	var (
-		destroyJob batchv1.Job
-		k8sClient  = r.Client
-	)
-	if configuration.Status.Apply.State == types.ConfigurationProvisioningAndChecking {
-		warning := fmt.Sprintf("Destroy could not complete and needs to wait for Provision to complet first: %s", MessageCloudResourceProvisioningAndChecking)
-		klog.Warning(warning)
-		return errors.New(warning)
+// detectStuckApply fails a provisioning Configuration whose apply Job won't tell the result, as it failed after its
+// pods were deleted with the logs, or it has run past the timeout. The Job isn't stopped, so that a late success is
+// still seen.
+func (r *ConfigurationReconciler) detectStuckApply(ctx context.Context, configuration *v1beta1.Configuration, job batchv1.Job) error {
+	if configuration.Status.Apply.State != types.ConfigurationProvisioningAndChecking || job.Status.Succeeded > 0 {
+		return nil
 	}
 
-	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.DestroyJobName, Namespace: meta.Namespace}, &destroyJob); err != nil {
-		if kerrors.IsNotFound(err) {
-			if err := r.Client.Get(ctx, client.ObjectKey{Name: configuration.Name, Namespace: configuration.Namespace}, &v1beta1.Configuration{}); err == nil {
-				if err = meta.assembleAndTriggerJob(ctx, k8sClient, &configuration, TerraformDestroy); err != nil {
-					return err
-				}
-			}
+	var reason, msg string
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == v1.ConditionTrue {
+			reason, msg = ReasonApplyJobFailed, fmt.Sprintf("the apply Job failed: %s", c.Message)
 		}
 	}
+	if terraform.IsJobDeadlineExceeded(job) {
+		reason, msg = ReasonApplyTimeout, terraform.DeadlineExceededMessage(job)
+	}
+	timeout := cfgvalidator.DefaultApplyTimeout
+	if configuration.Spec.ApplyTimeout != nil {
+		timeout = configuration.Spec.ApplyTimeout.Duration
+	}
+	if reason == "" && job.Status.StartTime != nil && time.Since(job.Status.StartTime.Time) > timeout {
+		reason, msg = ReasonApplyTimeout, fmt.Sprintf("the apply Job hasn't completed in %s", timeout)
+	}
+	if reason == "" {
+		return nil
+	}
 
-	// destroying
-	if err := updateStatus(ctx, k8sClient, configuration, types.ConfigurationDestroying, MessageCloudResourceDestroying); err != nil {
+	klog.InfoS(msg, "Namespace", configuration.Namespace, "Name", configuration.Name)
+	r.Recorder.Event(configuration, v1.EventTypeWarning, reason, msg)
+	if err := updateStatus(ctx, r.Client, *configuration, types.ConfigurationApplyFailed, msg); err != nil {
 		return err
 	}
+	configuration.Status.Apply.State = types.ConfigurationApplyFailed
+	return nil
+}
 
-	if err := meta.updateTerraformJobIfNeeded(ctx, k8sClient, configuration, destroyJob, meta.ConfigurationChanged); err != nil {
-		klog.ErrorS(err, ErrUpdateTerraformApplyJob, "Name", meta.ApplyJobName)
-		return errors.Wrap(err, ErrUpdateTerraformApplyJob)
+// appliedInputsHash hashes the configuration and the variables of an apply
+func (meta *TFConfigurationMeta) appliedInputsHash(envs []v1.EnvVar) string {
+	sorted := append([]v1.EnvVar(nil), envs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s", meta.CompleteConfiguration, meta.sourceRevision())
+	for _, env := range sorted {
+		fmt.Fprintf(h, "\x00%s=%s", env.Name, env.Value)
+	}
+	if meta.VarFilesHash != "" {
+		fmt.Fprintf(h, "\x00%s", meta.VarFilesHash)
 	}
+	return hex.EncodeToString(h.Sum(nil))
+}
 
-	// When the deletion Job process succeeded, clean up work is starting.
-	if destroyJob.Status.Succeeded == int32(1) {
-		// 1. delete Terraform input Configuration ConfigMap
-		if err := deleteConfigMap(ctx, k8sClient, meta.ConfigurationCMName); err != nil {
-			return err
-		}
+// sourceRevision identifies the revision of the configuration which isn't in the spec, which is the commit of the
+// remote git repo, or the OCI artifact
+func (meta *TFConfigurationMeta) sourceRevision() string {
+	if meta.OCI != nil {
+		return meta.OCI.Artifact + "\x00" + meta.OCI.Path
+	}
+	return meta.RemoteGitCommit
+}
 
-		// 2. delete connectionSecret
-		if configuration.Spec.WriteConnectionSecretToReference != nil {
-			secretName := configuration.Spec.WriteConnectionSecretToReference.Name
-			secretNameSpace := configuration.Spec.WriteConnectionSecretToReference.Namespace
-			if err := deleteConnectionSecret(ctx, k8sClient, secretName, secretNameSpace); err != nil {
-				return err
-			}
+// inputsHashes hashes the inputs of an apply, and the configuration, the variables and the credentials apart, which
+// tells what changed
+func (meta *TFConfigurationMeta) inputsHashes(envs []v1.EnvVar) v1beta1.InputsHashes {
+	var variables, credentials []v1.EnvVar
+	for _, env := range envs {
+		if (strings.HasPrefix(env.Name, "TF_VAR_") && !meta.isAliasedProviderVariable(env.Name)) || meta.isSpecEnv(env.Name) {
+			variables = append(variables, env)
+		} else {
+			credentials = append(credentials, env)
 		}
+	}
+	configuration := sha256.Sum256([]byte(meta.CompleteConfiguration + "\x00" + meta.sourceRevision()))
+	variablesHash := hashEnvs(variables)
+	if meta.VarFilesHash != "" {
+		// the var files set the variables too
+		hash := sha256.Sum256([]byte(variablesHash + "\x00" + meta.VarFilesHash))
+		variablesHash = hex.EncodeToString(hash[:])
+	}
+	return v1beta1.InputsHashes{
+		InputsHash:        meta.appliedInputsHash(envs),
+		ConfigurationHash: hex.EncodeToString(configuration[:]),
+		VariablesHash:     variablesHash,
+		CredentialsHash:   hashEnvs(credentials),
+	}
+}
 
-		// 3. delete apply job
-		var applyJob batchv1.Job
-		if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.ApplyJobName, Namespace: controllerNamespace}, &applyJob); err == nil {
-			if err := k8sClient.Delete(ctx, &applyJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
-				return err
-			}
+// isSpecEnv tells whether an environment variable is set by spec.env
+func (meta *TFConfigurationMeta) isSpecEnv(name string) bool {
+	for _, env := range meta.Env {
+		if env.Name == name {
+			return true
 		}
+	}
+	return false
+}
 
-		// 4. delete destroy job
-		var j batchv1.Job
-		if err := r.Client.Get(ctx, client.ObjectKey{Name: destroyJob.Name, Namespace: destroyJob.Namespace}, &j); err == nil {
-			return r.Client.Delete(ctx, &j, client.PropagationPolicy(metav1.DeletePropagationBackground))
+// isAliasedProviderVariable tells whether a Terraform variable is a credential of an aliased Provider
+func (meta *TFConfigurationMeta) isAliasedProviderVariable(name string) bool {
+	for _, ref := range meta.AliasedProviders {
+		if strings.HasPrefix(name, fmt.Sprintf("TF_VAR_%s_", ref.Alias)) {
+			return true
 		}
 	}
-	return errors.New(MessageDestroyJobNotCompleted)
+	return false
 }
 
-func (r *ConfigurationReconciler) preCheck(ctx context.Context, configuration *v1beta1.Configuration, meta *TFConfigurationMeta) error {
-	var k8sClient = r.Client
-
-	// Validation: 1) validate Configuration itself
-	configurationType, err := cfgvalidator.ValidConfigurationObject(configuration)
-	if err != nil {
-		return updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error())
+// hashEnvs hashes environment variables regardless of their order
+func hashEnvs(envs []v1.EnvVar) string {
+	sorted := append([]v1.EnvVar(nil), envs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	h := sha256.New()
+	for _, env := range sorted {
+		fmt.Fprintf(h, "%s=%s\x00", env.Name, env.Value)
 	}
-	meta.ConfigurationType = configurationType
-
-	// TODO(zzxwill) Need to find an alternative to check whether there is an state backend in the Configuration
+	return hex.EncodeToString(h.Sum(nil))
+}
 
-	// Render configuration with backend
-	completeConfiguration, err := cfgvalidator.RenderConfiguration(configuration, controllerNamespace, configurationType)
+// recordDesiredInputs records the hashes of the current inputs and the effective region in the status. External tools
+// compare the hashes with the ones of the latest successful apply to tell whether changes are pending
+func (meta *TFConfigurationMeta) recordDesiredInputs(ctx context.Context, k8sClient client.Client,
+	configuration *v1beta1.Configuration) error {
+	envs, err := meta.prepareTFVariables(ctx, k8sClient, configuration)
 	if err != nil {
 		return err
 	}
-	meta.CompleteConfiguration = completeConfiguration
-
-	var inputConfigurationCM v1.ConfigMap
-	if err := r.Client.Get(ctx, client.ObjectKey{Name: meta.ConfigurationCMName, Namespace: controllerNamespace}, &inputConfigurationCM); err != nil {
-		if kerrors.IsNotFound(err) {
-			klog.InfoS("The input Configuration ConfigMaps doesn't exist", "Namespace", controllerNamespace, "Name", meta.ConfigurationCMName)
-		} else {
-			return err
-		}
+	desired := meta.inputsHashes(envs)
+	if current := configuration.Status.DesiredInputs; current != nil && *current == desired &&
+		configuration.Status.Region == meta.Region {
+		return nil
 	}
+	configuration.Status.DesiredInputs = &desired
+	configuration.Status.Region = meta.Region
+	return k8sClient.Status().Update(ctx, configuration)
+}
 
-	// Check whether configuration(hcl/json) is changed
-	configurationChanged, err := cfgvalidator.CheckWhetherConfigurationChanges(configurationType, &inputConfigurationCM, completeConfiguration)
+// recordLastApplied records the succeeded apply Job in the status, so that it's not run again after it's cleaned up
+func (meta *TFConfigurationMeta) recordLastApplied(ctx context.Context, k8sClient client.Client,
+	configuration *v1beta1.Configuration, applyJob batchv1.Job) error {
+	envs, err := meta.prepareTFVariables(ctx, k8sClient, configuration)
 	if err != nil {
 		return err
 	}
+	record := &v1beta1.AppliedRecord{InputsHashes: meta.inputsHashes(envs)}
+	if applyJob.Status.CompletionTime != nil {
+		record.Time = *applyJob.Status.CompletionTime
+	}
+	if last := configuration.Status.LastApplied; last != nil && last.InputsHashes == record.InputsHashes && last.Time.Equal(&record.Time) {
+		return nil
+	}
+	configuration.Status.LastApplied = record
+	return k8sClient.Status().Update(ctx, configuration)
+}
 
-	meta.ConfigurationChanged = configurationChanged
-	if configurationChanged {
-		if err := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationReloading, ConfigurationReloading); err != nil {
-			return err
-		}
-		// store configuration to ConfigMap
-		return meta.storeTFConfiguration(ctx, k8sClient)
+// jobTimes returns when a Job started and completed
+func jobTimes(job batchv1.Job) v1beta1.JobTimes {
+	times := v1beta1.JobTimes{StartTime: job.Status.StartTime.DeepCopy(), CompletionTime: job.Status.CompletionTime.DeepCopy()}
+	if times.StartTime != nil && times.CompletionTime != nil {
+		times.Duration = &metav1.Duration{Duration: times.CompletionTime.Sub(times.StartTime.Time)}
 	}
-	return nil
+	return times
 }
 
-func updateStatus(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration, state types.ConfigurationState, message string) error {
-	if !configuration.ObjectMeta.DeletionTimestamp.IsZero() {
-		configuration.Status.Destroy = v1beta1.ConfigurationDestroyStatus{
-			State:   state,
-			Message: message,
-		}
+// recordJobTimes records when the apply or destroy Job ran in the status, along with when the latest apply succeeded
+func recordJobTimes(ctx context.Context, k8sClient client.Client, configuration *v1beta1.Configuration,
+	executionType TerraformExecutionType, job batchv1.Job) error {
+	status := configuration.Status.DeepCopy()
+	times := jobTimes(job)
+	if executionType == TerraformDestroy {
+		status.Destroy.JobTimes = times
 	} else {
-		configuration.Status.Apply = v1beta1.ConfigurationApplyStatus{
-			State:   state,
-			Message: message,
-		}
-		if state == types.Available {
-			outputs, err := getTFOutputs(ctx, k8sClient, configuration)
-			if err != nil {
-				return err
-			}
-			configuration.Status.Apply.Outputs = outputs
+		status.Apply.JobTimes = times
+		if job.Status.Succeeded == int32(1) && times.CompletionTime != nil {
+			status.Apply.LastAppliedTime = times.CompletionTime
 		}
 	}
-	return k8sClient.Status().Update(ctx, &configuration)
-}
-
-func (meta *TFConfigurationMeta) assembleAndTriggerJob(ctx context.Context, k8sClient client.Client,
-	configuration *v1beta1.Configuration, executionType TerraformExecutionType) error {
-	envs, err := meta.prepareTFVariables(ctx, k8sClient, configuration)
-	if err != nil {
-		return err
+	if equality.Semantic.DeepEqual(configuration.Status, *status) {
+		return nil
 	}
-	meta.Envs = envs
-
-	job := meta.assembleTerraformJob(executionType)
-	return k8sClient.Create(ctx, job)
+	configuration.Status = *status
+	return k8sClient.Status().Update(ctx, configuration)
 }
 
-// updateTerraformJob will set deletion finalizer to the Terraform job if its envs are changed, which will result in
-// deleting the job. Finally a new Terraform job will be generated
-func (meta *TFConfigurationMeta) updateTerraformJobIfNeeded(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration,
-	job batchv1.Job, configurationChanged bool) error {
-
+// isAppliedUpToDate tells whether the Configuration is available, and its configuration and variables haven't changed
+// since the latest successful apply
+func (meta *TFConfigurationMeta) isAppliedUpToDate(ctx context.Context, k8sClient client.Client,
+	configuration v1beta1.Configuration) (bool, error) {
+	if configuration.Status.Apply.State != types.Available || configuration.Status.LastApplied == nil ||
+		meta.ConfigurationChanged {
+		return false, nil
+	}
 	envs, err := meta.prepareTFVariables(ctx, k8sClient, &configuration)
 	if err != nil {
-		return err
+		return false, err
 	}
+	return configuration.Status.LastApplied.InputsHash == meta.appliedInputsHash(envs), nil
+}
 
-	// check whether env changes
-	var envChanged bool
-	if len(job.Spec.Template.Spec.Containers) == 1 && !cfgvalidator.CompareTwoContainerEnvs(job.Spec.Template.Spec.Containers[0].Env, envs) {
-		envChanged = true
-		klog.InfoS("Job's env changed", "Previous", envs, "Current", job.Spec.Template.Spec.Containers[0].Env)
+// triggerPostApplyHooks runs the post-apply hooks once per successful apply Job
+func (meta *TFConfigurationMeta) triggerPostApplyHooks(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration,
+	applyJob batchv1.Job) error {
+	var postApplyJob batchv1.Job
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.PostApplyJobName, Namespace: meta.Namespace}, &postApplyJob); err != nil {
+		if kerrors.IsNotFound(err) {
+			klog.InfoS("running post-apply hooks", "Name", meta.PostApplyJobName)
+			return k8sClient.Create(ctx, meta.assemblePostApplyJob(configuration, applyJob))
+		}
+		return err
 	}
 
-	if configurationChanged {
-		klog.InfoS("configuration(hcl/json) changed")
+	// the hooks ran after a previous apply, run them again for the current one
+	if postApplyJob.Annotations[ApplyJobUIDAnnotation] != string(applyJob.UID) {
+		return k8sClient.Delete(ctx, &postApplyJob, client.PropagationPolicy(metav1.DeletePropagationBackground))
 	}
-
-	// if either one changes, delete the job
-	if envChanged || configurationChanged {
-		var j batchv1.Job
-		if err := k8sClient.Get(ctx, client.ObjectKey{Name: job.Name, Namespace: job.Namespace}, &j); err == nil {
-			return k8sClient.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground))
-		}
+	if postApplyJob.Status.Failed > 0 {
+		klog.InfoS("post-apply hooks failed", "Name", meta.PostApplyJobName, "Failed", postApplyJob.Status.Failed)
 	}
 	return nil
 }
 
-func (meta *TFConfigurationMeta) assembleTerraformJob(executionType TerraformExecutionType) *batchv1.Job {
+// assemblePostApplyJob assembles a Job which runs the post-apply hooks in order as init containers
+func (meta *TFConfigurationMeta) assemblePostApplyJob(configuration v1beta1.Configuration, applyJob batchv1.Job) *batchv1.Job {
 	var (
-		initContainer  v1.Container
-		initContainers []v1.Container
-		parallelism    int32 = 1
-		completions    int32 = 1
-		backoffLimit   int32 = math.MaxInt32
+		hookContainers []v1.Container
+		envs           []v1.EnvVar
+		backoffLimit   int32 = 3
 	)
+	for k, v := range configuration.Status.Apply.Outputs {
+		// the values of sensitive outputs are redacted in the status
+		if v.Sensitive {
+			continue
+		}
+		envs = append(envs, v1.EnvVar{Name: OutputEnvPrefix + k, Value: v.Value})
+	}
+	for _, hook := range configuration.Spec.PostApplyHooks {
+		hookContainers = append(hookContainers, v1.Container{
+			Name:            hook.Name,
+			Image:           hook.Image,
+			ImagePullPolicy: v1.PullIfNotPresent,
+			Command:         hook.Command,
+			Args:            hook.Args,
+			Env:             envs,
+		})
+	}
 
-	executorVolumes := meta.assembleExecutorVolumes()
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            meta.PostApplyJobName,
+			Namespace:       meta.Namespace,
+			OwnerReferences: meta.OwnerReferences,
+			Labels:          meta.Labels,
+			Annotations:     mergeMaps(meta.Annotations, map[string]string{ApplyJobUIDAnnotation: string(applyJob.UID)}),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      meta.Labels,
+					Annotations: meta.PodAnnotations,
+				},
+				Spec: v1.PodSpec{
+					// Init containers run one by one, which keeps the hooks in order
+					InitContainers: hookContainers,
+					Containers: []v1.Container{{
+						Name:            "post-apply-completed",
+						Image:           "busybox:latest",
+						ImagePullPolicy: v1.PullIfNotPresent,
+						Command:         []string{"echo", "post-apply hooks completed"},
+					}},
+					// The hooks don't touch the Terraform state, so they run as the default ServiceAccount unless
+					// one is specified
+					ServiceAccountName: configuration.Spec.ServiceAccountName,
+					RestartPolicy:      v1.RestartPolicyOnFailure,
+				},
+			},
+		},
+	}
+}
+
+// terraformValidate runs `terraform validate` in a Job before the apply Job is triggered. It returns nil only when the
+// validation succeeded.
+func (r *ConfigurationReconciler) terraformValidate(ctx context.Context, configuration v1beta1.Configuration, meta *TFConfigurationMeta) error {
+	var (
+		k8sClient   = r.Client
+		validateJob batchv1.Job
+	)
+
+	if err := clusterClient(ctx, k8sClient).Get(ctx, client.ObjectKey{Name: meta.ValidateJobName, Namespace: meta.Namespace}, &validateJob); err != nil {
+		if kerrors.IsNotFound(err) {
+			if err := meta.assembleAndTriggerJob(ctx, k8sClient, &configuration, TerraformValidate); err != nil {
+				return err
+			}
+			return errors.New(MessageValidateJobNotCompleted)
+		}
+		return err
+	}
+
+	if _, err := meta.updateTerraformJobIfNeeded(ctx, k8sClient, r.Recorder, &configuration, validateJob, meta.ConfigurationChanged); err != nil {
+		return errors.Wrap(err, "failed to update Terraform validate job")
+	}
+
+	switch {
+	case validateJob.Status.Succeeded == int32(1):
+		return nil
+	case validateJob.Status.Failed > 0:
+		errMsg := "Terraform validate failed"
+		if _, err := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.ValidateJobName); err != nil {
+			errMsg = err.Error()
+		}
+		if configuration.Status.Apply.State != types.ConfigurationValidationFailed || configuration.Status.Apply.Message != errMsg {
+			if err := updateStatus(ctx, k8sClient, configuration, types.ConfigurationValidationFailed, errMsg); err != nil {
+				return err
+			}
+		}
+		return errors.New(errMsg)
+	default:
+		return errors.New(MessageValidateJobNotCompleted)
+	}
+}
+
+// errPendingApproval means the apply waits for the approval of its plan
+var errPendingApproval = errors.New("the plan is waiting for approval")
+
+// errCredentialsPending means the apply waits for the credentials of the Provider which aren't found yet
+var errCredentialsPending = errors.New("the credentials of the Provider are not found yet")
+
+// waitForApproval plans the changes of an apply in a Job, and returns nil once the plan is approved by the annotation
+// ApprovedAnnotation. The hash and the summary of the plan are recorded in the status for the approvers.
+func (r *ConfigurationReconciler) waitForApproval(ctx context.Context, configuration v1beta1.Configuration, meta *TFConfigurationMeta) error {
+	var (
+		k8sClient = r.Client
+		planJob   batchv1.Job
+	)
+
+	if err := clusterClient(ctx, k8sClient).Get(ctx, client.ObjectKey{Name: meta.PlanJobName, Namespace: meta.Namespace}, &planJob); err != nil {
+		if kerrors.IsNotFound(err) {
+			if err := meta.assembleAndTriggerJob(ctx, k8sClient, &configuration, TerraformPlan); err != nil {
+				return err
+			}
+			return errors.New(MessagePlanJobNotCompleted)
+		}
+		return err
+	}
+
+	if _, err := meta.updateTerraformJobIfNeeded(ctx, k8sClient, r.Recorder, &configuration, planJob, meta.ConfigurationChanged); err != nil {
+		return errors.Wrap(err, "failed to update Terraform plan job")
+	}
+
+	if r.planExpired(meta, planJob, time.Now()) {
+		klog.InfoS("the saved plan expired, which is planned again", "Namespace", configuration.Namespace, "Name", configuration.Name)
+		if err := deleteJob(ctx, clusterClient(ctx, k8sClient), meta.PlanJobName, meta.Namespace); err != nil {
+			return err
+		}
+		return errors.New(MessagePlanJobNotCompleted)
+	}
+
+	summary, err := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.PlanJobName)
+	switch {
+	case planJob.Status.Succeeded == int32(1):
+	case planJob.Status.Failed > 0:
+		errMsg := "Terraform plan failed"
+		if err != nil {
+			errMsg = err.Error()
+		}
+		if state := applyFailedState(errMsg); configuration.Status.Apply.State != state || configuration.Status.Apply.Message != errMsg {
+			if err := updateStatus(ctx, k8sClient, configuration, state, errMsg); err != nil {
+				return err
+			}
+		}
+		return errors.New(errMsg)
+	default:
+		return errors.New(MessagePlanJobNotCompleted)
+	}
+
+	hash, err := terraform.GetTerraformOutputs(ctx, meta.Namespace, meta.PlanJobName, terraformExecutorContainerName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the hash of the plan")
+	}
+	if summary == nil {
+		summary = &v1beta1.PlanSummary{}
+	}
+	summary.Hash = strings.TrimSpace(string(hash))
+	if err := r.recordPlanSummary(ctx, &configuration, summary); err != nil {
+		return err
+	}
+
+	if summary.Hash != "" && configuration.Annotations[ApprovedAnnotation] == summary.Hash {
+		meta.ApprovedPlanHash = summary.Hash
+		return nil
+	}
+	message := fmt.Sprintf(MessagePendingApproval, summary.Hash)
+	if configuration.Status.Apply.State != types.ConfigurationPendingApproval || configuration.Status.Apply.Message != message {
+		if err := updateStatus(ctx, k8sClient, configuration, types.ConfigurationPendingApproval, message); err != nil {
+			return err
+		}
+	}
+	return errPendingApproval
+}
+
+// planExpired tells whether the plan saved by the plan Job has waited longer than the expiry to be applied. A plan is
+// applied against the cloud resources as they were when it was made, which drift over time.
+func (r *ConfigurationReconciler) planExpired(meta *TFConfigurationMeta, planJob batchv1.Job, now time.Time) bool {
+	if meta.PlanArtifacts == nil || r.PlanExpiry <= 0 || planJob.Status.Succeeded == 0 || planJob.Status.CompletionTime == nil {
+		return false
+	}
+	return now.Sub(planJob.Status.CompletionTime.Time) > r.PlanExpiry
+}
+
+func (r *ConfigurationReconciler) terraformDestroy(ctx context.Context, configuration v1beta1.Configuration, meta *TFConfigurationMeta) error {
+	var (
+		destroyJob batchv1.Job
+		k8sClient  = r.Client
+	)
+	if configuration.Status.Apply.State == types.ConfigurationProvisioningAndChecking {
+		warning := fmt.Sprintf("Destroy could not complete and needs to wait for Provision to complet first: %s", MessageCloudResourceProvisioningAndChecking)
+		klog.Warning(warning)
+		return errors.New(warning)
+	}
+
+	// the stages of spec.destroyStages are destroyed one by one by the destroy Job with their targets, followed by the
+	// destroy of all the resources left
+	stages := configuration.Spec.DestroyStages
+	meta.DestroyStage = configuration.Status.Destroy.CompletedStages
+	if meta.DestroyStage < len(stages) {
+		meta.DestroyTargets = stages[meta.DestroyStage].Targets
+	}
+
+	if err := clusterClient(ctx, k8sClient).Get(ctx, client.ObjectKey{Name: meta.DestroyJobName, Namespace: meta.Namespace}, &destroyJob); err != nil {
+		if kerrors.IsNotFound(err) {
+			if err := r.Client.Get(ctx, client.ObjectKey{Name: configuration.Name, Namespace: configuration.Namespace}, &v1beta1.Configuration{}); err == nil {
+				if err = meta.assembleAndTriggerJob(ctx, k8sClient, &configuration, TerraformDestroy); err != nil {
+					return err
+				}
+			}
+		}
+	} else {
+		if stage, ok := destroyJob.Annotations[DestroyStageAnnotation]; ok && stage != strconv.Itoa(meta.DestroyStage) {
+			// the Job of another stage, e.g. after spec.destroyStages is changed, is replaced
+			if err := deleteJob(ctx, clusterClient(ctx, k8sClient), meta.DestroyJobName, meta.Namespace); err != nil {
+				return err
+			}
+			return errors.New(MessageDestroyJobNotCompleted)
+		}
+		if destroyJob.Status.Succeeded == int32(1) && meta.DestroyStage < len(stages) {
+			// the next stage is destroyed by another Job
+			configuration.Status.Destroy.CompletedStages = meta.DestroyStage + 1
+			klog.InfoS("destroyed a stage of spec.destroyStages", "Namespace", configuration.Namespace, "Name", configuration.Name,
+				"Stage", configuration.Status.Destroy.CompletedStages)
+			if err := updateStatus(ctx, k8sClient, configuration, types.ConfigurationDestroying, destroyingMessage(configuration)); err != nil {
+				return err
+			}
+			if err := deleteJob(ctx, clusterClient(ctx, k8sClient), meta.DestroyJobName, meta.Namespace); err != nil {
+				return err
+			}
+			return errors.New(MessageDestroyJobNotCompleted)
+		}
+		if err := recordJobTimes(ctx, k8sClient, &configuration, TerraformDestroy, destroyJob); err != nil {
+			return err
+		}
+	}
+
+	// destroying
+	if err := updateStatus(ctx, k8sClient, configuration, types.ConfigurationDestroying, destroyingMessage(configuration)); err != nil {
+		return err
+	}
+
+	recreated, err := meta.updateTerraformJobIfNeeded(ctx, k8sClient, r.Recorder, &configuration, destroyJob, meta.ConfigurationChanged)
+	if err != nil {
+		klog.ErrorS(err, ErrUpdateTerraformApplyJob, "Name", meta.ApplyJobName)
+		return errors.Wrap(err, ErrUpdateTerraformApplyJob)
+	}
+	if recreated {
+		return errors.New(MessageDestroyJobNotCompleted)
+	}
+
+	// When the deletion Job process succeeded, clean up work is starting.
+	if destroyJob.Status.Succeeded == int32(1) {
+		if configuration.DeletionTimestamp.IsZero() {
+			return meta.cleanUpAfterDestroy(ctx, k8sClient, configuration)
+		}
+		return meta.cleanUpSubResources(ctx, k8sClient, configuration)
+	}
+	return errors.New(MessageDestroyJobNotCompleted)
+}
+
+// blockDeletion refuses to destroy the cloud resources of a deleted Configuration which is protected from deletion,
+// which is recorded in the status and an event once
+func (r *ConfigurationReconciler) blockDeletion(ctx context.Context, configuration v1beta1.Configuration) (ctrl.Result, error) {
+	if configuration.Status.Destroy.State != types.ConfigurationDeletionBlocked {
+		klog.InfoS("blocking the deletion of the protected Configuration", "Namespace", configuration.Namespace, "Name", configuration.Name)
+		r.Recorder.Event(&configuration, v1.EventTypeWarning, ReasonDeletionBlocked, MessageDeletionBlocked)
+		if err := updateStatus(ctx, r.Client, configuration, types.ConfigurationDeletionBlocked, MessageDeletionBlocked); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: deletionBlockedRequeueInterval}, nil
+}
+
+// removeFinalizer removes the finalizer once the cloud resources are destroyed and the sub-resources are cleaned up.
+// It's held for FinalizerGracePeriod after the destroy, and as long as HoldFinalizerAnnotation is set, during which
+// the Configuration is Destroyed.
+func (r *ConfigurationReconciler) removeFinalizer(ctx context.Context, configuration *v1beta1.Configuration) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(configuration, configurationFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	_, held := configuration.Annotations[HoldFinalizerAnnotation]
+	if r.FinalizerGracePeriod > 0 || held {
+		destroyed := configuration.Status.GetCondition(v1beta1.ConditionDestroyed)
+		if configuration.Status.Destroy.State != types.ConfigurationDestroyed || destroyed == nil {
+			r.Recorder.Event(configuration, v1.EventTypeNormal, ReasonDestroyed, MessageFinalizerHeld)
+			if err := updateStatus(ctx, r.Client, *configuration, types.ConfigurationDestroyed, MessageFinalizerHeld); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: r.FinalizerGracePeriod}, nil
+		}
+		// removing the annotation triggers another reconciliation
+		if held {
+			return ctrl.Result{}, nil
+		}
+		if remaining := r.FinalizerGracePeriod - time.Since(destroyed.LastTransitionTime.Time); remaining > 0 {
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+	}
+	klog.InfoS("removing the finalizer", "Namespace", configuration.Namespace, "Name", configuration.Name)
+	r.Recorder.Event(configuration, v1.EventTypeNormal, ReasonDestroyed, MessageDeleting)
+	controllerutil.RemoveFinalizer(configuration, configurationFinalizer)
+	if err := r.Update(ctx, configuration); err != nil {
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, errors.Wrap(err, "failed to remove finalizer")
+	}
+	return ctrl.Result{}, nil
+}
+
+// destroyingMessage tells which stage of spec.destroyStages is being destroyed
+func destroyingMessage(configuration v1beta1.Configuration) string {
+	stage, stages := configuration.Status.Destroy.CompletedStages, len(configuration.Spec.DestroyStages)
+	if stage < stages {
+		return fmt.Sprintf(MessageDestroyingStage, stage+1, stages)
+	}
+	return MessageCloudResourceDestroying
+}
+
+// destroyWithoutDeletion destroys the cloud resources as spec.destroy is set, while the Configuration is kept
+func (r *ConfigurationReconciler) destroyWithoutDeletion(ctx context.Context, configuration v1beta1.Configuration,
+	meta *TFConfigurationMeta) (ctrl.Result, error) {
+	if configuration.Status.Apply.State == types.ConfigurationDestroyed {
+		return ctrl.Result{}, nil
+	}
+	klog.InfoS("performing Configuration Destroy requested by spec.destroy", "Namespace", configuration.Namespace,
+		"Name", configuration.Name, "JobName", meta.DestroyJobName)
+
+	summary, err := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.DestroyJobName)
+	if recordErr := r.recordPlanSummary(ctx, &configuration, summary); recordErr != nil {
+		return ctrl.Result{}, recordErr
+	}
+	if errors.Is(err, terraform.ErrPodEvicted) {
+		if err := r.recordEviction(ctx, configuration, meta.DestroyJobName, types.ConfigurationDestroying, err); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		klog.ErrorS(err, "Terraform destroy failed")
+		if updateErr := updateStatus(ctx, r.Client, configuration, types.ConfigurationDestroyFailed, err.Error()); updateErr != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.terraformDestroy(ctx, configuration, meta); err != nil {
+		if err.Error() == MessageDestroyJobNotCompleted {
+			return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+		}
+		return ctrl.Result{RequeueAfter: 3 * time.Second}, errors.Wrap(err, "continue reconciling to destroy cloud resource")
+	}
+	return ctrl.Result{}, nil
+}
+
+// recordPlanSummary records the summary of the plan of the latest apply or destroy in the status when it changes
+func (r *ConfigurationReconciler) recordPlanSummary(ctx context.Context, configuration *v1beta1.Configuration,
+	summary *v1beta1.PlanSummary) error {
+	if summary == nil || reflect.DeepEqual(configuration.Status.Plan, summary) {
+		return nil
+	}
+	configuration.Status.Plan = summary
+	return r.Status().Update(ctx, configuration)
+}
+
+// recordApplyProgress records how many of the planned changes the running apply Job has made in the status, and
+// returns when to read them again. It clears the progress once the Job isn't running.
+func (r *ConfigurationReconciler) recordApplyProgress(ctx context.Context, name client.ObjectKey,
+	meta *TFConfigurationMeta) (time.Duration, error) {
+	if r.ApplyProgressInterval <= 0 {
+		return 0, nil
+	}
+	// the Configuration is read again, as its status could have been updated from a copy during the reconciliation
+	var configuration v1beta1.Configuration
+	if err := r.Get(ctx, name, &configuration); err != nil {
+		return 0, client.IgnoreNotFound(err)
+	}
+	var applyJob batchv1.Job
+	err := clusterClient(ctx, r.Client).Get(ctx, client.ObjectKey{Name: meta.ApplyJobName, Namespace: meta.Namespace}, &applyJob)
+	if client.IgnoreNotFound(err) != nil {
+		return 0, err
+	}
+	if err != nil || applyJob.Status.Active == 0 {
+		if configuration.Status.Progress == nil {
+			return 0, nil
+		}
+		configuration.Status.Progress = nil
+		return 0, r.Status().Update(ctx, &configuration)
+	}
+
+	progress := configuration.Status.Progress
+	if progress != nil {
+		if elapsed := time.Since(progress.UpdateTime.Time); elapsed < r.ApplyProgressInterval {
+			return r.ApplyProgressInterval - elapsed, nil
+		}
+	}
+	completed, total, err := terraform.GetApplyProgress(ctx, meta.Namespace, meta.ApplyJobName)
+	if err != nil {
+		return r.ApplyProgressInterval, err
+	}
+	// the approved plan applied isn't logged, whose summary was recorded by the plan Job
+	if total == 0 && configuration.Spec.RequireApproval && configuration.Status.Plan != nil {
+		total = configuration.Status.Plan.Add + configuration.Status.Plan.Change + configuration.Status.Plan.Destroy
+	}
+	if progress != nil && progress.Completed == completed && progress.Total == total {
+		return r.ApplyProgressInterval, nil
+	}
+	configuration.Status.Progress = &v1beta1.ApplyProgress{Completed: completed, Total: total, UpdateTime: metav1.Now()}
+	return r.ApplyProgressInterval, r.Status().Update(ctx, &configuration)
+}
+
+// resolveExecutionNamespace returns the namespace of the sub-resources of a Configuration, which is set by
+// ExecutionNamespaceAnnotation, and records it in the status the first time. It can't be changed afterwards, as the
+// Jobs and the state in the previous namespace would be left behind.
+func (r *ConfigurationReconciler) resolveExecutionNamespace(ctx context.Context, configuration *v1beta1.Configuration) (string, error) {
+	desired := configuration.Annotations[ExecutionNamespaceAnnotation]
+	if desired == "" {
+		desired = controllerNamespace
+	}
+	// the namespace in the workload cluster isn't one of the cluster of the controller
+	cluster := configuration.Spec.Cluster
+	if cluster != nil {
+		desired = cluster.Namespace
+	}
+	recorded := configuration.Status.ExecutionNamespace
+	if recorded != "" {
+		// the sub-resources are cleaned up in the recorded namespace however the annotation is changed
+		if desired != recorded && configuration.DeletionTimestamp.IsZero() {
+			return recorded, errors.Errorf("the execution namespace can't be changed from %s to %s", recorded, desired)
+		}
+		return recorded, nil
+	}
+	// nothing was created for the Configuration which is deleted before the namespace is recorded
+	if !configuration.DeletionTimestamp.IsZero() {
+		return controllerNamespace, nil
+	}
+	if cluster == nil && desired != controllerNamespace && !isExecutionNamespace(r.ExecutionNamespaces, desired) {
+		return controllerNamespace, errors.Errorf("%s is not one of the execution namespaces of the controller", desired)
+	}
+	configuration.Status.ExecutionNamespace = desired
+	return desired, r.Status().Update(ctx, configuration)
+}
+
+func isExecutionNamespace(namespaces []string, namespace string) bool {
+	for _, ns := range namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// executionNamespace returns the namespace of the sub-resources of a Configuration, which is the one of the
+// controller for the Configurations reconciled before it's recorded
+func executionNamespace(configuration v1beta1.Configuration) string {
+	if configuration.Status.ExecutionNamespace != "" {
+		return configuration.Status.ExecutionNamespace
+	}
+	return controllerNamespace
+}
+
+// resumeAfterDestroy deletes the destroy Job left by spec.destroy once it's set back to false, so that the
+// configuration is applied again. It returns true while the destroy is still running, which isn't interrupted.
+func (r *ConfigurationReconciler) resumeAfterDestroy(ctx context.Context, configuration *v1beta1.Configuration,
+	meta *TFConfigurationMeta) (bool, error) {
+	cluster := clusterClient(ctx, r.Client)
+	var destroyJob batchv1.Job
+	if err := cluster.Get(ctx, client.ObjectKey{Name: meta.DestroyJobName, Namespace: meta.Namespace}, &destroyJob); err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, r.resetDestroyStages(ctx, configuration)
+		}
+		return false, err
+	}
+	if destroyJob.Status.Succeeded == 0 && destroyJob.Status.Failed == 0 {
+		return true, nil
+	}
+	klog.InfoS("applying the cloud resources again as spec.destroy is unset", "JobName", meta.DestroyJobName)
+	if err := cluster.Delete(ctx, &destroyJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return false, r.resetDestroyStages(ctx, configuration)
+}
+
+// resetDestroyStages resets the stages of spec.destroyStages, which are destroyed again the next time
+func (r *ConfigurationReconciler) resetDestroyStages(ctx context.Context, configuration *v1beta1.Configuration) error {
+	if configuration.Status.Destroy.CompletedStages == 0 {
+		return nil
+	}
+	configuration.Status.Destroy.CompletedStages = 0
+	return r.Status().Update(ctx, configuration)
+}
+
+// reconcilePause records whether the Configuration is paused in its Paused condition, and returns true if it's paused,
+// in which case the reconciliation stops there
+func (r *ConfigurationReconciler) reconcilePause(ctx context.Context, configuration *v1beta1.Configuration) (bool, error) {
+	paused := configuration.Annotations[PauseAnnotation] == "true"
+	current := configuration.Status.GetCondition(v1beta1.ConditionPaused)
+	wasPaused := current != nil && current.Status == v1.ConditionTrue
+	if paused == wasPaused {
+		return paused, nil
+	}
+
+	condition := v1beta1.Condition{
+		Type:               v1beta1.ConditionPaused,
+		Status:             v1.ConditionTrue,
+		Reason:             ReasonPaused,
+		Message:            MessagePaused,
+		LastTransitionTime: metav1.Now(),
+	}
+	if !paused {
+		condition.Status = v1.ConditionFalse
+		condition.Reason = ReasonResumed
+		condition.Message = MessageResumed
+	}
+	klog.InfoS(condition.Message, "Namespace", configuration.Namespace, "Name", configuration.Name)
+	r.Recorder.Event(configuration, v1.EventTypeNormal, condition.Reason, condition.Message)
+	configuration.Status.SetCondition(condition)
+	return paused, r.Status().Update(ctx, configuration)
+}
+
+// recordReconcileTimeout sets the ReconcileTimedOut condition and warns by an event when the reconciliation timed out,
+// and resets the condition once a reconciliation finishes in time
+func (r *ConfigurationReconciler) recordReconcileTimeout(ctx context.Context, key client.ObjectKey, timedOut bool,
+	reconcileErr error) error {
+	var configuration v1beta1.Configuration
+	if err := r.Get(ctx, key, &configuration); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	current := configuration.Status.GetCondition(v1beta1.ConditionReconcileTimedOut)
+	if !timedOut && (current == nil || current.Status != v1.ConditionTrue) {
+		return nil
+	}
+
+	condition := v1beta1.Condition{
+		Type:               v1beta1.ConditionReconcileTimedOut,
+		Status:             v1.ConditionFalse,
+		Reason:             ReasonReconcileFinished,
+		Message:            MessageReconcileFinished,
+		LastTransitionTime: metav1.Now(),
+	}
+	if timedOut {
+		message := fmt.Sprintf(MessageReconcileTimedOut, r.ReconcileTimeout)
+		if reconcileErr != nil {
+			message += ": " + reconcileErr.Error()
+		}
+		condition.Status = v1.ConditionTrue
+		condition.Reason = ReasonReconcileTimedOut
+		condition.Message = message
+		klog.InfoS(message, "Namespace", configuration.Namespace, "Name", configuration.Name)
+		r.Recorder.Event(&configuration, v1.EventTypeWarning, ReasonReconcileTimedOut, message)
+	}
+	configuration.Status.SetCondition(condition)
+	return r.Status().Update(ctx, &configuration)
+}
+
+// escalateDestroyTimeout escalates when the destroy doesn't succeed within the timeout. It warns by an event, and
+// cleans up the sub-resources if ForceDelete is set, which returns true to remove the finalizer. The escalation is
+// recorded by the condition DestroyTimedOut, so that the event isn't sent again by the following reconciliations.
+func (r *ConfigurationReconciler) escalateDestroyTimeout(ctx context.Context, configuration v1beta1.Configuration,
+	meta *TFConfigurationMeta, destroyErr error) (bool, error) {
+	timeout := cfgvalidator.DefaultDestroyTimeout
+	if configuration.Spec.DestroyTimeout != nil {
+		timeout = configuration.Spec.DestroyTimeout.Duration
+	}
+	if time.Since(configuration.DeletionTimestamp.Time) < timeout {
+		return false, nil
+	}
+
+	msg := fmt.Sprintf("Destroy hasn't succeeded in %s: %s", timeout, destroyErr.Error())
+	reason, message := ReasonDestroyTimeout, msg+", set spec.forceDelete to clean it up"
+	if configuration.Spec.ForceDelete {
+		reason, message = ReasonForceDeleting, msg+", force deleting and the cloud resources may be left behind"
+	}
+	if err := r.recordDestroyTimeout(ctx, configuration, reason, message); err != nil {
+		return false, err
+	}
+	if !configuration.Spec.ForceDelete {
+		return false, nil
+	}
+	if err := meta.cleanUpSubResources(ctx, r.Client, configuration); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordDestroyTimeout records the escalation of the destroy timeout by the condition DestroyTimedOut, along with an
+// event which is sent once per escalation
+func (r *ConfigurationReconciler) recordDestroyTimeout(ctx context.Context, configuration v1beta1.Configuration,
+	reason, message string) error {
+	// the status could have been updated by the destroy in the same reconciliation
+	if err := r.Get(ctx, client.ObjectKey{Name: configuration.Name, Namespace: configuration.Namespace}, &configuration); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if current := configuration.Status.GetCondition(v1beta1.ConditionDestroyTimedOut); current != nil &&
+		current.Status == v1.ConditionTrue && current.Reason == reason {
+		return nil
+	}
+	klog.InfoS(message, "Namespace", configuration.Namespace, "Name", configuration.Name)
+	r.Recorder.Event(&configuration, v1.EventTypeWarning, ReasonDestroyTimeout, message)
+	configuration.Status.SetCondition(v1beta1.Condition{
+		Type:               v1beta1.ConditionDestroyTimedOut,
+		Status:             v1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+	return r.Status().Update(ctx, &configuration)
+}
+
+// cleanUpSubResources deletes all the sub-resources created for the Configuration
+func (meta *TFConfigurationMeta) cleanUpSubResources(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration) error {
+	// 1. label the sub-resources created before they were labeled, so that all of them are deleted by the labels
+	if err := meta.adoptSubResources(ctx, clusterClient(ctx, k8sClient)); err != nil {
+		return err
+	}
+
+	appliedOutputs.forget(configuration)
+
+	// 2. delete the rest in parallel, as they don't depend on each other. All of them are deleted even if some fail,
+	// and the failed ones are deleted again by the next reconciliation.
+	return runInParallel(
+		// the connection Secret
+		func() error {
+			if ref := configuration.Spec.WriteConnectionSecretToReference; ref != nil {
+				return deleteConnectionSecret(ctx, k8sClient, ref.Name, ref.Namespace)
+			}
+			return nil
+		},
+		// the outputs ConfigMap
+		func() error {
+			if ref := configuration.Spec.WriteOutputsToConfigMapReference; ref != nil {
+				return deleteOutputsConfigMap(ctx, k8sClient, ref.Name, ref.Namespace)
+			}
+			return nil
+		},
+		// the Terraform state, which is empty after the cloud resources are destroyed
+		func() error {
+			b, err := getBackend(ctx, k8sClient, configuration)
+			if err != nil {
+				return err
+			}
+			return b.CleanUp(ctx)
+		},
+		// the Jobs and the ConfigMaps, like the input configuration and the logs retained after the Jobs failed, in all
+		// the namespaces by the owner labels, including the ones left in a namespace no longer used
+		func() error {
+			return deleteOwnedSubResources(ctx, clusterClient(ctx, k8sClient), configuration)
+		},
+	)
+}
+
+// runInParallel runs the functions concurrently, and aggregates their errors after all of them return
+func runInParallel(fns ...func() error) error {
+	errs := make([]error, len(fns))
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		wg.Add(1)
+		go func(i int, fn func() error) {
+			defer wg.Done()
+			errs[i] = fn()
+		}(i, fn)
+	}
+	wg.Wait()
+	return utilerrors.NewAggregate(errs)
+}
+
+// adoptSubResources adds the owner labels to the ConfigMaps of the Configuration in the execution namespace which were
+// created before the sub-resources were labeled, and deletes such Jobs by their names, as the controller isn't granted
+// to update Jobs. The ones labeled for another Configuration are left alone.
+func (meta *TFConfigurationMeta) adoptSubResources(ctx context.Context, k8sClient client.Client) error {
+	var objects []subResource
+	for _, name := range []string{meta.ConfigurationCMName, fmt.Sprintf(RenderedConfigMapName, meta.Name)} {
+		objects = append(objects, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	for _, jobName := range []string{meta.ApplyJobName, meta.ValidateJobName, meta.PostApplyJobName, meta.DestroyJobName,
+		meta.StateRemoveJobName, meta.PlanJobName, meta.MigrateStateJobName} {
+		objects = append(objects, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: jobName}},
+			&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf(FailedJobLogsConfigMapName, jobName)}})
+	}
+	for _, obj := range objects {
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: obj.GetName(), Namespace: meta.Namespace}, obj); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		labels := obj.GetLabels()
+		if labels[LabelKeyOwnedBy] != "" || labels[LabelKeyOwnedNamespace] != "" {
+			continue
+		}
+		if _, ok := obj.(*batchv1.Job); ok {
+			if err := k8sClient.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil &&
+				!kerrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to delete the legacy Job %s", obj.GetName())
+			}
+			continue
+		}
+		obj.SetLabels(mergeMaps(labels, meta.Labels))
+		if err := k8sClient.Update(ctx, obj); err != nil {
+			return errors.Wrapf(err, "failed to label %s", obj.GetName())
+		}
+	}
+	return nil
+}
+
+// cleanUpAfterDestroy cleans up after the destroy requested by spec.destroy. The outputs are gone with the cloud
+// resources, and the Jobs of the apply are deleted so that it runs again after spec.destroy is unset. The destroy Job
+// is kept, which marks the cloud resources are destroyed.
+func (meta *TFConfigurationMeta) cleanUpAfterDestroy(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration) error {
+	appliedOutputs.forget(configuration)
+	if ref := configuration.Spec.WriteConnectionSecretToReference; ref != nil {
+		if err := deleteConnectionSecret(ctx, k8sClient, ref.Name, ref.Namespace); err != nil {
+			return err
+		}
+	}
+	if ref := configuration.Spec.WriteOutputsToConfigMapReference; ref != nil {
+		if err := deleteOutputsConfigMap(ctx, k8sClient, ref.Name, ref.Namespace); err != nil {
+			return err
+		}
+	}
+	for _, jobName := range []string{meta.ApplyJobName, meta.ValidateJobName, meta.PostApplyJobName, meta.PlanJobName} {
+		if err := deleteJob(ctx, clusterClient(ctx, k8sClient), jobName, meta.Namespace); err != nil {
+			return err
+		}
+	}
+	return updateStatus(ctx, k8sClient, configuration, types.ConfigurationDestroyed, MessageCloudResourceDestroyed)
+}
+
+func (r *ConfigurationReconciler) preCheck(ctx context.Context, configuration *v1beta1.Configuration, meta *TFConfigurationMeta) error {
+	var k8sClient = r.Client
+
+	// Validation: 1) validate Configuration itself
+	configurationType, err := cfgvalidator.ValidConfigurationObject(configuration)
+	if err != nil {
+		return updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error())
+	}
+	meta.ConfigurationType = configurationType
+	// the remote git repo is cloned by the Jobs, which must not be tricked into running commands
+	if remote := configuration.Spec.Remote; remote != "" {
+		if err := cfgvalidator.ValidateRemoteGit(remote); err != nil {
+			err = errors.Wrapf(err, "invalid spec.remote %s", remote)
+			if updateErr := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error()); updateErr != nil {
+				return updateErr
+			}
+			return err
+		}
+	}
+	// the OCI artifact is pulled by the Jobs too
+	if oci := configuration.Spec.OCI; oci != nil {
+		err := cfgvalidator.ValidateOCIArtifact(oci.Artifact)
+		if err == nil && configuration.Spec.Verify != nil {
+			err = cfgvalidator.ValidateVerifiedOCIArtifact(oci.Artifact)
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "invalid spec.oci.artifact %s", oci.Artifact)
+			if updateErr := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error()); updateErr != nil {
+				return updateErr
+			}
+			return err
+		}
+	}
+	if err := append(ValidateExecutorVolumes(configuration), ValidateEnv(configuration)...).ToAggregate(); err != nil {
+		if updateErr := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error()); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+	if _, err := util.RawExtension2Map(configuration.Spec.Variable); err != nil {
+		err = errors.Wrap(err, "invalid spec.variable")
+		if updateErr := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error()); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+
+	// TODO(zzxwill) Need to find an alternative to check whether there is an state backend in the Configuration
+
+	// Render configuration with backend
+	completeConfiguration, err := cfgvalidator.RenderConfiguration(configuration, meta.Namespace, configurationType)
+	if err != nil {
+		return err
+	}
+	meta.CompleteConfiguration = completeConfiguration
+
+	if err := meta.loadVarFiles(ctx, k8sClient, configuration); err != nil {
+		err = errors.Wrap(err, "invalid spec.varFiles")
+		if updateErr := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error()); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+	if err := meta.loadExecutorVolumes(ctx, k8sClient, configuration); err != nil {
+		err = errors.Wrap(err, "invalid spec.volumes")
+		if updateErr := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error()); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+
+	// Validation: 2) check all the required variables are supplied, so that it fails fast rather than in the apply Job
+	if configuration.ObjectMeta.DeletionTimestamp.IsZero() {
+		if err := checkRequiredVariables(ctx, k8sClient, configuration, configurationType, completeConfiguration,
+			meta.VarFileVariables); err != nil {
+			return err
+		}
+		if err := checkRegions(ctx, k8sClient, configuration); err != nil {
+			return err
+		}
+	}
+
+	cluster := clusterClient(ctx, k8sClient)
+	var inputConfigurationCM v1.ConfigMap
+	if err := cluster.Get(ctx, client.ObjectKey{Name: meta.ConfigurationCMName, Namespace: meta.Namespace}, &inputConfigurationCM); err != nil {
+		if kerrors.IsNotFound(err) {
+			klog.InfoS("The input Configuration ConfigMaps doesn't exist", "Namespace", meta.Namespace, "Name", meta.ConfigurationCMName)
+		} else {
+			return err
+		}
+	}
+
+	// Check whether configuration(hcl/json) is changed
+	configurationChanged, err := cfgvalidator.CheckWhetherConfigurationChanges(configurationType, &inputConfigurationCM, completeConfiguration)
+	if err != nil {
+		return err
+	}
+
+	if configurationType == types.ConfigurationRemote {
+		commit, err := util.GetRemoteGitHeadCommit(ctx, meta.RemoteGit)
+		if err != nil {
+			// Fall back to cloning the default branch without change detection
+			klog.ErrorS(err, "Failed to get the latest commit of the remote git repo", "Remote", meta.RemoteGit)
+			commit = inputConfigurationCM.Annotations[cfgvalidator.RemoteGitCommitAnnotation]
+		} else if cfgvalidator.CheckWhetherRemoteGitChanges(&inputConfigurationCM, commit) {
+			configurationChanged = true
+		}
+		if cfgvalidator.CheckWhetherRemoteGitPathChanges(&inputConfigurationCM, meta.RemoteGitPath) {
+			configurationChanged = true
+		}
+		meta.RemoteGitCommit = commit
+		configuration.Status.RemoteGitCommit = commit
+	}
+	if configurationType == types.ConfigurationOCI && cfgvalidator.CheckWhetherOCIArtifactChanges(&inputConfigurationCM, meta.OCI) {
+		configurationChanged = true
+	}
+	if cfgvalidator.CheckWhetherVarFilesChange(&inputConfigurationCM, meta.VarFilesHash) {
+		configurationChanged = true
+	}
+	// the var files are kept even if the configuration isn't changed, as the Jobs can't start without them
+	if err := meta.storeVarFiles(ctx, cluster); err != nil {
+		return err
+	}
+	if err := meta.storeExecutorVolumes(ctx, cluster); err != nil {
+		return err
+	}
+
+	meta.ConfigurationChanged = configurationChanged
+	if configurationChanged {
+		if err := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationReloading, ConfigurationReloading); err != nil {
+			return err
+		}
+		// store configuration to ConfigMap
+		if err := meta.storeTFConfiguration(ctx, cluster); err != nil {
+			if errors.Is(err, errConfigurationTooLarge) {
+				if updateErr := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error()); updateErr != nil {
+					return updateErr
+				}
+			}
+			return err
+		}
+		// the redacted copy is only for inspection, which doesn't block the Configuration
+		if err := meta.storeRenderedConfiguration(ctx, cluster); err != nil {
+			klog.ErrorS(err, "failed to store the rendered configuration", "Name", fmt.Sprintf(RenderedConfigMapName, meta.Name))
+		}
+	}
+	return nil
+}
+
+func checkRequiredVariables(ctx context.Context, k8sClient client.Client, configuration *v1beta1.Configuration,
+	configurationType types.ConfigurationType, completeConfiguration string, varFileVariables []string) error {
+	variables, err := util.RawExtension2Map(configuration.Spec.Variable)
+	if err != nil {
+		return err
+	}
+	if variables == nil {
+		variables = make(map[string]interface{}, len(varFileVariables)+len(configuration.Spec.VariableFrom))
+	}
+	// the variables of the var files, spec.variableFrom and the credentials of the aliased Providers are supplied,
+	// though their values aren't known yet
+	for _, name := range varFileVariables {
+		variables[name] = true
+	}
+	for _, ref := range configuration.Spec.VariableFrom {
+		variables[ref.Var] = true
+	}
+	for _, ref := range configuration.Spec.AliasedProviders {
+		for _, env := range util.CredentialEnvs() {
+			variables[aliasedProviderVariable(ref.Alias, env)] = true
+		}
+	}
+	missing, err := cfgvalidator.CheckRequiredVariables(configurationType, completeConfiguration, variables)
+	if err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	errMsg := fmt.Sprintf("%s: %s", MessageRequiredVariablesMissing, strings.Join(missing, ", "))
+	if configuration.Status.Apply.State != types.ConfigurationValidationFailed || configuration.Status.Apply.Message != errMsg {
+		if err := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationValidationFailed, errMsg); err != nil {
+			return err
+		}
+	}
+	return errors.New(errMsg)
+}
+
+// checkRegions checks the effective regions of the Providers against the known regions of their clouds, so that a typo
+// fails fast rather than in the apply Job
+func checkRegions(ctx context.Context, k8sClient client.Client, configuration *v1beta1.Configuration) error {
+	providers := []v1beta1.AliasedProviderReference{{
+		Name:      configuration.Spec.ProviderReference.Name,
+		Namespace: configuration.Spec.ProviderReference.Namespace,
+		Region:    configuration.Spec.Region,
+	}}
+	providers = append(providers, configuration.Spec.AliasedProviders...)
+	for _, ref := range providers {
+		provider, err := util.GetProviderFromConfiguration(ctx, k8sClient, ref.Namespace, ref.Name)
+		if err != nil {
+			// a Provider which can't be got is reported when its credentials are read
+			continue
+		}
+		region := ref.Region
+		if region == "" {
+			region = provider.Spec.Region
+		}
+		if err := util.ValidateRegion(provider.Spec.Provider, region); err != nil {
+			err = errors.Wrapf(err, "Provider %s/%s", ref.Namespace, ref.Name)
+			if configuration.Status.Apply.State != types.InvalidRegion || configuration.Status.Apply.Message != err.Error() {
+				if updateErr := updateStatus(ctx, k8sClient, *configuration, types.InvalidRegion, err.Error()); updateErr != nil {
+					return updateErr
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func updateStatus(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration, state types.ConfigurationState, message string) error {
+	condition := v1beta1.Condition{
+		Status:             conditionStatus(state),
+		Reason:             string(state),
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	}
+	if !configuration.ObjectMeta.DeletionTimestamp.IsZero() {
+		configuration.Status.Destroy = v1beta1.ConfigurationDestroyStatus{
+			State:           state,
+			Message:         message,
+			Reason:          failureReason(state, message),
+			JobTimes:        configuration.Status.Destroy.JobTimes,
+			CompletedStages: configuration.Status.Destroy.CompletedStages,
+		}
+		condition.Type = v1beta1.ConditionDestroyed
+		configuration.Status.SetCondition(condition)
+	} else {
+		reason := failureReason(state, message)
+		if reason == types.FailureReasonResourceAlreadyExists {
+			message = fmt.Sprintf("%s\n%s", MessageImportSuggestion, message)
+		}
+		configuration.Status.Apply = v1beta1.ConfigurationApplyStatus{
+			State:           state,
+			Message:         message,
+			Reason:          reason,
+			JobTimes:        configuration.Status.Apply.JobTimes,
+			LastAppliedTime: configuration.Status.Apply.LastAppliedTime,
+		}
+		condition.Type = v1beta1.ConditionApplied
+		configuration.Status.SetCondition(condition)
+		if state == types.Available {
+			outputs, err := getTFOutputs(ctx, k8sClient, configuration)
+			if err != nil {
+				return err
+			}
+			configuration.Status.Apply.Outputs = outputs
+			// the outputs of a successful apply are kept for the consumers even if the next apply fails
+			now := metav1.Now()
+			configuration.Status.LastSuccessfulOutputs = outputs
+			configuration.Status.LastSuccessfulOutputsTime = &now
+		}
+	}
+
+	// Only when the configuration is applied and the outputs are generated, it's ready
+	ready := condition
+	ready.Type = v1beta1.ConditionReady
+	if ready.Status != v1.ConditionTrue {
+		ready.Status = v1.ConditionFalse
+	}
+	if !configuration.ObjectMeta.DeletionTimestamp.IsZero() {
+		ready.Status = v1.ConditionFalse
+	}
+	configuration.Status.SetCondition(ready)
+	configuration.Status.OutputsCount = len(configuration.Status.Apply.Outputs)
+	configuration.Status.Summary = statusSummary(configuration)
+	return k8sClient.Status().Update(ctx, &configuration)
+}
+
+// statusSummary tells the state of a Configuration in one line, which is the state of the destroy once it's deleted
+func statusSummary(configuration v1beta1.Configuration) string {
+	s := configuration.Status
+	state, reason := s.Apply.State, s.Apply.Reason
+	if !configuration.DeletionTimestamp.IsZero() {
+		state, reason = s.Destroy.State, s.Destroy.Reason
+	}
+	summary := string(state)
+	if s.Region != "" {
+		summary += fmt.Sprintf(" (%s)", s.Region)
+	}
+	if reason != "" {
+		summary += fmt.Sprintf(", %s", reason)
+	}
+	if state == types.Available {
+		summary += fmt.Sprintf(", %d outputs", len(s.Apply.Outputs))
+	}
+	if s.Apply.LastAppliedTime != nil {
+		summary += ", last applied at " + s.Apply.LastAppliedTime.UTC().Format(time.RFC3339)
+	}
+	return summary
+}
+
+// failureReason classifies why a Configuration failed, which is empty when the state isn't a failed one
+func failureReason(state types.ConfigurationState, message string) types.FailureReason {
+	switch state {
+	case types.ConfigurationStaticChecking, types.ConfigurationSyntaxError, types.ConfigurationValidationFailed,
+		types.InvalidRegion:
+		return types.FailureReasonInvalidConfiguration
+	case types.ProviderNotReady:
+		return types.FailureReasonCredentialError
+	case types.ConfigurationApplyFailed, types.ConfigurationDestroyFailed:
+		return terraform.ClassifyFailure(message)
+	case types.ConfigurationVerificationFailed:
+		return types.FailureReasonUntrustedSource
+	default:
+		return ""
+	}
+}
+
+// applyFailedState is the state of a failed apply or plan, which tells apart the source of the configuration not
+// signed by a trusted key
+func applyFailedState(message string) types.ConfigurationState {
+	if terraform.IsVerificationFailure(message) {
+		return types.ConfigurationVerificationFailed
+	}
+	return types.ConfigurationApplyFailed
+}
+
+// conditionStatus maps the state of a Configuration to the status of its condition
+func conditionStatus(state types.ConfigurationState) v1.ConditionStatus {
+	switch state {
+	case types.Available:
+		return v1.ConditionTrue
+	case types.ConfigurationApplyFailed, types.ConfigurationDestroyFailed, types.ConfigurationValidationFailed,
+		types.ConfigurationVerificationFailed, types.ConfigurationSyntaxError, types.ConfigurationStaticChecking, types.ProviderNotReady, types.ConfigurationDestroyed,
+		types.InvalidRegion, types.ClusterNotReady, types.ConfigurationDeletionBlocked:
+		return v1.ConditionFalse
+	default:
+		return v1.ConditionUnknown
+	}
+}
+
+func (meta *TFConfigurationMeta) assembleAndTriggerJob(ctx context.Context, k8sClient client.Client,
+	configuration *v1beta1.Configuration, executionType TerraformExecutionType) error {
+	envs, err := meta.prepareTFVariables(ctx, k8sClient, configuration)
+	if err != nil {
+		return err
+	}
+	meta.Envs = envs
+
+	job := meta.assembleTerraformJob(executionType)
+	return createJob(ctx, clusterClient(ctx, k8sClient), job)
+}
+
+// createJob creates a Job, which is taken as triggered when it already exists. The Jobs of a Configuration have fixed
+// names, so the API server creates only one of them when the Job isn't in the cache yet, like when the previous leader
+// created it right before a failover, or when two replicas act as the leader for a moment. The existing Job is checked
+// by the next reconciliation, which re-creates it once it's finished if it's out of date. Two Jobs with different
+// names, like apply and destroy, are kept from writing the state at the same time by the lock of the backend, which
+// their commands hold, see executorCommand.
+func createJob(ctx context.Context, k8sClient client.Client, job *batchv1.Job) error {
+	err := k8sClient.Create(ctx, job)
+	if kerrors.IsAlreadyExists(err) {
+		klog.InfoS("the Job has been created, which is left as it is", "Namespace", job.Namespace, "Name", job.Name)
+		return nil
+	}
+	return err
+}
+
+// updateTerraformJob will set deletion finalizer to the Terraform job if its envs are changed, which will result in
+// deleting the job. Finally a new Terraform job will be generated. It returns true when the Job is deleted.
+//
+// A running apply or destroy Job is left to finish rather than deleted, as terraform writes the state as it changes
+// the resources, and the ones changed by an interrupted run could be missing in the state. The state is kept in the
+// backend across the Jobs, so that a changed configuration, like the one with `moved` blocks, is planned against it.
+func (meta *TFConfigurationMeta) updateTerraformJobIfNeeded(ctx context.Context, k8sClient client.Client, recorder record.EventRecorder,
+	configuration *v1beta1.Configuration, job batchv1.Job, configurationChanged bool) (bool, error) {
+	if (job.Name == meta.ApplyJobName || job.Name == meta.DestroyJobName) && isJobRunning(job) {
+		if configurationChanged {
+			klog.InfoS("configuration(hcl/json) changed, waiting for the running Job to finish", "Name", job.Name)
+		}
+		return false, nil
+	}
+
+	envs, err := meta.prepareTFVariables(ctx, k8sClient, configuration)
+	if err != nil {
+		return false, err
+	}
+
+	// check whether env changes, which is compared with the envs the executor runs with
+	var envChanged bool
+	if len(job.Spec.Template.Spec.Containers) == 1 {
+		previous, current := job.Spec.Template.Spec.Containers[0].Env, meta.executorEnvsOf(envs)
+		if !cfgvalidator.CompareTwoContainerEnvs(previous, current) {
+			envChanged = true
+			if err := recordInputsChange(ctx, k8sClient, recorder, configuration, job.Name, previous, current); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// the configuration could have been changed while the apply Job was left to finish
+	if hash, ok := job.Annotations[ConfigurationHashAnnotation]; ok && job.Name == meta.ApplyJobName && hash != meta.configurationHash() {
+		configurationChanged = true
+	}
+	if configurationChanged {
+		klog.InfoS("configuration(hcl/json) changed")
+	}
+
+	// the imports are run by the apply Job, whose pod would retry the previous ones
+	var importsChanged bool
+	if job.Name == meta.ApplyJobName && job.Annotations[ImportsHashAnnotation] != meta.importsHash() {
+		importsChanged = true
+		klog.InfoS("imports changed", "Name", job.Name)
+	}
+
+	// if any one changes, delete the job
+	if envChanged || configurationChanged || importsChanged {
+		cluster := clusterClient(ctx, k8sClient)
+		var j batchv1.Job
+		if err := cluster.Get(ctx, client.ObjectKey{Name: job.Name, Namespace: job.Namespace}, &j); err == nil {
+			if configuration.Spec.RetainFailedJobLogs && isJobFailed(*configuration, j) {
+				meta.retainFailedJobLogs(ctx, cluster, j)
+			}
+			return true, cluster.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		}
+	}
+	return false, nil
+}
+
+// isJobRunning tells whether a Job has started and hasn't finished yet
+func isJobRunning(job batchv1.Job) bool {
+	return job.Status.Active > 0 && job.Status.Succeeded == 0 && job.Status.Failed == 0
+}
+
+// waitsForCredentials tells whether the credentials of the Provider which aren't found are still waited for, in the
+// grace period which starts when they're found missing. The state is kept meanwhile. The destroy doesn't wait for them.
+func (meta *TFConfigurationMeta) waitsForCredentials(configuration v1beta1.Configuration) bool {
+	if meta.CredentialsGracePeriod <= 0 || !configuration.DeletionTimestamp.IsZero() {
+		return false
+	}
+	since := configuration.Status.CredentialsPendingSince
+	return since == nil || time.Since(since.Time) < meta.CredentialsGracePeriod
+}
+
+// recordInputsChange tells which variables changed since the Job was created in the status and an event before the
+// Job is re-created. Only their names are recorded, as the values may be sensitive.
+func recordInputsChange(ctx context.Context, k8sClient client.Client, recorder record.EventRecorder,
+	configuration *v1beta1.Configuration, jobName string, previous, current []v1.EnvVar) error {
+	added, removed, changed := cfgvalidator.DiffContainerEnvs(previous, current)
+	klog.InfoS("Job's env changed", "Name", jobName, "Added", added, "Removed", removed, "Changed", changed)
+
+	var changes []string
+	for _, c := range []struct {
+		verb  string
+		names []string
+	}{{"added", added}, {"removed", removed}, {"changed", changed}} {
+		if len(c.names) > 0 {
+			changes = append(changes, fmt.Sprintf("%s %s", c.verb, strings.Join(c.names, ", ")))
+		}
+	}
+	recorder.Event(configuration, v1.EventTypeNormal, ReasonInputsChanged,
+		fmt.Sprintf("Variables changed, re-creating the Job %s: %s", jobName, strings.Join(changes, "; ")))
+
+	configuration.Status.InputsChange = &v1beta1.InputsChangeRecord{Job: jobName, Time: metav1.Now(), Added: added,
+		Removed: removed, Changed: changed}
+	return k8sClient.Status().Update(ctx, configuration)
+}
+
+// isJobFailed tells whether a Job failed, either by its status, or by the failure which is found in its logs and
+// recorded in the status of the Configuration
+func isJobFailed(configuration v1beta1.Configuration, job batchv1.Job) bool {
+	if job.Status.Failed > 0 {
+		return true
+	}
+	switch configuration.Status.Apply.State {
+	case types.ConfigurationApplyFailed, types.ConfigurationValidationFailed, types.ConfigurationDestroyFailed,
+		types.ConfigurationVerificationFailed:
+		return true
+	}
+	return configuration.Status.Destroy.State == types.ConfigurationDestroyFailed
+}
+
+// retainFailedJobLogs snapshots the logs of a failed Job before it's deleted. A failure to do so doesn't block the
+// re-creation of the Job.
+func (meta *TFConfigurationMeta) retainFailedJobLogs(ctx context.Context, k8sClient client.Client, job batchv1.Job) {
+	logs, err := terraform.GetTerraformLogs(ctx, job.Namespace, job.Name)
+	if err == nil {
+		err = meta.writeFailedJobLogs(ctx, k8sClient, job.Name, logs)
+	}
+	if err != nil {
+		klog.ErrorS(err, "failed to retain the logs of the failed Job", "Name", job.Name)
+	}
+}
+
+// writeFailedJobLogs writes the logs of a failed Job to a ConfigMap, replacing the ones of the previous failure. The
+// beginning of the logs is dropped if they don't fit in the ConfigMap, as the errors are at the end.
+func (meta *TFConfigurationMeta) writeFailedJobLogs(ctx context.Context, k8sClient client.Client, jobName, logs string) error {
+	if len(logs) > maxConfigMapDataSize {
+		logs = logs[len(logs)-maxConfigMapDataSize:]
+	}
+	name := fmt.Sprintf(FailedJobLogsConfigMapName, jobName)
+	var cm v1.ConfigMap
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: meta.Namespace}, &cm); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		cm = v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       meta.Namespace,
+				Labels:          meta.Labels,
+				Annotations:     meta.Annotations,
+				OwnerReferences: meta.OwnerReferences,
+			},
+			Data: map[string]string{FailedJobLogsKey: logs},
+		}
+		return k8sClient.Create(ctx, &cm)
+	}
+	cm.Data = map[string]string{FailedJobLogsKey: logs}
+	return k8sClient.Update(ctx, &cm)
+}
+
+// storeRenderedConfiguration writes the redacted copy of the rendered configuration to a ConfigMap labeled by
+// RenderedConfigurationLabel, which shows what the controller generated without exposing the credentials. The input
+// ConfigMap may be compressed and carries the configuration as it's written.
+func (meta *TFConfigurationMeta) storeRenderedConfiguration(ctx context.Context, k8sClient client.Client) error {
+	rendered := cfgvalidator.RedactConfiguration(meta.ConfigurationType, meta.CompleteConfiguration)
+	if len(rendered) > maxConfigMapDataSize {
+		rendered = rendered[:maxConfigMapDataSize]
+	}
+	data := map[string]string{meta.inputConfigurationDataName(): rendered}
+	labels := mergeMaps(meta.Labels, map[string]string{RenderedConfigurationLabel: "redacted"})
+	name := fmt.Sprintf(RenderedConfigMapName, meta.Name)
+	var cm v1.ConfigMap
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: meta.Namespace}, &cm); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		cm = v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       meta.Namespace,
+				Labels:          labels,
+				Annotations:     meta.Annotations,
+				OwnerReferences: meta.OwnerReferences,
+			},
+			Data: data,
+		}
+		return k8sClient.Create(ctx, &cm)
+	}
+	cm.Labels = mergeMaps(cm.Labels, labels)
+	cm.Data = data
+	return k8sClient.Update(ctx, &cm)
+}
+
+// gitConfigurationScript clones the repo $1, checks out the commit $2 which the change detection is based on, rather
+// than whatever HEAD is now, and copies the configuration in the directory $3 of the repo. The repo is cloned to $4, and
+// the configuration is copied to $5. A missing or empty directory is reported in the termination message along with
+// the available ones. The `--` stops the values from being taken as the options of git.
+const gitConfigurationScript = `set -e
+git clone -- "$1" "$4"
+if [ -n "$2" ]; then git -C "$4" checkout --detach "$2" --; fi
+origin=repo
+` + copyConfigurationScript
+
+// ociConfigurationScript pulls the OCI artifact $1, with the credentials of the registry in $2 if it's set, and copies
+// the configuration in the directory $3 of the artifact. The artifact is pulled to $4, and the configuration is copied
+// to $5, like gitConfigurationScript. The `--` stops the reference from being taken as an option of oras.
+const ociConfigurationScript = `set -e
+if [ -n "$2" ]; then oras pull --registry-config "$2" -o "$4" -- "$1"; else oras pull -o "$4" -- "$1"; fi
+origin=artifact
+` + copyConfigurationScript
+
+// gitVerifyScript verifies the commit of the repo cloned to $1 is signed by one of the SSH keys in the allowed signers
+// file $2
+const gitVerifyScript = `commit=$(git -C "$1" rev-parse HEAD)
+git -C "$1" -c gpg.format=ssh -c gpg.ssh.allowedSignersFile="$2" verify-commit "$commit" ||
+  { echo "Error: commit $commit isn't signed by a trusted key"; exit 1; }`
+
+// copyConfigurationScript copies the configuration in the directory $3 of the $origin pulled to $4 to $5
+const copyConfigurationScript = `dir="$4/$3"
+if [ ! -d "$dir" ] || [ -z "$(ls -A "$dir")" ]; then
+  available=$(cd "$4" && find . -maxdepth 3 -type d ! -path './.git*' ! -path . | sed 's|^\./||' | sort | head -n 50 | tr '\n' ' ')
+  echo "Error: path $3 doesn't exist or is empty in the $origin, available directories: $available" | tee /dev/termination-log
+  exit 1
+fi
+cp -r "$dir"/* "$5"`
+
+// prepareInputScript copies the input configuration in $1 to $2, and decompresses the files with the suffix $3
+const prepareInputScript = `cp "$1"/* "$2" && if ls "$2"/*"$3" >/dev/null 2>&1; then gunzip -f "$2"/*"$3"; fi`
+
+// gitAllowedProtocols are the protocols which git is allowed to clone the remote repo with
+const gitAllowedProtocols = "http:https:ssh:git"
+
+// gitCommitRegexp matches a full SHA-1 or SHA-256 commit hash
+var gitCommitRegexp = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// verifiedGitCommit returns the commit if it's a commit hash, which could come from the annotation of the input
+// ConfigMap, or empty to clone the default branch
+func verifiedGitCommit(commit string) string {
+	if !gitCommitRegexp.MatchString(commit) {
+		return ""
+	}
+	return commit
+}
+
+func (meta *TFConfigurationMeta) assembleTerraformJob(executionType TerraformExecutionType) *batchv1.Job {
+	var (
+		initContainer  v1.Container
+		initContainers []v1.Container
+		parallelism    int32 = 1
+		completions    int32 = 1
+		backoffLimit   int32 = math.MaxInt32
+		restartPolicy        = v1.RestartPolicyOnFailure
+	)
+
+	// A validation is deterministic, so retrying it makes no sense, neither does a removal from the state. A failed
+	// plan is reported for the approvers rather than retried, and a failed refresh for whoever requested it, as is a
+	// failed migration of the state, which is run again once spec.backend is changed.
+	if executionType == TerraformValidate || executionType == TerraformStateRemove || executionType == TerraformPlan ||
+		executionType == TerraformRefresh || executionType == TerraformMigrateState {
+		backoffLimit = 0
+		restartPolicy = v1.RestartPolicyNever
+	}
+	// the failed pods of the apply and destroy are kept for inspection by spec.jobRestartPolicy, and a new pod is
+	// created for each retry, which are limited as Kubernetes does by default
+	if meta.JobRestartPolicy == v1.RestartPolicyNever && (executionType == TerraformApply || executionType == TerraformDestroy) {
+		backoffLimit = neverRestartBackoffLimit
+		restartPolicy = v1.RestartPolicyNever
+	}
+
+	// The validate Job is kept, as a validation is run again when it's not found, while the state-rm Job is deleted
+	// once its result is recorded. The apply and destroy change the cloud resources, which are protected from being
+	// interrupted by an eviction, and given time to stop gracefully by the SIGTERM which the runner forwards.
+	var (
+		ttlSecondsAfterFinished       *int32
+		terminationGracePeriodSeconds *int64
+		command                       = meta.executorCommand(executionType)
+		podAnnotations                = meta.PodAnnotations
+	)
+	if executionType == TerraformApply || executionType == TerraformDestroy {
+		ttlSecondsAfterFinished = meta.JobTTLSecondsAfterFinished
+		gracePeriod := executorTerminationGracePeriodSeconds
+		terminationGracePeriodSeconds = &gracePeriod
+		podAnnotations = mergeMaps(notSafeToEvictAnnotations, meta.PodAnnotations)
+	}
+	if len(meta.Entrypoint) > 0 {
+		command = append(append([]string{}, meta.Entrypoint...), command...)
+	}
+
+	executorVolumes := meta.assembleExecutorVolumes()
 	initContainerVolumeMounts := []v1.VolumeMount{
 		{
 			Name:      meta.Name,
@@ -462,34 +2556,77 @@ func (meta *TFConfigurationMeta) assembleTerraformJob(executionType TerraformExe
 		},
 	}
 
+	// the runner installs itself, as the image of the controller has no shell either
+	initContainers = append(initContainers, v1.Container{
+		Name:            "install-runner",
+		Image:           meta.runnerImage(),
+		ImagePullPolicy: v1.PullIfNotPresent,
+		Command:         []string{runnerImageBinary, "install", runnerBinary},
+		VolumeMounts:    []v1.VolumeMount{{Name: RunnerVolumeName, MountPath: RunnerMountPath}},
+	})
 	initContainer = v1.Container{
 		Name:            "prepare-input-terraform-configurations",
 		Image:           "busybox:latest",
 		ImagePullPolicy: v1.PullIfNotPresent,
-		Command: []string{
-			"sh",
-			"-c",
-			fmt.Sprintf("cp %s/* %s", InputTFConfigurationVolumeMountPath, WorkingVolumeMountPath),
-		},
+		Env:             append(proxyEnvs(), meta.Env...),
+		Command: []string{"sh", "-c", prepareInputScript, "prepare-input-terraform-configurations",
+			InputTFConfigurationVolumeMountPath, WorkingVolumeMountPath, cfgvalidator.CompressedFileSuffix},
 		VolumeMounts: initContainerVolumeMounts,
 	}
 	initContainers = append(initContainers, initContainer)
 
 	if meta.RemoteGit != "" {
+		// The values are passed as the positional parameters of the script, so that they are never parsed by the shell
 		initContainers = append(initContainers,
 			v1.Container{
 				Name:            "git-configuration",
 				Image:           "alpine/git:latest",
 				ImagePullPolicy: v1.PullIfNotPresent,
-				Command: []string{
-					"sh",
-					"-c",
-					fmt.Sprintf("git clone %s %s && cp -r %s/* %s", meta.RemoteGit, BackendVolumeMountPath,
-						BackendVolumeMountPath, WorkingVolumeMountPath),
-				},
+				// The transports like ext:: which run commands are not allowed
+				Env: append(append(proxyEnvs(), meta.Env...), v1.EnvVar{Name: "GIT_ALLOW_PROTOCOL", Value: gitAllowedProtocols}),
+				Command: []string{"sh", "-c", gitConfigurationScript, "git-configuration", meta.RemoteGit,
+					verifiedGitCommit(meta.RemoteGitCommit), meta.RemoteGitPath, BackendVolumeMountPath, WorkingVolumeMountPath},
 				VolumeMounts: initContainerVolumeMounts,
 			})
 	}
+	if meta.OCI != nil {
+		// a verified artifact is pinned by a digest, and it's verified before it's pulled by the same reference, so that
+		// the artifact pulled is the one verified
+		if meta.Verify != nil {
+			initContainers = append(initContainers, meta.assembleVerifyContainer(initContainerVolumeMounts))
+		}
+		var registryConfig string
+		ociVolumeMounts := initContainerVolumeMounts
+		if meta.OCI.PullSecretName != "" {
+			registryConfig = path.Join(OCIRegistryConfigMountPath, "config.json")
+			ociVolumeMounts = append(append([]v1.VolumeMount(nil), initContainerVolumeMounts...),
+				v1.VolumeMount{Name: OCIRegistryConfigVolumeName, MountPath: OCIRegistryConfigMountPath, ReadOnly: true})
+		}
+		initContainers = append(initContainers,
+			v1.Container{
+				Name:            "oci-configuration",
+				Image:           orasImage,
+				ImagePullPolicy: v1.PullIfNotPresent,
+				Env:             append(proxyEnvs(), meta.Env...),
+				Command: []string{"sh", "-c", ociConfigurationScript, "oci-configuration", meta.OCI.Artifact, registryConfig,
+					meta.OCI.Path, BackendVolumeMountPath, WorkingVolumeMountPath},
+				VolumeMounts: ociVolumeMounts,
+			})
+	}
+	if meta.Verify != nil && meta.OCI == nil {
+		initContainers = append(initContainers, meta.assembleVerifyContainer(initContainerVolumeMounts))
+	}
+
+	annotations := meta.Annotations
+	if executionType == TerraformApply {
+		annotations = mergeMaps(meta.Annotations, map[string]string{ConfigurationHashAnnotation: meta.configurationHash()})
+	}
+	if hash := meta.importsHash(); hash != "" && executionType == TerraformApply {
+		annotations = mergeMaps(annotations, map[string]string{ImportsHashAnnotation: hash})
+	}
+	if executionType == TerraformDestroy {
+		annotations = mergeMaps(meta.Annotations, map[string]string{DestroyStageAnnotation: strconv.Itoa(meta.DestroyStage)})
+	}
 
 	return &batchv1.Job{
 		TypeMeta: metav1.TypeMeta{
@@ -497,14 +2634,23 @@ func (meta *TFConfigurationMeta) assembleTerraformJob(executionType TerraformExe
 			APIVersion: "batch/v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      meta.Name + "-" + string(executionType),
-			Namespace: controllerNamespace,
+			Name:            meta.Name + "-" + string(executionType),
+			Namespace:       meta.Namespace,
+			OwnerReferences: meta.OwnerReferences,
+			Labels:          meta.Labels,
+			Annotations:     annotations,
 		},
 		Spec: batchv1.JobSpec{
-			Parallelism:  &parallelism,
-			Completions:  &completions,
-			BackoffLimit: &backoffLimit,
+			Parallelism:             &parallelism,
+			Completions:             &completions,
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   meta.JobActiveDeadlineSeconds,
+			TTLSecondsAfterFinished: ttlSecondsAfterFinished,
 			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      meta.Labels,
+					Annotations: podAnnotations,
+				},
 				Spec: v1.PodSpec{
 					// InitContainer will copy Terraform configuration files to working directory and create Terraform
 					// state file directory in advance
@@ -512,15 +2658,12 @@ func (meta *TFConfigurationMeta) assembleTerraformJob(executionType TerraformExe
 					// Container terraform-executor will first copy predefined terraform.d to working directory, and
 					// then run terraform init/apply.
 					Containers: []v1.Container{{
-						Name:            "terraform-executor",
-						Image:           terraformImage,
+						Name:            terraformExecutorContainerName,
+						Image:           meta.executorImage(),
 						ImagePullPolicy: v1.PullIfNotPresent,
-						Command: []string{
-							"bash",
-							"-c",
-							fmt.Sprintf("terraform init && terraform %s -lock=false -auto-approve", executionType),
-						},
-						VolumeMounts: []v1.VolumeMount{
+						Command:         command,
+						WorkingDir:      WorkingVolumeMountPath,
+						VolumeMounts: append([]v1.VolumeMount{
 							{
 								Name:      meta.Name,
 								MountPath: WorkingVolumeMountPath,
@@ -529,25 +2672,428 @@ func (meta *TFConfigurationMeta) assembleTerraformJob(executionType TerraformExe
 								Name:      InputTFConfigurationVolumeName,
 								MountPath: InputTFConfigurationVolumeMountPath,
 							},
-						},
-						Env: meta.Envs,
+							{
+								Name:      RunnerVolumeName,
+								MountPath: RunnerMountPath,
+								ReadOnly:  true,
+							},
+						}, meta.assembleExtraVolumeMounts()...),
+						Env: meta.executorEnvs(),
 					},
 					},
-					ServiceAccountName: "tf-executor-service-account",
-					Volumes:            executorVolumes,
-					RestartPolicy:      v1.RestartPolicyOnFailure,
+					ServiceAccountName:            meta.ServiceAccountName,
+					Volumes:                       executorVolumes,
+					RestartPolicy:                 restartPolicy,
+					TerminationGracePeriodSeconds: terminationGracePeriodSeconds,
 				},
 			},
 		},
 	}
 }
 
+// assembleVerifyContainer assembles the init container which verifies the signature of the remote git repo after it's
+// cloned, or the OCI artifact before it's pulled. Its logs are the termination message of a failure, which is told apart by
+// its name.
+func (meta *TFConfigurationMeta) assembleVerifyContainer(volumeMounts []v1.VolumeMount) v1.Container {
+	container := v1.Container{
+		Name:                     terraform.VerifyContainerName,
+		ImagePullPolicy:          v1.PullIfNotPresent,
+		Env:                      append(proxyEnvs(), meta.Env...),
+		TerminationMessagePolicy: v1.TerminationMessageFallbackToLogsOnError,
+		VolumeMounts: append(append([]v1.VolumeMount(nil), volumeMounts...),
+			v1.VolumeMount{Name: TrustedKeysVolumeName, MountPath: TrustedKeysMountPath, ReadOnly: true}),
+	}
+	if meta.OCI == nil {
+		container.Image = "alpine/git:latest"
+		container.Command = []string{"sh", "-c", gitVerifyScript, terraform.VerifyContainerName, BackendVolumeMountPath,
+			path.Join(TrustedKeysMountPath, AllowedSignersKey)}
+		return container
+	}
+
+	// the image has no shell, and the `--` stops the reference from being taken as an option of cosign
+	container.Image = cosignImage
+	container.Command = []string{"cosign", "verify", "--key", path.Join(TrustedKeysMountPath, CosignPublicKeyKey), "--",
+		meta.OCI.Artifact}
+	if meta.OCI.PullSecretName != "" {
+		container.Env = append(container.Env, v1.EnvVar{Name: "DOCKER_CONFIG", Value: OCIRegistryConfigMountPath})
+		container.VolumeMounts = append(container.VolumeMounts,
+			v1.VolumeMount{Name: OCIRegistryConfigVolumeName, MountPath: OCIRegistryConfigMountPath, ReadOnly: true})
+	}
+	return container
+}
+
+// mergeMaps merges labels or annotations, and the latter map wins on conflicts
+func mergeMaps(maps ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// configurationHash hashes the configuration which the Jobs run with, including the revision of the remote one
+func (meta *TFConfigurationMeta) configurationHash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", meta.CompleteConfiguration, meta.sourceRevision(), meta.RemoteGitPath, meta.VarFilesHash)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// importsHash hashes spec.imports, which is empty without any imports
+func (meta *TFConfigurationMeta) importsHash() string {
+	if len(meta.Imports) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, i := range meta.Imports {
+		fmt.Fprintf(h, "%s\x00%s\x00", i.Address, i.ID)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// importSteps import the resources of spec.imports which aren't in the state yet
+func (meta *TFConfigurationMeta) importSteps(binary string) []runner.Step {
+	steps := make([]runner.Step, 0, len(meta.Imports))
+	for _, i := range meta.Imports {
+		steps = append(steps, runner.Step{Run: &runner.Run{
+			Args:           joinArgs([]string{binary, "import", "-lock-timeout=5m"}, meta.varFileArgs(), []string{i.Address, i.ID}),
+			Unless:         []string{binary, "state", "show", i.Address},
+			FailureMessage: fmt.Sprintf("%s: %s", terraform.ImportFailedMessage, i.Address),
+		}})
+	}
+	return steps
+}
+
+// runStep runs a command with its arguments
+func runStep(args ...string) runner.Step {
+	return runner.Step{Run: &runner.Run{Args: args}}
+}
+
+// joinArgs joins the groups of the arguments of a command
+func joinArgs(groups ...[]string) []string {
+	var args []string
+	for _, group := range groups {
+		args = append(args, group...)
+	}
+	return args
+}
+
+// executorImage returns the image which ships the binary of the execution engine. It needs nothing else, as the steps
+// are run by the runner rather than a shell, so it could be distroless.
+func (meta *TFConfigurationMeta) executorImage() string {
+	if meta.Engine == types.OpenTofuEngine {
+		if meta.OpenTofuImage != "" {
+			return meta.OpenTofuImage
+		}
+		return openTofuImage
+	}
+	if meta.TerraformImage != "" {
+		return meta.TerraformImage
+	}
+	return terraformImage
+}
+
+// runnerImage returns the image which ships the runner binary installed to the Jobs
+func (meta *TFConfigurationMeta) runnerImage() string {
+	if meta.RunnerImage != "" {
+		return meta.RunnerImage
+	}
+	return runnerImage
+}
+
+// executorCommand is the command of the executor, which runs the steps of the execution type by the runner
+func (meta *TFConfigurationMeta) executorCommand(executionType TerraformExecutionType) []string {
+	return runner.Command(runnerBinary, meta.executorSteps(executionType))
+}
+
+// executorSteps composes the steps of `init` and `apply`/`destroy` for the execution engine. The commands writing the
+// state, like apply, destroy, import and the state operations, hold the lock of the backend, and wait a while for the
+// one held by another Job, so that two Jobs of a Configuration never write the state at the same time. The plans only
+// read the state, which are run without the lock.
+func (meta *TFConfigurationMeta) executorSteps(executionType TerraformExecutionType) []runner.Step {
+	binary := string(types.TerraformEngine)
+	if meta.Engine == types.OpenTofuEngine {
+		binary = string(types.OpenTofuEngine)
+	}
+	initStep := meta.initStep(binary)
+	switch executionType {
+	case TerraformValidate:
+		// validation doesn't need the state, so skip initializing the backend
+		return []runner.Step{meta.initStep(binary, "-backend=false"), runStep(binary, "validate", "-no-color")}
+	case TerraformPlan:
+		// The hash of the plan is passed back in the termination message
+		hash := &runner.Hash{File: planTextFile, Output: terminationMessagePath}
+		if meta.PlanArtifacts != nil {
+			// the plan is saved by its hash, replacing the previous ones which can't be approved any more
+			hash.SaveFile, hash.SaveDir = planFile, PlanArtifactsMountPath
+		}
+		return append(append([]runner.Step{initStep}, meta.planSteps(binary)...), runner.Step{Hash: hash})
+	case TerraformStateRemove:
+		return []runner.Step{initStep, runStep(joinArgs([]string{binary, "state", "rm", "-lock-timeout=5m"}, meta.StateRemoveAddresses)...)}
+	case TerraformMigrateState:
+		// The working directory is initialized with the previous backend by an override file, which replaces the
+		// backend block of the configuration. The state is then copied to the current backend once the file is removed.
+		return []runner.Step{
+			{WriteFile: &runner.WriteFile{Path: previousBackendOverrideFile, Content: meta.PreviousBackendHCL + "\n"}},
+			meta.initStep(binary, "-input=false"),
+			{Remove: previousBackendOverrideFile},
+			meta.initStep(binary, "-migrate-state", "-force-copy", "-lock-timeout=5m", "-input=false"),
+		}
+	case TerraformDestroy:
+		args := joinArgs([]string{binary, "destroy", "-lock-timeout=5m", "-auto-approve"}, meta.jsonArgs(), meta.varFileArgs())
+		// only the resources of a stage of spec.destroyStages are destroyed, along with the ones depending on them
+		for _, target := range meta.DestroyTargets {
+			args = append(args, "-target="+target)
+		}
+		steps := []runner.Step{initStep, runStep(args...)}
+		if meta.PlanArtifacts != nil {
+			// the saved plans have the values of the variables, which are left nowhere once the resources are gone
+			steps = append(steps, runner.Step{Remove: path.Join(PlanArtifactsMountPath, "*")})
+		}
+		return steps
+	}
+
+	steps := []runner.Step{initStep}
+	if executionType == TerraformRefresh {
+		// the state is updated to match the cloud resources, which are left as they are
+		steps = append(steps, runStep(joinArgs([]string{binary, "apply", "-refresh-only", "-lock-timeout=5m", "-auto-approve"},
+			meta.jsonArgs(), meta.varFileArgs())...))
+	} else {
+		steps = append(steps, meta.applySteps(binary)...)
+	}
+	if meta.OutputsFromJob {
+		// The controller can't read the state, so the outputs are passed back in the termination message
+		steps = append(steps, runner.Step{Run: &runner.Run{Args: []string{binary, "output", "-json"}, Stdout: terminationMessagePath}})
+	}
+	return steps
+}
+
+// applySteps apply the changes after importing the resources of spec.imports. Only the approved plan is applied, which
+// is planned again and compared with the approved one by its hash.
+func (meta *TFConfigurationMeta) applySteps(binary string) []runner.Step {
+	steps := meta.importSteps(binary)
+	if meta.ApprovedPlanHash == "" {
+		return append(steps, runStep(joinArgs([]string{binary, "apply", "-lock-timeout=5m", "-auto-approve"}, meta.jsonArgs(),
+			meta.refreshArgs(), meta.varFileArgs())...))
+	}
+	steps = append(append(steps, meta.planSteps(binary)...),
+		runner.Step{Hash: &runner.Hash{File: planTextFile, Expected: meta.ApprovedPlanHash, FailureMessage: terraform.PlanChangedMessage}},
+		runStep(joinArgs([]string{binary, "apply", "-lock-timeout=5m", "-auto-approve"}, meta.jsonArgs(), []string{planFile})...))
+	if meta.PlanArtifacts == nil || len(meta.Imports) > 0 {
+		return steps
+	}
+	// The approved plan saved by the plan Job is applied as it is, which Terraform refuses once the state has changed
+	// since. It's hashed again rather than trusted by its name, and it's planned again and compared as above if the
+	// saved plan is gone.
+	saved := path.Join(PlanArtifactsMountPath, meta.ApprovedPlanHash)
+	return []runner.Step{{IfExists: &runner.IfExists{
+		Path: saved,
+		Then: []runner.Step{
+			{Run: &runner.Run{Args: []string{binary, "show", "-no-color", saved}, Stdout: savedPlanTextFile}},
+			{Hash: &runner.Hash{File: savedPlanTextFile, Expected: meta.ApprovedPlanHash, FailureMessage: terraform.PlanChangedMessage}},
+			runStep(joinArgs([]string{binary, "apply", "-lock-timeout=5m", "-auto-approve"}, meta.jsonArgs(), []string{saved})...),
+			{Remove: saved},
+		},
+		Else: steps,
+	}}}
+}
+
+// initStep initializes the working directory. The shared plugin cache isn't safe for the concurrent inits to write,
+// so they are serialized by a lock file in the cache, which is released by the kernel even if the executor is killed.
+func (meta *TFConfigurationMeta) initStep(binary string, args ...string) runner.Step {
+	step := runStep(joinArgs([]string{binary, "init"}, args)...)
+	if meta.PluginCache != nil {
+		step.Run.Lock = path.Join(PluginCacheMountPath, ".lock")
+	}
+	return step
+}
+
+// executorEnvs are the environment variables of the executor, which are the variables and the credentials, along
+// with the settings of the controller, like the shared plugin cache and the CLI configuration
+func (meta *TFConfigurationMeta) executorEnvs() []v1.EnvVar {
+	return meta.executorEnvsOf(meta.Envs)
+}
+
+// executorEnvsOf are the envs of the executor container for the variables
+func (meta *TFConfigurationMeta) executorEnvsOf(variables []v1.EnvVar) []v1.EnvVar {
+	envs := append([]v1.EnvVar{}, variables...)
+	if meta.PluginCache != nil {
+		envs = append(envs, v1.EnvVar{Name: "TF_PLUGIN_CACHE_DIR", Value: PluginCacheMountPath})
+	}
+	if meta.CLIConfig != nil {
+		envs = append(envs, v1.EnvVar{Name: "TF_CLI_CONFIG_FILE", Value: path.Join(CLIConfigMountPath, CLIConfigKey)})
+	}
+	if meta.LogLevel != "" {
+		envs = append(envs, v1.EnvVar{Name: "TF_LOG", Value: meta.LogLevel})
+	}
+	return envs
+}
+
+// planSteps save the plan of the changes, along with its text which is hashed to tell whether it's the approved one
+func (meta *TFConfigurationMeta) planSteps(binary string) []runner.Step {
+	return []runner.Step{
+		runStep(joinArgs([]string{binary, "plan", "-lock=false", "-input=false"}, meta.jsonArgs(), meta.refreshArgs(),
+			meta.varFileArgs(), []string{"-out=" + planFile})...),
+		{Run: &runner.Run{Args: []string{binary, "show", "-no-color", planFile}, Stdout: planTextFile}},
+	}
+}
+
+// jsonArgs log the machine-readable messages of plan, apply and destroy, which are parsed for the status
+func (meta *TFConfigurationMeta) jsonArgs() []string {
+	if meta.JSONLogs {
+		return []string{"-json"}
+	}
+	return nil
+}
+
+// planArtifactsSubPath is the directory of the volume of the saved plans which the Jobs of the Configuration mount,
+// so that they can't read or replace the plans of the others. The plans in it are named by their hashes.
+func (meta *TFConfigurationMeta) planArtifactsSubPath() string {
+	return path.Join(meta.Namespace, meta.PlanJobName)
+}
+
+// refreshArgs are the arguments of plan and apply to skip the refresh of the state for spec.skipRefresh
+func (meta *TFConfigurationMeta) refreshArgs() []string {
+	if meta.SkipRefresh {
+		return []string{"-refresh=false"}
+	}
+	return nil
+}
+
 func (meta *TFConfigurationMeta) assembleExecutorVolumes() []v1.Volume {
 	workingVolume := v1.Volume{Name: meta.Name}
-	workingVolume.EmptyDir = &v1.EmptyDirVolumeSource{}
+	workingVolume.EmptyDir = meta.emptyDirVolumeSource()
 	inputTFConfigurationVolume := meta.createConfigurationVolume()
 	tfBackendVolume := meta.createTFBackendVolume()
-	return []v1.Volume{workingVolume, inputTFConfigurationVolume, tfBackendVolume}
+	runnerVolume := v1.Volume{Name: RunnerVolumeName, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}
+	volumes := []v1.Volume{workingVolume, inputTFConfigurationVolume, tfBackendVolume, runnerVolume}
+	if meta.PluginCache != nil {
+		volumes = append(volumes, v1.Volume{Name: PluginCacheVolumeName, VolumeSource: *meta.PluginCache})
+	}
+	if meta.PlanArtifacts != nil {
+		volumes = append(volumes, v1.Volume{Name: PlanArtifactsVolumeName, VolumeSource: *meta.PlanArtifacts})
+	}
+	if meta.CLIConfig != nil {
+		volumes = append(volumes, v1.Volume{Name: CLIConfigVolumeName, VolumeSource: *meta.CLIConfig})
+	}
+	if meta.OCI != nil && meta.OCI.PullSecretName != "" {
+		volumes = append(volumes, v1.Volume{
+			Name: OCIRegistryConfigVolumeName,
+			VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{
+				SecretName: meta.OCI.PullSecretName,
+				Items:      []v1.KeyToPath{{Key: v1.DockerConfigJsonKey, Path: "config.json"}},
+			}},
+		})
+	}
+	if len(meta.VarFiles) > 0 {
+		volumes = append(volumes, v1.Volume{
+			Name:         VarFilesVolumeName,
+			VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: fmt.Sprintf(VarFilesSecretName, meta.Name)}},
+		})
+	}
+	if meta.Verify != nil {
+		volumes = append(volumes, v1.Volume{
+			Name:         TrustedKeysVolumeName,
+			VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: meta.Verify.TrustedKeysSecretName}},
+		})
+	}
+	for _, v := range meta.ExecutorVolumes {
+		volumes = append(volumes, v1.Volume{Name: v.Name, VolumeSource: meta.executorVolumeSource(v)})
+	}
+	return volumes
+}
+
+func (meta *TFConfigurationMeta) assembleExtraVolumeMounts() []v1.VolumeMount {
+	var mounts []v1.VolumeMount
+	if meta.PluginCache != nil {
+		mounts = append(mounts, v1.VolumeMount{Name: PluginCacheVolumeName, MountPath: PluginCacheMountPath,
+			SubPath: meta.PluginCacheSubPath})
+	}
+	if meta.PlanArtifacts != nil {
+		mounts = append(mounts, v1.VolumeMount{Name: PlanArtifactsVolumeName, MountPath: PlanArtifactsMountPath,
+			SubPath: meta.planArtifactsSubPath()})
+	}
+	if meta.CLIConfig != nil {
+		mounts = append(mounts, v1.VolumeMount{Name: CLIConfigVolumeName, MountPath: CLIConfigMountPath, ReadOnly: true})
+	}
+	if len(meta.VarFiles) > 0 {
+		mounts = append(mounts, v1.VolumeMount{Name: VarFilesVolumeName, MountPath: VarFilesMountPath, ReadOnly: true})
+	}
+	for _, v := range meta.ExecutorVolumes {
+		mounts = append(mounts, v1.VolumeMount{
+			Name:      v.Name,
+			MountPath: v.MountPath,
+			SubPath:   v.SubPath,
+			ReadOnly:  true,
+		})
+	}
+	return mounts
+}
+
+// reservedEnvs are the environment variables set by the controller, which spec.env can't set
+var reservedEnvs = map[string]bool{
+	"HTTP_PROXY": true, "HTTPS_PROXY": true, "NO_PROXY": true, "http_proxy": true, "https_proxy": true, "no_proxy": true,
+	"GIT_ALLOW_PROTOCOL": true, "TF_PLUGIN_CACHE_DIR": true, "TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE": true,
+	"TF_CLI_CONFIG_FILE": true,
+}
+
+// ValidateEnv validates the environment variables of spec.env don't collide with the ones set by the controller
+func ValidateEnv(configuration *v1beta1.Configuration) field.ErrorList {
+	var allErrs field.ErrorList
+	names := make(map[string]bool)
+	for i, env := range configuration.Spec.Env {
+		namePath := field.NewPath("spec", "env").Index(i).Child("name")
+		for _, msg := range validation.IsEnvVarName(env.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, env.Name, msg))
+		}
+		switch {
+		case names[env.Name]:
+			allErrs = append(allErrs, field.Duplicate(namePath, env.Name))
+		case strings.HasPrefix(env.Name, "TF_VAR_"):
+			allErrs = append(allErrs, field.Invalid(namePath, env.Name, "must not start with TF_VAR_, which are set by spec.variable"))
+		case reservedEnvs[env.Name] || util.IsCredentialEnv(env.Name):
+			allErrs = append(allErrs, field.Forbidden(namePath, fmt.Sprintf("%s is set by the controller", env.Name)))
+		case env.Name == "TF_LOG" && configuration.Spec.LogLevel != "":
+			allErrs = append(allErrs, field.Forbidden(namePath, "TF_LOG is set by spec.logLevel"))
+		}
+		names[env.Name] = true
+	}
+	return allErrs
+}
+
+// ValidateExecutorVolumes validates the extra volumes of the executor don't collide with the reserved ones
+func ValidateExecutorVolumes(configuration *v1beta1.Configuration) field.ErrorList {
+	var allErrs field.ErrorList
+	names := map[string]bool{configuration.Name: true, InputTFConfigurationVolumeName: true, BackendVolumeName: true,
+		PluginCacheVolumeName: true, CLIConfigVolumeName: true, OCIRegistryConfigVolumeName: true, TrustedKeysVolumeName: true,
+		VarFilesVolumeName: true, PlanArtifactsVolumeName: true}
+	mountPaths := map[string]bool{WorkingVolumeMountPath: true, InputTFConfigurationVolumeMountPath: true,
+		PluginCacheMountPath: true, CLIConfigMountPath: true, VarFilesMountPath: true, PlanArtifactsMountPath: true}
+	for i, v := range configuration.Spec.Volumes {
+		volumePath := field.NewPath("spec", "volumes").Index(i)
+		if names[v.Name] {
+			allErrs = append(allErrs, field.Duplicate(volumePath.Child("name"), v.Name))
+		}
+		names[v.Name] = true
+		for _, msg := range validation.IsDNS1123Label(v.Name) {
+			allErrs = append(allErrs, field.Invalid(volumePath.Child("name"), v.Name, msg))
+		}
+
+		mountPath := path.Clean(v.MountPath)
+		switch {
+		case !path.IsAbs(v.MountPath):
+			allErrs = append(allErrs, field.Invalid(volumePath.Child("mountPath"), v.MountPath, "must be an absolute path"))
+		case mountPaths[mountPath]:
+			allErrs = append(allErrs, field.Duplicate(volumePath.Child("mountPath"), v.MountPath))
+		}
+		mountPaths[mountPath] = true
+
+		if (v.Secret == nil) == (v.ConfigMap == nil) {
+			allErrs = append(allErrs, field.Invalid(volumePath, v.Name, "exactly one of secret and configMap must be set"))
+		}
+	}
+	return allErrs
 }
 
 func (meta *TFConfigurationMeta) createConfigurationVolume() v1.Volume {
@@ -561,50 +3107,172 @@ func (meta *TFConfigurationMeta) createConfigurationVolume() v1.Volume {
 
 func (meta *TFConfigurationMeta) createTFBackendVolume() v1.Volume {
 	gitVolume := v1.Volume{Name: BackendVolumeName}
-	gitVolume.EmptyDir = &v1.EmptyDirVolumeSource{}
+	gitVolume.EmptyDir = meta.emptyDirVolumeSource()
 	return gitVolume
 }
 
+func (meta *TFConfigurationMeta) emptyDirVolumeSource() *v1.EmptyDirVolumeSource {
+	source := &v1.EmptyDirVolumeSource{Medium: meta.WorkingVolume.Medium}
+	if meta.WorkingVolume.SizeLimit != nil {
+		sizeLimit := meta.WorkingVolume.SizeLimit.DeepCopy()
+		source.SizeLimit = &sizeLimit
+	}
+	return source
+}
+
+// workingVolume overrides the default emptyDir settings of the controller by the ones of the Configuration
+func workingVolume(defaults v1beta1.WorkingVolume, override *v1beta1.WorkingVolume) v1beta1.WorkingVolume {
+	if override == nil {
+		return defaults
+	}
+	if override.Medium != "" {
+		defaults.Medium = override.Medium
+	}
+	if override.SizeLimit != nil {
+		defaults.SizeLimit = override.SizeLimit
+	}
+	return defaults
+}
+
 // TFState is Terraform State
 type TFState struct {
-	Outputs map[string]v1beta1.Property `json:"outputs"`
+	Outputs map[string]TfStateProperty `json:"outputs"`
 }
 
-//nolint:funlen
-func getTFOutputs(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration) (map[string]v1beta1.Property, error) {
-	var s = v1.Secret{}
-	// Check the existence of Terraform state secret which is used to store TF state file. For detailed information,
-	// please refer to https://www.terraform.io/docs/language/settings/backends/kubernetes.html#configuration-variables
-	var backendSecretSuffix string
-	if configuration.Spec.Backend != nil && configuration.Spec.Backend.SecretSuffix != "" {
-		backendSecretSuffix = configuration.Spec.Backend.SecretSuffix
-	} else {
-		backendSecretSuffix = configuration.Name
+// TfStateProperty is the property of an output in Terraform State
+type TfStateProperty struct {
+	Value     interface{} `json:"value,omitempty"`
+	Type      interface{} `json:"type,omitempty"`
+	Sensitive bool        `json:"sensitive,omitempty"`
+}
+
+// ToProperty converts TfStateProperty to Property, which keeps the value of a complex type structured in JSON
+func (tp TfStateProperty) ToProperty() (v1beta1.Property, error) {
+	value, err := util.Interface2String(tp.Value)
+	if err != nil {
+		return v1beta1.Property{}, errors.Wrap(err, "failed to convert the value of the output")
 	}
-	// Secrets will be named in the format: tfstate-{workspace}-{secret_suffix}
-	k8sBackendSecretName := fmt.Sprintf("tfstate-%s-%s", terraformWorkspace, backendSecretSuffix)
-	if err := k8sClient.Get(ctx, client.ObjectKey{Name: k8sBackendSecretName, Namespace: controllerNamespace}, &s); err != nil {
-		return nil, errors.Wrap(err, "terraform state file backend secret is not generated")
+	outputType, err := util.Interface2String(tp.Type)
+	if err != nil {
+		return v1beta1.Property{}, errors.Wrap(err, "failed to convert the type of the output")
 	}
-	tfStateData, ok := s.Data[TerraformStateNameInSecret]
-	if !ok {
-		return nil, fmt.Errorf("failed to get %s from Terraform State secret %s", TerraformStateNameInSecret, s.Name)
+	return v1beta1.Property{Value: value, Type: outputType, Sensitive: tp.Sensitive}, nil
+}
+
+// getBackend returns the Backend storing the state of a Configuration. The azurerm backend is accessed with the
+// credentials of the Provider.
+func getBackend(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration) (backend.Backend, error) {
+	defaulted := configuration.DeepCopy()
+	cfgvalidator.SetDefaults(defaulted)
+	return newBackend(ctx, k8sClient, *defaulted, defaulted.Spec.Backend)
+}
+
+// newBackend returns a Backend of a defaulted Configuration, which isn't necessarily the one of its spec, like the
+// backend which the state is migrated from
+func newBackend(ctx context.Context, k8sClient client.Client, defaulted v1beta1.Configuration, conf *v1beta1.Backend) (backend.Backend, error) {
+	var credentials map[string]string
+	if conf != nil && conf.BackendType == backend.TypeAzureRM {
+		ref := defaulted.Spec.ProviderReference
+		var err error
+		if credentials, err = util.GetProviderCredentials(ctx, k8sClient, ref.Namespace, ref.Name); err != nil {
+			return nil, errors.Wrap(err, "failed to get the credentials to access the Terraform state")
+		}
 	}
+	// the state of the kubernetes backend is written by the Jobs into the cluster they run in
+	return backend.New(clusterClient(ctx, k8sClient), conf, executionNamespace(defaulted), credentials)
+}
 
-	tfStateJSON, err := util.DecompressTerraformStateSecret(string(tfStateData))
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to decompress state secret data")
+// getTFOutputs reads the outputs of the latest successful apply, and writes them to the connection Secret and the
+// outputs ConfigMap
+func getTFOutputs(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration) (map[string]v1beta1.Property, error) {
+	// the state isn't read again until another apply succeeds
+	if stateOutputs, ok := appliedOutputs.get(configuration); ok {
+		return writeTFOutputs(ctx, k8sClient, configuration, stateOutputs)
 	}
+	return readTFOutputs(ctx, k8sClient, configuration, configuration.Name+"-"+string(TerraformApply))
+}
 
+// readTFOutputs reads the outputs from the state, or from the Job which passed them back when the state can't be read
+// by the controller, and writes them to the connection Secret and the outputs ConfigMap
+func readTFOutputs(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration, jobName string) (map[string]v1beta1.Property, error) {
 	var tfState TFState
-	if err := json.Unmarshal(tfStateJSON, &tfState); err != nil {
+	if outputsFromJob(configuration) {
+		outputsJSON, err := terraform.GetTerraformOutputs(ctx, executionNamespace(configuration), jobName, terraformExecutorContainerName)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeJSONWithNumber(outputsJSON, &tfState.Outputs); err != nil {
+			return nil, errors.Wrap(err, "failed to decode the outputs of the apply Job, which might exceed the size limit of the termination message")
+		}
+	} else {
+		b, err := getBackend(ctx, k8sClient, configuration)
+		if err != nil {
+			return nil, err
+		}
+		tfStateJSON, err := b.GetTFStateJSON(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeJSONWithNumber(tfStateJSON, &tfState); err != nil {
+			return nil, err
+		}
+	}
+	// the outputs read after the state is changed by another Job, like refresh, replace the ones of the apply
+	appliedOutputs.set(configuration, tfState.Outputs)
+	return writeTFOutputs(ctx, k8sClient, configuration, tfState.Outputs)
+}
+
+// writeTFOutputs writes the outputs in the state to the connection Secret and the outputs ConfigMap, and returns them
+// for the status
+func writeTFOutputs(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration,
+	stateOutputs map[string]TfStateProperty) (map[string]v1beta1.Property, error) {
+	var (
+		outputs         = make(map[string]v1beta1.Property)
+		redactedOutputs = make(map[string]v1beta1.Property)
+	)
+	for k, v := range stateOutputs {
+		property, err := v.ToProperty()
+		if err != nil {
+			return nil, errors.Wrapf(err, "output %s", k)
+		}
+		outputs[k] = property
+		if property.Sensitive {
+			property.Value = ""
+		}
+		redactedOutputs[k] = property
+	}
+
+	if err := writeConnectionSecret(ctx, k8sClient, configuration, outputs); err != nil {
+		return nil, err
+	}
+	if err := writeOutputsConfigMap(ctx, k8sClient, configuration, stateOutputs); err != nil {
 		return nil, err
 	}
+	// sensitive values are only written to the connection secret, and never exposed in the status
+	return redactedOutputs, nil
+}
+
+// outputsFromJob tells whether the outputs are collected by the apply Job with `terraform output -json`, which is
+// chosen by spec.outputsFrom, or the only way to get the outputs of the custom backend or the encrypted state
+func outputsFromJob(configuration v1beta1.Configuration) bool {
+	if configuration.Spec.OutputsFrom == types.OutputsFromTerraformOutput {
+		return true
+	}
+	b := configuration.Spec.Backend
+	return b != nil && (b.BackendType == backend.TypeCustom || b.Encryption != nil)
+}
+
+// decodeJSONWithNumber keeps numbers as they are rather than converting them to float64
+func decodeJSONWithNumber(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
 
-	outputs := tfState.Outputs
+func writeConnectionSecret(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration, outputs map[string]v1beta1.Property) error {
 	writeConnectionSecretToReference := configuration.Spec.WriteConnectionSecretToReference
 	if writeConnectionSecretToReference == nil || writeConnectionSecretToReference.Name == "" {
-		return outputs, nil
+		return nil
 	}
 
 	name := writeConnectionSecretToReference.Name
@@ -612,9 +3280,9 @@ func getTFOutputs(ctx context.Context, k8sClient client.Client, configuration v1
 	if ns == "" {
 		ns = "default"
 	}
-	data := make(map[string][]byte)
-	for k, v := range outputs {
-		data[k] = []byte(v.Value)
+	data, err := connectionSecretData(writeConnectionSecretToReference, outputs)
+	if err != nil {
+		return err
 	}
 	var gotSecret v1.Secret
 	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: ns}, &gotSecret); err != nil {
@@ -623,21 +3291,129 @@ func getTFOutputs(ctx context.Context, k8sClient client.Client, configuration v1
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      name,
 					Namespace: ns,
+					Labels:    ownerLabels(configuration),
 				},
 				TypeMeta: metav1.TypeMeta{Kind: "Secret"},
 				Data:     data,
 			}
-			if err := k8sClient.Create(ctx, &secret); err != nil {
-				return nil, err
+			return k8sClient.Create(ctx, &secret)
+		}
+		return err
+	}
+	if err := claimOwnership(&gotSecret, configuration); err != nil {
+		return err
+	}
+	gotSecret.Data = data
+	return k8sClient.Update(ctx, &gotSecret)
+}
+
+// connectionSecretData returns the data of the connection secret, which are the outputs of its keys if they're set,
+// renamed by its key mapping. A renamed output can't collide with another output.
+func connectionSecretData(ref *v1beta1.ConnectionSecretReference, outputs map[string]v1beta1.Property) (map[string][]byte, error) {
+	selected := outputs
+	if len(ref.Keys) > 0 {
+		selected = make(map[string]v1beta1.Property, len(ref.Keys))
+		for _, key := range ref.Keys {
+			if v, ok := outputs[key]; ok {
+				selected[key] = v
 			}
 		}
-	} else {
-		gotSecret.Data = data
-		if err := k8sClient.Update(ctx, &gotSecret); err != nil {
-			return nil, err
+	}
+	data := make(map[string][]byte, len(selected))
+	writtenBy := make(map[string]string, len(selected))
+	for output, v := range selected {
+		key := ref.SecretKey(output)
+		if previous, ok := writtenBy[key]; ok {
+			return nil, errors.Errorf("outputs %s and %s are both written to the key %s of the connection secret",
+				previous, output, key)
+		}
+		writtenBy[key] = output
+		data[key] = []byte(v.Value)
+	}
+	return data, nil
+}
+
+// writeOutputsConfigMap writes the non-sensitive outputs to the ConfigMap specified by WriteOutputsToConfigMapReference
+func writeOutputsConfigMap(ctx context.Context, k8sClient client.Client, configuration v1beta1.Configuration, outputs map[string]TfStateProperty) error {
+	ref := configuration.Spec.WriteOutputsToConfigMapReference
+	if ref == nil || ref.Name == "" {
+		return nil
+	}
+
+	ns := ref.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+	data := make(map[string]string)
+	for k, v := range outputs {
+		if v.Sensitive {
+			continue
 		}
+		property, err := v.ToProperty()
+		if err != nil {
+			return errors.Wrapf(err, "output %s", k)
+		}
+		data[k] = property.Value
+	}
+	var gotCM v1.ConfigMap
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ns}, &gotCM); err != nil {
+		if kerrors.IsNotFound(err) {
+			cm := v1.ConfigMap{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ref.Name,
+					Namespace: ns,
+					Labels:    ownerLabels(configuration),
+				},
+				Data: data,
+			}
+			return errors.Wrap(k8sClient.Create(ctx, &cm), "failed to create outputs ConfigMap")
+		}
+		return err
+	}
+	if err := claimOwnership(&gotCM, configuration); err != nil {
+		return err
+	}
+	gotCM.Data = data
+	return errors.Wrap(k8sClient.Update(ctx, &gotCM), "failed to update outputs ConfigMap")
+}
+
+// ownerLabels are the labels which mark a resource as owned by the Configuration
+func ownerLabels(configuration v1beta1.Configuration) map[string]string {
+	return map[string]string{
+		LabelKeyOwnedBy:        configuration.Name,
+		LabelKeyOwnedNamespace: configuration.Namespace,
+	}
+}
+
+// ownerReferences returns the controller reference to the Configuration, so that its sub-resources are garbage
+// collected even if its finalizer is removed by force. As cross-namespace owner references are not allowed, the
+// sub-resources living in another namespace are only identified by the owner labels and cleaned up by OrphanCollector.
+func ownerReferences(configuration v1beta1.Configuration, namespace string) []metav1.OwnerReference {
+	if configuration.Namespace != namespace || configuration.UID == "" || configuration.Spec.Cluster != nil {
+		return nil
+	}
+	return []metav1.OwnerReference{*metav1.NewControllerRef(&configuration, v1beta1.GroupVersion.WithKind("Configuration"))}
+}
+
+// claimOwnership returns an error if the resource is owned by another Configuration, which prevents the outputs of
+// a Configuration from overwriting the ones of others. Resources without owner labels are created by older versions,
+// which are labeled as owned by the Configuration, so that they're protected and cleaned up like the ones created
+// since. The labels are saved along with the outputs by the caller.
+func claimOwnership(obj metav1.Object, configuration v1beta1.Configuration) error {
+	labels := obj.GetLabels()
+	ownedBy, ownedNamespace := labels[LabelKeyOwnedBy], labels[LabelKeyOwnedNamespace]
+	if ownedBy == "" && ownedNamespace == "" {
+		klog.InfoS("adopting the resource created by an older version", "Namespace", obj.GetNamespace(),
+			"Name", obj.GetName(), "Configuration", configuration.Name)
+		obj.SetLabels(mergeMaps(labels, ownerLabels(configuration)))
+		return nil
+	}
+	if ownedBy != configuration.Name || ownedNamespace != configuration.Namespace {
+		return fmt.Errorf("%s/%s is owned by Configuration %s/%s, not %s/%s", obj.GetNamespace(), obj.GetName(),
+			ownedNamespace, ownedBy, configuration.Namespace, configuration.Name)
 	}
-	return outputs, nil
+	return nil
 }
 
 func (meta *TFConfigurationMeta) prepareTFVariables(ctx context.Context, k8sClient client.Client, configuration *v1beta1.Configuration) ([]v1.EnvVar, error) {
@@ -654,13 +3430,55 @@ func (meta *TFConfigurationMeta) prepareTFVariables(ctx context.Context, k8sClie
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("failed to get Terraform JSON variables from Configuration Variables %v", configuration.Spec.Variable))
 	}
+	variablesFrom, err := resolveVariablesFrom(ctx, k8sClient, configuration)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range variablesFrom {
+		tfVariable[k] = v
+	}
 	for k, v := range tfVariable {
 		envs = append(envs, v1.EnvVar{Name: k, Value: v})
 	}
 
-	credential, err := util.GetProviderCredentials(ctx, k8sClient, meta.ProviderReference.Namespace, meta.ProviderReference.Name)
+	credential, region, err := util.GetProviderCredentialsInRegion(ctx, k8sClient, meta.ProviderReference.Namespace,
+		meta.ProviderReference.Name, meta.RegionOverride)
+	meta.Region = region
+	if err == nil {
+		var aliased map[string]string
+		if aliased, err = aliasedProviderVariables(ctx, k8sClient, meta.AliasedProviders); err == nil {
+			for k, v := range aliased {
+				envs = append(envs, v1.EnvVar{Name: k, Value: v})
+			}
+		}
+	}
+	// an invalid region is a mistake of the Configuration rather than the Provider
+	if errors.Is(err, util.ErrInvalidRegion) {
+		if updateStatusErr := updateStatus(ctx, k8sClient, *configuration, types.InvalidRegion, err.Error()); updateStatusErr != nil {
+			return nil, errors.Wrap(updateStatusErr, errSettingStatus)
+		}
+		return nil, err
+	}
+	if util.IsCredentialsNotFound(err) && meta.waitsForCredentials(*configuration) {
+		klog.InfoS("the credentials of the Provider are not found yet, retrying", "Namespace", configuration.Namespace,
+			"Name", configuration.Name, "Error", err.Error())
+		if configuration.Status.CredentialsPendingSince == nil {
+			now := metav1.Now()
+			configuration.Status.CredentialsPendingSince = &now
+			if updateStatusErr := k8sClient.Status().Update(ctx, configuration); updateStatusErr != nil {
+				return nil, errors.Wrap(updateStatusErr, errSettingStatus)
+			}
+		}
+		return nil, errors.Wrap(errCredentialsPending, err.Error())
+	}
+	if !util.IsCredentialsNotFound(err) && configuration.Status.CredentialsPendingSince != nil {
+		configuration.Status.CredentialsPendingSince = nil
+		if updateStatusErr := k8sClient.Status().Update(ctx, configuration); updateStatusErr != nil {
+			return nil, errors.Wrap(updateStatusErr, errSettingStatus)
+		}
+	}
 	if err != nil {
-		if updateStatusErr := updateStatus(ctx, k8sClient, *configuration, types.ProviderNotReady, ErrProviderNotReady); updateStatusErr != nil {
+		if updateStatusErr := updateStatus(ctx, k8sClient, *configuration, types.ProviderNotReady, fmt.Sprintf("%s: %s", ErrProviderNotReady, err.Error())); updateStatusErr != nil {
 			return nil, errors.Wrap(updateStatusErr, errSettingStatus)
 		}
 		return nil, errors.Wrap(err, ErrProviderNotReady)
@@ -677,14 +3495,111 @@ func (meta *TFConfigurationMeta) prepareTFVariables(ctx context.Context, k8sClie
 				Value: v,
 			})
 	}
-	return envs, nil
+
+	b, err := getBackend(ctx, k8sClient, *configuration)
+	if err != nil {
+		return nil, err
+	}
+	backendEnvs, err := b.Envs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range backendEnvs {
+		envs = append(envs, v1.EnvVar{Name: k, Value: v})
+	}
+	envs = append(envs, proxyEnvs()...)
+
+	// spec.env can't override the environment variables set by the controller, like the ones of the backend
+	set := make(map[string]bool, len(envs))
+	for _, env := range envs {
+		set[env.Name] = true
+	}
+	for _, env := range meta.Env {
+		if set[env.Name] {
+			err := errors.Errorf("spec.env %s collides with the environment variable set by the controller", env.Name)
+			if updateStatusErr := updateStatus(ctx, k8sClient, *configuration, types.ConfigurationStaticChecking, err.Error()); updateStatusErr != nil {
+				return nil, errors.Wrap(updateStatusErr, errSettingStatus)
+			}
+			return nil, err
+		}
+	}
+	return append(envs, meta.Env...), nil
+}
+
+// specEnv returns the environment variables of spec.env
+func specEnv(configuration v1beta1.Configuration) []v1.EnvVar {
+	var envs []v1.EnvVar
+	for _, env := range configuration.Spec.Env {
+		envs = append(envs, v1.EnvVar{Name: env.Name, Value: env.Value})
+	}
+	return envs
+}
+
+// aliasedProviderVariables returns the Terraform variables of the credentials of the aliased Providers, which are
+// named after the aliases and the environment variables of the credentials
+func aliasedProviderVariables(ctx context.Context, k8sClient client.Client, refs []v1beta1.AliasedProviderReference) (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, ref := range refs {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = util.ProviderDefaultNamespace
+		}
+		credentials, _, err := util.GetProviderCredentialsInRegion(ctx, k8sClient, namespace, ref.Name, ref.Region)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the credentials of the aliased provider %s", ref.Alias)
+		}
+		for k, v := range credentials {
+			variables["TF_VAR_"+aliasedProviderVariable(ref.Alias, k)] = v
+		}
+	}
+	return variables, nil
+}
+
+// aliasedProviderVariable is the Terraform variable of an environment variable of the credentials of an aliased Provider
+func aliasedProviderVariable(alias, env string) string {
+	return fmt.Sprintf("%s_%s", alias, strings.ToLower(env))
+}
+
+// proxyEnvs propagates the proxy settings of the controller to the containers of Jobs, which need them to download
+// providers and modules, or clone git repos. NO_PROXY always includes the API server, which the kubernetes backend of
+// Terraform talks to.
+func proxyEnvs() []v1.EnvVar {
+	httpProxy, httpsProxy := os.Getenv("HTTP_PROXY"), os.Getenv("HTTPS_PROXY")
+	if httpProxy == "" && httpsProxy == "" {
+		return nil
+	}
+	noProxy := []string{os.Getenv("NO_PROXY")}
+	if host := os.Getenv("KUBERNETES_SERVICE_HOST"); host != "" {
+		noProxy = append(noProxy, host)
+	}
+	noProxy = append(noProxy, "kubernetes.default.svc", "kubernetes.default.svc.cluster.local")
+
+	var envs []v1.EnvVar
+	for _, env := range []v1.EnvVar{
+		{Name: "HTTP_PROXY", Value: httpProxy},
+		{Name: "HTTPS_PROXY", Value: httpsProxy},
+		{Name: "NO_PROXY", Value: strings.Trim(strings.Join(noProxy, ","), ",")},
+	} {
+		if env.Value == "" {
+			continue
+		}
+		// Some tools, like curl used by git, only read the lower case ones
+		envs = append(envs, env, v1.EnvVar{Name: strings.ToLower(env.Name), Value: env.Value})
+	}
+	return envs
 }
 
 // SetupWithManager setups with a manager
 func (r *ConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1beta1.Configuration{}).
-		Complete(r)
+	blder := ctrl.NewControllerManagedBy(mgr).
+		For(&v1beta1.Configuration{})
+	if err := r.setupProviderWatches(mgr, blder); err != nil {
+		return err
+	}
+	if err := r.setupDependencyWatches(mgr, blder); err != nil {
+		return err
+	}
+	return blder.Complete(r)
 }
 
 func getTerraformJSONVariable(tfVariables *runtime.RawExtension) (map[string]string, error) {
@@ -695,17 +3610,39 @@ func getTerraformJSONVariable(tfVariables *runtime.RawExtension) (map[string]str
 	var environments = make(map[string]string)
 
 	for k, v := range variables {
-		environments[fmt.Sprintf("TF_VAR_%s", k)] = fmt.Sprint(v)
+		// a null variable takes the default of the variable, as an empty string isn't a value of all the types
+		if v == nil {
+			continue
+		}
+		// the lists, maps and objects are encoded in JSON, which Terraform parses as HCL expressions of the
+		// declared types of the variables
+		value, err := util.Interface2String(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode variable %s", k)
+		}
+		environments[fmt.Sprintf("TF_VAR_%s", k)] = value
 	}
 	return environments, nil
 }
 
-func deleteConfigMap(ctx context.Context, k8sClient client.Client, name string) error {
+func deleteOutputsConfigMap(ctx context.Context, k8sClient client.Client, name, ns string) error {
+	if len(name) == 0 {
+		return nil
+	}
+	if len(ns) == 0 {
+		ns = "default"
+	}
 	var cm v1.ConfigMap
-	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: controllerNamespace}, &cm); err == nil {
-		if err := k8sClient.Delete(ctx, &cm); err != nil {
-			return err
-		}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: ns}, &cm); err == nil {
+		return k8sClient.Delete(ctx, &cm)
+	}
+	return nil
+}
+
+func deleteJob(ctx context.Context, k8sClient client.Client, name, ns string) error {
+	var job batchv1.Job
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: ns}, &job); err == nil {
+		return k8sClient.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground))
 	}
 	return nil
 }
@@ -725,17 +3662,21 @@ func deleteConnectionSecret(ctx context.Context, k8sClient client.Client, name,
 	return nil
 }
 
-func (meta *TFConfigurationMeta) createOrUpdateConfigMap(ctx context.Context, k8sClient client.Client, data map[string]string) error {
+func (meta *TFConfigurationMeta) createOrUpdateConfigMap(ctx context.Context, k8sClient client.Client, data map[string]string, binaryData map[string][]byte) error {
 	var gotCM v1.ConfigMap
-	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.ConfigurationCMName, Namespace: controllerNamespace}, &gotCM); err != nil {
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.ConfigurationCMName, Namespace: meta.Namespace}, &gotCM); err != nil {
 		if kerrors.IsNotFound(err) {
 			cm := v1.ConfigMap{
 				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      meta.ConfigurationCMName,
-					Namespace: controllerNamespace,
+					Name:            meta.ConfigurationCMName,
+					Namespace:       meta.Namespace,
+					OwnerReferences: meta.OwnerReferences,
+					Labels:          meta.Labels,
+					Annotations:     meta.inputConfigMapAnnotations(),
 				},
-				Data: data,
+				Data:       data,
+				BinaryData: binaryData,
 			}
 			err := k8sClient.Create(ctx, &cm)
 			return errors.Wrap(err, "failed to create TF configuration ConfigMap")
@@ -743,26 +3684,93 @@ func (meta *TFConfigurationMeta) createOrUpdateConfigMap(ctx context.Context, k8
 		return err
 	}
 	gotCM.Data = data
+	gotCM.BinaryData = binaryData
+	if len(gotCM.OwnerReferences) == 0 {
+		gotCM.OwnerReferences = meta.OwnerReferences
+	}
+	gotCM.Labels = mergeMaps(gotCM.Labels, meta.Labels)
+	gotCM.Annotations = mergeMaps(gotCM.Annotations, meta.inputConfigMapAnnotations())
+	if meta.VarFilesHash == "" {
+		// the var files are removed
+		delete(gotCM.Annotations, cfgvalidator.VarFilesHashAnnotation)
+	}
 	err := k8sClient.Update(ctx, &gotCM)
 	return errors.Wrap(err, "failed to update TF configuration ConfigMap")
 }
 
-func (meta *TFConfigurationMeta) prepareTFInputConfigurationData() map[string]string {
-	var dataName string
+func (meta *TFConfigurationMeta) inputConfigMapAnnotations() map[string]string {
+	annotations := make(map[string]string)
+	if meta.OCI != nil {
+		annotations[cfgvalidator.OCIArtifactAnnotation] = meta.OCI.Artifact
+		annotations[cfgvalidator.OCIPathAnnotation] = meta.OCI.Path
+	}
+	if meta.RemoteGit != "" {
+		annotations[cfgvalidator.RemoteGitPathAnnotation] = meta.RemoteGitPath
+		if meta.RemoteGitCommit != "" {
+			annotations[cfgvalidator.RemoteGitCommitAnnotation] = meta.RemoteGitCommit
+		}
+	}
+	if meta.VarFilesHash != "" {
+		annotations[cfgvalidator.VarFilesHashAnnotation] = meta.VarFilesHash
+	}
+	return mergeMaps(meta.Annotations, annotations)
+}
+
+func (meta *TFConfigurationMeta) inputConfigurationDataName() string {
 	switch meta.ConfigurationType {
 	case types.ConfigurationJSON:
-		dataName = types.TerraformJSONConfigurationName
+		return types.TerraformJSONConfigurationName
 	case types.ConfigurationHCL:
-		dataName = types.TerraformHCLConfigurationName
-	case types.ConfigurationRemote:
-		dataName = "terraform-backend.tf"
+		return types.TerraformHCLConfigurationName
+	case types.ConfigurationRemote, types.ConfigurationOCI:
+		return "terraform-backend.tf"
+	}
+	return ""
+}
+
+// prepareTFInputConfigurationData returns the data of the input ConfigMap, which is the configuration file only. The
+// kubernetes backend uses the in-cluster config of the Jobs rather than a kubeconfig.
+func (meta *TFConfigurationMeta) prepareTFInputConfigurationData() map[string]string {
+	return map[string]string{meta.inputConfigurationDataName(): meta.CompleteConfiguration}
+}
+
+// compressTFInputConfigurationData moves the configuration file into gzip compressed BinaryData when the data
+// exceeds the size limit of a ConfigMap. The init container of the Terraform Job decompresses it.
+func (meta *TFConfigurationMeta) compressTFInputConfigurationData(data map[string]string) (map[string]string, map[string][]byte, error) {
+	var size int
+	for k, v := range data {
+		size += len(k) + len(v)
+	}
+	if size <= maxConfigMapDataSize {
+		return data, nil, nil
+	}
+
+	dataName := meta.inputConfigurationDataName()
+	compressed, err := util.CompressData(data[dataName])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to compress Terraform configuration")
+	}
+	size = size - len(data[dataName]) + len(compressed)
+	if size > maxConfigMapDataSize {
+		return nil, nil, errors.Wrapf(errConfigurationTooLarge, "the configuration is %d bytes after compression", size)
+	}
+	klog.InfoS("Terraform configuration is too large for a ConfigMap, compressed it", "Name", meta.ConfigurationCMName,
+		"Size", len(data[dataName]), "CompressedSize", len(compressed))
+
+	compressedData := make(map[string]string, len(data))
+	for k, v := range data {
+		if k != dataName {
+			compressedData[k] = v
+		}
 	}
-	data := map[string]string{dataName: meta.CompleteConfiguration, "kubeconfig": ""}
-	return data
+	return compressedData, map[string][]byte{dataName + cfgvalidator.CompressedFileSuffix: compressed}, nil
 }
 
 // storeTFConfiguration will store Terraform configuration to ConfigMap
 func (meta *TFConfigurationMeta) storeTFConfiguration(ctx context.Context, k8sClient client.Client) error {
-	data := meta.prepareTFInputConfigurationData()
-	return meta.createOrUpdateConfigMap(ctx, k8sClient, data)
+	data, binaryData, err := meta.compressTFInputConfigurationData(meta.prepareTFInputConfigurationData())
+	if err != nil {
+		return err
+	}
+	return meta.createOrUpdateConfigMap(ctx, k8sClient, data, binaryData)
 }
@@ -0,0 +1,2518 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kfake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	cfgvalidator "github.com/oam-dev/terraform-controller/controllers/configuration"
+	"github.com/oam-dev/terraform-controller/controllers/runner"
+	"github.com/oam-dev/terraform-controller/controllers/terraform"
+)
+
+func TestAssembleTerraformJobExtraVolumes(t *testing.T) {
+	meta := &TFConfigurationMeta{
+		Name:                "oss",
+		ConfigurationCMName: "oss-tf-input",
+		ExecutorVolumes: []v1beta1.ExecutorVolume{{
+			Name:      "ca-bundle",
+			MountPath: "/etc/ssl/custom",
+			Secret:    &v1.SecretVolumeSource{SecretName: "ca-bundle"},
+		}},
+	}
+	job := meta.assembleTerraformJob(TerraformApply)
+	podSpec := job.Spec.Template.Spec
+
+	var volume *v1.Volume
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == "ca-bundle" {
+			volume = &podSpec.Volumes[i]
+		}
+	}
+	if volume == nil || volume.Secret == nil || volume.Secret.SecretName != "oss-tf-volume-ca-bundle" {
+		t.Fatalf("expected the copy of the Secret ca-bundle mounted, got %v", podSpec.Volumes)
+	}
+
+	var mount *v1.VolumeMount
+	for i, m := range podSpec.Containers[0].VolumeMounts {
+		if m.Name == "ca-bundle" {
+			mount = &podSpec.Containers[0].VolumeMounts[i]
+		}
+	}
+	if mount == nil || mount.MountPath != "/etc/ssl/custom" || !mount.ReadOnly {
+		t.Fatalf("expected ca-bundle to be mounted read-only at /etc/ssl/custom, got %v", podSpec.Containers[0].VolumeMounts)
+	}
+}
+
+func TestAssembleTerraformJobWorkingVolume(t *testing.T) {
+	defaultLimit, limit := resource.MustParse("1Gi"), resource.MustParse("4Gi")
+	meta := &TFConfigurationMeta{
+		Name:                "oss",
+		ConfigurationCMName: "oss-tf-input",
+		WorkingVolume: workingVolume(v1beta1.WorkingVolume{Medium: v1.StorageMediumMemory, SizeLimit: &defaultLimit},
+			&v1beta1.WorkingVolume{SizeLimit: &limit}),
+	}
+	job := meta.assembleTerraformJob(TerraformApply)
+	for _, v := range job.Spec.Template.Spec.Volumes {
+		if v.Name != meta.Name && v.Name != BackendVolumeName {
+			continue
+		}
+		if v.EmptyDir == nil || v.EmptyDir.Medium != v1.StorageMediumMemory || v.EmptyDir.SizeLimit == nil ||
+			v.EmptyDir.SizeLimit.Cmp(limit) != 0 {
+			t.Errorf("expected the emptyDir volume %s backed by memory and limited to %s, got %v", v.Name, limit.String(), v.EmptyDir)
+		}
+	}
+}
+
+func TestAssembleTerraformJobEviction(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "oss", ConfigurationCMName: "oss-tf-input", PodAnnotations: defaultPodAnnotations}
+	job := meta.assembleTerraformJob(TerraformApply)
+	podSpec := job.Spec.Template.Spec
+	if podSpec.TerminationGracePeriodSeconds == nil || *podSpec.TerminationGracePeriodSeconds != executorTerminationGracePeriodSeconds {
+		t.Errorf("expected terminationGracePeriodSeconds %d, got %v", executorTerminationGracePeriodSeconds, podSpec.TerminationGracePeriodSeconds)
+	}
+	if job.Spec.Template.Annotations["cluster-autoscaler.kubernetes.io/safe-to-evict"] != "false" {
+		t.Errorf("expected the pod not safe to evict, got annotations %v", job.Spec.Template.Annotations)
+	}
+	// the runner is PID 1 of the executor, which forwards SIGTERM to Terraform
+	if command := podSpec.Containers[0].Command; command[0] != runnerBinary {
+		t.Errorf("expected the steps run by the runner, got %v", command)
+	}
+
+	job = meta.assembleTerraformJob(TerraformValidate)
+	if _, ok := job.Spec.Template.Annotations["cluster-autoscaler.kubernetes.io/safe-to-evict"]; ok {
+		t.Errorf("expected the validate pod safe to evict, got annotations %v", job.Spec.Template.Annotations)
+	}
+}
+
+func TestAssembleTerraformJobTTL(t *testing.T) {
+	ttl := int32(600)
+	meta := &TFConfigurationMeta{Name: "oss", ConfigurationCMName: "oss-tf-input", JobTTLSecondsAfterFinished: &ttl}
+	for _, executionType := range []TerraformExecutionType{TerraformApply, TerraformDestroy} {
+		job := meta.assembleTerraformJob(executionType)
+		if job.Spec.TTLSecondsAfterFinished == nil || *job.Spec.TTLSecondsAfterFinished != ttl {
+			t.Errorf("expected ttlSecondsAfterFinished %d of the %s Job, got %v", ttl, executionType, job.Spec.TTLSecondsAfterFinished)
+		}
+	}
+	if job := meta.assembleTerraformJob(TerraformValidate); job.Spec.TTLSecondsAfterFinished != nil {
+		t.Errorf("expected no ttlSecondsAfterFinished of the validate Job, got %d", *job.Spec.TTLSecondsAfterFinished)
+	}
+}
+
+func TestAppliedInputsHash(t *testing.T) {
+	meta := &TFConfigurationMeta{CompleteConfiguration: `resource "null_resource" "a" {}`}
+	envs := []v1.EnvVar{{Name: "TF_VAR_a", Value: "1"}, {Name: "TF_VAR_b", Value: "2"}}
+	hash := meta.appliedInputsHash(envs)
+	if got := meta.appliedInputsHash([]v1.EnvVar{envs[1], envs[0]}); got != hash {
+		t.Error("expected the hash regardless of the order of the envs")
+	}
+	if got := meta.appliedInputsHash([]v1.EnvVar{envs[0], {Name: "TF_VAR_b", Value: "3"}}); got == hash {
+		t.Error("expected a different hash when a variable changes")
+	}
+	meta.CompleteConfiguration = `resource "null_resource" "b" {}`
+	if got := meta.appliedInputsHash(envs); got == hash {
+		t.Error("expected a different hash when the configuration changes")
+	}
+}
+
+func TestInputsHashes(t *testing.T) {
+	meta := &TFConfigurationMeta{CompleteConfiguration: `resource "null_resource" "a" {}`}
+	envs := []v1.EnvVar{{Name: "TF_VAR_a", Value: "1"}, {Name: "ALICLOUD_ACCESS_KEY", Value: "ak"}}
+	hashes := meta.inputsHashes(envs)
+	if hashes.InputsHash != meta.appliedInputsHash(envs) {
+		t.Errorf("expected the inputs hash %s, got %s", meta.appliedInputsHash(envs), hashes.InputsHash)
+	}
+
+	rotated := meta.inputsHashes([]v1.EnvVar{envs[0], {Name: "ALICLOUD_ACCESS_KEY", Value: "ak2"}})
+	if rotated.CredentialsHash == hashes.CredentialsHash || rotated.InputsHash == hashes.InputsHash {
+		t.Error("expected different hashes when the credentials change")
+	}
+	if rotated.VariablesHash != hashes.VariablesHash || rotated.ConfigurationHash != hashes.ConfigurationHash {
+		t.Errorf("expected the same hashes of the variables and the configuration, got %v and %v", hashes, rotated)
+	}
+}
+
+// stepsJSON renders the steps of the executor for the messages of the failed tests
+func stepsJSON(steps []runner.Step) string {
+	data, _ := json.Marshal(steps)
+	return string(data)
+}
+
+func TestExecutorCommandWithOutputsFromJob(t *testing.T) {
+	meta := &TFConfigurationMeta{Engine: types.TerraformEngine, OutputsFromJob: true}
+	output := runner.Step{Run: &runner.Run{Args: []string{"terraform", "output", "-json"}, Stdout: "/dev/termination-log"}}
+	steps := meta.executorSteps(TerraformApply)
+	if !reflect.DeepEqual(steps[len(steps)-1], output) {
+		t.Errorf("expected the apply Job to write the outputs to the termination message, got %s", stepsJSON(steps))
+	}
+	if steps = meta.executorSteps(TerraformDestroy); strings.Contains(stepsJSON(steps), "output") {
+		t.Errorf("expected the destroy Job not to write the outputs, got %s", stepsJSON(steps))
+	}
+	expected := []runner.Step{
+		runStep("terraform", "init"),
+		runStep("terraform", "apply", "-refresh-only", "-lock-timeout=5m", "-auto-approve"),
+		output,
+	}
+	if steps = meta.executorSteps(TerraformRefresh); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected the refresh Job to write the refreshed outputs, got %s", stepsJSON(steps))
+	}
+}
+
+func TestOutputsFromJob(t *testing.T) {
+	testcases := map[string]struct {
+		spec     v1beta1.ConfigurationSpec
+		expected bool
+	}{
+		"default":          {expected: false},
+		"state":            {spec: v1beta1.ConfigurationSpec{OutputsFrom: types.OutputsFromState}, expected: false},
+		"terraform output": {spec: v1beta1.ConfigurationSpec{OutputsFrom: types.OutputsFromTerraformOutput}, expected: true},
+		"custom backend": {
+			spec:     v1beta1.ConfigurationSpec{Backend: &v1beta1.Backend{BackendType: "custom", Custom: `backend "s3" {}`}},
+			expected: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			if got := outputsFromJob(v1beta1.Configuration{Spec: tc.spec}); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateExecutorVolumes(t *testing.T) {
+	secret := &v1.SecretVolumeSource{SecretName: "creds"}
+	testcases := map[string]struct {
+		volumes []v1beta1.ExecutorVolume
+		errMsg  string
+	}{
+		"valid": {
+			volumes: []v1beta1.ExecutorVolume{
+				{Name: "creds", MountPath: "/etc/creds", Secret: secret},
+				{Name: "license", MountPath: "/data/license", SubPath: "license", ConfigMap: &v1.ConfigMapVolumeSource{}},
+			},
+		},
+		"reserved volume name": {
+			volumes: []v1beta1.ExecutorVolume{{Name: InputTFConfigurationVolumeName, MountPath: "/etc/creds", Secret: secret}},
+			errMsg:  "spec.volumes[0].name: Duplicate value",
+		},
+		"working volume name": {
+			volumes: []v1beta1.ExecutorVolume{{Name: "oss", MountPath: "/etc/creds", Secret: secret}},
+			errMsg:  "spec.volumes[0].name: Duplicate value",
+		},
+		"reserved mount path": {
+			volumes: []v1beta1.ExecutorVolume{{Name: "creds", MountPath: "/data/", Secret: secret}},
+			errMsg:  "spec.volumes[0].mountPath: Duplicate value",
+		},
+		"relative mount path": {
+			volumes: []v1beta1.ExecutorVolume{{Name: "creds", MountPath: "creds", Secret: secret}},
+			errMsg:  "must be an absolute path",
+		},
+		"no source": {
+			volumes: []v1beta1.ExecutorVolume{{Name: "creds", MountPath: "/etc/creds"}},
+			errMsg:  "exactly one of secret and configMap must be set",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			configuration := &v1beta1.Configuration{
+				ObjectMeta: metav1.ObjectMeta{Name: "oss"},
+				Spec:       v1beta1.ConfigurationSpec{Volumes: tc.volumes},
+			}
+			err := ValidateExecutorVolumes(configuration).ToAggregate()
+			if tc.errMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateEnv(t *testing.T) {
+	testcases := map[string]struct {
+		env      []v1beta1.EnvVar
+		logLevel string
+		errMsg   string
+	}{
+		"valid":            {env: []v1beta1.EnvVar{{Name: "AWS_PROFILE", Value: "prod"}, {Name: "TF_LOG", Value: "DEBUG"}}},
+		"invalid name":     {env: []v1beta1.EnvVar{{Name: "1PROFILE"}}, errMsg: "spec.env[0].name: Invalid value"},
+		"duplicate":        {env: []v1beta1.EnvVar{{Name: "TF_LOG"}, {Name: "TF_LOG"}}, errMsg: "spec.env[1].name: Duplicate value"},
+		"terraform var":    {env: []v1beta1.EnvVar{{Name: "TF_VAR_name"}}, errMsg: "must not start with TF_VAR_"},
+		"credential":       {env: []v1beta1.EnvVar{{Name: "AWS_SECRET_ACCESS_KEY"}}, errMsg: "AWS_SECRET_ACCESS_KEY is set by the controller"},
+		"controller proxy": {env: []v1beta1.EnvVar{{Name: "https_proxy"}}, errMsg: "https_proxy is set by the controller"},
+		"log level":        {env: []v1beta1.EnvVar{{Name: "TF_LOG"}}, logLevel: "DEBUG", errMsg: "TF_LOG is set by spec.logLevel"},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			configuration := &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{Env: tc.env, LogLevel: tc.logLevel}}
+			err := ValidateEnv(configuration).ToAggregate()
+			if tc.errMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestProxyEnvs(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.96.0.1")
+	if envs := proxyEnvs(); envs != nil {
+		t.Fatalf("expected no envs without a proxy, got %v", envs)
+	}
+
+	t.Setenv("HTTPS_PROXY", "http://proxy:3128")
+	t.Setenv("NO_PROXY", "10.0.0.0/8")
+	expected := []v1.EnvVar{
+		{Name: "HTTPS_PROXY", Value: "http://proxy:3128"},
+		{Name: "https_proxy", Value: "http://proxy:3128"},
+		{Name: "NO_PROXY", Value: "10.0.0.0/8,10.96.0.1,kubernetes.default.svc,kubernetes.default.svc.cluster.local"},
+		{Name: "no_proxy", Value: "10.0.0.0/8,10.96.0.1,kubernetes.default.svc,kubernetes.default.svc.cluster.local"},
+	}
+	if envs := proxyEnvs(); !reflect.DeepEqual(envs, expected) {
+		t.Errorf("expected envs %v, got %v", expected, envs)
+	}
+}
+
+func TestReconcilePause(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{
+		Name:        "oss",
+		Namespace:   "default",
+		Annotations: map[string]string{PauseAnnotation: "true"},
+	}}
+	recorder := record.NewFakeRecorder(10)
+	r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, configuration), Recorder: recorder}
+
+	paused, err := r.reconcilePause(ctx, configuration)
+	if err != nil || !paused {
+		t.Fatalf("expected paused without error, got %v, %v", paused, err)
+	}
+	if c := configuration.Status.GetCondition(v1beta1.ConditionPaused); c == nil || c.Status != v1.ConditionTrue {
+		t.Fatalf("expected the Paused condition to be true, got %v", c)
+	}
+	// Staying paused doesn't emit more events
+	if paused, err = r.reconcilePause(ctx, configuration); err != nil || !paused {
+		t.Fatalf("expected paused without error, got %v, %v", paused, err)
+	}
+
+	delete(configuration.Annotations, PauseAnnotation)
+	if paused, err = r.reconcilePause(ctx, configuration); err != nil || paused {
+		t.Fatalf("expected resumed without error, got %v, %v", paused, err)
+	}
+	if c := configuration.Status.GetCondition(v1beta1.ConditionPaused); c == nil || c.Status != v1.ConditionFalse || c.Reason != ReasonResumed {
+		t.Fatalf("expected the Paused condition to be false, got %v", c)
+	}
+
+	close(recorder.Events)
+	var events []string
+	for e := range recorder.Events {
+		events = append(events, e)
+	}
+	expected := []string{"Normal Paused " + MessagePaused, "Normal Resumed " + MessageResumed}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+}
+
+func TestRecordReconcileTimeout(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	key := client.ObjectKey{Name: "oss", Namespace: "default"}
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	recorder := record.NewFakeRecorder(10)
+	r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, configuration), Recorder: recorder,
+		ReconcileTimeout: time.Minute}
+
+	// nothing is recorded while the reconciliations finish in time
+	if err := r.recordReconcileTimeout(ctx, key, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, key, &got); err != nil {
+		t.Fatal(err)
+	}
+	if c := got.Status.GetCondition(v1beta1.ConditionReconcileTimedOut); c != nil {
+		t.Fatalf("expected no ReconcileTimedOut condition, got %v", c)
+	}
+
+	if err := r.recordReconcileTimeout(ctx, key, true, context.DeadlineExceeded); err != nil {
+		t.Fatal(err)
+	}
+	var timedOut v1beta1.Configuration
+	if err := r.Get(ctx, key, &timedOut); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Reconciliation didn't finish in 1m0s, and is retried: context deadline exceeded"
+	if c := timedOut.Status.GetCondition(v1beta1.ConditionReconcileTimedOut); c == nil || c.Status != v1.ConditionTrue || c.Message != expected {
+		t.Fatalf("expected the ReconcileTimedOut condition to be true, got %v", c)
+	}
+	if event := <-recorder.Events; event != "Warning ReconcileTimedOut "+expected {
+		t.Errorf("expected the timeout warned, got %s", event)
+	}
+
+	if err := r.recordReconcileTimeout(ctx, key, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	var finished v1beta1.Configuration
+	if err := r.Get(ctx, key, &finished); err != nil {
+		t.Fatal(err)
+	}
+	if c := finished.Status.GetCondition(v1beta1.ConditionReconcileTimedOut); c == nil || c.Status != v1.ConditionFalse || c.Reason != ReasonReconcileFinished {
+		t.Fatalf("expected the ReconcileTimedOut condition to be false, got %v", c)
+	}
+}
+
+func TestRecordApplyProgress(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	meta := &TFConfigurationMeta{Namespace: controllerNamespace, ApplyJobName: "oss-apply"}
+	key := client.ObjectKey{Name: "oss", Namespace: "default"}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Status: v1beta1.ConfigurationStatus{Progress: &v1beta1.ApplyProgress{
+			Completed: 1, Total: 3, UpdateTime: metav1.NewTime(time.Now().Add(-5 * time.Second)),
+		}},
+	}
+	applyJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", Namespace: controllerNamespace},
+		Status:     batchv1.JobStatus{Active: 1},
+	}
+	r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, configuration, applyJob), ApplyProgressInterval: time.Minute}
+
+	// the logs aren't read again within the interval
+	requeueAfter, err := r.recordApplyProgress(ctx, key, meta)
+	if err != nil || requeueAfter <= 50*time.Second || requeueAfter > 55*time.Second {
+		t.Fatalf("expected to read the progress again in 55s, got %v, %v", requeueAfter, err)
+	}
+
+	// the progress is cleared once the Job finishes
+	applyJob.Status = batchv1.JobStatus{Succeeded: 1}
+	if err := r.Status().Update(ctx, applyJob); err != nil {
+		t.Fatal(err)
+	}
+	if requeueAfter, err = r.recordApplyProgress(ctx, key, meta); err != nil || requeueAfter != 0 {
+		t.Fatalf("expected not to read the progress again, got %v, %v", requeueAfter, err)
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, key, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Progress != nil {
+		t.Errorf("expected the progress to be cleared, got %v", got.Status.Progress)
+	}
+}
+
+func TestResolveExecutionNamespace(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{
+		Name:        "oss",
+		Namespace:   "team-a",
+		Annotations: map[string]string{ExecutionNamespaceAnnotation: "tf-team-a"},
+	}}
+	r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, configuration)}
+
+	if _, err := r.resolveExecutionNamespace(ctx, configuration); err == nil {
+		t.Fatal("expected the namespace not allowed to be rejected")
+	}
+
+	r.ExecutionNamespaces = []string{"tf-team-a", "tf-team-b"}
+	if ns, err := r.resolveExecutionNamespace(ctx, configuration); err != nil || ns != "tf-team-a" {
+		t.Fatalf("expected tf-team-a, got %s, %v", ns, err)
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "team-a"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.ExecutionNamespace != "tf-team-a" || executionNamespace(got) != "tf-team-a" {
+		t.Fatalf("expected the execution namespace to be recorded, got %q", got.Status.ExecutionNamespace)
+	}
+
+	// the recorded namespace is kept, so that the sub-resources aren't left behind
+	got.Annotations[ExecutionNamespaceAnnotation] = "tf-team-b"
+	if ns, err := r.resolveExecutionNamespace(ctx, &got); err == nil || ns != "tf-team-a" {
+		t.Fatalf("expected the change to be rejected, got %s, %v", ns, err)
+	}
+	now := metav1.Now()
+	got.DeletionTimestamp = &now
+	if ns, err := r.resolveExecutionNamespace(ctx, &got); err != nil || ns != "tf-team-a" {
+		t.Fatalf("expected the recorded namespace to be cleaned up, got %s, %v", ns, err)
+	}
+}
+
+func TestAdoptSubResources(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"}}
+	meta := &TFConfigurationMeta{
+		Name:                "oss",
+		Namespace:           "vela-system",
+		ConfigurationCMName: "tf-oss",
+		ApplyJobName:        "oss-apply",
+		Labels:              ownerLabels(configuration),
+	}
+	legacyCM := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tf-oss", Namespace: "vela-system"}}
+	legacyJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", Namespace: "vela-system",
+		Labels: map[string]string{"app": "oss"}}}
+	othersCM := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "oss-tf-rendered", Namespace: "vela-system",
+		Labels: map[string]string{LabelKeyOwnedBy: "oss", LabelKeyOwnedNamespace: "team-a"}}}
+	othersJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "oss-destroy", Namespace: "vela-system",
+		Labels: map[string]string{LabelKeyOwnedBy: "oss", LabelKeyOwnedNamespace: "team-a"}}}
+	meta.DestroyJobName = "oss-destroy"
+	// the controller is granted to create, get and delete Jobs, but not to update them
+	k8sClient := &jobUpdateForbiddenClient{Client: fake.NewFakeClientWithScheme(s, legacyCM, legacyJob, othersCM, othersJob)}
+
+	if err := meta.adoptSubResources(ctx, k8sClient); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var job batchv1.Job
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: legacyJob.Name, Namespace: legacyJob.Namespace}, &job); !kerrors.IsNotFound(err) {
+		t.Errorf("the unlabeled Job should be deleted by its name, got %v", err)
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: othersJob.Name, Namespace: othersJob.Namespace}, &job); err != nil {
+		t.Errorf("the Job of another Configuration should be kept, got %v", err)
+	}
+	if err := deleteOwnedSubResources(ctx, k8sClient, configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, obj := range []subResource{legacyCM, legacyJob} {
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj); !kerrors.IsNotFound(err) {
+			t.Errorf("%s should be deleted after being adopted, got %v", obj.GetName(), err)
+		}
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: othersCM.Name, Namespace: othersCM.Namespace}, othersCM); err != nil {
+		t.Errorf("the ConfigMap of another Configuration should be kept, got %v", err)
+	}
+}
+
+// jobUpdateForbiddenClient refuses to update Jobs, like the RBAC of the controller
+type jobUpdateForbiddenClient struct {
+	client.Client
+}
+
+func (c *jobUpdateForbiddenClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if job, ok := obj.(*batchv1.Job); ok {
+		return kerrors.NewForbidden(batchv1.Resource("jobs"), job.Name, errors.New("update is not granted"))
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestWriteConnectionSecretKeys(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{WriteConnectionSecretToReference: &v1beta1.ConnectionSecretReference{
+			SecretReference: crossplane.SecretReference{Name: "db-conn", Namespace: "default"},
+			Keys:            []string{"endpoint", "password", "port"},
+		}},
+	}
+	outputs := map[string]v1beta1.Property{
+		"endpoint": {Value: "db.example.com"},
+		"password": {Value: "s3cret", Sensitive: true},
+		"vpc_id":   {Value: "vpc-123"},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s)
+	if err := writeConnectionSecret(ctx, k8sClient, configuration, outputs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var secret v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "db-conn", Namespace: "default"}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]byte{"endpoint": []byte("db.example.com"), "password": []byte("s3cret")}
+	if !reflect.DeepEqual(secret.Data, expected) {
+		t.Errorf("expected only the outputs of the keys written, got %v", secret.Data)
+	}
+
+	// all the outputs are written without the keys
+	configuration.Spec.WriteConnectionSecretToReference.Keys = nil
+	if err := writeConnectionSecret(ctx, k8sClient, configuration, outputs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "db-conn", Namespace: "default"}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if len(secret.Data) != 3 {
+		t.Errorf("expected all the outputs written, got %v", secret.Data)
+	}
+}
+
+func TestWriteConnectionSecretOwnership(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{WriteConnectionSecretToReference: &v1beta1.ConnectionSecretReference{
+			SecretReference: crossplane.SecretReference{Name: "db-conn", Namespace: "default"},
+		}},
+	}
+	outputs := map[string]v1beta1.Property{"endpoint": {Value: "db.example.com"}}
+	// created by an older version without the owner labels
+	legacy := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-conn", Namespace: "default", Labels: map[string]string{"app": "db"}}}
+	foreign := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cache-conn", Namespace: "default",
+		Labels: map[string]string{LabelKeyOwnedBy: "cache", LabelKeyOwnedNamespace: "default"}}}
+	k8sClient := fake.NewFakeClientWithScheme(s, legacy, foreign)
+
+	if err := writeConnectionSecret(ctx, k8sClient, configuration, outputs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var adopted v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "db-conn", Namespace: "default"}, &adopted); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"app": "db", LabelKeyOwnedBy: "db", LabelKeyOwnedNamespace: "default"}
+	if !reflect.DeepEqual(adopted.Labels, expected) || string(adopted.Data["endpoint"]) != "db.example.com" {
+		t.Errorf("expected the legacy Secret adopted with the outputs, got %v, %v", adopted.Labels, adopted.Data)
+	}
+
+	configuration.Spec.WriteConnectionSecretToReference.Name = "cache-conn"
+	if err := writeConnectionSecret(ctx, k8sClient, configuration, outputs); err == nil ||
+		!strings.Contains(err.Error(), "owned by Configuration default/cache") {
+		t.Fatalf("expected the Secret of another Configuration refused, got %v", err)
+	}
+	var kept v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "cache-conn", Namespace: "default"}, &kept); err != nil {
+		t.Fatal(err)
+	}
+	if kept.Labels[LabelKeyOwnedBy] != "cache" || len(kept.Data) != 0 {
+		t.Errorf("expected the Secret of another Configuration kept, got %v, %v", kept.Labels, kept.Data)
+	}
+}
+
+func TestConnectionSecretDataKeyMapping(t *testing.T) {
+	outputs := map[string]v1beta1.Property{
+		"endpoint": {Value: "db.example.com"},
+		"password": {Value: "s3cret", Sensitive: true},
+	}
+	ref := &v1beta1.ConnectionSecretReference{
+		SecretReference: crossplane.SecretReference{Name: "db-conn"},
+		KeyMapping:      map[string]string{"endpoint": "DB_HOST"},
+	}
+	data, err := connectionSecretData(ref, outputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string][]byte{"DB_HOST": []byte("db.example.com"), "password": []byte("s3cret")}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected the endpoint renamed, got %v", data)
+	}
+
+	// a renamed output can't overwrite another output
+	ref.KeyMapping = map[string]string{"endpoint": "password"}
+	if _, err := connectionSecretData(ref, outputs); err == nil {
+		t.Error("expected an error about the colliding keys")
+	}
+}
+
+func TestOCIConfigurationArgs(t *testing.T) {
+	meta := &TFConfigurationMeta{
+		Name:                "oss",
+		ConfigurationCMName: "oss-tf-input",
+		OCI: &v1beta1.OCISource{
+			Artifact:       "registry.example.com/modules/oss:v1.0.0",
+			Path:           "modules/oss",
+			PullSecretName: "registry-creds",
+		},
+	}
+	job := meta.assembleTerraformJob(TerraformApply)
+	var container *v1.Container
+	for i, c := range job.Spec.Template.Spec.InitContainers {
+		if c.Name == "oci-configuration" {
+			container = &job.Spec.Template.Spec.InitContainers[i]
+		}
+	}
+	if container == nil {
+		t.Fatal("expected the init container pulling the artifact")
+	}
+	expected := []string{"sh", "-c", ociConfigurationScript, "oci-configuration", "registry.example.com/modules/oss:v1.0.0",
+		"/etc/oras/config.json", "modules/oss", BackendVolumeMountPath, WorkingVolumeMountPath}
+	if !reflect.DeepEqual(container.Command, expected) {
+		t.Errorf("expected the artifact pulled with the credentials, got %v", container.Command)
+	}
+	var mounted, hasVolume bool
+	for _, m := range container.VolumeMounts {
+		mounted = mounted || m.Name == OCIRegistryConfigVolumeName
+	}
+	for _, v := range job.Spec.Template.Spec.Volumes {
+		if v.Name == OCIRegistryConfigVolumeName {
+			hasVolume = v.Secret != nil && v.Secret.SecretName == "registry-creds"
+		}
+	}
+	if !mounted || !hasVolume {
+		t.Errorf("expected the pull Secret mounted, got %v and %v", container.VolumeMounts, job.Spec.Template.Spec.Volumes)
+	}
+	for _, c := range job.Spec.Template.Spec.InitContainers {
+		for _, m := range c.VolumeMounts {
+			if c.Name != container.Name && m.Name == OCIRegistryConfigVolumeName {
+				t.Errorf("expected the pull Secret only mounted to the container pulling the artifact, got %v", c.VolumeMounts)
+			}
+		}
+	}
+
+	// the artifact of a different path is another revision
+	revision := meta.sourceRevision()
+	meta.OCI.Path = "modules/oss-v2"
+	if meta.sourceRevision() == revision {
+		t.Error("expected the revision changed with the path")
+	}
+}
+
+func TestVerifyContainer(t *testing.T) {
+	meta := &TFConfigurationMeta{
+		Name:                "oss",
+		ConfigurationCMName: "oss-tf-input",
+		RemoteGit:           "https://github.com/org/repo.git",
+		Verify:              &v1beta1.SourceVerification{TrustedKeysSecretName: "trusted-keys"},
+	}
+	verifyContainer := func(job *batchv1.Job) *v1.Container {
+		containers := job.Spec.Template.Spec.InitContainers
+		// the source is verified after it's prepared, before the configuration is run
+		if c := containers[len(containers)-1]; c.Name == terraform.VerifyContainerName {
+			return &c
+		}
+		return nil
+	}
+
+	container := verifyContainer(meta.assembleTerraformJob(TerraformApply))
+	if container == nil {
+		t.Fatal("expected the init container verifying the commit last")
+	}
+	expected := []string{"sh", "-c", gitVerifyScript, terraform.VerifyContainerName, BackendVolumeMountPath,
+		"/etc/trusted-keys/allowed_signers"}
+	if !reflect.DeepEqual(container.Command, expected) {
+		t.Errorf("expected the commit verified by the allowed signers, got %v", container.Command)
+	}
+
+	meta.RemoteGit = ""
+	meta.OCI = &v1beta1.OCISource{Artifact: "registry.example.com/modules/oss@sha256:" + strings.Repeat("a", 64),
+		PullSecretName: "registry-creds"}
+	job := meta.assembleTerraformJob(TerraformApply)
+	// the artifact is verified before it's pulled, by the same digest
+	containers := job.Spec.Template.Spec.InitContainers
+	if len(containers) < 2 || containers[len(containers)-2].Name != terraform.VerifyContainerName ||
+		containers[len(containers)-1].Name != "oci-configuration" {
+		t.Fatalf("expected the init container verifying the artifact before the one pulling it, got %v", containers)
+	}
+	container = &containers[len(containers)-2]
+	expected = []string{"cosign", "verify", "--key", "/etc/trusted-keys/cosign.pub", "--", meta.OCI.Artifact}
+	if !reflect.DeepEqual(container.Command, expected) {
+		t.Errorf("expected the artifact verified by the public key, got %v", container.Command)
+	}
+	if pull := containers[len(containers)-1].Command; pull[4] != meta.OCI.Artifact {
+		t.Errorf("expected the artifact pulled by the verified digest, got %v", pull)
+	}
+	if env := container.Env[len(container.Env)-1]; env.Name != "DOCKER_CONFIG" || env.Value != OCIRegistryConfigMountPath {
+		t.Errorf("expected the credentials of the registry, got %v", container.Env)
+	}
+	var hasVolume bool
+	for _, v := range job.Spec.Template.Spec.Volumes {
+		hasVolume = hasVolume || (v.Name == TrustedKeysVolumeName && v.Secret.SecretName == "trusted-keys")
+	}
+	if !hasVolume {
+		t.Errorf("expected the volume of the trusted keys, got %v", job.Spec.Template.Spec.Volumes)
+	}
+
+	msg := "init container verify-configuration failed: Error: no matching signatures"
+	if state := applyFailedState(msg); state != types.ConfigurationVerificationFailed {
+		t.Errorf("expected the verification failed, got %s", state)
+	}
+	if reason := failureReason(applyFailedState(msg), msg); reason != types.FailureReasonUntrustedSource {
+		t.Errorf("expected the untrusted source, got %s", reason)
+	}
+	if state := applyFailedState("init container git-configuration failed: Error: path aws doesn't exist"); state != types.ConfigurationApplyFailed {
+		t.Errorf("expected the apply failed, got %s", state)
+	}
+}
+
+func TestDetectStuckApply(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	startTime := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+
+	testcases := map[string]struct {
+		state   types.ConfigurationState
+		status  batchv1.JobStatus
+		message string
+		reason  types.FailureReason
+	}{
+		"running in time": {
+			state:  types.ConfigurationProvisioningAndChecking,
+			status: batchv1.JobStatus{Active: 1, StartTime: &metav1.Time{Time: time.Now()}},
+		},
+		"running past the timeout": {
+			state:   types.ConfigurationProvisioningAndChecking,
+			status:  batchv1.JobStatus{Active: 1, StartTime: &startTime},
+			message: "the apply Job hasn't completed in 1h0m0s",
+		},
+		"failed without the logs": {
+			state: types.ConfigurationProvisioningAndChecking,
+			status: batchv1.JobStatus{Failed: 4, Conditions: []batchv1.JobCondition{{
+				Type: batchv1.JobFailed, Status: v1.ConditionTrue, Message: "Job has reached the specified backoff limit",
+			}}},
+			message: "the apply Job failed: Job has reached the specified backoff limit",
+		},
+		"stopped after the deadline": {
+			state: types.ConfigurationProvisioningAndChecking,
+			status: batchv1.JobStatus{Failed: 1, Conditions: []batchv1.JobCondition{{
+				Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: "DeadlineExceeded",
+				Message: "Job was active longer than specified deadline",
+			}}},
+			message: "Error: The Job was stopped after its deadline",
+			reason:  types.FailureReasonTimeout,
+		},
+		"failure already told by the logs": {
+			state:  types.ConfigurationApplyFailed,
+			status: batchv1.JobStatus{Active: 1, StartTime: &startTime},
+		},
+		"succeeded": {
+			state:  types.ConfigurationProvisioningAndChecking,
+			status: batchv1.JobStatus{Succeeded: 1, StartTime: &startTime},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			configuration := &v1beta1.Configuration{
+				ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+				Status:     v1beta1.ConfigurationStatus{Apply: v1beta1.ConfigurationApplyStatus{State: tc.state}},
+			}
+			r := &ConfigurationReconciler{
+				Client:   fake.NewFakeClientWithScheme(s, configuration.DeepCopy()),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "oss-apply"}, Status: tc.status}
+			if err := r.detectStuckApply(ctx, configuration, job); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got v1beta1.Configuration
+			if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+				t.Fatal(err)
+			}
+			if tc.message == "" {
+				if got.Status.Apply.State != tc.state {
+					t.Errorf("expected the state kept, got %s", got.Status.Apply.State)
+				}
+				return
+			}
+			if got.Status.Apply.State != types.ConfigurationApplyFailed || got.Status.Apply.Message != tc.message {
+				t.Errorf("expected the apply failed with %q, got %s: %s", tc.message, got.Status.Apply.State, got.Status.Apply.Message)
+			}
+			if tc.reason != "" && got.Status.Apply.Reason != tc.reason {
+				t.Errorf("expected the failure reason %s, got %s", tc.reason, got.Status.Apply.Reason)
+			}
+			if configuration.Status.Apply.State != types.ConfigurationApplyFailed {
+				t.Error("expected the state of the reconciled Configuration updated")
+			}
+		})
+	}
+}
+
+func TestRecordInputsChange(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"}}
+	k8sClient := fake.NewFakeClientWithScheme(s, configuration.DeepCopy())
+	recorder := record.NewFakeRecorder(10)
+
+	previous := []v1.EnvVar{{Name: "TF_VAR_bucket", Value: "old-secret"}, {Name: "TF_VAR_acl", Value: "private"},
+		{Name: "TF_VAR_region", Value: "cn-beijing"}}
+	current := []v1.EnvVar{{Name: "TF_VAR_acl", Value: "private"}, {Name: "TF_VAR_bucket", Value: "new-secret"},
+		{Name: "TF_VAR_tags", Value: "{}"}}
+	if err := recordInputsChange(ctx, k8sClient, recorder, configuration, "oss-apply", previous, current); err != nil {
+		t.Fatal(err)
+	}
+
+	var got v1beta1.Configuration
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	change := got.Status.InputsChange
+	if change == nil || change.Job != "oss-apply" || !reflect.DeepEqual(change.Added, []string{"TF_VAR_tags"}) ||
+		!reflect.DeepEqual(change.Removed, []string{"TF_VAR_region"}) || !reflect.DeepEqual(change.Changed, []string{"TF_VAR_bucket"}) {
+		t.Errorf("unexpected inputs change: %+v", change)
+	}
+	event := <-recorder.Events
+	expected := "Normal InputsChanged Variables changed, re-creating the Job oss-apply: added TF_VAR_tags; removed TF_VAR_region; changed TF_VAR_bucket"
+	if event != expected {
+		t.Errorf("expected the event %q, got %q", expected, event)
+	}
+	if strings.Contains(event, "secret") {
+		t.Error("expected the values of the variables not told")
+	}
+}
+
+func TestReapplyAfterInterval(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	meta := &TFConfigurationMeta{ApplyJobName: "oss-apply", PostApplyJobName: "oss-post-apply"}
+	configuration := func() *v1beta1.Configuration {
+		return &v1beta1.Configuration{
+			ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+			Spec:       v1beta1.ConfigurationSpec{ApplyInterval: &metav1.Duration{Duration: time.Hour}},
+			Status:     v1beta1.ConfigurationStatus{Apply: v1beta1.ConfigurationApplyStatus{State: types.Available}},
+		}
+	}
+	applyJob := func(completed time.Duration, succeeded int32) *batchv1.Job {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", Namespace: controllerNamespace},
+			Status:     batchv1.JobStatus{Succeeded: succeeded},
+		}
+		if succeeded == 1 {
+			completionTime := metav1.NewTime(time.Now().Add(-completed))
+			job.Status.CompletionTime = &completionTime
+		}
+		return job
+	}
+
+	testcases := map[string]struct {
+		job        *batchv1.Job
+		deleted    bool
+		minRequeue time.Duration
+		maxRequeue time.Duration
+		state      types.ConfigurationState
+	}{
+		"interval not passed": {
+			job:        applyJob(20*time.Minute, 1),
+			minRequeue: 39 * time.Minute,
+			maxRequeue: 40 * time.Minute,
+			state:      types.Available,
+		},
+		"interval passed": {
+			job:        applyJob(2*time.Hour, 1),
+			deleted:    true,
+			maxRequeue: 3 * time.Second,
+			state:      types.ConfigurationProvisioningAndChecking,
+		},
+		"apply is running": {
+			job:        applyJob(0, 0),
+			maxRequeue: 3 * time.Second,
+			state:      types.Available,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			c := configuration()
+			r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, c, tc.job)}
+			requeueAfter, err := r.reapplyAfterInterval(ctx, *c, meta)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if requeueAfter < tc.minRequeue || requeueAfter > tc.maxRequeue {
+				t.Errorf("expected requeue after between %s and %s, got %s", tc.minRequeue, tc.maxRequeue, requeueAfter)
+			}
+			var job batchv1.Job
+			err = r.Get(ctx, client.ObjectKey{Name: "oss-apply", Namespace: controllerNamespace}, &job)
+			if deleted := kerrors.IsNotFound(err); deleted != tc.deleted {
+				t.Errorf("expected the apply Job deleted %v, got %v", tc.deleted, deleted)
+			}
+			var got v1beta1.Configuration
+			if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Apply.State != tc.state {
+				t.Errorf("expected state %s, got %s", tc.state, got.Status.Apply.State)
+			}
+		})
+	}
+}
+
+func TestDestroyWithoutDeletion(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	meta := &TFConfigurationMeta{
+		Namespace:        controllerNamespace,
+		ApplyJobName:     "oss-apply",
+		ValidateJobName:  "oss-validate",
+		PostApplyJobName: "oss-post-apply",
+		DestroyJobName:   "oss-destroy",
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{
+			Destroy:                          true,
+			WriteConnectionSecretToReference: &v1beta1.ConnectionSecretReference{SecretReference: crossplane.SecretReference{Name: "oss-conn", Namespace: "default"}},
+		},
+		Status: v1beta1.ConfigurationStatus{Apply: v1beta1.ConfigurationApplyStatus{State: types.ConfigurationDestroying}},
+	}
+	job := func(name string, succeeded int32) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: controllerNamespace},
+			Status:     batchv1.JobStatus{Succeeded: succeeded},
+		}
+	}
+	connectionSecret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "oss-conn", Namespace: "default"}}
+	r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, configuration, connectionSecret,
+		job("oss-apply", 1), job("oss-destroy", 0))}
+
+	exists := func(obj runtime.Object, name, namespace string) bool {
+		return !kerrors.IsNotFound(r.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, obj))
+	}
+
+	// The destroy is still running, so the apply waits for it
+	if destroying, err := r.resumeAfterDestroy(ctx, configuration, meta); err != nil || !destroying {
+		t.Fatalf("expected waiting for the destroy, got %v, %v", destroying, err)
+	}
+
+	if err := meta.cleanUpAfterDestroy(ctx, r.Client, *configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exists(&v1.Secret{}, "oss-conn", "default") || exists(&batchv1.Job{}, "oss-apply", controllerNamespace) {
+		t.Error("expected the connection secret and the apply Job deleted")
+	}
+	if !exists(&batchv1.Job{}, "oss-destroy", controllerNamespace) {
+		t.Error("expected the destroy Job kept")
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Apply.State != types.ConfigurationDestroyed {
+		t.Errorf("expected state %s, got %s", types.ConfigurationDestroyed, got.Status.Apply.State)
+	}
+
+	// Once spec.destroy is unset, the destroy Job is deleted so that the configuration is applied again
+	if err := r.Delete(ctx, job("oss-destroy", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(ctx, job("oss-destroy", 1)); err != nil {
+		t.Fatal(err)
+	}
+	got.Status.Destroy.CompletedStages = 2
+	if err := r.Status().Update(ctx, &got); err != nil {
+		t.Fatal(err)
+	}
+	if destroying, err := r.resumeAfterDestroy(ctx, &got, meta); err != nil || destroying {
+		t.Fatalf("expected resumed, got %v, %v", destroying, err)
+	}
+	if exists(&batchv1.Job{}, "oss-destroy", controllerNamespace) {
+		t.Error("expected the destroy Job deleted")
+	}
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Destroy.CompletedStages != 0 {
+		t.Errorf("expected the destroy stages reset, got %d", got.Status.Destroy.CompletedStages)
+	}
+}
+
+func TestWaitForDependencies(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	vpc := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "network"},
+		Status:     v1beta1.ConfigurationStatus{Apply: v1beta1.ConfigurationApplyStatus{State: types.ConfigurationProvisioningAndChecking}},
+	}
+	vswitch := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "vswitch", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{
+			DependsOn: []v1beta1.ConfigurationReference{{Name: "vpc", Namespace: "network"}},
+		},
+	}
+	r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, vpc, vswitch)}
+
+	if keys := indexConfigurationByDependencies(vswitch); len(keys) != 1 || keys[0] != "network/vpc" {
+		t.Errorf("expected the index key network/vpc, got %v", keys)
+	}
+
+	waiting, err := r.waitForDependencies(ctx, vswitch)
+	if err != nil || !waiting {
+		t.Fatalf("expected waiting for the dependency, got %v, %v", waiting, err)
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, client.ObjectKey{Name: "vswitch", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Apply.State != types.ConfigurationWaitingForDependencies || !strings.Contains(got.Status.Apply.Message, "network/vpc") {
+		t.Errorf("expected waiting for network/vpc, got %s: %s", got.Status.Apply.State, got.Status.Apply.Message)
+	}
+
+	vpc.Status.Apply.State = types.Available
+	if err := r.Status().Update(ctx, vpc); err != nil {
+		t.Fatal(err)
+	}
+	if waiting, err := r.waitForDependencies(ctx, &got); err != nil || waiting {
+		t.Errorf("expected the dependency available, got %v, %v", waiting, err)
+	}
+}
+
+func TestResolveVariablesFrom(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	net := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "net", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{
+			WriteConnectionSecretToReference: &v1beta1.ConnectionSecretReference{SecretReference: crossplane.SecretReference{Name: "net-conn", Namespace: "default"}},
+		},
+		Status: v1beta1.ConfigurationStatus{Apply: v1beta1.ConfigurationApplyStatus{
+			State: types.Available,
+			Outputs: map[string]v1beta1.Property{
+				"vpc_id": {Value: "vpc-123", Type: "string"},
+				"token":  {Type: "string", Sensitive: true},
+			},
+		}},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "net-conn", Namespace: "default"},
+		Data:       map[string][]byte{"vpc_id": []byte("vpc-123"), "token": []byte("s3cr3t")},
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{
+			VariableFrom: []v1beta1.VariableFromOutput{
+				{Configuration: "net", Output: "vpc_id", Var: "vpc_id"},
+				{Configuration: "net", Output: "token", Var: "api_token"},
+			},
+		},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, net, secret, configuration)
+
+	if deps := dependencies(configuration); len(deps) != 1 || deps[0].String() != "default/net" {
+		t.Errorf("expected the implicit dependency default/net, got %v", deps)
+	}
+	variables, err := resolveVariablesFrom(ctx, k8sClient, configuration)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"TF_VAR_vpc_id": "vpc-123", "TF_VAR_api_token": "s3cr3t"}
+	if !reflect.DeepEqual(variables, expected) {
+		t.Errorf("expected variables %v, got %v", expected, variables)
+	}
+
+	// the outputs of the Configurations in other namespaces aren't read
+	other := configuration.DeepCopy()
+	other.Namespace = "team-a"
+	if _, err := resolveVariablesFrom(ctx, k8sClient, other); err == nil {
+		t.Error("expected an error for the output of another namespace")
+	}
+	other.Spec.VariableFrom = []v1beta1.VariableFromOutput{{Configuration: "net", Namespace: "default", Output: "token", Var: "api_token"}}
+	if _, err := resolveVariablesFrom(ctx, k8sClient, other); err == nil || !strings.Contains(err.Error(), "namespace team-a") {
+		t.Errorf("expected an error for the sensitive output of another namespace, got %v", err)
+	}
+
+	configuration.Spec.VariableFrom = append(configuration.Spec.VariableFrom, v1beta1.VariableFromOutput{Configuration: "net", Output: "subnet_id", Var: "subnet_id"})
+	r := &ConfigurationReconciler{Client: k8sClient}
+	unavailable, err := r.checkDependencies(ctx, configuration)
+	if err != nil || len(unavailable) != 1 || !strings.Contains(unavailable[0], "output subnet_id not found") {
+		t.Errorf("expected waiting for the output subnet_id, got %v, %v", unavailable, err)
+	}
+}
+
+func TestWriteFailedJobLogs(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	meta := &TFConfigurationMeta{Namespace: controllerNamespace, Labels: map[string]string{LabelKeyOwnedBy: "oss"}}
+	k8sClient := fake.NewFakeClientWithScheme(s)
+
+	for _, logs := range []string{"Error: first failure", "Error: second failure"} {
+		if err := meta.writeFailedJobLogs(ctx, k8sClient, "oss-apply", logs); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var cm v1.ConfigMap
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: "oss-apply-failed-logs", Namespace: controllerNamespace}, &cm); err != nil {
+			t.Fatal(err)
+		}
+		if cm.Data[FailedJobLogsKey] != logs || cm.Labels[LabelKeyOwnedBy] != "oss" {
+			t.Errorf("expected the logs %q of the latest failure, got %v", logs, cm)
+		}
+	}
+
+	failed := v1beta1.Configuration{Status: v1beta1.ConfigurationStatus{Apply: v1beta1.ConfigurationApplyStatus{State: types.ConfigurationApplyFailed}}}
+	if !isJobFailed(failed, batchv1.Job{}) || isJobFailed(v1beta1.Configuration{}, batchv1.Job{}) {
+		t.Error("expected the Job failed only when the apply failed")
+	}
+}
+
+func TestAliasedProviderVariables(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	provider := func(name string, state types.ProviderState) *v1beta1.Provider {
+		return &v1beta1.Provider{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1beta1.ProviderSpec{
+				Provider: "aws",
+				Region:   "us-west-2",
+				Credentials: v1beta1.ProviderCredentials{
+					Source: "Secret",
+					SecretRef: &crossplane.SecretKeySelector{
+						SecretReference: crossplane.SecretReference{Name: "aws-creds", Namespace: "default"},
+						Key:             "credentials",
+					},
+				},
+			},
+			Status: v1beta1.ProviderStatus{State: state},
+		}
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws-creds", Namespace: "default"},
+		Data:       map[string][]byte{"credentials": []byte("awsAccessKeyID: ak\nawsSecretAccessKey: sk\n")},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, provider("aws-west", types.ProviderIsReady),
+		provider("aws-east", types.ProviderIsInitializing), secret)
+
+	variables, err := aliasedProviderVariables(ctx, k8sClient, []v1beta1.AliasedProviderReference{{Alias: "west", Name: "aws-west"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if variables["TF_VAR_west_aws_access_key_id"] != "ak" || variables["TF_VAR_west_aws_default_region"] != "us-west-2" {
+		t.Errorf("expected the credentials of the aliased provider west, got %v", variables)
+	}
+
+	// the region of the aliased provider overrides the one of the Provider
+	variables, err = aliasedProviderVariables(ctx, k8sClient, []v1beta1.AliasedProviderReference{{Alias: "eu", Name: "aws-west", Region: "eu-central-1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if variables["TF_VAR_eu_aws_default_region"] != "eu-central-1" {
+		t.Errorf("expected the region of the aliased provider eu to be overridden, got %v", variables)
+	}
+
+	_, err = aliasedProviderVariables(ctx, k8sClient, []v1beta1.AliasedProviderReference{{Alias: "east", Name: "aws-east"}})
+	if err == nil || !strings.Contains(err.Error(), "aliased provider east") {
+		t.Errorf("expected an error about the aliased provider which isn't ready, got %v", err)
+	}
+}
+
+func TestCheckRequiredVariablesSupplied(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{
+			VariableFrom: []v1beta1.VariableFromOutput{{Configuration: "net", Output: "vpc_id", Var: "vpc_id"}},
+		},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, configuration)
+
+	hcl := `variable "vpc_id" {}`
+	if err := checkRequiredVariables(ctx, k8sClient, configuration, types.ConfigurationHCL, hcl, nil); err != nil {
+		t.Errorf("expected the variable from the output supplied, got %v", err)
+	}
+
+	// the credentials of the aliased Providers are supplied as well
+	configuration.Spec.AliasedProviders = []v1beta1.AliasedProviderReference{{Alias: "west", Name: "aws-west"}}
+	hcl += "\nvariable \"west_aws_access_key_id\" {}\nvariable \"west_aws_secret_access_key\" {}"
+	if err := checkRequiredVariables(ctx, k8sClient, configuration, types.ConfigurationHCL, hcl, nil); err != nil {
+		t.Errorf("expected the credentials of the aliased provider supplied, got %v", err)
+	}
+
+	hcl += "\nvariable \"subnet_id\" {}"
+	err := checkRequiredVariables(ctx, k8sClient, configuration, types.ConfigurationHCL, hcl, nil)
+	if err == nil || !strings.Contains(err.Error(), "subnet_id") || strings.Contains(err.Error(), "vpc_id") {
+		t.Errorf("expected only subnet_id missing, got %v", err)
+	}
+}
+
+func TestPrepareTFVariablesCredentialsPending(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	// the Provider is initializing until the Secret of its credentials is created
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: v1beta1.ProviderSpec{
+			Provider: "aws",
+			Region:   "us-east-1",
+			Credentials: v1beta1.ProviderCredentials{
+				Source:    crossplane.CredentialsSourceSecret,
+				SecretRef: &crossplane.SecretKeySelector{SecretReference: crossplane.SecretReference{Name: "aws-account-creds", Namespace: "default"}, Key: "credentials"},
+			},
+		},
+		Status: v1beta1.ProviderStatus{State: types.ProviderIsInitializing},
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "default"},
+		Status:     v1beta1.ConfigurationStatus{Apply: v1beta1.ConfigurationApplyStatus{State: types.Available}},
+	}
+	meta := &TFConfigurationMeta{
+		ProviderReference:      &crossplane.Reference{Name: "default", Namespace: "default"},
+		CredentialsGracePeriod: time.Minute,
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, provider, configuration.DeepCopy())
+	get := func() *v1beta1.Configuration {
+		var got v1beta1.Configuration
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: "vpc", Namespace: "default"}, &got); err != nil {
+			t.Fatal(err)
+		}
+		return &got
+	}
+
+	if _, err := meta.prepareTFVariables(ctx, k8sClient, get()); !errors.Is(err, errCredentialsPending) {
+		t.Fatalf("expected the credentials waited for, got %v", err)
+	}
+	got := get()
+	if got.Status.CredentialsPendingSince == nil || got.Status.Apply.State != types.Available {
+		t.Fatalf("expected the state kept while the credentials are waited for, got %s since %v", got.Status.Apply.State,
+			got.Status.CredentialsPendingSince)
+	}
+
+	got.Status.CredentialsPendingSince = &metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+	if err := k8sClient.Status().Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := meta.prepareTFVariables(ctx, k8sClient, get()); err == nil || errors.Is(err, errCredentialsPending) {
+		t.Fatalf("expected the provider not ready after the grace period, got %v", err)
+	}
+	if state := get().Status.Apply.State; state != types.ProviderNotReady {
+		t.Fatalf("expected the state ProviderNotReady, got %s", state)
+	}
+
+	provider.Spec.Credentials = v1beta1.ProviderCredentials{Source: crossplane.CredentialsSourceInjectedIdentity}
+	provider.Status.State = types.ProviderIsReady
+	if err := k8sClient.Update(ctx, provider); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := meta.prepareTFVariables(ctx, k8sClient, get()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := get(); got.Status.CredentialsPendingSince != nil || got.Status.Apply.State != types.ProviderReady {
+		t.Errorf("expected the provider ready and the grace period reset, got %s since %v", got.Status.Apply.State,
+			got.Status.CredentialsPendingSince)
+	}
+}
+
+func TestPrepareTFVariablesComplexValues(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: v1beta1.ProviderSpec{
+			Provider:    "aws",
+			Region:      "us-east-1",
+			Credentials: v1beta1.ProviderCredentials{Source: crossplane.CredentialsSourceInjectedIdentity},
+		},
+		Status: v1beta1.ProviderStatus{State: types.ProviderIsReady},
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{Variable: &runtime.RawExtension{Raw: []byte(`{
+			"name": "vpc",
+			"count": 12345678901234567890,
+			"enabled": true,
+			"zones": ["us-east-1a", "us-east-1b"],
+			"tags": {"env": "prod", "team": "infra"},
+			"subnets": [{"cidr": "10.0.0.0/24", "public": true, "ports": [80, 443]}],
+			"nothing": null
+		}`)}},
+	}
+	meta := &TFConfigurationMeta{ProviderReference: &crossplane.Reference{Name: "default", Namespace: "default"}}
+	k8sClient := fake.NewFakeClientWithScheme(s, provider, configuration)
+	envs, err := meta.prepareTFVariables(ctx, k8sClient, configuration)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := make(map[string]string)
+	for _, env := range envs {
+		got[env.Name] = env.Value
+	}
+	expected := map[string]string{
+		"TF_VAR_name":    "vpc",
+		"TF_VAR_count":   "12345678901234567890",
+		"TF_VAR_enabled": "true",
+		"TF_VAR_zones":   `["us-east-1a","us-east-1b"]`,
+		"TF_VAR_tags":    `{"env":"prod","team":"infra"}`,
+		"TF_VAR_subnets": `[{"cidr":"10.0.0.0/24","ports":[80,443],"public":true}]`,
+	}
+	for name, value := range expected {
+		if got[name] != value {
+			t.Errorf("expected %s to be %s, got %q", name, value, got[name])
+		}
+	}
+	if _, ok := got["TF_VAR_nothing"]; ok {
+		t.Error("expected the null variable to be left to its default")
+	}
+
+	// spec.env is passed as it is, but can't override the environment variables set by the controller
+	meta.Env = []v1.EnvVar{{Name: "AWS_PROFILE", Value: "prod"}}
+	envs, err = meta.prepareTFVariables(ctx, k8sClient, configuration)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last := envs[len(envs)-1]; last.Name != "AWS_PROFILE" || last.Value != "prod" {
+		t.Errorf("expected the environment variable of spec.env, got %v", envs)
+	}
+	meta.Env = []v1.EnvVar{{Name: "AWS_DEFAULT_REGION", Value: "eu-west-1"}}
+	if _, err := meta.prepareTFVariables(ctx, k8sClient, configuration); err == nil || !strings.Contains(err.Error(), "collides") {
+		t.Errorf("expected an error about the collision, got %v", err)
+	}
+}
+
+func TestCheckRegions(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "default"},
+		Spec:       v1beta1.ProviderSpec{Provider: "aws", Region: "us-wset-2"},
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{
+			ProviderReference: &crossplane.Reference{Name: "aws", Namespace: "default"},
+			Region:            "us-west-2",
+		},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, provider, configuration)
+
+	// the region of the Configuration overrides the mistyped one of the Provider
+	if err := checkRegions(ctx, k8sClient, configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configuration.Spec.Region = ""
+	err := checkRegions(ctx, k8sClient, configuration)
+	if err == nil || !strings.Contains(err.Error(), "us-wset-2 is not a region of provider aws") {
+		t.Fatalf("expected an invalid region error, got %v", err)
+	}
+	var got v1beta1.Configuration
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Apply.State != types.InvalidRegion || got.Status.Apply.Reason != types.FailureReasonInvalidConfiguration {
+		t.Errorf("expected the state InvalidRegion, got %v", got.Status.Apply)
+	}
+}
+
+func TestRecordJobTimes(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"}}
+	k8sClient := fake.NewFakeClientWithScheme(s, configuration)
+
+	start := metav1.NewTime(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	job := batchv1.Job{Status: batchv1.JobStatus{StartTime: &start}}
+	if err := recordJobTimes(ctx, k8sClient, configuration, TerraformApply, job); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if apply := configuration.Status.Apply; apply.StartTime == nil || apply.CompletionTime != nil || apply.LastAppliedTime != nil {
+		t.Errorf("expected only the start time of the running Job, got %v", apply)
+	}
+
+	completion := metav1.NewTime(start.Add(90 * time.Second))
+	job.Status.CompletionTime, job.Status.Succeeded = &completion, 1
+	if err := recordJobTimes(ctx, k8sClient, configuration, TerraformApply, job); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got v1beta1.Configuration
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	apply := got.Status.Apply
+	if apply.Duration == nil || apply.Duration.Duration != 90*time.Second || apply.LastAppliedTime == nil ||
+		!apply.LastAppliedTime.Equal(&completion) {
+		t.Errorf("expected the apply to take 90s and complete at %s, got %v", completion, apply)
+	}
+	if got.Status.Destroy.StartTime != nil {
+		t.Errorf("expected no times of the destroy, got %v", got.Status.Destroy)
+	}
+}
+
+func TestExecutorCommandWithImports(t *testing.T) {
+	meta := &TFConfigurationMeta{
+		Engine:  types.TerraformEngine,
+		Imports: []v1beta1.ResourceImport{{Address: `alicloud_vpc.main["it's"]`, ID: "vpc-123"}},
+	}
+	// the address is passed as it is, which is never parsed by a shell
+	expected := []runner.Step{
+		runStep("terraform", "init"),
+		{Run: &runner.Run{
+			Args:           []string{"terraform", "import", "-lock-timeout=5m", `alicloud_vpc.main["it's"]`, "vpc-123"},
+			Unless:         []string{"terraform", "state", "show", `alicloud_vpc.main["it's"]`},
+			FailureMessage: `Error: Import failed: alicloud_vpc.main["it's"]`,
+		}},
+		runStep("terraform", "apply", "-lock-timeout=5m", "-auto-approve"),
+	}
+	if steps := meta.executorSteps(TerraformApply); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected the steps %s, got %s", stepsJSON(expected), stepsJSON(steps))
+	}
+	if steps := meta.executorSteps(TerraformDestroy); strings.Contains(stepsJSON(steps), "import") {
+		t.Errorf("expected the destroy Job not to import, got %s", stepsJSON(steps))
+	}
+	if job := meta.assembleTerraformJob(TerraformApply); job.Annotations[ImportsHashAnnotation] != meta.importsHash() {
+		t.Errorf("expected the imports hash annotation, got %v", job.Annotations)
+	}
+}
+
+func TestExecutorCommandWithDestroyTargets(t *testing.T) {
+	meta := &TFConfigurationMeta{
+		Engine:         types.TerraformEngine,
+		DestroyStage:   1,
+		DestroyTargets: []string{"module.network", `alicloud_vpc.main["it's"]`},
+	}
+	expected := []runner.Step{
+		runStep("terraform", "init"),
+		runStep("terraform", "destroy", "-lock-timeout=5m", "-auto-approve", "-target=module.network",
+			`-target=alicloud_vpc.main["it's"]`),
+	}
+	if steps := meta.executorSteps(TerraformDestroy); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected the steps %s, got %s", stepsJSON(expected), stepsJSON(steps))
+	}
+	if job := meta.assembleTerraformJob(TerraformDestroy); job.Annotations[DestroyStageAnnotation] != "1" {
+		t.Errorf("expected the destroy stage annotation, got %v", job.Annotations)
+	}
+}
+
+func TestTerraformDestroyStages(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	meta := &TFConfigurationMeta{Namespace: controllerNamespace, DestroyJobName: "oss-destroy"}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{
+			Destroy:       true,
+			DestroyStages: []v1beta1.DestroyStage{{Targets: []string{"alicloud_instance.web"}}, {Targets: []string{"module.network"}}},
+		},
+	}
+	destroyJob := func(stage string) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "oss-destroy", Namespace: controllerNamespace,
+				Annotations: map[string]string{DestroyStageAnnotation: stage}},
+			Status: batchv1.JobStatus{Succeeded: 1},
+		}
+	}
+	r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, configuration, destroyJob("0"))}
+
+	// the first stage is destroyed, and its Job is deleted for the next stage
+	if err := r.terraformDestroy(ctx, *configuration, meta); err == nil || err.Error() != MessageDestroyJobNotCompleted {
+		t.Fatalf("expected the destroy not completed, got %v", err)
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Destroy.CompletedStages != 1 {
+		t.Errorf("expected a stage completed, got %d", got.Status.Destroy.CompletedStages)
+	}
+	if expected := fmt.Sprintf(MessageDestroyingStage, 2, 2); got.Status.Apply.Message != expected {
+		t.Errorf("expected message %q, got %q", expected, got.Status.Apply.Message)
+	}
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss-destroy", Namespace: controllerNamespace}, &batchv1.Job{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the destroy Job of the first stage deleted, got %v", err)
+	}
+
+	// the Job of another stage is replaced
+	if err := r.Create(ctx, destroyJob("0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.terraformDestroy(ctx, got, meta); err == nil || err.Error() != MessageDestroyJobNotCompleted {
+		t.Fatalf("expected the destroy not completed, got %v", err)
+	}
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss-destroy", Namespace: controllerNamespace}, &batchv1.Job{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the destroy Job of another stage deleted, got %v", err)
+	}
+}
+
+func TestExecutorCommandWithApproval(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "oss", Engine: types.TerraformEngine}
+	plan := []runner.Step{
+		runStep("terraform", "plan", "-lock=false", "-input=false", "-out=tfplan"),
+		{Run: &runner.Run{Args: []string{"terraform", "show", "-no-color", "tfplan"}, Stdout: "tfplan.txt"}},
+	}
+	expected := append(append([]runner.Step{runStep("terraform", "init")}, plan...),
+		runner.Step{Hash: &runner.Hash{File: "tfplan.txt", Output: "/dev/termination-log"}})
+	if steps := meta.executorSteps(TerraformPlan); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected the plan steps %s, got %s", stepsJSON(expected), stepsJSON(steps))
+	}
+	if job := meta.assembleTerraformJob(TerraformPlan); *job.Spec.BackoffLimit != 0 {
+		t.Errorf("expected the failed plan not to be retried, got the backoff limit %d", *job.Spec.BackoffLimit)
+	}
+
+	meta.ApprovedPlanHash = "abc'123"
+	expected = append(append([]runner.Step{runStep("terraform", "init")}, plan...),
+		runner.Step{Hash: &runner.Hash{File: "tfplan.txt", Expected: "abc'123", FailureMessage: "Error: The plan changed after it was approved"}},
+		runStep("terraform", "apply", "-lock-timeout=5m", "-auto-approve", "tfplan"))
+	if steps := meta.executorSteps(TerraformApply); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected the apply steps %s, got %s", stepsJSON(expected), stepsJSON(steps))
+	}
+	if steps := meta.executorSteps(TerraformDestroy); strings.Contains(stepsJSON(steps), "tfplan") {
+		t.Errorf("expected the destroy not to wait for approval, got %s", stepsJSON(steps))
+	}
+}
+
+func TestExecutorCommandWithPlanArtifacts(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "oss", Namespace: "default", PlanJobName: "oss-plan", Engine: types.TerraformEngine,
+		PlanArtifacts: &v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "plans"}}}
+	saved := runner.Step{Hash: &runner.Hash{File: "tfplan.txt", Output: "/dev/termination-log", SaveFile: "tfplan",
+		SaveDir: "/plan-artifacts"}}
+	if steps := meta.executorSteps(TerraformPlan); !reflect.DeepEqual(steps[len(steps)-1], saved) {
+		t.Errorf("expected the plan saved, got %s", stepsJSON(steps))
+	}
+
+	// the saved plan is applied as it is once it's hashed again, or else the changes are planned again and compared
+	// with the approved plan
+	meta.ApprovedPlanHash = "abc"
+	changed := "Error: The plan changed after it was approved"
+	expected := []runner.Step{
+		runStep("terraform", "init"),
+		{IfExists: &runner.IfExists{
+			Path: "/plan-artifacts/abc",
+			Then: []runner.Step{
+				{Run: &runner.Run{Args: []string{"terraform", "show", "-no-color", "/plan-artifacts/abc"}, Stdout: "tfplan.saved.txt"}},
+				{Hash: &runner.Hash{File: "tfplan.saved.txt", Expected: "abc", FailureMessage: changed}},
+				runStep("terraform", "apply", "-lock-timeout=5m", "-auto-approve", "/plan-artifacts/abc"),
+				{Remove: "/plan-artifacts/abc"},
+			},
+			Else: []runner.Step{
+				runStep("terraform", "plan", "-lock=false", "-input=false", "-out=tfplan"),
+				{Run: &runner.Run{Args: []string{"terraform", "show", "-no-color", "tfplan"}, Stdout: "tfplan.txt"}},
+				{Hash: &runner.Hash{File: "tfplan.txt", Expected: "abc", FailureMessage: changed}},
+				runStep("terraform", "apply", "-lock-timeout=5m", "-auto-approve", "tfplan"),
+			},
+		}},
+	}
+	if steps := meta.executorSteps(TerraformApply); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected the saved plan applied by the steps %s, got %s", stepsJSON(expected), stepsJSON(steps))
+	}
+	if steps := meta.executorSteps(TerraformDestroy); !reflect.DeepEqual(steps[len(steps)-1], runner.Step{Remove: "/plan-artifacts/*"}) {
+		t.Errorf("expected the saved plans removed after the destroy, got %s", stepsJSON(steps))
+	}
+	// only the directory of the Configuration is mounted
+	job := meta.assembleTerraformJob(TerraformApply)
+	if mounts := job.Spec.Template.Spec.Containers[0].VolumeMounts; mounts[len(mounts)-1].MountPath != PlanArtifactsMountPath ||
+		mounts[len(mounts)-1].SubPath != "default/oss-plan" {
+		t.Errorf("expected the directory of the saved plans of the Configuration mounted, got %v", mounts)
+	}
+
+	r := &ConfigurationReconciler{PlanExpiry: time.Hour}
+	completed := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	planJob := batchv1.Job{Status: batchv1.JobStatus{Succeeded: 1, CompletionTime: &completed}}
+	if !r.planExpired(meta, planJob, time.Now()) {
+		t.Error("expected the plan saved 2 hours ago expired")
+	}
+	if r.planExpired(meta, planJob, completed.Add(time.Minute)) {
+		t.Error("expected the plan saved a minute ago not expired")
+	}
+	meta.PlanArtifacts = nil
+	if r.planExpired(meta, planJob, time.Now()) {
+		t.Error("expected the plan planned again by the apply never expired")
+	}
+}
+
+func TestExecutorCommandStateLock(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "oss", Engine: types.TerraformEngine, StateRemoveAddresses: []string{"alicloud_vpc.main"},
+		Imports: []v1beta1.ResourceImport{{Address: "alicloud_vpc.main", ID: "vpc-123"}}}
+	// the Jobs writing the state, like the apply of a replica and the destroy of another during a failover, hold the
+	// lock of the backend, and wait for each other rather than writing the state at the same time
+	for _, executionType := range []TerraformExecutionType{TerraformApply, TerraformDestroy, TerraformRefresh, TerraformStateRemove} {
+		steps := meta.executorSteps(executionType)
+		if !strings.Contains(stepsJSON(steps[len(steps)-1:]), `"-lock-timeout=5m"`) || strings.Contains(stepsJSON(steps), "-lock=false") {
+			t.Errorf("expected the %s to hold the lock of the backend, got %s", executionType, stepsJSON(steps))
+		}
+	}
+	if steps := meta.executorSteps(TerraformApply); !reflect.DeepEqual(steps[1].Run.Args[:3], []string{"terraform", "import", "-lock-timeout=5m"}) {
+		t.Errorf("expected the imports to hold the lock of the backend, got %s", stepsJSON(steps))
+	}
+	if steps := meta.executorSteps(TerraformPlan); !reflect.DeepEqual(steps[1].Run.Args[:3], []string{"terraform", "plan", "-lock=false"}) {
+		t.Errorf("expected the plan to read the state without the lock, got %s", stepsJSON(steps))
+	}
+}
+
+func TestExecutorCommandJSONLogs(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "oss", Engine: types.TerraformEngine, JSONLogs: true}
+	for executionType, expected := range map[TerraformExecutionType][]string{
+		TerraformApply:    {"terraform", "apply", "-lock-timeout=5m", "-auto-approve", "-json"},
+		TerraformDestroy:  {"terraform", "destroy", "-lock-timeout=5m", "-auto-approve", "-json"},
+		TerraformValidate: {"terraform", "validate", "-no-color"},
+		TerraformPlan:     {"terraform", "plan", "-lock=false", "-input=false", "-json", "-out=tfplan"},
+	} {
+		if steps := meta.executorSteps(executionType); !reflect.DeepEqual(steps[1].Run.Args, expected) {
+			t.Errorf("expected the %s command %v, got %s", executionType, expected, stepsJSON(steps))
+		}
+	}
+	meta.ApprovedPlanHash = "abc"
+	steps := meta.executorSteps(TerraformApply)
+	if expected := []string{"terraform", "apply", "-lock-timeout=5m", "-auto-approve", "-json", "tfplan"}; !reflect.DeepEqual(steps[len(steps)-1].Run.Args, expected) {
+		t.Errorf("expected the approved plan to be applied with -json, got %s", stepsJSON(steps))
+	}
+}
+
+func TestExecutorCommandSkipRefresh(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "oss", Engine: types.TerraformEngine, SkipRefresh: true, JSONLogs: true,
+		VarFiles: []string{"0-prod.tfvars"}}
+	varFile := "-var-file=/opt/tf-var-files/0-prod.tfvars"
+	for executionType, expected := range map[TerraformExecutionType][]string{
+		TerraformApply:   {"terraform", "apply", "-lock-timeout=5m", "-auto-approve", "-json", "-refresh=false", varFile},
+		TerraformDestroy: {"terraform", "destroy", "-lock-timeout=5m", "-auto-approve", "-json", varFile},
+		TerraformRefresh: {"terraform", "apply", "-refresh-only", "-lock-timeout=5m", "-auto-approve", "-json", varFile},
+		TerraformPlan:    {"terraform", "plan", "-lock=false", "-input=false", "-json", "-refresh=false", varFile, "-out=tfplan"},
+	} {
+		if steps := meta.executorSteps(executionType); !reflect.DeepEqual(steps[1].Run.Args, expected) {
+			t.Errorf("expected the %s command %v, got %s", executionType, expected, stepsJSON(steps))
+		}
+	}
+	meta.Imports = []v1beta1.ResourceImport{{Address: "alicloud_vpc.main", ID: "vpc-123"}}
+	expected := []string{"terraform", "apply", "-lock-timeout=5m", "-auto-approve", "-json", "-refresh=false", varFile}
+	if steps := meta.executorSteps(TerraformApply); !reflect.DeepEqual(steps[len(steps)-1].Run.Args, expected) {
+		t.Errorf("expected the apply after the imports to skip the refresh, got %s", stepsJSON(steps))
+	}
+}
+
+func TestAssembleTerraformJobPluginCache(t *testing.T) {
+	claim := &v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "tf-plugin-cache"}}
+	meta := &TFConfigurationMeta{Name: "oss", ConfigurationCMName: "oss-tf-input", PluginCache: claim, PluginCacheSubPath: "team-a"}
+	job := meta.assembleTerraformJob(TerraformApply)
+	podSpec := job.Spec.Template.Spec
+
+	var hasVolume, hasMount bool
+	for _, v := range podSpec.Volumes {
+		hasVolume = hasVolume || (v.Name == PluginCacheVolumeName && reflect.DeepEqual(v.VolumeSource, *claim))
+	}
+	executor := podSpec.Containers[0]
+	for _, m := range executor.VolumeMounts {
+		hasMount = hasMount || (m.Name == PluginCacheVolumeName && m.MountPath == PluginCacheMountPath && m.SubPath == "team-a")
+	}
+	if !hasVolume || !hasMount {
+		t.Errorf("expected the directory of the namespace of the plugin cache to be mounted, got %v, %v", podSpec.Volumes, executor.VolumeMounts)
+	}
+	// the cached providers are only used when they match the dependency lock file
+	if len(executor.Env) != 1 || executor.Env[0].Name != "TF_PLUGIN_CACHE_DIR" || executor.Env[0].Value != PluginCacheMountPath {
+		t.Errorf("expected only TF_PLUGIN_CACHE_DIR to be set, got %v", executor.Env)
+	}
+	expected := runner.Step{Run: &runner.Run{Args: []string{"terraform", "init", "-backend=false"}, Lock: "/plugin-cache/.lock"}}
+	if steps := meta.executorSteps(TerraformValidate); !reflect.DeepEqual(steps[0], expected) {
+		t.Errorf("expected the init to be serialized, got %s", stepsJSON(steps))
+	}
+
+	meta.PluginCache = nil
+	if steps := meta.executorSteps(TerraformValidate); steps[0].Run.Lock != "" {
+		t.Errorf("expected no lock without the plugin cache, got %s", stepsJSON(steps))
+	}
+}
+
+func TestAssembleTerraformJobCLIConfig(t *testing.T) {
+	mirror := &v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+		LocalObjectReference: v1.LocalObjectReference{Name: "terraformrc"},
+		Items:                []v1.KeyToPath{{Key: CLIConfigKey, Path: CLIConfigKey}},
+	}}
+	meta := &TFConfigurationMeta{Name: "oss", ConfigurationCMName: "oss-tf-input", CLIConfig: mirror,
+		Envs: []v1.EnvVar{{Name: "TF_VAR_name", Value: "oss"}}}
+	podSpec := meta.assembleTerraformJob(TerraformApply).Spec.Template.Spec
+
+	var hasVolume bool
+	for _, v := range podSpec.Volumes {
+		hasVolume = hasVolume || (v.Name == CLIConfigVolumeName && reflect.DeepEqual(v.VolumeSource, *mirror))
+	}
+	if !hasVolume {
+		t.Errorf("expected the volume of the CLI configuration, got %v", podSpec.Volumes)
+	}
+	expected := []v1.EnvVar{{Name: "TF_VAR_name", Value: "oss"}, {Name: "TF_CLI_CONFIG_FILE", Value: "/etc/terraform-cli/.terraformrc"}}
+	if env := podSpec.Containers[0].Env; !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected the environment variables %v, got %v", expected, env)
+	}
+	if len(meta.Envs) != 1 {
+		t.Errorf("expected the environment variables of the meta to be kept, got %v", meta.Envs)
+	}
+}
+
+func TestAssembleTerraformJobEntrypoint(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "oss", ConfigurationCMName: "oss-tf-input", Engine: types.TerraformEngine}
+	plain := meta.assembleTerraformJob(TerraformApply).Spec.Template.Spec.Containers[0].Command
+	if !reflect.DeepEqual(plain, meta.executorCommand(TerraformApply)) || plain[0] != runnerBinary {
+		t.Fatalf("expected the steps run by the runner by default, got %v", plain)
+	}
+
+	meta.Entrypoint = []string{"sh", "/opt/hooks/wrapper.sh"}
+	for _, executionType := range []TerraformExecutionType{TerraformApply, TerraformPlan} {
+		command := meta.assembleTerraformJob(executionType).Spec.Template.Spec.Containers[0].Command
+		expected := append([]string{"sh", "/opt/hooks/wrapper.sh"}, meta.executorCommand(executionType)...)
+		if !reflect.DeepEqual(command, expected) {
+			t.Errorf("expected the %s command %v passed to the entrypoint, got %v", executionType, expected, command)
+		}
+	}
+}
+
+func TestAssembleTerraformJobRunner(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "oss", ConfigurationCMName: "oss-tf-input", Engine: types.TerraformEngine}
+	podSpec := meta.assembleTerraformJob(TerraformApply).Spec.Template.Spec
+	// the runner installs itself, so neither the image of the controller nor the one of the executor needs a shell
+	install := podSpec.InitContainers[0]
+	if install.Image != runnerImage || !reflect.DeepEqual(install.Command, []string{"/runner", "install", runnerBinary}) {
+		t.Errorf("expected the runner installed from the image of the controller, got %s %v", install.Image, install.Command)
+	}
+	executor := podSpec.Containers[0]
+	if executor.Image != terraformImage || executor.WorkingDir != WorkingVolumeMountPath {
+		t.Errorf("expected the built-in image working in %s, got %s in %s", WorkingVolumeMountPath, executor.Image, executor.WorkingDir)
+	}
+	var mounted bool
+	for _, m := range executor.VolumeMounts {
+		mounted = mounted || (m.Name == RunnerVolumeName && m.MountPath == RunnerMountPath && m.ReadOnly)
+	}
+	if !mounted {
+		t.Errorf("expected the runner mounted to the executor, got %v", executor.VolumeMounts)
+	}
+
+	meta.TerraformImage, meta.OpenTofuImage, meta.RunnerImage = "registry.local/terraform:distroless", "registry.local/tofu:distroless",
+		"registry.local/terraform-controller:0.2.4"
+	podSpec = meta.assembleTerraformJob(TerraformApply).Spec.Template.Spec
+	if podSpec.Containers[0].Image != meta.TerraformImage || podSpec.InitContainers[0].Image != meta.RunnerImage {
+		t.Errorf("expected the configured images, got %s and %s", podSpec.Containers[0].Image, podSpec.InitContainers[0].Image)
+	}
+	meta.Engine = types.OpenTofuEngine
+	podSpec = meta.assembleTerraformJob(TerraformApply).Spec.Template.Spec
+	if podSpec.Containers[0].Image != meta.OpenTofuImage || podSpec.Containers[0].Command[0] != runnerBinary {
+		t.Errorf("expected the configured image of OpenTofu run by the runner, got %s %v", podSpec.Containers[0].Image,
+			podSpec.Containers[0].Command)
+	}
+}
+
+func TestPrepareTFInputConfigurationData(t *testing.T) {
+	hcl := `resource "random_id" "server" {}`
+	meta := &TFConfigurationMeta{ConfigurationType: types.ConfigurationHCL, CompleteConfiguration: hcl}
+	data := meta.prepareTFInputConfigurationData()
+	if !reflect.DeepEqual(data, map[string]string{types.TerraformHCLConfigurationName: hcl}) {
+		t.Fatalf("expected only the configuration file, got %v", data)
+	}
+
+	// the ConfigMap stored by older versions with the kubeconfig placeholder isn't taken as changed
+	legacy := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tf-oss"},
+		Data: map[string]string{types.TerraformHCLConfigurationName: hcl, "kubeconfig": ""}}
+	if changed, err := cfgvalidator.CheckWhetherConfigurationChanges(types.ConfigurationHCL, legacy, hcl); err != nil || changed {
+		t.Errorf("expected the configuration unchanged, got %v, %v", changed, err)
+	}
+}
+
+func TestAssembleTerraformJobLogLevel(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "oss", ConfigurationCMName: "oss-tf-input"}
+	if env := meta.assembleTerraformJob(TerraformApply).Spec.Template.Spec.Containers[0].Env; len(env) != 0 {
+		t.Errorf("expected TF_LOG not set by default, got %v", env)
+	}
+	meta.LogLevel = "DEBUG"
+	expected := []v1.EnvVar{{Name: "TF_LOG", Value: "DEBUG"}}
+	if env := meta.assembleTerraformJob(TerraformApply).Spec.Template.Spec.Containers[0].Env; !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected the environment variables %v, got %v", expected, env)
+	}
+}
+
+func TestGitConfigurationArgs(t *testing.T) {
+	meta := &TFConfigurationMeta{
+		Name:                "oss",
+		ConfigurationCMName: "oss-tf-input",
+		RemoteGit:           "https://github.com/org/repo.git",
+		RemoteGitCommit:     "$(touch /tmp/pwned)",
+		RemoteGitPath:       "modules/oss; rm -rf /",
+	}
+	job := meta.assembleTerraformJob(TerraformApply)
+	var command []string
+	for _, c := range job.Spec.Template.Spec.InitContainers {
+		if c.Name == "git-configuration" {
+			command = c.Command
+		}
+	}
+	expected := []string{"sh", "-c", gitConfigurationScript, "git-configuration", "https://github.com/org/repo.git", "",
+		"modules/oss; rm -rf /", BackendVolumeMountPath, WorkingVolumeMountPath}
+	if !reflect.DeepEqual(command, expected) {
+		t.Errorf("expected the user inputs passed as arguments, got %v", command)
+	}
+}
+
+func TestRemoveFinalizer(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	now := metav1.Now()
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{
+		Name:              "oss",
+		Namespace:         "default",
+		DeletionTimestamp: &now,
+		Finalizers:        []string{configurationFinalizer},
+		Annotations:       map[string]string{HoldFinalizerAnnotation: "dns"},
+	}}
+	r := &ConfigurationReconciler{
+		Client:   fake.NewFakeClientWithScheme(s, configuration),
+		Recorder: record.NewFakeRecorder(10),
+	}
+	get := func() *v1beta1.Configuration {
+		var got v1beta1.Configuration
+		if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+			t.Fatal(err)
+		}
+		return &got
+	}
+
+	// the finalizer is held by the annotation after the destroy is recorded
+	for i := 0; i < 2; i++ {
+		if _, err := r.removeFinalizer(ctx, get()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	got := get()
+	if len(got.Finalizers) != 1 || got.Status.Destroy.State != types.ConfigurationDestroyed {
+		t.Fatalf("expected the finalizer held and the Configuration destroyed, got %v, %s", got.Finalizers, got.Status.Destroy.State)
+	}
+
+	// then by the grace period once the annotation is removed
+	delete(got.Annotations, HoldFinalizerAnnotation)
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	r.FinalizerGracePeriod = time.Hour
+	if result, err := r.removeFinalizer(ctx, get()); err != nil || result.RequeueAfter <= 0 || result.RequeueAfter > time.Hour {
+		t.Fatalf("expected to wait for the grace period, got %v, %v", result, err)
+	}
+
+	r.FinalizerGracePeriod = 0
+	if _, err := r.removeFinalizer(ctx, get()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := get(); len(got.Finalizers) != 0 {
+		t.Errorf("expected the finalizer removed, got %v", got.Finalizers)
+	}
+}
+
+func TestBlockDeletion(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	now := metav1.Now()
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{
+		Name:              "oss",
+		Namespace:         "default",
+		DeletionTimestamp: &now,
+		Finalizers:        []string{configurationFinalizer},
+		Annotations:       map[string]string{DeletionProtectionAnnotation: "true"},
+	}}
+	recorder := record.NewFakeRecorder(10)
+	r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, configuration), Recorder: recorder}
+
+	for i := 0; i < 2; i++ {
+		var got v1beta1.Configuration
+		if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+			t.Fatal(err)
+		}
+		result, err := r.blockDeletion(ctx, got)
+		if err != nil || result.RequeueAfter != deletionBlockedRequeueInterval {
+			t.Fatalf("expected the deletion blocked, got %v, %v", result, err)
+		}
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Destroy.State != types.ConfigurationDeletionBlocked || len(got.Finalizers) != 1 {
+		t.Errorf("expected the Configuration DeletionBlocked with its finalizer, got %s, %v", got.Status.Destroy.State, got.Finalizers)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected a single event, got %d", len(recorder.Events))
+	}
+}
+
+func TestUpdateStatusLastSuccessfulOutputs(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Spec:       v1beta1.ConfigurationSpec{HCL: "output \"bucket\" {}"},
+	}
+	state := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-oss", Namespace: controllerNamespace},
+		Data:       map[string][]byte{"tfstate": []byte(`{"outputs":{"bucket":{"value":"oss-bucket","type":"string"}}}`)},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, configuration, state)
+	key := client.ObjectKey{Name: "oss", Namespace: "default"}
+
+	if err := updateStatus(ctx, k8sClient, *configuration, types.Available, MessageCloudResourceDeployed); err != nil {
+		t.Fatal(err)
+	}
+	var got v1beta1.Configuration
+	if err := k8sClient.Get(ctx, key, &got); err != nil {
+		t.Fatal(err)
+	}
+	if output := got.Status.LastSuccessfulOutputs["bucket"]; output.Value != "oss-bucket" || got.Status.LastSuccessfulOutputsTime == nil {
+		t.Fatalf("expected the outputs of the successful apply recorded, got %v", got.Status.LastSuccessfulOutputs)
+	}
+	if got.Status.OutputsCount != 1 || got.Status.Summary != "Available, 1 outputs" {
+		t.Errorf("expected the summary of the available Configuration, got %d %q", got.Status.OutputsCount, got.Status.Summary)
+	}
+
+	// the outputs are cleared when the next apply fails, but the last successful ones are kept
+	if err := updateStatus(ctx, k8sClient, got, types.ConfigurationApplyFailed, "failed"); err != nil {
+		t.Fatal(err)
+	}
+	var failed v1beta1.Configuration
+	if err := k8sClient.Get(ctx, key, &failed); err != nil {
+		t.Fatal(err)
+	}
+	if len(failed.Status.Apply.Outputs) != 0 {
+		t.Errorf("expected the outputs cleared, got %v", failed.Status.Apply.Outputs)
+	}
+	if output := failed.Status.LastSuccessfulOutputs["bucket"]; output.Value != "oss-bucket" {
+		t.Errorf("expected the last successful outputs kept, got %v", failed.Status.LastSuccessfulOutputs)
+	}
+	if failed.Status.OutputsCount != 0 || failed.Status.Summary != "ApplyFailed, Unknown" {
+		t.Errorf("expected the summary of the failed apply, got %d %q", failed.Status.OutputsCount, failed.Status.Summary)
+	}
+}
+
+func TestStatusSummary(t *testing.T) {
+	lastApplied := metav1.NewTime(time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC))
+	configuration := v1beta1.Configuration{Status: v1beta1.ConfigurationStatus{
+		Region: "us-west-2",
+		Apply: v1beta1.ConfigurationApplyStatus{
+			State:           types.Available,
+			Outputs:         map[string]v1beta1.Property{"a": {}, "b": {}, "c": {}},
+			LastAppliedTime: &lastApplied,
+		},
+		Destroy: v1beta1.ConfigurationDestroyStatus{State: types.ConfigurationDestroyFailed, Reason: types.FailureReasonDependencyViolation},
+	}}
+	if got := statusSummary(configuration); got != "Available (us-west-2), 3 outputs, last applied at 2022-01-02T15:04:05Z" {
+		t.Errorf("unexpected summary of the available Configuration %q", got)
+	}
+	configuration.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	if got := statusSummary(configuration); got != "DestroyFailed (us-west-2), DependencyViolation, last applied at 2022-01-02T15:04:05Z" {
+		t.Errorf("unexpected summary of the deleted Configuration %q", got)
+	}
+}
+
+func TestUpdateTerraformJobIfNeededRunningApply(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: v1beta1.ProviderSpec{
+			Provider:    "aws",
+			Region:      "us-east-1",
+			Credentials: v1beta1.ProviderCredentials{Source: crossplane.CredentialsSourceInjectedIdentity},
+		},
+		Status: v1beta1.ProviderStatus{State: types.ProviderIsReady},
+	}
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "default"}}
+	k8sClient := fake.NewFakeClientWithScheme(s, provider, configuration)
+	meta := &TFConfigurationMeta{
+		Name:                  "vpc",
+		Namespace:             controllerNamespace,
+		ApplyJobName:          "vpc-apply",
+		ProviderReference:     &crossplane.Reference{Name: "default", Namespace: "default"},
+		CompleteConfiguration: `resource "aws_vpc" "main" {}`,
+	}
+	envs, err := meta.prepareTFVariables(ctx, k8sClient, configuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.Envs = envs
+	job := meta.assembleTerraformJob(TerraformApply)
+	job.Status.Active = 1
+	if err := k8sClient.Create(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	// the resource is renamed with a moved block while the apply is running, which is left to write the state
+	meta.CompleteConfiguration = `resource "aws_vpc" "this" {}
+moved {
+  from = aws_vpc.main
+  to   = aws_vpc.this
+}`
+	recreated, err := meta.updateTerraformJobIfNeeded(ctx, k8sClient, record.NewFakeRecorder(10), configuration, *job, true)
+	if err != nil || recreated {
+		t.Fatalf("expected the running Job left to finish, got %v, %v", recreated, err)
+	}
+	key := client.ObjectKey{Name: "vpc-apply", Namespace: controllerNamespace}
+	if err := k8sClient.Get(ctx, key, &batchv1.Job{}); err != nil {
+		t.Fatalf("expected the running Job kept, got %v", err)
+	}
+
+	// the Job which applied the previous configuration is re-created once it's finished, though the change has been
+	// stored in the input ConfigMap since
+	job.Status = batchv1.JobStatus{Succeeded: 1}
+	recreated, err = meta.updateTerraformJobIfNeeded(ctx, k8sClient, record.NewFakeRecorder(10), configuration, *job, false)
+	if err != nil || !recreated {
+		t.Fatalf("expected the out-of-date Job re-created, got %v, %v", recreated, err)
+	}
+	if err := k8sClient.Get(ctx, key, &batchv1.Job{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the out-of-date Job deleted, got %v", err)
+	}
+	if job := meta.assembleTerraformJob(TerraformApply); job.Annotations[ConfigurationHashAnnotation] != meta.configurationHash() {
+		t.Errorf("expected the configuration hash annotation, got %v", job.Annotations)
+	}
+}
+
+func TestAssembleTerraformJobDeadlineAndRestartPolicy(t *testing.T) {
+	deadline := int64(1800)
+	meta := &TFConfigurationMeta{
+		Name:                     "oss",
+		JobActiveDeadlineSeconds: &deadline,
+		JobRestartPolicy:         v1.RestartPolicyNever,
+	}
+	for _, executionType := range []TerraformExecutionType{TerraformApply, TerraformDestroy} {
+		job := meta.assembleTerraformJob(executionType)
+		if d := job.Spec.ActiveDeadlineSeconds; d == nil || *d != deadline {
+			t.Errorf("expected the deadline of the %s Job set, got %v", executionType, d)
+		}
+		if job.Spec.Template.Spec.RestartPolicy != v1.RestartPolicyNever || *job.Spec.BackoffLimit != neverRestartBackoffLimit {
+			t.Errorf("expected the failed pods of the %s Job kept, got %s with the backoff limit %d", executionType,
+				job.Spec.Template.Spec.RestartPolicy, *job.Spec.BackoffLimit)
+		}
+	}
+	// the Jobs which aren't retried are left as they are
+	if job := meta.assembleTerraformJob(TerraformPlan); *job.Spec.BackoffLimit != 0 || job.Spec.ActiveDeadlineSeconds == nil {
+		t.Errorf("expected the plan Job not retried with the deadline, got %v", job.Spec)
+	}
+
+	job := (&TFConfigurationMeta{Name: "oss"}).assembleTerraformJob(TerraformApply)
+	if job.Spec.ActiveDeadlineSeconds != nil || job.Spec.Template.Spec.RestartPolicy != v1.RestartPolicyOnFailure {
+		t.Errorf("expected the apply Job restarted without a deadline by default, got %v", job.Spec)
+	}
+}
+
+func TestAssembleAndTriggerJobCreatedByPreviousLeader(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: v1beta1.ProviderSpec{
+			Provider:    "aws",
+			Region:      "us-east-1",
+			Credentials: v1beta1.ProviderCredentials{Source: crossplane.CredentialsSourceInjectedIdentity},
+		},
+		Status: v1beta1.ProviderStatus{State: types.ProviderIsReady},
+	}
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "default"}}
+	// the previous leader created the apply Job, which is running, right before the failover
+	running := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc-apply", Namespace: controllerNamespace, Labels: map[string]string{"leader": "previous"}},
+		Status:     batchv1.JobStatus{Active: 1},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, provider, configuration, running)
+	meta := &TFConfigurationMeta{
+		Name:                  "vpc",
+		Namespace:             controllerNamespace,
+		ApplyJobName:          "vpc-apply",
+		ProviderReference:     &crossplane.Reference{Name: "default", Namespace: "default"},
+		CompleteConfiguration: `resource "aws_vpc" "main" {}`,
+	}
+
+	if err := meta.assembleAndTriggerJob(ctx, k8sClient, configuration, TerraformApply); err != nil {
+		t.Fatalf("expected the existing Job taken as triggered, got %v", err)
+	}
+	var job batchv1.Job
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-apply", Namespace: controllerNamespace}, &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Labels["leader"] != "previous" || job.Status.Active != 1 {
+		t.Errorf("expected the running Job left as it is, got %v %+v", job.Labels, job.Status)
+	}
+}
+
+func TestTerraformValidate(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: v1beta1.ProviderSpec{
+			Provider:    "aws",
+			Region:      "us-east-1",
+			Credentials: v1beta1.ProviderCredentials{Source: crossplane.CredentialsSourceInjectedIdentity},
+		},
+		Status: v1beta1.ProviderStatus{State: types.ProviderIsReady},
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "default"},
+		Spec:       v1beta1.ConfigurationSpec{Validate: true},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, provider, configuration)
+	// the pod of the validate Job failed before it ran `terraform validate`
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc-validate-abc", Namespace: controllerNamespace, Labels: map[string]string{"job-name": "vpc-validate"}},
+		Status: v1.PodStatus{InitContainerStatuses: []v1.ContainerStatus{{
+			Name:  "prepare-input-terraform-configurations",
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Message: "no space left on device"}},
+		}}},
+	}
+	ctx := terraform.WithClientSet(context.Background(), kfake.NewSimpleClientset(pod))
+	r := &ConfigurationReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10)}
+	meta := &TFConfigurationMeta{
+		Name:                  "vpc",
+		Namespace:             controllerNamespace,
+		ValidateJobName:       "vpc-validate",
+		ProviderReference:     &crossplane.Reference{Name: "default", Namespace: "default"},
+		CompleteConfiguration: `resource "aws_vpc" "main" {}`,
+	}
+	key := client.ObjectKey{Name: "vpc-validate", Namespace: controllerNamespace}
+
+	// the validate Job is triggered first, which the apply waits for
+	if err := r.terraformValidate(ctx, *configuration, meta); err == nil || err.Error() != MessageValidateJobNotCompleted {
+		t.Fatalf("expected waiting for the validation, got %v", err)
+	}
+	var job batchv1.Job
+	if err := k8sClient.Get(ctx, key, &job); err != nil {
+		t.Fatalf("expected the validate Job created, got %v", err)
+	}
+	if command := job.Spec.Template.Spec.Containers[0].Command; !reflect.DeepEqual(command, meta.executorCommand(TerraformValidate)) ||
+		*job.Spec.BackoffLimit != 0 {
+		t.Errorf("unexpected validate Job %v, backoff limit %d", command, *job.Spec.BackoffLimit)
+	}
+
+	// the failure is recorded as ValidationFailed along with the output of the Job
+	job.Status.Failed = 1
+	if err := k8sClient.Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	expected := "init container prepare-input-terraform-configurations failed: no space left on device"
+	if err := r.terraformValidate(ctx, *configuration, meta); err == nil || err.Error() != expected {
+		t.Fatalf("expected the validation failed, got %v", err)
+	}
+	var failed v1beta1.Configuration
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "vpc", Namespace: "default"}, &failed); err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status.Apply.State != types.ConfigurationValidationFailed || failed.Status.Apply.Message != expected {
+		t.Errorf("expected the configuration ValidationFailed, got %s: %s", failed.Status.Apply.State, failed.Status.Apply.Message)
+	}
+
+	// the apply proceeds once the validation succeeds
+	job.Status = batchv1.JobStatus{Succeeded: 1}
+	if err := k8sClient.Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.terraformValidate(ctx, *configuration, meta); err != nil {
+		t.Errorf("expected the apply to proceed, got %v", err)
+	}
+}
+
+func TestTriggerPostApplyHooks(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{PostApplyHooks: []v1beta1.Hook{
+			{Name: "register", Image: "cmdb-client", Command: []string{"register"}},
+			{Name: "notify", Image: "curl"},
+		}},
+		Status: v1beta1.ConfigurationStatus{Apply: v1beta1.ConfigurationApplyStatus{
+			State: types.Available,
+			Outputs: map[string]v1beta1.Property{
+				"bucket":   {Value: "oss-bucket", Type: "string"},
+				"password": {Type: "string", Sensitive: true},
+			},
+		}},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s)
+	meta := &TFConfigurationMeta{Namespace: controllerNamespace, PostApplyJobName: "oss-post-apply"}
+	applyJob := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", UID: "apply-1"}, Status: batchv1.JobStatus{Succeeded: 1}}
+	key := client.ObjectKey{Name: "oss-post-apply", Namespace: controllerNamespace}
+
+	if err := meta.triggerPostApplyHooks(ctx, k8sClient, configuration, applyJob); err != nil {
+		t.Fatal(err)
+	}
+	var job batchv1.Job
+	if err := k8sClient.Get(ctx, key, &job); err != nil {
+		t.Fatalf("expected the post-apply Job created, got %v", err)
+	}
+	hooks := job.Spec.Template.Spec.InitContainers
+	if len(hooks) != 2 || hooks[0].Name != "register" || hooks[1].Name != "notify" {
+		t.Fatalf("expected the hooks run in order, got %v", hooks)
+	}
+	expected := []v1.EnvVar{{Name: OutputEnvPrefix + "bucket", Value: "oss-bucket"}}
+	for _, hook := range hooks {
+		if !reflect.DeepEqual(hook.Env, expected) {
+			t.Errorf("expected only the non-sensitive outputs injected into %s, got %v", hook.Name, hook.Env)
+		}
+	}
+
+	// the hooks run once per successful apply Job
+	if err := meta.triggerPostApplyHooks(ctx, k8sClient, configuration, applyJob); err != nil {
+		t.Fatal(err)
+	}
+	if err := k8sClient.Get(ctx, key, &batchv1.Job{}); err != nil {
+		t.Fatalf("expected the post-apply Job of the same apply kept, got %v", err)
+	}
+
+	// another apply runs the hooks again, whose previous Job is replaced
+	applyJob.UID = "apply-2"
+	if err := meta.triggerPostApplyHooks(ctx, k8sClient, configuration, applyJob); err != nil {
+		t.Fatal(err)
+	}
+	if err := k8sClient.Get(ctx, key, &batchv1.Job{}); !kerrors.IsNotFound(err) {
+		t.Fatalf("expected the post-apply Job of the previous apply deleted, got %v", err)
+	}
+	if err := meta.triggerPostApplyHooks(ctx, k8sClient, configuration, applyJob); err != nil {
+		t.Fatal(err)
+	}
+	var rerun batchv1.Job
+	if err := k8sClient.Get(ctx, key, &rerun); err != nil || rerun.Annotations[ApplyJobUIDAnnotation] != "apply-2" {
+		t.Errorf("expected the hooks run again for the new apply, got %v, %v", rerun.Annotations, err)
+	}
+}
+
+func TestWriteTFOutputsSensitive(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "rds", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{
+			WriteConnectionSecretToReference: &v1beta1.ConnectionSecretReference{SecretReference: crossplane.SecretReference{Name: "rds-conn", Namespace: "default"}},
+			WriteOutputsToConfigMapReference: &v1beta1.ConfigMapReference{Name: "rds-outputs", Namespace: "default"},
+			PostApplyHooks:                   []v1beta1.Hook{{Name: "register", Image: "cmdb-client"}},
+		},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s)
+	stateOutputs := map[string]TfStateProperty{
+		"host":     {Value: "rds.example.com", Type: "string"},
+		"password": {Value: "s3cr3t", Type: "string", Sensitive: true},
+	}
+
+	outputs, err := writeTFOutputs(ctx, k8sClient, configuration, stateOutputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password := outputs["password"]; password.Value != "" || !password.Sensitive {
+		t.Errorf("expected the sensitive output redacted in the status, got %+v", password)
+	}
+	if outputs["host"].Value != "rds.example.com" {
+		t.Errorf("expected the non-sensitive output in the status, got %v", outputs)
+	}
+
+	var secret v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "rds-conn", Namespace: "default"}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["password"]) != "s3cr3t" || string(secret.Data["host"]) != "rds.example.com" {
+		t.Errorf("expected the connection secret to keep all the values, got %v", secret.Data)
+	}
+	var cm v1.ConfigMap
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "rds-outputs", Namespace: "default"}, &cm); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cm.Data["password"]; ok || cm.Data["host"] != "rds.example.com" {
+		t.Errorf("expected the sensitive output left out of the outputs ConfigMap, got %v", cm.Data)
+	}
+
+	configuration.Status.Apply.Outputs = outputs
+	meta := &TFConfigurationMeta{Namespace: controllerNamespace, PostApplyJobName: "rds-post-apply"}
+	for _, env := range meta.assemblePostApplyJob(configuration, batchv1.Job{}).Spec.Template.Spec.InitContainers[0].Env {
+		if env.Name == OutputEnvPrefix+"password" || env.Value == "s3cr3t" {
+			t.Errorf("expected the sensitive output left out of the post-apply env, got %v", env)
+		}
+	}
+}
+
+func TestEscalateDestroyTimeout(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	key := client.ObjectKey{Name: "oss", Namespace: "default"}
+	configuration := func(deleted time.Duration, forceDelete bool) *v1beta1.Configuration {
+		return &v1beta1.Configuration{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace,
+				DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-deleted)}},
+			Spec: v1beta1.ConfigurationSpec{DestroyTimeout: &metav1.Duration{Duration: time.Hour}, ForceDelete: forceDelete},
+		}
+	}
+	meta := &TFConfigurationMeta{Name: key.Name, Namespace: controllerNamespace, DestroyJobName: "oss-destroy"}
+	destroyErr := errors.New(MessageDestroyJobNotCompleted)
+
+	testcases := map[string]struct {
+		configuration *v1beta1.Configuration
+		forceDeleted  bool
+		reason        string
+	}{
+		"before the deadline": {
+			configuration: configuration(time.Minute, true),
+		},
+		"after the deadline": {
+			configuration: configuration(2*time.Hour, false),
+			reason:        ReasonDestroyTimeout,
+		},
+		"after the deadline with forceDelete": {
+			configuration: configuration(2*time.Hour, true),
+			forceDeleted:  true,
+			reason:        ReasonForceDeleting,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			destroyJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "oss-destroy", Namespace: controllerNamespace,
+				Labels: map[string]string{LabelKeyOwnedBy: key.Name, LabelKeyOwnedNamespace: key.Namespace}}}
+			recorder := record.NewFakeRecorder(10)
+			r := &ConfigurationReconciler{Client: fake.NewFakeClientWithScheme(s, tc.configuration, destroyJob), Recorder: recorder}
+
+			// the escalation is retried by the requeued reconciliations, which send the event only once
+			for i := 0; i < 2; i++ {
+				forceDeleted, err := r.escalateDestroyTimeout(ctx, *tc.configuration, meta, destroyErr)
+				if err != nil || forceDeleted != tc.forceDeleted {
+					t.Fatalf("expected force deleted %v, got %v, %v", tc.forceDeleted, forceDeleted, err)
+				}
+			}
+			events := len(recorder.Events)
+			if (tc.reason == "" && events != 0) || (tc.reason != "" && events != 1) {
+				t.Errorf("expected the event sent once after the deadline, got %d", events)
+			}
+			var got v1beta1.Configuration
+			if err := r.Get(ctx, key, &got); err != nil {
+				t.Fatal(err)
+			}
+			condition := got.Status.GetCondition(v1beta1.ConditionDestroyTimedOut)
+			if tc.reason == "" && condition != nil {
+				t.Errorf("expected no DestroyTimedOut condition before the deadline, got %v", condition)
+			}
+			if tc.reason != "" && (condition == nil || condition.Status != v1.ConditionTrue || condition.Reason != tc.reason) {
+				t.Errorf("expected the DestroyTimedOut condition %s, got %v", tc.reason, condition)
+			}
+			deleted := kerrors.IsNotFound(r.Get(ctx, client.ObjectKey{Name: "oss-destroy", Namespace: controllerNamespace}, &batchv1.Job{}))
+			if deleted != tc.forceDeleted {
+				t.Errorf("expected the sub-resources cleaned up only when force deleted, got %v", deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	state "github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+const (
+	// dependsOnIndex indexes Configurations by the namespaced names of the Configurations they depend on
+	dependsOnIndex = "spec.dependsOn"
+	// dependencyRequeueInterval is how often the dependencies are checked again, besides being triggered by the
+	// changes of the Configurations depended on
+	dependencyRequeueInterval = 30 * time.Second
+)
+
+// dependencies returns the namespaced names of the Configurations which a Configuration depends on, including the
+// ones whose outputs are referenced by spec.variableFrom
+func dependencies(configuration *v1beta1.Configuration) []types.NamespacedName {
+	var (
+		deps []types.NamespacedName
+		seen = make(map[types.NamespacedName]bool)
+	)
+	add := func(name, namespace string) {
+		if namespace == "" {
+			namespace = configuration.Namespace
+		}
+		dep := types.NamespacedName{Namespace: namespace, Name: name}
+		if !seen[dep] {
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+	}
+	for _, ref := range configuration.Spec.DependsOn {
+		add(ref.Name, ref.Namespace)
+	}
+	for _, ref := range configuration.Spec.VariableFrom {
+		add(ref.Configuration, ref.Namespace)
+	}
+	return deps
+}
+
+// variableFromProducer returns the namespaced name of the Configuration producing the output referenced by ref
+func variableFromProducer(configuration *v1beta1.Configuration, ref v1beta1.VariableFromOutput) types.NamespacedName {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = configuration.Namespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: ref.Configuration}
+}
+
+// indexConfigurationByDependencies returns the Configurations which a Configuration depends on
+func indexConfigurationByDependencies(obj runtime.Object) []string {
+	configuration, ok := obj.(*v1beta1.Configuration)
+	if !ok {
+		return nil
+	}
+	var keys []string
+	for _, dep := range dependencies(configuration) {
+		keys = append(keys, dep.String())
+	}
+	return keys
+}
+
+// checkDependencies returns the Configurations a Configuration depends on which are not available yet
+func (r *ConfigurationReconciler) checkDependencies(ctx context.Context, configuration *v1beta1.Configuration) ([]string, error) {
+	var unavailable []string
+	for _, dep := range dependencies(configuration) {
+		var c v1beta1.Configuration
+		if err := r.Get(ctx, dep, &c); err != nil {
+			if kerrors.IsNotFound(err) {
+				unavailable = append(unavailable, dep.String()+" (not found)")
+				continue
+			}
+			return nil, err
+		}
+		if c.Status.Apply.State != state.Available || !c.DeletionTimestamp.IsZero() {
+			unavailable = append(unavailable, dep.String())
+			continue
+		}
+		for _, ref := range configuration.Spec.VariableFrom {
+			if variableFromProducer(configuration, ref) != dep {
+				continue
+			}
+			if _, ok := c.Status.Apply.Outputs[ref.Output]; !ok {
+				unavailable = append(unavailable, fmt.Sprintf("%s (output %s not found)", dep, ref.Output))
+			}
+		}
+	}
+	return unavailable, nil
+}
+
+// resolveVariablesFrom returns the values of the variables set by spec.variableFrom, keyed by the environment
+// variables of Terraform. The outputs are only read from the namespace of the Configuration, and so are the connection
+// secrets keeping the sensitive ones.
+func resolveVariablesFrom(ctx context.Context, k8sClient client.Client, configuration *v1beta1.Configuration) (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, ref := range configuration.Spec.VariableFrom {
+		producer := variableFromProducer(configuration, ref)
+		if producer.Namespace != configuration.Namespace {
+			return nil, errors.Errorf("variable %s can't be from Configuration %s, which isn't in the namespace %s",
+				ref.Var, producer, configuration.Namespace)
+		}
+		var c v1beta1.Configuration
+		if err := k8sClient.Get(ctx, producer, &c); err != nil {
+			return nil, errors.Wrapf(err, "failed to get Configuration %s which variable %s is from", producer, ref.Var)
+		}
+		if c.Status.Apply.State != state.Available {
+			return nil, errors.Errorf("Configuration %s which variable %s is from is not available", producer, ref.Var)
+		}
+		output, ok := c.Status.Apply.Outputs[ref.Output]
+		if !ok {
+			return nil, errors.Errorf("output %s of Configuration %s is not found", ref.Output, producer)
+		}
+		value := output.Value
+		if output.Sensitive {
+			// sensitive values are redacted in the status, and only written to the connection secret
+			secretRef := c.Spec.WriteConnectionSecretToReference
+			if secretRef == nil || secretRef.Name == "" {
+				return nil, errors.Errorf("output %s of Configuration %s is sensitive, but it has no connection secret",
+					ref.Output, producer)
+			}
+			namespace := secretRef.Namespace
+			if namespace == "" {
+				namespace = "default"
+			}
+			if namespace != configuration.Namespace {
+				return nil, errors.Errorf("output %s of Configuration %s is sensitive, but its connection secret isn't in the namespace %s",
+					ref.Output, producer, configuration.Namespace)
+			}
+			var secret v1.Secret
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: secretRef.Name, Namespace: namespace}, &secret); err != nil {
+				return nil, errors.Wrapf(err, "failed to get the connection secret of Configuration %s", producer)
+			}
+			data, ok := secret.Data[secretRef.SecretKey(ref.Output)]
+			if !ok {
+				return nil, errors.Errorf("output %s is not found in the connection secret %s/%s", ref.Output, namespace,
+					secretRef.Name)
+			}
+			value = string(data)
+		}
+		variables[fmt.Sprintf("TF_VAR_%s", ref.Var)] = value
+	}
+	return variables, nil
+}
+
+// waitForDependencies returns true if some Configurations the Configuration depends on are not available yet, in which
+// case it's not applied and its status shows what it's waiting for
+func (r *ConfigurationReconciler) waitForDependencies(ctx context.Context, configuration *v1beta1.Configuration) (bool, error) {
+	unavailable, err := r.checkDependencies(ctx, configuration)
+	if err != nil || len(unavailable) == 0 {
+		return false, err
+	}
+	message := fmt.Sprintf("Waiting for the Configurations to be available: %s", strings.Join(unavailable, ", "))
+	klog.InfoS(message, "Namespace", configuration.Namespace, "Name", configuration.Name)
+	if configuration.Status.Apply.State != state.ConfigurationWaitingForDependencies || configuration.Status.Apply.Message != message {
+		if err := updateStatus(ctx, r.Client, *configuration, state.ConfigurationWaitingForDependencies, message); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// setupDependencyWatches lets a Configuration becoming available trigger the reconciliation of the Configurations
+// depending on it
+func (r *ConfigurationReconciler) setupDependencyWatches(mgr ctrl.Manager, blder *ctrl.Builder) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1beta1.Configuration{}, dependsOnIndex, indexConfigurationByDependencies); err != nil {
+		return err
+	}
+	blder.Watches(&source.Kind{Type: &v1beta1.Configuration{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			return r.dependentConfigurations(context.Background(), types.NamespacedName{Namespace: o.Meta.GetNamespace(), Name: o.Meta.GetName()})
+		}),
+	})
+	return nil
+}
+
+func (r *ConfigurationReconciler) dependentConfigurations(ctx context.Context, dependency types.NamespacedName) []reconcile.Request {
+	var configurations v1beta1.ConfigurationList
+	if err := r.List(ctx, &configurations, client.MatchingFields{dependsOnIndex: dependency.String()}); err != nil {
+		klog.ErrorS(err, "failed to list the Configurations depending on Configuration", "Configuration", dependency)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(configurations.Items))
+	for _, c := range configurations.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: c.Namespace, Name: c.Name}})
+	}
+	return requests
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+// ExecutorVolumeObjectName is the name of the copy of a Secret or ConfigMap of spec.volumes in the namespace of the
+// Jobs, by the name of the Configuration and the volume
+const ExecutorVolumeObjectName = "%s-tf-volume-%s"
+
+// loadExecutorVolumes reads the Secrets and ConfigMaps of spec.volumes in the namespace of the Configuration, which are
+// copied to the namespace of the Jobs and mounted from there. They are never mounted from the namespace of the Jobs by
+// their names, as it's shared by the Configurations of all the namespaces, whose states and var files are kept in it.
+// The missing optional ones are skipped.
+func (meta *TFConfigurationMeta) loadExecutorVolumes(ctx context.Context, k8sClient client.Client, configuration *v1beta1.Configuration) error {
+	meta.executorVolumeSecrets, meta.executorVolumeConfigMaps = nil, nil
+	for _, v := range configuration.Spec.Volumes {
+		objectMeta := metav1.ObjectMeta{
+			Name:            meta.executorVolumeObjectName(v.Name),
+			Namespace:       meta.Namespace,
+			OwnerReferences: meta.OwnerReferences,
+			Labels:          meta.Labels,
+		}
+		switch {
+		case v.Secret != nil:
+			var secret v1.Secret
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: v.Secret.SecretName, Namespace: configuration.Namespace}, &secret); err != nil {
+				if kerrors.IsNotFound(err) && v.Secret.Optional != nil && *v.Secret.Optional {
+					continue
+				}
+				return errors.Wrapf(err, "failed to get the Secret %s of the volume %s", v.Secret.SecretName, v.Name)
+			}
+			meta.executorVolumeSecrets = append(meta.executorVolumeSecrets, v1.Secret{
+				ObjectMeta: objectMeta,
+				Type:       v1.SecretTypeOpaque,
+				Data:       secret.Data,
+			})
+		case v.ConfigMap != nil:
+			var cm v1.ConfigMap
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: v.ConfigMap.Name, Namespace: configuration.Namespace}, &cm); err != nil {
+				if kerrors.IsNotFound(err) && v.ConfigMap.Optional != nil && *v.ConfigMap.Optional {
+					continue
+				}
+				return errors.Wrapf(err, "failed to get the ConfigMap %s of the volume %s", v.ConfigMap.Name, v.Name)
+			}
+			meta.executorVolumeConfigMaps = append(meta.executorVolumeConfigMaps, v1.ConfigMap{
+				ObjectMeta: objectMeta,
+				Data:       cm.Data,
+				BinaryData: cm.BinaryData,
+			})
+		}
+	}
+	return nil
+}
+
+// storeExecutorVolumes keeps the copies of the Secrets and ConfigMaps of spec.volumes in the namespace of the Jobs.
+// They are updated only when they're changed, and the ones no longer copied are deleted.
+func (meta *TFConfigurationMeta) storeExecutorVolumes(ctx context.Context, k8sClient client.Client) error {
+	copied := make(map[string]bool)
+	for i := range meta.executorVolumeSecrets {
+		desired := meta.executorVolumeSecrets[i].DeepCopy()
+		copied["Secret/"+desired.Name] = true
+		var secret v1.Secret
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: desired.Name, Namespace: desired.Namespace}, &secret)
+		switch {
+		case kerrors.IsNotFound(err):
+			if err := k8sClient.Create(ctx, desired); err != nil {
+				return errors.Wrapf(err, "failed to create the Secret %s of the volume", desired.Name)
+			}
+		case err != nil:
+			return err
+		case !reflect.DeepEqual(secret.Data, desired.Data):
+			secret.Data = desired.Data
+			secret.Labels = mergeMaps(secret.Labels, meta.Labels)
+			if err := k8sClient.Update(ctx, &secret); err != nil {
+				return errors.Wrapf(err, "failed to update the Secret %s of the volume", desired.Name)
+			}
+		}
+	}
+	for i := range meta.executorVolumeConfigMaps {
+		desired := meta.executorVolumeConfigMaps[i].DeepCopy()
+		copied["ConfigMap/"+desired.Name] = true
+		var cm v1.ConfigMap
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: desired.Name, Namespace: desired.Namespace}, &cm)
+		switch {
+		case kerrors.IsNotFound(err):
+			if err := k8sClient.Create(ctx, desired); err != nil {
+				return errors.Wrapf(err, "failed to create the ConfigMap %s of the volume", desired.Name)
+			}
+		case err != nil:
+			return err
+		case !reflect.DeepEqual(cm.Data, desired.Data) || !reflect.DeepEqual(cm.BinaryData, desired.BinaryData):
+			cm.Data, cm.BinaryData = desired.Data, desired.BinaryData
+			cm.Labels = mergeMaps(cm.Labels, meta.Labels)
+			if err := k8sClient.Update(ctx, &cm); err != nil {
+				return errors.Wrapf(err, "failed to update the ConfigMap %s of the volume", desired.Name)
+			}
+		}
+	}
+
+	// the copies of the volumes removed from spec.volumes, or whose optional sources are gone
+	prefix := meta.executorVolumeObjectName("")
+	var (
+		secrets    v1.SecretList
+		configMaps v1.ConfigMapList
+	)
+	for _, list := range []runtime.Object{&secrets, &configMaps} {
+		if err := k8sClient.List(ctx, list, client.InNamespace(meta.Namespace), client.MatchingLabels(meta.Labels)); err != nil {
+			return err
+		}
+	}
+	var stale []subResource
+	for i := range secrets.Items {
+		if name := secrets.Items[i].Name; strings.HasPrefix(name, prefix) && !copied["Secret/"+name] {
+			stale = append(stale, &secrets.Items[i])
+		}
+	}
+	for i := range configMaps.Items {
+		if name := configMaps.Items[i].Name; strings.HasPrefix(name, prefix) && !copied["ConfigMap/"+name] {
+			stale = append(stale, &configMaps.Items[i])
+		}
+	}
+	for _, obj := range stale {
+		klog.InfoS("deleting the copy of the volume", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+		if err := k8sClient.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// executorVolumeObjectName is the name of the copy of the Secret or ConfigMap of a volume of spec.volumes
+func (meta *TFConfigurationMeta) executorVolumeObjectName(volume string) string {
+	return fmt.Sprintf(ExecutorVolumeObjectName, meta.Name, volume)
+}
+
+// executorVolumeSource is the source of a volume of spec.volumes, which is the copy of its Secret or ConfigMap
+func (meta *TFConfigurationMeta) executorVolumeSource(v v1beta1.ExecutorVolume) v1.VolumeSource {
+	var source v1.VolumeSource
+	if v.Secret != nil {
+		source.Secret = v.Secret.DeepCopy()
+		source.Secret.SecretName = meta.executorVolumeObjectName(v.Name)
+	}
+	if v.ConfigMap != nil {
+		source.ConfigMap = v.ConfigMap.DeepCopy()
+		source.ConfigMap.Name = meta.executorVolumeObjectName(v.Name)
+	}
+	return source
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestExecutorVolumes(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	optional := true
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "team-a"},
+		Spec: v1beta1.ConfigurationSpec{Volumes: []v1beta1.ExecutorVolume{
+			{Name: "ca-bundle", MountPath: "/etc/ssl/custom", Secret: &v1.SecretVolumeSource{SecretName: "tfstate-default-vpc"}},
+			{Name: "kubeconfig", MountPath: "/etc/kube", ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: "kubeconfig"}}},
+			{Name: "extra", MountPath: "/etc/extra", Secret: &v1.SecretVolumeSource{SecretName: "extra", Optional: &optional}},
+		}},
+	}
+	// the Secret of the same name in the namespace of the Jobs, like the state of another Configuration, isn't mounted
+	shared := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-vpc", Namespace: controllerNamespace},
+		Data:       map[string][]byte{"tfstate": []byte("state of others")},
+	}
+	own := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-vpc", Namespace: "team-a"},
+		Data:       map[string][]byte{"ca.crt": []byte("bundle")},
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubeconfig", Namespace: "team-a"},
+		Data:       map[string]string{"config": "apiVersion: v1"},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, shared, own, cm)
+	labels := map[string]string{LabelKeyOwnedBy: "vpc"}
+	meta := &TFConfigurationMeta{Name: "vpc", Namespace: controllerNamespace, Labels: labels, ExecutorVolumes: configuration.Spec.Volumes}
+
+	if err := meta.loadExecutorVolumes(ctx, k8sClient, configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := meta.storeExecutorVolumes(ctx, k8sClient); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var copied v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-tf-volume-ca-bundle", Namespace: controllerNamespace}, &copied); err != nil {
+		t.Fatal(err)
+	}
+	if string(copied.Data["ca.crt"]) != "bundle" || copied.Data["tfstate"] != nil {
+		t.Errorf("expected the Secret copied from the namespace of the Configuration, got %v", copied.Data)
+	}
+	var copiedCM v1.ConfigMap
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-tf-volume-kubeconfig", Namespace: controllerNamespace}, &copiedCM); err != nil {
+		t.Fatal(err)
+	}
+	if copiedCM.Data["config"] != "apiVersion: v1" {
+		t.Errorf("expected the ConfigMap copied, got %v", copiedCM.Data)
+	}
+	var missing v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-tf-volume-extra", Namespace: controllerNamespace}, &missing); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the missing optional Secret skipped, got %v", err)
+	}
+
+	// the Jobs mount the copies only
+	for _, volume := range meta.assembleExecutorVolumes() {
+		if volume.Secret != nil && volume.Secret.SecretName == "tfstate-default-vpc" {
+			t.Errorf("expected the copy mounted rather than the Secret of the namespace of the Jobs, got %v", volume)
+		}
+	}
+
+	// the copies of the removed volumes are deleted
+	configuration.Spec.Volumes = configuration.Spec.Volumes[1:]
+	if err := meta.loadExecutorVolumes(ctx, k8sClient, configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := meta.storeExecutorVolumes(ctx, k8sClient); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var removed v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-tf-volume-ca-bundle", Namespace: controllerNamespace}, &removed); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the copy of the removed volume deleted, got %v", err)
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "tfstate-default-vpc", Namespace: controllerNamespace}, &removed); err != nil {
+		t.Errorf("expected the Secret of the namespace of the Jobs untouched, got %v", err)
+	}
+
+	// a missing required Secret fails
+	configuration.Spec.Volumes = []v1beta1.ExecutorVolume{{Name: "gone", MountPath: "/etc/gone", Secret: &v1.SecretVolumeSource{SecretName: "gone"}}}
+	if err := meta.loadExecutorVolumes(ctx, k8sClient, configuration); err == nil {
+		t.Error("expected an error for the missing Secret")
+	}
+}
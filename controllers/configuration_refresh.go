@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/terraform"
+)
+
+// RefreshAnnotation refreshes the state by `terraform apply -refresh-only` and reads the outputs again, without
+// changing the cloud resources, e.g. after they're changed out-of-band. It's removed once the refresh finishes, whose
+// result is recorded in status.refresh. It needs Terraform 0.15.4 or later.
+const RefreshAnnotation = "terraform.core.oam.dev/refresh"
+
+const (
+	// ReasonRefreshed is the event reason when the state and the outputs are refreshed
+	ReasonRefreshed = "Refreshed"
+	// ReasonRefreshFailed is the event reason when the state or the outputs failed to be refreshed
+	ReasonRefreshFailed = "RefreshFailed"
+)
+
+// reconcileRefresh refreshes the state by a Job when the refresh annotation is set, and updates the outputs after it
+// succeeds. It returns true while the refresh is in progress, during which neither apply nor destroy runs, and it
+// waits for the running apply or destroy, as the state isn't locked.
+func (r *ConfigurationReconciler) reconcileRefresh(ctx context.Context, configuration *v1beta1.Configuration,
+	meta *TFConfigurationMeta) (bool, error) {
+	if _, ok := configuration.Annotations[RefreshAnnotation]; !ok {
+		return false, nil
+	}
+
+	var job batchv1.Job
+	if err := clusterClient(ctx, r.Client).Get(ctx, client.ObjectKey{Name: meta.RefreshJobName, Namespace: meta.Namespace}, &job); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return true, err
+		}
+		if busy, err := r.stopJobsForStateChange(ctx, *configuration, meta); err != nil || busy {
+			return true, err
+		}
+		klog.InfoS("refreshing the state", "Namespace", configuration.Namespace, "Name", configuration.Name)
+		return true, meta.assembleAndTriggerJob(ctx, r.Client, configuration, TerraformRefresh)
+	}
+
+	var refreshErr error
+	switch {
+	case job.Status.Succeeded > 0:
+		outputs, err := readTFOutputs(ctx, r.Client, *configuration, meta.RefreshJobName)
+		if err != nil {
+			refreshErr = errors.Wrap(err, "failed to read the outputs")
+		} else {
+			configuration.Status.Apply.Outputs = outputs
+		}
+	case job.Status.Failed > 0:
+		refreshErr = errors.New("terraform apply -refresh-only failed")
+		if _, logErr := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.RefreshJobName); logErr != nil {
+			refreshErr = logErr
+		}
+	default:
+		return true, nil
+	}
+	if err := r.finishRefresh(ctx, configuration, refreshErr); err != nil {
+		return true, err
+	}
+	return false, deleteJob(ctx, clusterClient(ctx, r.Client), meta.RefreshJobName, meta.Namespace)
+}
+
+// finishRefresh records the result of the refresh in the status and an event, and removes the annotation
+func (r *ConfigurationReconciler) finishRefresh(ctx context.Context, configuration *v1beta1.Configuration, refreshErr error) error {
+	record := &v1beta1.RefreshRecord{Time: metav1.Now(), Succeeded: refreshErr == nil}
+	if refreshErr != nil {
+		record.Message = refreshErr.Error()
+		klog.ErrorS(refreshErr, "failed to refresh the state", "Namespace", configuration.Namespace, "Name", configuration.Name)
+		r.Recorder.Event(configuration, v1.EventTypeWarning, ReasonRefreshFailed,
+			fmt.Sprintf("Failed to refresh the state: %s", refreshErr.Error()))
+	} else {
+		klog.InfoS("refreshed the state", "Namespace", configuration.Namespace, "Name", configuration.Name)
+		r.Recorder.Event(configuration, v1.EventTypeNormal, ReasonRefreshed, "Refreshed the state and the outputs")
+	}
+	configuration.Status.Refresh = record
+	if err := r.Status().Update(ctx, configuration); err != nil {
+		return err
+	}
+	delete(configuration.Annotations, RefreshAnnotation)
+	return r.Update(ctx, configuration)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestReconcileRefresh(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	meta := &TFConfigurationMeta{
+		Namespace:      controllerNamespace,
+		ApplyJobName:   "oss-apply",
+		DestroyJobName: "oss-destroy",
+		RefreshJobName: "oss-refresh",
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "oss",
+			Namespace:   "default",
+			Annotations: map[string]string{RefreshAnnotation: "true"},
+		},
+		Spec: v1beta1.ConfigurationSpec{HCL: "output \"bucket\" {}"},
+	}
+	applyJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", Namespace: controllerNamespace},
+		Status:     batchv1.JobStatus{Active: 1},
+	}
+	state := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-oss", Namespace: controllerNamespace},
+		Data:       map[string][]byte{"tfstate": []byte(`{"outputs":{"bucket":{"value":"oss-bucket","type":"string"}}}`)},
+	}
+	r := &ConfigurationReconciler{
+		Client:   fake.NewFakeClientWithScheme(s, configuration, applyJob, state),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	// the refresh waits for the running apply
+	if refreshing, err := r.reconcileRefresh(ctx, configuration, meta); err != nil || !refreshing {
+		t.Fatalf("expected to wait for the apply, got %v, %v", refreshing, err)
+	}
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss-refresh", Namespace: controllerNamespace}, &batchv1.Job{}); !kerrors.IsNotFound(err) {
+		t.Fatalf("expected no refresh Job while the apply is running, got %v", err)
+	}
+
+	refreshJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss-refresh", Namespace: controllerNamespace},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	if err := r.Create(ctx, refreshJob); err != nil {
+		t.Fatal(err)
+	}
+	if refreshing, err := r.reconcileRefresh(ctx, configuration, meta); err != nil || refreshing {
+		t.Fatalf("expected the refresh finished, got %v, %v", refreshing, err)
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.Annotations[RefreshAnnotation]; ok {
+		t.Error("expected the annotation removed")
+	}
+	if record := got.Status.Refresh; record == nil || !record.Succeeded {
+		t.Errorf("expected the succeeded refresh recorded, got %v", record)
+	}
+	if output := got.Status.Apply.Outputs["bucket"]; output.Value != "oss-bucket" {
+		t.Errorf("expected the outputs read again, got %v", got.Status.Apply.Outputs)
+	}
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss-refresh", Namespace: controllerNamespace}, &batchv1.Job{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the refresh Job deleted, got %v", err)
+	}
+}
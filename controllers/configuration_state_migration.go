@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/backend"
+	cfgvalidator "github.com/oam-dev/terraform-controller/controllers/configuration"
+	"github.com/oam-dev/terraform-controller/controllers/terraform"
+)
+
+// StateMigrationHashAnnotation records the hash of the backends which the migrate-state Job migrates the state
+// between, which tells whether the Job is out of date after spec.backend is changed again
+const StateMigrationHashAnnotation = "terraform.core.oam.dev/state-migration-hash"
+
+// previousBackendOverrideFile is the override file of the previous backend in the working directory of the
+// migrate-state Job
+const previousBackendOverrideFile = "zz_previous_backend_override.tf"
+
+const (
+	// ReasonStateMigrated is the event reason when the state is migrated to the new backend
+	ReasonStateMigrated = "StateMigrated"
+	// ReasonStateMigrationFailed is the event reason when the state failed to be migrated to the new backend
+	ReasonStateMigrationFailed = "StateMigrationFailed"
+)
+
+// reconcileStateMigration migrates the state by a Job when spec.backend differs from the backend recorded in the
+// status, which is switched to the new one once the migration succeeds. It returns true while the migration is in
+// progress or has failed, during which neither apply nor destroy runs, as they'd start over with an empty state. The
+// state left in the previous backend isn't deleted, and the state in the new backend, if any, is replaced.
+func (r *ConfigurationReconciler) reconcileStateMigration(ctx context.Context, configuration *v1beta1.Configuration,
+	meta *TFConfigurationMeta) (bool, error) {
+	// the backend of a JSON configuration is a part of it, which the controller doesn't render
+	if meta.ConfigurationType == types.ConfigurationJSON {
+		return false, nil
+	}
+	defaulted := configuration.DeepCopy()
+	cfgvalidator.SetDefaults(defaulted)
+	current := defaulted.Spec.Backend
+	if configuration.Status.Backend == nil {
+		// the state is stored in the current backend since the first Job
+		configuration.Status.Backend = current
+		return false, r.Status().Update(ctx, configuration)
+	}
+	previous := configuration.Status.Backend
+
+	previousHCL, err := backendHCL(previous, meta.Namespace)
+	if err != nil {
+		return false, err
+	}
+	currentHCL, err := backendHCL(current, meta.Namespace)
+	if err != nil {
+		return false, err
+	}
+	k8sClient := clusterClient(ctx, r.Client)
+	if previousHCL == currentHCL {
+		// the Job left after a failure is no longer needed once spec.backend is changed back
+		return false, deleteJob(ctx, k8sClient, meta.MigrateStateJobName, meta.Namespace)
+	}
+	meta.PreviousBackendHCL = previousHCL
+	hash := stateMigrationHash(previousHCL, currentHCL)
+	from, to := backendType(previous), backendType(current)
+
+	var job batchv1.Job
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.MigrateStateJobName, Namespace: meta.Namespace}, &job); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return true, err
+		}
+		if busy, err := r.stopJobsForStateChange(ctx, *configuration, meta); err != nil || busy {
+			return true, err
+		}
+		klog.InfoS("migrating the state", "Namespace", configuration.Namespace, "Name", configuration.Name, "From", from, "To", to)
+		return true, r.triggerStateMigration(ctx, defaulted, meta, previous, hash)
+	}
+	if job.Annotations[StateMigrationHashAnnotation] != hash {
+		// the migration to the backend which spec.backend was changed from is stopped, and started over
+		return true, deleteJob(ctx, k8sClient, meta.MigrateStateJobName, meta.Namespace)
+	}
+
+	switch {
+	case job.Status.Succeeded > 0:
+		err = nil
+	case job.Status.Failed > 0:
+		if record := configuration.Status.StateMigration; record != nil && !record.Succeeded &&
+			!record.Time.Before(&job.CreationTimestamp) {
+			// the failure is recorded, and the Job is kept until spec.backend is changed, or it's deleted to retry
+			return true, nil
+		}
+		err = errors.New("terraform init -migrate-state failed")
+		if _, logErr := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.MigrateStateJobName); logErr != nil {
+			err = logErr
+		}
+	default:
+		return true, nil
+	}
+	if err := r.finishStateMigration(ctx, configuration, from, to, err); err != nil || !configuration.Status.StateMigration.Succeeded {
+		return true, err
+	}
+	return false, deleteJob(ctx, k8sClient, meta.MigrateStateJobName, meta.Namespace)
+}
+
+// triggerStateMigration creates the migrate-state Job, which has the environment variables of both backends. The ones
+// of the current backend take precedence when both backends use the same variables.
+func (r *ConfigurationReconciler) triggerStateMigration(ctx context.Context, configuration *v1beta1.Configuration,
+	meta *TFConfigurationMeta, previous *v1beta1.Backend, hash string) error {
+	envs, err := meta.prepareTFVariables(ctx, r.Client, configuration)
+	if err != nil {
+		return err
+	}
+	b, err := newBackend(ctx, r.Client, *configuration, previous)
+	if err != nil {
+		return errors.Wrap(err, "failed to access the backend which the state is migrated from")
+	}
+	previousEnvs, err := b.Envs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to access the backend which the state is migrated from")
+	}
+	set := make(map[string]bool, len(envs))
+	for _, env := range envs {
+		set[env.Name] = true
+	}
+	for k, v := range previousEnvs {
+		if !set[k] {
+			envs = append(envs, v1.EnvVar{Name: k, Value: v})
+		}
+	}
+	meta.Envs = envs
+
+	job := meta.assembleTerraformJob(TerraformMigrateState)
+	job.Annotations = mergeMaps(job.Annotations, map[string]string{StateMigrationHashAnnotation: hash})
+	return createJob(ctx, clusterClient(ctx, r.Client), job)
+}
+
+// finishStateMigration records the result of the migration in the status and an event. The backend in the status is
+// switched to the current one once the migration succeeds, and the apply or destroy fails until then.
+func (r *ConfigurationReconciler) finishStateMigration(ctx context.Context, configuration *v1beta1.Configuration, from, to string,
+	migrationErr error) error {
+	record := &v1beta1.StateMigrationRecord{From: from, To: to, Time: metav1.Now(), Succeeded: migrationErr == nil}
+	configuration.Status.StateMigration = record
+	if migrationErr != nil {
+		record.Message = migrationErr.Error()
+		klog.ErrorS(migrationErr, "failed to migrate the state", "Namespace", configuration.Namespace, "Name", configuration.Name)
+		message := fmt.Sprintf("Failed to migrate the state from the %s backend to the %s backend: %s", from, to, migrationErr.Error())
+		r.Recorder.Event(configuration, v1.EventTypeWarning, ReasonStateMigrationFailed, message)
+		state := types.ConfigurationApplyFailed
+		if !configuration.DeletionTimestamp.IsZero() {
+			state = types.ConfigurationDestroyFailed
+		}
+		return updateStatus(ctx, r.Client, *configuration, state, message)
+	}
+	klog.InfoS("migrated the state", "Namespace", configuration.Namespace, "Name", configuration.Name, "From", from, "To", to)
+	r.Recorder.Event(configuration, v1.EventTypeNormal, ReasonStateMigrated,
+		fmt.Sprintf("Migrated the state from the %s backend to the %s backend", from, to))
+	defaulted := configuration.DeepCopy()
+	cfgvalidator.SetDefaults(defaulted)
+	configuration.Status.Backend = defaulted.Spec.Backend
+	return r.Status().Update(ctx, configuration)
+}
+
+// backendHCL renders the backend block of a backend, which tells whether the state is stored elsewhere
+func backendHCL(conf *v1beta1.Backend, namespace string) (string, error) {
+	b, err := backend.New(nil, conf, namespace, nil)
+	if err != nil {
+		return "", err
+	}
+	return b.HCL()
+}
+
+// backendType returns the type of a backend, which is kubernetes by default
+func backendType(conf *v1beta1.Backend) string {
+	if conf.BackendType == "" {
+		return backend.TypeKubernetes
+	}
+	return conf.BackendType
+}
+
+// stateMigrationHash hashes the backends which the state is migrated between
+func stateMigrationHash(previousHCL, currentHCL string) string {
+	h := sha256.Sum256([]byte(previousHCL + "\x00" + currentHCL))
+	return hex.EncodeToString(h[:])
+}
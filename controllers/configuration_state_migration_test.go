@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/backend"
+	"github.com/oam-dev/terraform-controller/controllers/runner"
+)
+
+func TestReconcileStateMigration(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	meta := &TFConfigurationMeta{
+		Namespace:           controllerNamespace,
+		ConfigurationType:   types.ConfigurationHCL,
+		ApplyJobName:        "oss-apply",
+		DestroyJobName:      "oss-destroy",
+		RefreshJobName:      "oss-refresh",
+		MigrateStateJobName: "oss-migrate-state",
+	}
+	previous := &v1beta1.Backend{SecretSuffix: "oss", InClusterConfig: true}
+	current := &v1beta1.Backend{BackendType: backend.TypeCustom, Custom: `backend "s3" {}`}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Spec:       v1beta1.ConfigurationSpec{HCL: "output \"bucket\" {}", Backend: current},
+		Status:     v1beta1.ConfigurationStatus{Backend: previous},
+	}
+	applyJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", Namespace: controllerNamespace},
+		Status:     batchv1.JobStatus{Active: 1},
+	}
+	r := &ConfigurationReconciler{
+		Client:   fake.NewFakeClientWithScheme(s, configuration, applyJob),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	// the migration waits for the running apply
+	if migrating, err := r.reconcileStateMigration(ctx, configuration, meta); err != nil || !migrating {
+		t.Fatalf("expected to wait for the apply, got %v, %v", migrating, err)
+	}
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss-migrate-state", Namespace: controllerNamespace}, &batchv1.Job{}); !kerrors.IsNotFound(err) {
+		t.Fatalf("expected no migrate-state Job while the apply is running, got %v", err)
+	}
+	if !strings.Contains(meta.PreviousBackendHCL, `backend "kubernetes"`) {
+		t.Errorf("expected the previous backend rendered, got %s", meta.PreviousBackendHCL)
+	}
+
+	previousHCL, err := backendHCL(previous, controllerNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentHCL, err := backendHCL(current, controllerNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrateStateJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "oss-migrate-state",
+			Namespace:   controllerNamespace,
+			Annotations: map[string]string{StateMigrationHashAnnotation: stateMigrationHash(previousHCL, currentHCL)},
+		},
+		Status: batchv1.JobStatus{Succeeded: 1},
+	}
+	if err := r.Create(ctx, migrateStateJob); err != nil {
+		t.Fatal(err)
+	}
+	if migrating, err := r.reconcileStateMigration(ctx, configuration, meta); err != nil || migrating {
+		t.Fatalf("expected the migration finished, got %v, %v", migrating, err)
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if b := got.Status.Backend; b == nil || b.BackendType != backend.TypeCustom {
+		t.Errorf("expected the backend switched to the custom one, got %v", b)
+	}
+	if record := got.Status.StateMigration; record == nil || !record.Succeeded || record.From != backend.TypeKubernetes ||
+		record.To != backend.TypeCustom {
+		t.Errorf("expected the succeeded migration recorded, got %v", record)
+	}
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss-migrate-state", Namespace: controllerNamespace}, &batchv1.Job{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the migrate-state Job deleted, got %v", err)
+	}
+
+	// nothing is migrated once the state is in the current backend
+	if migrating, err := r.reconcileStateMigration(ctx, &got, meta); err != nil || migrating {
+		t.Errorf("expected no migration, got %v, %v", migrating, err)
+	}
+}
+
+func TestMigrateStateCommand(t *testing.T) {
+	meta := &TFConfigurationMeta{PreviousBackendHCL: "terraform {\n  backend \"kubernetes\" {}\n}"}
+	expected := []runner.Step{
+		{WriteFile: &runner.WriteFile{Path: previousBackendOverrideFile, Content: meta.PreviousBackendHCL + "\n"}},
+		runStep("terraform", "init", "-input=false"),
+		{Remove: previousBackendOverrideFile},
+		runStep("terraform", "init", "-migrate-state", "-force-copy", "-lock-timeout=5m", "-input=false"),
+	}
+	if steps := meta.executorSteps(TerraformMigrateState); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected the state migrated by the steps %s, got %s", stepsJSON(expected), stepsJSON(steps))
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/terraform"
+)
+
+// StateRemoveAnnotation removes resources from the state without destroying them, e.g. the ones deleted out-of-band.
+// Its value is an address, or a JSON array of addresses. It's removed once the removal finishes, whose result is
+// recorded in status.stateRemoval. It only works when the state surgery is enabled for the controller.
+const StateRemoveAnnotation = "terraform.core.oam.dev/state-rm"
+
+const (
+	// ReasonStateRemoved is the event reason when resources are removed from the state
+	ReasonStateRemoved = "StateRemoved"
+	// ReasonStateRemoveFailed is the event reason when resources failed to be removed from the state
+	ReasonStateRemoveFailed = "StateRemoveFailed"
+	// ReasonStateSurgeryDisabled is the event reason when the state-rm annotation is ignored
+	ReasonStateSurgeryDisabled = "StateSurgeryDisabled"
+)
+
+// stateRemoveAddresses parses the addresses of the state-rm annotation
+func stateRemoveAddresses(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(value, "[") {
+		return []string{value}, nil
+	}
+	var addresses []string
+	if err := json.Unmarshal([]byte(value), &addresses); err != nil {
+		return nil, errors.Wrapf(err, "invalid annotation %s", StateRemoveAnnotation)
+	}
+	return addresses, nil
+}
+
+// reconcileStateRemoval removes the resources of the state-rm annotation from the state by a Job. It returns true
+// while the removal is in progress, during which neither apply nor destroy runs.
+func (r *ConfigurationReconciler) reconcileStateRemoval(ctx context.Context, configuration *v1beta1.Configuration,
+	meta *TFConfigurationMeta) (bool, error) {
+	value, ok := configuration.Annotations[StateRemoveAnnotation]
+	if !ok {
+		return false, nil
+	}
+	if !r.EnableStateSurgery {
+		msg := fmt.Sprintf("the annotation %s is ignored as the state surgery isn't enabled for the controller", StateRemoveAnnotation)
+		klog.InfoS(msg, "Namespace", configuration.Namespace, "Name", configuration.Name)
+		r.Recorder.Event(configuration, v1.EventTypeWarning, ReasonStateSurgeryDisabled, msg)
+		return false, nil
+	}
+	addresses, err := stateRemoveAddresses(value)
+	if err != nil || len(addresses) == 0 {
+		if err == nil {
+			err = errors.Errorf("no address is set in annotation %s", StateRemoveAnnotation)
+		}
+		return false, r.finishStateRemoval(ctx, configuration, addresses, err)
+	}
+	meta.StateRemoveAddresses = addresses
+
+	var job batchv1.Job
+	if err := clusterClient(ctx, r.Client).Get(ctx, client.ObjectKey{Name: meta.StateRemoveJobName, Namespace: meta.Namespace}, &job); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return true, err
+		}
+		if busy, err := r.stopJobsForStateChange(ctx, *configuration, meta); err != nil || busy {
+			return true, err
+		}
+		klog.InfoS("removing resources from the state", "Namespace", configuration.Namespace, "Name", configuration.Name,
+			"Addresses", addresses)
+		return true, meta.assembleAndTriggerJob(ctx, r.Client, configuration, TerraformStateRemove)
+	}
+
+	switch {
+	case job.Status.Succeeded > 0:
+		err = nil
+	case job.Status.Failed > 0:
+		err = errors.New("terraform state rm failed")
+		if _, logErr := terraform.GetTerraformStatus(ctx, meta.Namespace, meta.StateRemoveJobName); logErr != nil {
+			err = logErr
+		}
+	default:
+		return true, nil
+	}
+	if err := r.finishStateRemoval(ctx, configuration, addresses, err); err != nil {
+		return true, err
+	}
+	return false, deleteJob(ctx, clusterClient(ctx, r.Client), meta.StateRemoveJobName, meta.Namespace)
+}
+
+// stopJobsForStateChange returns true if the apply or destroy is running, which the removal from the state or the
+// refresh waits for, as the state isn't locked. The Jobs which keep failing are deleted instead, and they're created
+// again afterwards.
+func (r *ConfigurationReconciler) stopJobsForStateChange(ctx context.Context, configuration v1beta1.Configuration,
+	meta *TFConfigurationMeta) (bool, error) {
+	keepsFailing := map[string]bool{
+		meta.ApplyJobName: configuration.Status.Apply.State == types.ConfigurationApplyFailed ||
+			configuration.Status.Apply.State == types.ConfigurationVerificationFailed,
+		meta.DestroyJobName: configuration.Status.Apply.State == types.ConfigurationDestroyFailed,
+		meta.RefreshJobName: false,
+	}
+	cluster := clusterClient(ctx, r.Client)
+	for name, failing := range keepsFailing {
+		var job batchv1.Job
+		if err := cluster.Get(ctx, client.ObjectKey{Name: name, Namespace: meta.Namespace}, &job); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return true, err
+		}
+		if job.Status.Succeeded > 0 || job.Status.Failed > 0 {
+			continue
+		}
+		if !failing {
+			return true, nil
+		}
+		if err := cluster.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// finishStateRemoval records the result of the removal in the status and an event, and removes the annotation
+func (r *ConfigurationReconciler) finishStateRemoval(ctx context.Context, configuration *v1beta1.Configuration,
+	addresses []string, removeErr error) error {
+	record := &v1beta1.StateRemovalRecord{Addresses: addresses, Time: metav1.Now(), Succeeded: removeErr == nil}
+	if removeErr != nil {
+		record.Message = removeErr.Error()
+		klog.ErrorS(removeErr, "failed to remove resources from the state", "Namespace", configuration.Namespace,
+			"Name", configuration.Name, "Addresses", addresses)
+		r.Recorder.Event(configuration, v1.EventTypeWarning, ReasonStateRemoveFailed,
+			fmt.Sprintf("Failed to remove %s from the state: %s", strings.Join(addresses, ", "), removeErr.Error()))
+	} else {
+		klog.InfoS("removed resources from the state", "Namespace", configuration.Namespace, "Name", configuration.Name,
+			"Addresses", addresses)
+		r.Recorder.Event(configuration, v1.EventTypeNormal, ReasonStateRemoved,
+			fmt.Sprintf("Removed %s from the state", strings.Join(addresses, ", ")))
+	}
+	configuration.Status.StateRemoval = record
+	if err := r.Status().Update(ctx, configuration); err != nil {
+		return err
+	}
+	delete(configuration.Annotations, StateRemoveAnnotation)
+	return r.Update(ctx, configuration)
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestStateRemoveAddresses(t *testing.T) {
+	testcases := map[string][]string{
+		"alicloud_vpc.main":                     {"alicloud_vpc.main"},
+		`["alicloud_vpc.main", "a.b[\"x,y\"]"]`: {"alicloud_vpc.main", `a.b["x,y"]`},
+		" ":                                     nil,
+	}
+	for value, expected := range testcases {
+		addresses, err := stateRemoveAddresses(value)
+		if err != nil || !reflect.DeepEqual(addresses, expected) {
+			t.Errorf("expected addresses %v of %q, got %v, %v", expected, value, addresses, err)
+		}
+	}
+	if _, err := stateRemoveAddresses("[broken"); err == nil {
+		t.Error("expected an error of the invalid JSON array")
+	}
+}
+
+func TestReconcileStateRemoval(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	meta := &TFConfigurationMeta{
+		Namespace:          controllerNamespace,
+		ApplyJobName:       "oss-apply",
+		DestroyJobName:     "oss-destroy",
+		StateRemoveJobName: "oss-state-rm",
+	}
+	configuration := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{
+		Name:        "oss",
+		Namespace:   "default",
+		Annotations: map[string]string{StateRemoveAnnotation: "alicloud_vpc.main"},
+	}}
+	stateRemoveJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss-state-rm", Namespace: controllerNamespace},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	r := &ConfigurationReconciler{
+		Client:   fake.NewFakeClientWithScheme(s, configuration, stateRemoveJob),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	// The annotation is ignored unless the state surgery is enabled
+	if removing, err := r.reconcileStateRemoval(ctx, configuration, meta); err != nil || removing {
+		t.Fatalf("expected the annotation ignored, got %v, %v", removing, err)
+	}
+	if configuration.Status.StateRemoval != nil {
+		t.Fatalf("expected nothing recorded, got %v", configuration.Status.StateRemoval)
+	}
+
+	r.EnableStateSurgery = true
+	if removing, err := r.reconcileStateRemoval(ctx, configuration, meta); err != nil || removing {
+		t.Fatalf("expected the removal finished, got %v, %v", removing, err)
+	}
+	var got v1beta1.Configuration
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss", Namespace: "default"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.Annotations[StateRemoveAnnotation]; ok {
+		t.Error("expected the annotation removed")
+	}
+	if record := got.Status.StateRemoval; record == nil || !record.Succeeded || !reflect.DeepEqual(record.Addresses, []string{"alicloud_vpc.main"}) {
+		t.Errorf("expected the succeeded removal recorded, got %v", record)
+	}
+	if err := r.Get(ctx, client.ObjectKey{Name: "oss-state-rm", Namespace: controllerNamespace}, &batchv1.Job{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the state-rm Job deleted, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"reflect"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	cfgvalidator "github.com/oam-dev/terraform-controller/controllers/configuration"
+)
+
+const (
+	// VarFilesSecretName is the name of the Secret which keeps the var files of a Configuration for the Jobs
+	VarFilesSecretName = "%s-tf-var-files"
+	// VarFilesVolumeName is the volume name for the var files
+	VarFilesVolumeName = "tf-var-files"
+	// VarFilesMountPath is the mount path of the var files, which are passed by -var-file
+	VarFilesMountPath = "/opt/tf-var-files"
+)
+
+// loadVarFiles reads the var files of spec.varFiles in the namespace of the Configuration. They are named by their
+// order and their keys, and hashed to tell whether they're changed. The missing optional ones are skipped.
+func (meta *TFConfigurationMeta) loadVarFiles(ctx context.Context, k8sClient client.Client, configuration *v1beta1.Configuration) error {
+	meta.VarFiles, meta.VarFileVariables, meta.VarFilesHash, meta.varFilesData = nil, nil, "", nil
+	if len(configuration.Spec.VarFiles) == 0 {
+		return nil
+	}
+
+	data := make(map[string][]byte, len(configuration.Spec.VarFiles))
+	h := sha256.New()
+	for i, source := range configuration.Spec.VarFiles {
+		key, content, found, err := readVarFile(ctx, k8sClient, configuration.Namespace, source)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		if !cfgvalidator.IsVarFile(key) {
+			return errors.Errorf("var file %s must have the suffix .tfvars or .tfvars.json", key)
+		}
+		variables, err := cfgvalidator.GetVarFileVariables(key, string(content))
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%d-%s", i, key)
+		meta.VarFiles = append(meta.VarFiles, name)
+		meta.VarFileVariables = append(meta.VarFileVariables, variables...)
+		data[name] = content
+		fmt.Fprintf(h, "%s\x00%s\x00", name, content)
+	}
+	if len(meta.VarFiles) > 0 {
+		meta.VarFilesHash = hex.EncodeToString(h.Sum(nil))
+		meta.varFilesData = data
+	}
+	return nil
+}
+
+// readVarFile reads a var file from its ConfigMap or Secret, which returns false if the optional one isn't found
+func readVarFile(ctx context.Context, k8sClient client.Client, namespace string, source v1beta1.VarFileSource) (string, []byte, bool, error) {
+	if ref := source.ConfigMapKeyRef; ref != nil {
+		optional := ref.Optional != nil && *ref.Optional
+		var cm v1.ConfigMap
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, &cm); err != nil {
+			if kerrors.IsNotFound(err) && optional {
+				return ref.Key, nil, false, nil
+			}
+			return "", nil, false, errors.Wrapf(err, "failed to get the ConfigMap %s of the var file %s", ref.Name, ref.Key)
+		}
+		if content, ok := cm.Data[ref.Key]; ok {
+			return ref.Key, []byte(content), true, nil
+		}
+		if content, ok := cm.BinaryData[ref.Key]; ok {
+			return ref.Key, content, true, nil
+		}
+		if optional {
+			return ref.Key, nil, false, nil
+		}
+		return "", nil, false, errors.Errorf("var file %s is not found in the ConfigMap %s/%s", ref.Key, namespace, ref.Name)
+	}
+	if ref := source.SecretKeyRef; ref != nil {
+		optional := ref.Optional != nil && *ref.Optional
+		var secret v1.Secret
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, &secret); err != nil {
+			if kerrors.IsNotFound(err) && optional {
+				return ref.Key, nil, false, nil
+			}
+			return "", nil, false, errors.Wrapf(err, "failed to get the Secret %s of the var file %s", ref.Name, ref.Key)
+		}
+		if content, ok := secret.Data[ref.Key]; ok {
+			return ref.Key, content, true, nil
+		}
+		if optional {
+			return ref.Key, nil, false, nil
+		}
+		return "", nil, false, errors.Errorf("var file %s is not found in the Secret %s/%s", ref.Key, namespace, ref.Name)
+	}
+	return "", nil, false, errors.New("either configMapKeyRef or secretKeyRef of a var file must be set")
+}
+
+// storeVarFiles keeps the var files in a Secret in the execution namespace, which is mounted into the Jobs. It's
+// updated only when the files are changed, and deleted when there are no var files.
+func (meta *TFConfigurationMeta) storeVarFiles(ctx context.Context, k8sClient client.Client) error {
+	name := fmt.Sprintf(VarFilesSecretName, meta.Name)
+	var secret v1.Secret
+	err := k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: meta.Namespace}, &secret)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if len(meta.VarFiles) == 0 {
+		if exists {
+			klog.InfoS("deleting the var files", "Namespace", meta.Namespace, "Name", name)
+			return client.IgnoreNotFound(k8sClient.Delete(ctx, &secret))
+		}
+		return nil
+	}
+	if !exists {
+		secret = v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       meta.Namespace,
+				OwnerReferences: meta.OwnerReferences,
+				Labels:          meta.Labels,
+			},
+			Type: v1.SecretTypeOpaque,
+			Data: meta.varFilesData,
+		}
+		return errors.Wrap(k8sClient.Create(ctx, &secret), "failed to create the Secret of the var files")
+	}
+	if reflect.DeepEqual(secret.Data, meta.varFilesData) {
+		return nil
+	}
+	secret.Data = meta.varFilesData
+	secret.Labels = mergeMaps(secret.Labels, meta.Labels)
+	return errors.Wrap(k8sClient.Update(ctx, &secret), "failed to update the Secret of the var files")
+}
+
+// varFileArgs are the -var-file arguments of the var files, which are passed in order
+func (meta *TFConfigurationMeta) varFileArgs() []string {
+	args := make([]string, 0, len(meta.VarFiles))
+	for _, name := range meta.VarFiles {
+		args = append(args, "-var-file="+path.Join(VarFilesMountPath, name))
+	}
+	return args
+}
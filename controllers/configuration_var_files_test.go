@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestVarFiles(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	optional := true
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "default"},
+		Spec: v1beta1.ConfigurationSpec{VarFiles: []v1beta1.VarFileSource{
+			{ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "vpc-vars"}, Key: "prod.tfvars"}},
+			{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "vpc-secrets"}, Key: "secrets.tfvars.json"}},
+			{ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "vpc-overrides"},
+				Key: "overrides.tfvars", Optional: &optional}},
+		}},
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc-vars", Namespace: "default"},
+		Data:       map[string]string{"prod.tfvars": "cidr = \"10.0.0.0/16\"\ntags = {\n  env = \"prod\"\n}\n"},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc-secrets", Namespace: "default"},
+		Data:       map[string][]byte{"secrets.tfvars.json": []byte(`{"password": "s3cret"}`)},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, cm, secret)
+	meta := &TFConfigurationMeta{Name: "vpc", Namespace: controllerNamespace}
+
+	if err := meta.loadVarFiles(ctx, k8sClient, configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"0-prod.tfvars", "1-secrets.tfvars.json"}; !reflect.DeepEqual(meta.VarFiles, expected) {
+		t.Errorf("expected the var files %v without the missing optional one, got %v", expected, meta.VarFiles)
+	}
+	if expected := []string{"cidr", "tags", "password"}; !reflect.DeepEqual(meta.VarFileVariables, expected) {
+		t.Errorf("expected the variables %v, got %v", expected, meta.VarFileVariables)
+	}
+	expectedArgs := []string{"-var-file=/opt/tf-var-files/0-prod.tfvars", "-var-file=/opt/tf-var-files/1-secrets.tfvars.json"}
+	if args := meta.varFileArgs(); !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected the var files passed in order, got %v", args)
+	}
+	apply := runStep(append([]string{"terraform", "apply", "-lock-timeout=5m", "-auto-approve"}, expectedArgs...)...)
+	if steps := meta.executorSteps(TerraformApply); !reflect.DeepEqual(steps[len(steps)-1], apply) {
+		t.Errorf("expected the var files passed to the apply, got %v", steps[len(steps)-1].Run)
+	}
+
+	// the Secret mounted into the Jobs keeps the var files
+	if err := meta.storeVarFiles(ctx, k8sClient); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var stored v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-tf-var-files", Namespace: controllerNamespace}, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if string(stored.Data["0-prod.tfvars"]) != cm.Data["prod.tfvars"] {
+		t.Errorf("expected the var file stored, got %v", stored.Data)
+	}
+
+	// a changed var file changes the hash
+	hash := meta.VarFilesHash
+	cm.Data["prod.tfvars"] = `cidr = "10.1.0.0/16"`
+	if err := k8sClient.Update(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+	if err := meta.loadVarFiles(ctx, k8sClient, configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.VarFilesHash == hash || meta.VarFilesHash == "" {
+		t.Errorf("expected the hash changed, got %s", meta.VarFilesHash)
+	}
+
+	// the Secret is deleted along with the var files
+	configuration.Spec.VarFiles = nil
+	if err := meta.loadVarFiles(ctx, k8sClient, configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := meta.storeVarFiles(ctx, k8sClient); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-tf-var-files", Namespace: controllerNamespace}, &stored); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the Secret deleted, got %v", err)
+	}
+
+	// a missing var file which isn't optional fails
+	configuration.Spec.VarFiles = []v1beta1.VarFileSource{
+		{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "vpc-secrets"}, Key: "missing.tfvars"}},
+	}
+	if err := meta.loadVarFiles(ctx, k8sClient, configuration); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected an error about the missing var file, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/util"
+)
+
+const (
+	// providerRefIndex indexes Configurations by the namespaced name of the Provider they reference
+	providerRefIndex = "spec.providerRef"
+	// credentialsSecretIndex indexes Providers by the namespaced name of their credentials Secret
+	credentialsSecretIndex = "spec.credentials.secretRef"
+)
+
+// indexConfigurationByProvider returns the Providers a Configuration references, which is the default one if not set,
+// along with the aliased ones
+func (r *ConfigurationReconciler) indexConfigurationByProvider(obj runtime.Object) []string {
+	configuration, ok := obj.(*v1beta1.Configuration)
+	if !ok {
+		return nil
+	}
+	ref := types.NamespacedName{Namespace: util.ProviderDefaultNamespace, Name: util.ProviderDefaultName}
+	if r.ProviderName != "" {
+		ref.Name = r.ProviderName
+	}
+	if configuration.Spec.ProviderReference != nil {
+		ref.Name = configuration.Spec.ProviderReference.Name
+		if configuration.Spec.ProviderReference.Namespace != "" {
+			ref.Namespace = configuration.Spec.ProviderReference.Namespace
+		}
+	}
+	keys := []string{ref.String()}
+	for _, aliased := range configuration.Spec.AliasedProviders {
+		ref := types.NamespacedName{Namespace: util.ProviderDefaultNamespace, Name: aliased.Name}
+		if aliased.Namespace != "" {
+			ref.Namespace = aliased.Namespace
+		}
+		keys = append(keys, ref.String())
+	}
+	return keys
+}
+
+// indexProviderBySecret returns the Secret which stores the credentials of a Provider
+func indexProviderBySecret(obj runtime.Object) []string {
+	provider, ok := obj.(*v1beta1.Provider)
+	if !ok || provider.Spec.Credentials.SecretRef == nil {
+		return nil
+	}
+	secretRef := provider.Spec.Credentials.SecretRef
+	return []string{types.NamespacedName{Namespace: secretRef.Namespace, Name: secretRef.Name}.String()}
+}
+
+// setupProviderWatches lets the changes of a Provider, or the Secret of its credentials, trigger the reconciliation of
+// the Configurations referencing it. Otherwise, they are stuck until the next re-sync when a Provider becomes ready.
+func (r *ConfigurationReconciler) setupProviderWatches(mgr ctrl.Manager, blder *ctrl.Builder) error {
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), &v1beta1.Configuration{}, providerRefIndex, r.indexConfigurationByProvider); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &v1beta1.Provider{}, credentialsSecretIndex, indexProviderBySecret); err != nil {
+		return err
+	}
+
+	blder.Watches(&source.Kind{Type: &v1beta1.Provider{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			return r.configurationsForProvider(context.Background(), types.NamespacedName{Namespace: o.Meta.GetNamespace(), Name: o.Meta.GetName()})
+		}),
+	})
+	blder.Watches(&source.Kind{Type: &v1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			return r.configurationsForSecret(context.Background(), types.NamespacedName{Namespace: o.Meta.GetNamespace(), Name: o.Meta.GetName()})
+		}),
+	})
+	return nil
+}
+
+func (r *ConfigurationReconciler) configurationsForProvider(ctx context.Context, provider types.NamespacedName) []reconcile.Request {
+	var configurations v1beta1.ConfigurationList
+	if err := r.List(ctx, &configurations, client.MatchingFields{providerRefIndex: provider.String()}); err != nil {
+		klog.ErrorS(err, "failed to list the Configurations referencing Provider", "Provider", provider)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(configurations.Items))
+	for _, c := range configurations.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: c.Namespace, Name: c.Name}})
+	}
+	return requests
+}
+
+func (r *ConfigurationReconciler) configurationsForSecret(ctx context.Context, secret types.NamespacedName) []reconcile.Request {
+	var providers v1beta1.ProviderList
+	if err := r.List(ctx, &providers, client.MatchingFields{credentialsSecretIndex: secret.String()}); err != nil {
+		klog.ErrorS(err, "failed to list the Providers referencing Secret", "Secret", secret)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, p := range providers.Items {
+		requests = append(requests, r.configurationsForProvider(ctx, types.NamespacedName{Namespace: p.Namespace, Name: p.Name})...)
+	}
+	return requests
+}
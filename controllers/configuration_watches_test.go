@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestIndexConfigurationByProvider(t *testing.T) {
+	testcases := map[string]struct {
+		obj  runtime.Object
+		keys []string
+	}{
+		"default provider": {
+			obj:  &v1beta1.Configuration{},
+			keys: []string{"default/default"},
+		},
+		"provider reference": {
+			obj: &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+				ProviderReference: &crossplane.Reference{Namespace: "prod", Name: "aws"},
+			}},
+			keys: []string{"prod/aws"},
+		},
+		"aliased providers": {
+			obj: &v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+				AliasedProviders: []v1beta1.AliasedProviderReference{{Alias: "west", Name: "aws-west"}, {Alias: "k8s", Name: "k8s", Namespace: "prod"}},
+			}},
+			keys: []string{"default/default", "default/aws-west", "prod/k8s"},
+		},
+		"not a Configuration": {
+			obj: &v1beta1.Provider{},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			if keys := (&ConfigurationReconciler{}).indexConfigurationByProvider(tc.obj); !reflect.DeepEqual(keys, tc.keys) {
+				t.Errorf("expected keys %v, got %v", tc.keys, keys)
+			}
+		})
+	}
+
+	// the default Provider of the controller is referenced by the Configurations which don't reference any
+	r := &ConfigurationReconciler{ProviderName: "aws"}
+	if keys := r.indexConfigurationByProvider(&v1beta1.Configuration{}); !reflect.DeepEqual(keys, []string{"default/aws"}) {
+		t.Errorf("expected the default Provider of the controller, got %v", keys)
+	}
+}
+
+func TestIndexProviderBySecret(t *testing.T) {
+	provider := &v1beta1.Provider{ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "prod"}}
+	if keys := indexProviderBySecret(provider); keys != nil {
+		t.Errorf("expected no keys without a credentials Secret, got %v", keys)
+	}
+
+	provider.Spec.Credentials.SecretRef = &crossplane.SecretKeySelector{
+		SecretReference: crossplane.SecretReference{Name: "aws-account-creds", Namespace: "vela-system"},
+		Key:             "credentials",
+	}
+	if keys := indexProviderBySecret(provider); !reflect.DeepEqual(keys, []string{"vela-system/aws-account-creds"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
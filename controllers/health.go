@@ -0,0 +1,192 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+const (
+	// configurationQueueName is the name of the work queue of the Configuration controller, which is the lowercase
+	// kind of Configuration
+	configurationQueueName = "configuration"
+	// apiServerCheckTimeout is how long the API server could take to answer the health check
+	apiServerCheckTimeout = 5 * time.Second
+)
+
+// HealthReport tells whether the Configuration controller is processing the Configurations
+type HealthReport struct {
+	// QueueDepth is how many Configurations are waiting to be reconciled
+	QueueDepth int `json:"queueDepth"`
+	// LastReconcileTime is when the latest reconciliation finished, successful or not
+	LastReconcileTime *time.Time `json:"lastReconcileTime,omitempty"`
+	// LastSuccessfulReconcileTime is when the latest successful reconciliation finished
+	LastSuccessfulReconcileTime *time.Time `json:"lastSuccessfulReconcileTime,omitempty"`
+	// LastReconcileTimeoutTime is when a reconciliation timed out the latest, like when a backend or a workload cluster
+	// is slow or unreachable, which is told by the condition ReconcileTimedOut of the Configuration
+	LastReconcileTimeoutTime *time.Time `json:"lastReconcileTimeoutTime,omitempty"`
+	// APIServerReachable tells whether the API server answered
+	APIServerReachable bool   `json:"apiServerReachable"`
+	APIServerError     string `json:"apiServerError,omitempty"`
+	// Stalled is true when Configurations are waiting but no reconciliation has finished within the stall timeout
+	Stalled bool `json:"stalled"`
+}
+
+// ReconcileHealth tracks the reconciliations of Configurations, which tells whether the controller is wedged. It checks
+// the liveness and the readiness of the manager, and serves a HealthReport in JSON.
+type ReconcileHealth struct {
+	// APIReader reads from the API server rather than the cache, which tells whether it's reachable
+	APIReader client.Reader
+	// StallTimeout is how long Configurations could wait in the queue without any reconciliation finishing before the
+	// controller is taken as wedged
+	StallTimeout time.Duration
+
+	mu          sync.Mutex
+	created     time.Time
+	lastFinish  time.Time
+	lastSuccess time.Time
+	lastTimeout time.Time
+}
+
+// NewReconcileHealth returns a ReconcileHealth which hasn't seen any reconciliation
+func NewReconcileHealth(apiReader client.Reader, stallTimeout time.Duration) *ReconcileHealth {
+	return &ReconcileHealth{APIReader: apiReader, StallTimeout: stallTimeout, created: time.Now()}
+}
+
+// observe records a finished reconciliation, it's a no-op if the health isn't tracked
+func (h *ReconcileHealth) observe(succeeded, timedOut bool) {
+	if h == nil {
+		return
+	}
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastFinish = now
+	if succeeded {
+		h.lastSuccess = now
+	}
+	if timedOut {
+		h.lastTimeout = now
+	}
+}
+
+// stalled tells whether Configurations have been waiting longer than the stall timeout while no reconciliation
+// finished, including since the controller started
+func (h *ReconcileHealth) stalled(queueDepth int, now time.Time) bool {
+	if queueDepth == 0 || h.StallTimeout <= 0 {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	last := h.lastFinish
+	if last.IsZero() {
+		last = h.created
+	}
+	return now.Sub(last) > h.StallTimeout
+}
+
+// CheckReconcile is the liveness check, which fails when the controller is wedged
+func (h *ReconcileHealth) CheckReconcile(_ *http.Request) error {
+	depth := configurationQueueDepth()
+	if h.stalled(depth, time.Now()) {
+		return errors.Errorf("%d Configurations are waiting, but no reconciliation has finished in %s", depth, h.StallTimeout)
+	}
+	return nil
+}
+
+// CheckAPIServer is the readiness check, which fails when the API server isn't reachable
+func (h *ReconcileHealth) CheckAPIServer(req *http.Request) error {
+	ctx, cancel := context.WithTimeout(req.Context(), apiServerCheckTimeout)
+	defer cancel()
+	return h.APIReader.List(ctx, &v1beta1.ConfigurationList{}, client.Limit(1))
+}
+
+// Report reports the health of the controller
+func (h *ReconcileHealth) Report(req *http.Request) HealthReport {
+	now := time.Now()
+	report := HealthReport{QueueDepth: configurationQueueDepth()}
+	report.Stalled = h.stalled(report.QueueDepth, now)
+	h.mu.Lock()
+	report.LastReconcileTime = timeOrNil(h.lastFinish)
+	report.LastSuccessfulReconcileTime = timeOrNil(h.lastSuccess)
+	report.LastReconcileTimeoutTime = timeOrNil(h.lastTimeout)
+	h.mu.Unlock()
+	if err := h.CheckAPIServer(req); err != nil {
+		report.APIServerError = err.Error()
+	} else {
+		report.APIServerReachable = true
+	}
+	return report
+}
+
+// ServeHTTP implements http.Handler, which serves the HealthReport. It responds 503 when the controller is wedged or
+// the API server isn't reachable.
+func (h *ReconcileHealth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report := h.Report(r)
+	w.Header().Set("Content-Type", "application/json")
+	if report.Stalled || !report.APIServerReachable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		klog.ErrorS(err, "failed to write the health report")
+	}
+}
+
+// configurationQueueDepth reads the depth of the work queue of Configurations from the metrics of the work queues,
+// which is 0 until the controller starts, like when it's not the leader
+func configurationQueueDepth() int {
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		klog.ErrorS(err, "failed to gather the metrics of the work queues")
+		return 0
+	}
+	for _, family := range families {
+		if family.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "name" && label.GetValue() == configurationQueueName {
+					return int(m.GetGauge().GetValue())
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
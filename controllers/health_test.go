@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestReconcileHealth(t *testing.T) {
+	h := NewReconcileHealth(nil, time.Minute)
+	now := time.Now()
+	if h.stalled(0, now.Add(time.Hour)) {
+		t.Error("expected not stalled without any Configuration waiting")
+	}
+	if h.stalled(3, now) {
+		t.Error("expected not stalled within the stall timeout since the controller started")
+	}
+	if !h.stalled(3, now.Add(2*time.Minute)) {
+		t.Error("expected stalled when nothing is reconciled within the stall timeout")
+	}
+	h.observe(false, true)
+	if h.stalled(3, time.Now()) {
+		t.Error("expected a timed-out reconciliation taken as progress")
+	}
+	if h.lastTimeout.IsZero() || !h.lastSuccess.IsZero() {
+		t.Errorf("expected only the timeout recorded, got %v and %v", h.lastTimeout, h.lastSuccess)
+	}
+
+	var untracked *ReconcileHealth
+	untracked.observe(true, false)
+}
+
+func TestReconcileHealthServeHTTP(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	h := NewReconcileHealth(fake.NewFakeClientWithScheme(s), time.Minute)
+	h.observe(true, false)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reconcile-health", nil))
+	var report HealthReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || !report.APIServerReachable || report.Stalled || report.LastSuccessfulReconcileTime == nil {
+		t.Errorf("expected a healthy report, got %d %+v", w.Code, report)
+	}
+
+	// the API server is unreachable when the scheme doesn't know Configurations
+	h.APIReader = fake.NewFakeClientWithScheme(runtime.NewScheme())
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reconcile-health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 when the API server isn't reachable, got %d", w.Code)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+const (
+	// notificationQueueSize is how many notifications could wait to be delivered, beyond which they're dropped
+	notificationQueueSize = 100
+	// notificationTimeout is how long a webhook could take to receive a notification
+	notificationTimeout = 10 * time.Second
+)
+
+// Notification is the JSON payload POSTed to the webhooks when the apply or destroy of a Configuration finishes
+type Notification struct {
+	// Text summarizes the notification for the incoming webhooks of chat tools, like Slack
+	Text      string                      `json:"text"`
+	Name      string                      `json:"name"`
+	Namespace string                      `json:"namespace"`
+	Operation string                      `json:"operation"`
+	State     types.ConfigurationState    `json:"state"`
+	Reason    types.FailureReason         `json:"reason,omitempty"`
+	Message   string                      `json:"message,omitempty"`
+	Outputs   map[string]v1beta1.Property `json:"outputs,omitempty"`
+	Time      metav1.Time                 `json:"time"`
+}
+
+// Notifier POSTs a Notification to each of the webhooks when a Configuration becomes available, or its apply or
+// destroy fails or finishes. The delivery is best-effort: the notifications are sent in the background without
+// retries, they are dropped when the webhooks can't keep up, and the transitions while the controller isn't the leader
+// aren't notified.
+type Notifier struct {
+	Cache cache.Informers
+	URLs  []string
+	// Client sends the notifications, which times out after notificationTimeout by default
+	Client *http.Client
+
+	queue chan Notification
+}
+
+// Start implements manager.Runnable
+func (n *Notifier) Start(stop <-chan struct{}) error {
+	if n.Client == nil {
+		n.Client = &http.Client{Timeout: notificationTimeout}
+	}
+	n.queue = make(chan Notification, notificationQueueSize)
+	informer, err := n.Cache.GetInformer(context.Background(), &v1beta1.Configuration{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get the informer of Configurations")
+	}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			previous, ok1 := oldObj.(*v1beta1.Configuration)
+			current, ok2 := newObj.(*v1beta1.Configuration)
+			if ok1 && ok2 {
+				n.enqueue(transitionNotification(previous, current))
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if configuration, ok := obj.(*v1beta1.Configuration); ok {
+				n.enqueue(deletionNotification(configuration))
+			}
+		},
+	})
+
+	klog.InfoS("Starting the notifier", "Webhooks", len(n.URLs))
+	for {
+		select {
+		case <-stop:
+			return nil
+		case notification := <-n.queue:
+			n.deliver(notification)
+		}
+	}
+}
+
+// enqueue queues a notification without blocking the informer
+func (n *Notifier) enqueue(notification *Notification) {
+	if notification == nil {
+		return
+	}
+	select {
+	case n.queue <- *notification:
+	default:
+		klog.InfoS("dropped the notification as the queue is full", "Namespace", notification.Namespace,
+			"Name", notification.Name, "State", notification.State)
+	}
+}
+
+// deliver POSTs a notification to each of the webhooks, whose failures are only logged
+func (n *Notifier) deliver(notification Notification) {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		klog.ErrorS(err, "failed to encode the notification", "Namespace", notification.Namespace, "Name", notification.Name)
+		return
+	}
+	for _, webhook := range n.URLs {
+		if err := n.post(webhook, body); err != nil {
+			// the URL isn't logged, as the ones of chat tools are credentials
+			klog.ErrorS(err, "failed to send the notification", "Namespace", notification.Namespace,
+				"Name", notification.Name, "State", notification.State)
+		}
+	}
+}
+
+func (n *Notifier) post(webhook string, body []byte) error {
+	resp, err := n.Client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			// the URL told by the error is left out
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("the webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// transitionNotification returns the notification when the apply or destroy of a Configuration finishes, or nil when
+// its state doesn't change to a finished one
+func transitionNotification(previous, current *v1beta1.Configuration) *Notification {
+	if s := current.Status.Destroy; s.State != previous.Status.Destroy.State {
+		switch s.State {
+		case types.ConfigurationDestroyed, types.ConfigurationDestroyFailed:
+			return newNotification(current, "destroy", s.State, s.Reason, s.Message)
+		}
+	}
+	if s := current.Status.Apply; s.State != previous.Status.Apply.State {
+		switch s.State {
+		case types.Available, types.ConfigurationApplyFailed, types.ConfigurationValidationFailed,
+			types.ConfigurationVerificationFailed:
+			return newNotification(current, "apply", s.State, s.Reason, s.Message)
+		case types.ConfigurationDestroyed:
+			// the cloud resources are destroyed by spec.destroy
+			return newNotification(current, "destroy", s.State, s.Reason, s.Message)
+		}
+	}
+	return nil
+}
+
+// deletionNotification returns the notification when a deleted Configuration is gone after its cloud resources are
+// destroyed, unless its destroy has been notified as destroyed or failed, like when it's deleted by force
+func deletionNotification(configuration *v1beta1.Configuration) *Notification {
+	if configuration.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	switch configuration.Status.Destroy.State {
+	case types.ConfigurationDestroyed, types.ConfigurationDestroyFailed:
+		return nil
+	}
+	return newNotification(configuration, "destroy", types.ConfigurationDestroyed, "", MessageCloudResourceDestroyed)
+}
+
+func newNotification(configuration *v1beta1.Configuration, operation string, state types.ConfigurationState,
+	reason types.FailureReason, message string) *Notification {
+	notification := &Notification{
+		Text:      fmt.Sprintf("Configuration %s/%s: %s", configuration.Namespace, configuration.Name, state),
+		Name:      configuration.Name,
+		Namespace: configuration.Namespace,
+		Operation: operation,
+		State:     state,
+		Reason:    reason,
+		Message:   message,
+		Time:      metav1.Now(),
+	}
+	if message != "" {
+		notification.Text += "\n" + message
+	}
+	if state == types.Available {
+		notification.Outputs = nonSensitiveOutputs(configuration.Status.Apply.Outputs)
+	}
+	return notification
+}
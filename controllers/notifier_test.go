@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestTransitionNotification(t *testing.T) {
+	configuration := func(apply, destroy types.ConfigurationState) *v1beta1.Configuration {
+		return &v1beta1.Configuration{
+			ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+			Status: v1beta1.ConfigurationStatus{
+				Apply: v1beta1.ConfigurationApplyStatus{State: apply, Outputs: map[string]v1beta1.Property{
+					"bucket":   {Value: "oss-bucket", Type: "string"},
+					"password": {Type: "string", Sensitive: true},
+				}},
+				Destroy: v1beta1.ConfigurationDestroyStatus{State: destroy},
+			},
+		}
+	}
+
+	testcases := map[string]struct {
+		previous, current *v1beta1.Configuration
+		operation         string
+		state             types.ConfigurationState
+	}{
+		"available": {
+			previous:  configuration(types.ConfigurationProvisioningAndChecking, ""),
+			current:   configuration(types.Available, ""),
+			operation: "apply",
+			state:     types.Available,
+		},
+		"apply failed": {
+			previous:  configuration(types.ConfigurationProvisioningAndChecking, ""),
+			current:   configuration(types.ConfigurationApplyFailed, ""),
+			operation: "apply",
+			state:     types.ConfigurationApplyFailed,
+		},
+		"destroy failed": {
+			previous:  configuration(types.Available, types.ConfigurationDestroying),
+			current:   configuration(types.Available, types.ConfigurationDestroyFailed),
+			operation: "destroy",
+			state:     types.ConfigurationDestroyFailed,
+		},
+		"destroyed by spec.destroy": {
+			previous:  configuration(types.Available, ""),
+			current:   configuration(types.ConfigurationDestroyed, ""),
+			operation: "destroy",
+			state:     types.ConfigurationDestroyed,
+		},
+		"still available": {
+			previous: configuration(types.Available, ""),
+			current:  configuration(types.Available, ""),
+		},
+		"provisioning": {
+			previous: configuration(types.Available, ""),
+			current:  configuration(types.ConfigurationProvisioningAndChecking, ""),
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			got := transitionNotification(tc.previous, tc.current)
+			if tc.state == "" {
+				if got != nil {
+					t.Errorf("expected no notification, got %+v", got)
+				}
+				return
+			}
+			if got == nil || got.Operation != tc.operation || got.State != tc.state || got.Name != "oss" || got.Namespace != "default" {
+				t.Fatalf("expected the %s %s notified, got %+v", tc.operation, tc.state, got)
+			}
+			if _, ok := got.Outputs["password"]; ok {
+				t.Error("expected the sensitive outputs left out")
+			}
+			if tc.state == types.Available && got.Outputs["bucket"].Value != "oss-bucket" {
+				t.Errorf("expected the outputs of the available Configuration, got %v", got.Outputs)
+			}
+		})
+	}
+
+	deleted := configuration(types.Available, types.ConfigurationDestroying)
+	deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	if got := deletionNotification(deleted); got == nil || got.State != types.ConfigurationDestroyed {
+		t.Errorf("expected the destroy notified when the deleted Configuration is gone, got %+v", got)
+	}
+	deleted.Status.Destroy.State = types.ConfigurationDestroyed
+	if got := deletionNotification(deleted); got != nil {
+		t.Errorf("expected the notified destroy not notified again, got %+v", got)
+	}
+}
+
+func TestNotifierDeliver(t *testing.T) {
+	received := make(chan Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification Notification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Errorf("failed to decode the notification: %v", err)
+		}
+		received <- notification
+	}))
+	defer server.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	n := &Notifier{URLs: []string{failing.URL, server.URL}, Client: server.Client()}
+	notification := newNotification(&v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"}},
+		"apply", types.ConfigurationApplyFailed, types.FailureReasonThrottled, "Error: Throttling")
+	// a failing webhook doesn't stop the others from being notified
+	n.deliver(*notification)
+
+	select {
+	case got := <-received:
+		if got.Name != "oss" || got.State != types.ConfigurationApplyFailed || got.Reason != types.FailureReasonThrottled ||
+			got.Text != "Configuration default/oss: ApplyFailed\nError: Throttling" {
+			t.Errorf("unexpected notification: %+v", got)
+		}
+	default:
+		t.Fatal("expected the notification delivered")
+	}
+	if err := n.post(failing.URL, []byte("{}")); err == nil {
+		t.Error("expected the error response told")
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+// subResource is a sub-resource created for a Configuration
+type subResource interface {
+	runtime.Object
+	metav1.Object
+}
+
+// OrphanCollector periodically deletes the Jobs, ConfigMaps and Secrets in the controller namespace and the execution
+// namespaces, which are owned by a Configuration that no longer exists. They could be left behind when a Configuration is deleted while its Job
+// is running, or when its finalizer is removed by force.
+type OrphanCollector struct {
+	client.Client
+	Interval time.Duration
+	// Namespaces are the execution namespaces besides the one of the controller
+	Namespaces []string
+}
+
+// Start implements manager.Runnable
+func (c *OrphanCollector) Start(stop <-chan struct{}) error {
+	wait.Until(func() {
+		if err := c.collect(context.Background()); err != nil {
+			klog.ErrorS(err, "failed to collect orphaned sub-resources")
+		}
+	}, c.Interval, stop)
+	return nil
+}
+
+func (c *OrphanCollector) collect(ctx context.Context) error {
+	var objects []subResource
+	for _, ns := range append([]string{controllerNamespace}, c.Namespaces...) {
+		owned, err := listSubResources(ctx, c.Client, client.InNamespace(ns), client.HasLabels{LabelKeyOwnedBy, LabelKeyOwnedNamespace})
+		if err != nil {
+			return err
+		}
+		objects = append(objects, owned...)
+	}
+
+	for _, obj := range objects {
+		orphaned, err := c.isOrphaned(ctx, obj)
+		if err != nil {
+			return err
+		}
+		if !orphaned {
+			continue
+		}
+		klog.InfoS("deleting orphaned sub-resource", "Namespace", obj.GetNamespace(), "Name", obj.GetName(),
+			"Configuration", obj.GetLabels()[LabelKeyOwnedBy])
+		if err := c.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *OrphanCollector) isOrphaned(ctx context.Context, obj metav1.Object) (bool, error) {
+	labels := obj.GetLabels()
+	key := client.ObjectKey{Name: labels[LabelKeyOwnedBy], Namespace: labels[LabelKeyOwnedNamespace]}
+	if err := c.Get(ctx, key, &v1beta1.Configuration{}); err != nil {
+		if kerrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// listSubResources lists the Jobs, ConfigMaps and Secrets which could be the sub-resources of Configurations
+func listSubResources(ctx context.Context, c client.Client, opts ...client.ListOption) ([]subResource, error) {
+	var (
+		jobs       batchv1.JobList
+		configMaps v1.ConfigMapList
+		secrets    v1.SecretList
+		objects    []subResource
+	)
+	for _, list := range []runtime.Object{&jobs, &configMaps, &secrets} {
+		if err := c.List(ctx, list, opts...); err != nil {
+			return nil, err
+		}
+	}
+	for i := range jobs.Items {
+		objects = append(objects, &jobs.Items[i])
+	}
+	for i := range configMaps.Items {
+		objects = append(objects, &configMaps.Items[i])
+	}
+	for i := range secrets.Items {
+		objects = append(objects, &secrets.Items[i])
+	}
+	return objects, nil
+}
+
+// deleteOwnedSubResources deletes the sub-resources of a Configuration in all the namespaces, which are discovered by
+// the owner labels rather than their names. It cleans up the ones left in a namespace which the Configuration no
+// longer uses, like the previous namespace of the controller after it's moved or rolled back, which OrphanCollector
+// doesn't look into. The state written by Terraform isn't labeled, which is cleaned up by the backend. They are deleted
+// in parallel, and the errors are aggregated.
+func deleteOwnedSubResources(ctx context.Context, c client.Client, configuration v1beta1.Configuration) error {
+	objects, err := listSubResources(ctx, c, client.MatchingLabels(ownerLabels(configuration)))
+	if err != nil {
+		return err
+	}
+	deletes := make([]func() error, 0, len(objects))
+	for _, obj := range objects {
+		obj := obj
+		deletes = append(deletes, func() error {
+			klog.InfoS("deleting sub-resource", "Namespace", obj.GetNamespace(), "Name", obj.GetName(),
+				"Configuration", configuration.Name)
+			if err := c.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !kerrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to delete %s/%s", obj.GetNamespace(), obj.GetName())
+			}
+			return nil
+		})
+	}
+	return runInParallel(deletes...)
+}
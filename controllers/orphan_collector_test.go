@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestOrphanCollectorCollect(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	owned := func(name string) map[string]string {
+		return map[string]string{LabelKeyOwnedBy: name, LabelKeyOwnedNamespace: "default"}
+	}
+	existing := &v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"}}
+	liveJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "existing-apply", Namespace: controllerNamespace, Labels: owned("existing")}}
+	orphanedJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "gone-apply", Namespace: controllerNamespace, Labels: owned("gone")}}
+	orphanedCM := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "gone-tf-input", Namespace: controllerNamespace, Labels: owned("gone")}}
+	unlabeledSecret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-gone", Namespace: controllerNamespace}}
+
+	c := &OrphanCollector{Client: fake.NewFakeClientWithScheme(s, existing, liveJob, orphanedJob, orphanedCM, unlabeledSecret)}
+	if err := c.collect(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, obj := range []subResource{liveJob, unlabeledSecret} {
+		if err := c.Get(ctx, client.ObjectKey{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj); err != nil {
+			t.Errorf("%s should be kept, got %v", obj.GetName(), err)
+		}
+	}
+	for _, obj := range []subResource{orphanedJob, orphanedCM} {
+		if err := c.Get(ctx, client.ObjectKey{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj); !kerrors.IsNotFound(err) {
+			t.Errorf("%s should be deleted, got %v", obj.GetName(), err)
+		}
+	}
+}
+
+func TestDeleteOwnedSubResources(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	configuration := v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"}}
+	// the sub-resources left in the previous namespace of the controller
+	legacyJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", Namespace: "vela-system", Labels: ownerLabels(configuration)}}
+	legacyCM := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tf-oss", Namespace: "vela-system", Labels: ownerLabels(configuration)}}
+	othersJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", Namespace: "terraform",
+		Labels: map[string]string{LabelKeyOwnedBy: "oss", LabelKeyOwnedNamespace: "team-a"}}}
+
+	c := fake.NewFakeClientWithScheme(s, legacyJob, legacyCM, othersJob)
+	if err := deleteOwnedSubResources(ctx, c, configuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, obj := range []subResource{legacyJob, legacyCM} {
+		if err := c.Get(ctx, client.ObjectKey{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj); !kerrors.IsNotFound(err) {
+			t.Errorf("%s/%s should be deleted, got %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	if err := c.Get(ctx, client.ObjectKey{Name: othersJob.Name, Namespace: othersJob.Namespace}, othersJob); err != nil {
+		t.Errorf("the Job of another Configuration should be kept, got %v", err)
+	}
+}
+
+// failingDeleteClient fails to delete the objects of a name
+type failingDeleteClient struct {
+	client.Client
+	name string
+}
+
+func (c *failingDeleteClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	if o, ok := obj.(metav1.Object); ok && o.GetName() == c.name {
+		return errors.New("etcdserver: request timed out")
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func TestDeleteOwnedSubResourcesAggregatesErrors(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	configuration := v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"}}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", Namespace: controllerNamespace, Labels: ownerLabels(configuration)}}
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tf-oss", Namespace: controllerNamespace, Labels: ownerLabels(configuration)}}
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "oss-git", Namespace: controllerNamespace, Labels: ownerLabels(configuration)}}
+
+	c := &failingDeleteClient{Client: fake.NewFakeClientWithScheme(s, job, cm, secret), name: "oss-apply"}
+	err := deleteOwnedSubResources(ctx, c, configuration)
+	if err == nil || !strings.Contains(err.Error(), "failed to delete "+controllerNamespace+"/oss-apply") {
+		t.Fatalf("expected the failed delete told, got %v", err)
+	}
+	// the failed delete doesn't stop the others
+	for _, obj := range []subResource{cm, secret} {
+		if err := c.Get(ctx, client.ObjectKey{Name: obj.GetName(), Namespace: obj.GetNamespace()}, obj); !kerrors.IsNotFound(err) {
+			t.Errorf("%s should be deleted, got %v", obj.GetName(), err)
+		}
+	}
+}
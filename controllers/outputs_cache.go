@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+// appliedOutputs caches the outputs in the state of the Configurations
+var appliedOutputs = &outputsCache{entries: make(map[types.UID]outputsCacheEntry)}
+
+// outputsCache keeps the outputs read from the state of each Configuration along with the apply which they were read
+// after, so that the state, which could be large or in a remote backend, isn't read again until another apply
+// succeeds, like when the status fails to be updated with the outputs and the reconciliation is retried. The sensitive
+// outputs are kept as well, which are written to the connection Secret.
+type outputsCache struct {
+	mu      sync.Mutex
+	entries map[types.UID]outputsCacheEntry
+}
+
+type outputsCacheEntry struct {
+	applied v1beta1.AppliedRecord
+	outputs map[string]TfStateProperty
+}
+
+// get returns the outputs cached for the latest successful apply of the Configuration
+func (c *outputsCache) get(configuration v1beta1.Configuration) (map[string]TfStateProperty, bool) {
+	applied := configuration.Status.LastApplied
+	if applied == nil || configuration.UID == "" {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[configuration.UID]
+	if !ok || entry.applied.InputsHashes != applied.InputsHashes || !entry.applied.Time.Equal(&applied.Time) {
+		return nil, false
+	}
+	return entry.outputs, true
+}
+
+// set caches the outputs read from the state after the latest successful apply of the Configuration
+func (c *outputsCache) set(configuration v1beta1.Configuration, outputs map[string]TfStateProperty) {
+	applied := configuration.Status.LastApplied
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if applied == nil || configuration.UID == "" {
+		delete(c.entries, configuration.UID)
+		return
+	}
+	c.entries[configuration.UID] = outputsCacheEntry{applied: *applied, outputs: outputs}
+}
+
+// forget drops the outputs of the Configuration, whose cloud resources are destroyed
+func (c *outputsCache) forget(configuration v1beta1.Configuration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, configuration.UID)
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestGetTFOutputsCached(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default", UID: "oss-uid"},
+		Status: v1beta1.ConfigurationStatus{LastApplied: &v1beta1.AppliedRecord{
+			Time:         metav1.NewTime(time.Now().Truncate(time.Second)),
+			InputsHashes: v1beta1.InputsHashes{InputsHash: "abc"},
+		}},
+	}
+	state := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-oss", Namespace: controllerNamespace},
+		Data:       map[string][]byte{"tfstate": []byte(`{"outputs":{"bucket":{"value":"oss-bucket","type":"string"}}}`)},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, state)
+	defer appliedOutputs.forget(configuration)
+
+	outputs, err := getTFOutputs(ctx, k8sClient, configuration)
+	if err != nil || outputs["bucket"].Value != "oss-bucket" {
+		t.Fatalf("expected the outputs read from the state, got %v, %v", outputs, err)
+	}
+
+	// the state isn't read again for the same apply
+	if err := k8sClient.Delete(ctx, state); err != nil {
+		t.Fatal(err)
+	}
+	if outputs, err = getTFOutputs(ctx, k8sClient, configuration); err != nil || outputs["bucket"].Value != "oss-bucket" {
+		t.Fatalf("expected the cached outputs, got %v, %v", outputs, err)
+	}
+
+	// but it's read again after another apply
+	configuration.Status.LastApplied.Time = metav1.NewTime(configuration.Status.LastApplied.Time.Add(time.Minute))
+	if _, err = getTFOutputs(ctx, k8sClient, configuration); err == nil {
+		t.Fatal("expected the state read again after another apply")
+	}
+	configuration.Status.LastApplied.Time = metav1.NewTime(configuration.Status.LastApplied.Time.Add(-time.Minute))
+	appliedOutputs.forget(configuration)
+	if _, err = getTFOutputs(ctx, k8sClient, configuration); err == nil {
+		t.Fatal("expected the state read again after the cloud resources are destroyed")
+	}
+}
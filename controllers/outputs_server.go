@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+// outputsPathPrefix is the path prefix of the outputs API, the full path is
+// /api/v1/namespaces/{namespace}/configurations/{name}/outputs
+const outputsPathPrefix = "/api/v1/namespaces/"
+
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// OutputsServer serves the non-sensitive outputs of Configurations over HTTPS, so that dashboards could read them
+// without access to the connection Secrets. A request is authenticated by the bearer token of a ServiceAccount or user,
+// who must be allowed to get the Configuration. It's only served over TLS, as the tokens could be replayed by anyone
+// on the path otherwise.
+type OutputsServer struct {
+	client.Client
+	Addr string
+	// CertFile and KeyFile are the serving certificate and its key
+	CertFile string
+	KeyFile  string
+}
+
+// Start implements manager.Runnable
+func (s *OutputsServer) Start(stop <-chan struct{}) error {
+	if s.CertFile == "" || s.KeyFile == "" {
+		return errors.New("the outputs server needs a serving certificate and its key")
+	}
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			klog.ErrorS(err, "failed to shut down the outputs server")
+		}
+	}()
+	klog.InfoS("Starting the outputs server", "Addr", s.Addr)
+	if err := server.ListenAndServeTLS(s.CertFile, s.KeyFile); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *OutputsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	namespace, name, ok := parseOutputsPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return
+	}
+	user, err := s.authenticate(ctx, token)
+	if err != nil {
+		klog.ErrorS(err, "failed to authenticate the request of outputs")
+		http.Error(w, "failed to authenticate", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+	allowed, err := s.authorize(ctx, user, namespace, name)
+	if err != nil {
+		klog.ErrorS(err, "failed to authorize the request of outputs", "User", user.Username)
+		http.Error(w, "failed to authorize", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var configuration v1beta1.Configuration
+	if err := s.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &configuration); err != nil {
+		if kerrors.IsNotFound(err) {
+			http.NotFound(w, r)
+			return
+		}
+		klog.ErrorS(err, "failed to get Configuration", "Namespace", namespace, "Name", name)
+		http.Error(w, "failed to get the Configuration", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(nonSensitiveOutputs(lastKnownOutputs(configuration))); err != nil {
+		klog.ErrorS(err, "failed to write outputs", "Namespace", namespace, "Name", name)
+	}
+}
+
+// authenticate reviews the token, and returns nil if the token isn't authenticated
+func (s *OutputsServer) authenticate(ctx context.Context, token string) (*authenticationv1.UserInfo, error) {
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := s.Create(ctx, review); err != nil {
+		return nil, err
+	}
+	if !review.Status.Authenticated {
+		return nil, nil
+	}
+	return &review.Status.User, nil
+}
+
+// authorize checks whether the user is allowed to get the Configuration
+func (s *OutputsServer) authorize(ctx context.Context, user *authenticationv1.UserInfo, namespace, name string) (bool, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "get",
+				Group:     v1beta1.GroupVersion.Group,
+				Resource:  "configurations",
+				Name:      name,
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+		},
+	}
+	if err := s.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// parseOutputsPath parses /api/v1/namespaces/{namespace}/configurations/{name}/outputs
+func parseOutputsPath(path string) (namespace, name string, ok bool) {
+	if !strings.HasPrefix(path, outputsPathPrefix) {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(path, outputsPathPrefix), "/")
+	if len(parts) != 4 || parts[0] == "" || parts[1] != "configurations" || parts[2] == "" || parts[3] != "outputs" {
+		return "", "", false
+	}
+	return parts[0], parts[2], true
+}
+
+// lastKnownOutputs are the current outputs, or the ones of the latest successful apply while the outputs are cleared,
+// e.g. when an apply is running or has failed
+func lastKnownOutputs(configuration v1beta1.Configuration) map[string]v1beta1.Property {
+	if len(configuration.Status.Apply.Outputs) == 0 {
+		return configuration.Status.LastSuccessfulOutputs
+	}
+	return configuration.Status.Apply.Outputs
+}
+
+func nonSensitiveOutputs(outputs map[string]v1beta1.Property) map[string]v1beta1.Property {
+	result := make(map[string]v1beta1.Property, len(outputs))
+	for k, v := range outputs {
+		if !v.Sensitive {
+			result[k] = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+// reviewClient answers TokenReviews and SubjectAccessReviews like the API server
+type reviewClient struct {
+	client.Client
+	tokens  map[string]string
+	allowed map[string]bool
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	switch review := obj.(type) {
+	case *authenticationv1.TokenReview:
+		user, ok := c.tokens[review.Spec.Token]
+		review.Status.Authenticated = ok
+		review.Status.User.Username = user
+		return nil
+	case *authorizationv1.SubjectAccessReview:
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = attrs.Verb == "get" && attrs.Resource == "configurations" &&
+			c.allowed[review.Spec.User+"/"+attrs.Namespace+"/"+attrs.Name]
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestOutputsServer(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	configuration := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Status: v1beta1.ConfigurationStatus{
+			Apply: v1beta1.ConfigurationApplyStatus{
+				Outputs: map[string]v1beta1.Property{
+					"bucket":   {Value: "my-bucket", Type: "string"},
+					"password": {Value: "<sensitive>", Type: "string", Sensitive: true},
+				},
+			},
+		},
+	}
+	failing := &v1beta1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "failing", Namespace: "default"},
+		Status: v1beta1.ConfigurationStatus{
+			LastSuccessfulOutputs: map[string]v1beta1.Property{"bucket": {Value: "old-bucket", Type: "string"}},
+		},
+	}
+	server := &OutputsServer{Client: &reviewClient{
+		Client:  fake.NewFakeClientWithScheme(s, configuration, failing),
+		tokens:  map[string]string{"dashboard-token": "dashboard", "other-token": "other"},
+		allowed: map[string]bool{"dashboard/default/oss": true, "dashboard/default/missing": true, "dashboard/default/failing": true},
+	}}
+
+	testcases := map[string]struct {
+		method  string
+		path    string
+		token   string
+		code    int
+		outputs map[string]v1beta1.Property
+	}{
+		"outputs": {
+			path:    "/api/v1/namespaces/default/configurations/oss/outputs",
+			token:   "dashboard-token",
+			code:    http.StatusOK,
+			outputs: map[string]v1beta1.Property{"bucket": {Value: "my-bucket", Type: "string"}},
+		},
+		"last successful outputs": {
+			path:    "/api/v1/namespaces/default/configurations/failing/outputs",
+			token:   "dashboard-token",
+			code:    http.StatusOK,
+			outputs: map[string]v1beta1.Property{"bucket": {Value: "old-bucket", Type: "string"}},
+		},
+		"no token": {
+			path: "/api/v1/namespaces/default/configurations/oss/outputs",
+			code: http.StatusUnauthorized,
+		},
+		"invalid token": {
+			path:  "/api/v1/namespaces/default/configurations/oss/outputs",
+			token: "invalid",
+			code:  http.StatusUnauthorized,
+		},
+		"forbidden": {
+			path:  "/api/v1/namespaces/default/configurations/oss/outputs",
+			token: "other-token",
+			code:  http.StatusForbidden,
+		},
+		"configuration not found": {
+			path:  "/api/v1/namespaces/default/configurations/missing/outputs",
+			token: "dashboard-token",
+			code:  http.StatusNotFound,
+		},
+		"unknown path": {
+			path:  "/api/v1/namespaces/default/configurations/oss",
+			token: "dashboard-token",
+			code:  http.StatusNotFound,
+		},
+		"method not allowed": {
+			method: http.MethodPost,
+			path:   "/api/v1/namespaces/default/configurations/oss/outputs",
+			token:  "dashboard-token",
+			code:   http.StatusMethodNotAllowed,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Fatalf("expected status code %d, got %d: %s", tc.code, rec.Code, rec.Body.String())
+			}
+			if tc.code != http.StatusOK {
+				return
+			}
+			var outputs map[string]v1beta1.Property
+			if err := json.NewDecoder(rec.Body).Decode(&outputs); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(outputs, tc.outputs) {
+				t.Errorf("expected outputs %v, got %v", tc.outputs, outputs)
+			}
+		})
+	}
+}
+
+func TestOutputsServerRequiresTLS(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+	if err := (&OutputsServer{Addr: "127.0.0.1:0"}).Start(stop); err == nil {
+		t.Error("expected the outputs server refused to serve the bearer tokens over plain HTTP")
+	}
+}
@@ -19,10 +19,12 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -34,8 +36,9 @@ import (
 )
 
 const (
-	errGetCredentials = "failed to get credentials from the cloud provider"
-	errSettingStatus  = "failed to set status"
+	errGetCredentials    = "failed to get credentials from the cloud provider"
+	errVerifyCredentials = "failed to verify the credentials by the cloud provider"
+	errSettingStatus     = "failed to set status"
 )
 
 // ProviderReconciler reconciles a Provider object
@@ -43,6 +46,15 @@ type ProviderReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// VerifyCredentials calls the API of the cloud to verify the credentials of Providers, which catches the invalid
+	// ones before the Configurations fail to be applied
+	VerifyCredentials bool
+	// CheckInterval is the interval to check the credentials of Providers again, so the rotated or expired ones are
+	// noticed without Providers being changed. 0 disables it.
+	CheckInterval time.Duration
+	// VerifyInterval is the minimum interval to verify the credentials of a Provider by the cloud again, which stops
+	// the checks and the changes of Providers from calling the API of the cloud too often
+	VerifyInterval time.Duration
 }
 
 // +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=providers,verbs=get;list;watch;create;update;patch;delete
@@ -50,12 +62,16 @@ type ProviderReconciler struct {
 
 // Reconcile will reconcile periodically
 func (r *ProviderReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	// controller-runtime v0.6 doesn't pass a context to Reconcile, which becomes the context passed by it once it's
+	// upgraded
+	return r.reconcile(context.Background(), req)
+}
+
+// reconcile reconciles a Provider with a context, which is honored by the calls to the API server and the cloud
+func (r *ProviderReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	klog.InfoS("reconciling Terraform Provider...", "NamespacedName", req.NamespacedName)
 
-	var (
-		ctx      = context.Background()
-		provider terraformv1beta1.Provider
-	)
+	var provider terraformv1beta1.Provider
 
 	if err := r.Get(ctx, req.NamespacedName, &provider); err != nil {
 		if kerrors.IsNotFound(err) {
@@ -66,25 +82,60 @@ func (r *ProviderReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	err := util.ValidateProviderCredentials(ctx, r.Client, &provider)
 	if err != nil {
-		provider.Status.State = types.ProviderIsInitializing
-		provider.Status.Message = fmt.Sprintf("%s: %s", errGetCredentials, err.Error())
-		klog.ErrorS(err, errGetCredentials, "Provider", req.NamespacedName)
-		if updateErr := r.Status().Update(ctx, &provider); updateErr != nil {
-			klog.ErrorS(updateErr, errSettingStatus, "Provider", req.NamespacedName)
-			return ctrl.Result{}, errors.Wrap(updateErr, errSettingStatus)
-		}
-		return ctrl.Result{}, errors.Wrap(err, errGetCredentials)
+		return ctrl.Result{}, r.setInitializing(ctx, &provider, errGetCredentials, err)
 	}
 
-	provider.Status = terraformv1beta1.ProviderStatus{
+	status := terraformv1beta1.ProviderStatus{
 		State: types.ProviderIsReady,
 	}
+	if r.VerifyCredentials {
+		if r.verifiedRecently(&provider) {
+			// updating the status with a new time would trigger another reconciliation and verification
+			status.Identity, status.VerifiedTime = provider.Status.Identity, provider.Status.VerifiedTime
+		} else {
+			identity, err := util.VerifyProviderIdentity(ctx, r.Client, &provider)
+			switch {
+			case errors.Is(err, util.ErrIdentityVerificationNotSupported):
+				status.Message = err.Error()
+			case err != nil:
+				return ctrl.Result{}, r.setInitializing(ctx, &provider, errVerifyCredentials, err)
+			default:
+				now := metav1.Now()
+				status.Identity, status.VerifiedTime = identity, &now
+			}
+		}
+	}
+	provider.Status = status
 	if updateErr := r.Status().Update(ctx, &provider); updateErr != nil {
 		klog.ErrorS(updateErr, errSettingStatus, "Provider", req.NamespacedName)
 		return ctrl.Result{}, errors.Wrap(updateErr, errSettingStatus)
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: r.CheckInterval}, nil
+}
+
+// verifiedRecently tells whether the credentials of a Provider were verified by the cloud within VerifyInterval
+func (r *ProviderReconciler) verifiedRecently(provider *terraformv1beta1.Provider) bool {
+	status := provider.Status
+	if status.State != types.ProviderIsReady || status.Identity == "" || status.VerifiedTime == nil {
+		return false
+	}
+	return time.Since(status.VerifiedTime.Time) < r.VerifyInterval
+}
+
+// setInitializing marks a Provider not ready as its credentials are not available
+func (r *ProviderReconciler) setInitializing(ctx context.Context, provider *terraformv1beta1.Provider, errMsg string, err error) error {
+	provider.Status = terraformv1beta1.ProviderStatus{
+		State:   types.ProviderIsInitializing,
+		Message: fmt.Sprintf("%s: %s", errMsg, err.Error()),
+	}
+	key := client.ObjectKey{Namespace: provider.Namespace, Name: provider.Name}
+	klog.ErrorS(err, errMsg, "Provider", key)
+	if updateErr := r.Status().Update(ctx, provider); updateErr != nil {
+		klog.ErrorS(updateErr, errSettingStatus, "Provider", key)
+		return errors.Wrap(updateErr, errSettingStatus)
+	}
+	return errors.Wrap(err, errMsg)
 }
 
 // SetupWithManager setups with a manager
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestProviderReconcilerRecheck(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	key := client.ObjectKey{Name: "default", Namespace: "default"}
+	verifiedTime := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+		Spec: v1beta1.ProviderSpec{
+			Provider: "aws",
+			Credentials: v1beta1.ProviderCredentials{
+				Source:    crossplane.CredentialsSourceSecret,
+				SecretRef: &crossplane.SecretKeySelector{SecretReference: crossplane.SecretReference{Name: "aws", Namespace: "default"}, Key: "credentials"},
+			},
+		},
+		Status: v1beta1.ProviderStatus{State: types.ProviderIsReady, Identity: "arn:aws:iam::123456789012:user/tf", VerifiedTime: &verifiedTime},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "default"},
+		Data:       map[string][]byte{"credentials": []byte("awsAccessKeyID: a\nawsSecretAccessKey: b\n")},
+	}
+	r := &ProviderReconciler{
+		Client:            fake.NewFakeClientWithScheme(s, provider, secret),
+		VerifyCredentials: true,
+		CheckInterval:     5 * time.Minute,
+		VerifyInterval:    time.Hour,
+	}
+
+	// the credentials verified a minute ago aren't verified by the cloud again
+	result, err := r.reconcile(ctx, ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != r.CheckInterval {
+		t.Errorf("expected the Provider to be checked again after %s, got %s", r.CheckInterval, result.RequeueAfter)
+	}
+	var got v1beta1.Provider
+	if err := r.Get(ctx, key, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.State != types.ProviderIsReady || got.Status.Identity != provider.Status.Identity ||
+		got.Status.VerifiedTime == nil || !got.Status.VerifiedTime.Equal(&verifiedTime) {
+		t.Errorf("expected the verified status to be kept, got %+v", got.Status)
+	}
+
+	// the credentials removed since the last check mark the Provider not ready
+	if err := r.Delete(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.reconcile(ctx, ctrl.Request{NamespacedName: key}); err == nil {
+		t.Error("expected an error for the missing credentials")
+	}
+	got = v1beta1.Provider{}
+	if err := r.Get(ctx, key, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.State != types.ProviderIsInitializing || got.Status.Identity != "" {
+		t.Errorf("expected the Provider to be initializing, got %+v", got.Status)
+	}
+}
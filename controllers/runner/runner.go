@@ -0,0 +1,296 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package runner runs the steps of the Terraform Jobs without a shell, so that the image of the executor only needs
+// the binary of Terraform or OpenTofu, and could be distroless. The steps are composed by the controller, and passed
+// to the runner binary as JSON.
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// Step is a step of a Terraform Job, which is one of running a command, hashing a file, removing files, writing a
+// file, and the steps depending on whether a file exists
+type Step struct {
+	Run       *Run       `json:"run,omitempty"`
+	Hash      *Hash      `json:"hash,omitempty"`
+	Remove    string     `json:"remove,omitempty"`
+	WriteFile *WriteFile `json:"writeFile,omitempty"`
+	IfExists  *IfExists  `json:"ifExists,omitempty"`
+}
+
+// Run runs a command
+type Run struct {
+	// Args are the command and its arguments, which are run as they are rather than parsed by a shell
+	Args []string `json:"args"`
+	// Unless skips the command if it succeeds, whose output is discarded
+	Unless []string `json:"unless,omitempty"`
+	// Stdout is the file which the output of the command is written to rather than the logs
+	Stdout string `json:"stdout,omitempty"`
+	// Lock is the file locked while the command runs, which is released by the kernel even if the runner is killed
+	Lock string `json:"lock,omitempty"`
+	// FailureMessage is logged when the command fails, which the controller tells the failure by
+	FailureMessage string `json:"failureMessage,omitempty"`
+}
+
+// Hash hashes a file by SHA-256
+type Hash struct {
+	// File is the file hashed
+	File string `json:"file"`
+	// Expected fails the step with FailureMessage unless the file has the hash
+	Expected       string `json:"expected,omitempty"`
+	FailureMessage string `json:"failureMessage,omitempty"`
+	// Output is the file which the hash is written to, like the termination message
+	Output string `json:"output,omitempty"`
+	// SaveFile is copied to SaveDir and named by the hash, replacing the other files in SaveDir
+	SaveFile string `json:"saveFile,omitempty"`
+	SaveDir  string `json:"saveDir,omitempty"`
+}
+
+// WriteFile writes the content to a file
+type WriteFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// IfExists runs Then if the regular file Path exists, or Else otherwise
+type IfExists struct {
+	Path string `json:"path"`
+	Then []Step `json:"then,omitempty"`
+	Else []Step `json:"else,omitempty"`
+}
+
+// Command is the command which runs the steps by the runner binary
+func Command(binary string, steps []Step) []string {
+	program, err := json.Marshal(steps)
+	if err != nil {
+		// the steps are plain strings, which are always marshalled
+		panic(err)
+	}
+	return []string{binary, "run", string(program)}
+}
+
+// ErrTerminated means the runner was terminated, e.g. by the eviction of the pod, before the steps finished
+var ErrTerminated = errors.New("terminated before the steps finished")
+
+// Runner runs the steps one by one, and stops at the first failure. The signals it receives, like SIGTERM when the pod
+// is evicted, are forwarded to the running command, so that Terraform stops gracefully, after which no more steps run.
+type Runner struct {
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Signals <-chan os.Signal
+
+	terminated bool
+}
+
+// Run runs the steps
+func (r *Runner) Run(steps []Step) error {
+	for _, step := range steps {
+		if r.receivedSignal() {
+			return ErrTerminated
+		}
+		if err := r.runStep(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) runStep(step Step) error {
+	switch {
+	case step.Run != nil:
+		return r.run(step.Run)
+	case step.Hash != nil:
+		return hashFile(step.Hash)
+	case step.Remove != "":
+		return removeFiles(step.Remove)
+	case step.WriteFile != nil:
+		return errors.Wrapf(os.WriteFile(step.WriteFile.Path, []byte(step.WriteFile.Content), 0644), //nolint:gosec
+			"failed to write %s", step.WriteFile.Path)
+	case step.IfExists != nil:
+		if info, err := os.Stat(step.IfExists.Path); err == nil && info.Mode().IsRegular() {
+			return r.Run(step.IfExists.Then)
+		}
+		return r.Run(step.IfExists.Else)
+	}
+	return errors.New("empty step")
+}
+
+func (r *Runner) run(run *Run) error {
+	if len(run.Unless) > 0 && r.command(run.Unless, io.Discard, io.Discard) == nil {
+		return nil
+	}
+	if r.terminated {
+		return ErrTerminated
+	}
+	if run.Lock != "" {
+		unlock, err := lockFile(run.Lock)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	stdout := r.Stdout
+	if run.Stdout != "" {
+		f, err := os.Create(run.Stdout)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create %s", run.Stdout)
+		}
+		defer f.Close() //nolint:errcheck
+		stdout = f
+	}
+	err := r.command(run.Args, stdout, r.Stderr)
+	if err != nil && run.FailureMessage != "" && !r.terminated {
+		return errors.New(run.FailureMessage)
+	}
+	return err
+}
+
+// command runs a command, and forwards the signals received meanwhile to it
+func (r *Runner) command(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("empty command")
+	}
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Start(); err != nil {
+		return errors.Wrapf(err, "failed to run %s", args[0])
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case sig := <-r.Signals:
+			r.terminated = true
+			_ = cmd.Process.Signal(sig)
+		}
+	}
+}
+
+// receivedSignal tells whether a signal was received, including the ones received between the commands
+func (r *Runner) receivedSignal() bool {
+	select {
+	case <-r.Signals:
+		r.terminated = true
+	default:
+	}
+	return r.terminated
+}
+
+func hashFile(hash *Hash) error {
+	content, err := os.ReadFile(hash.File)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", hash.File)
+	}
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	if hash.Expected != "" && checksum != hash.Expected {
+		if hash.FailureMessage == "" {
+			return errors.Errorf("the hash of %s is %s rather than %s", hash.File, checksum, hash.Expected)
+		}
+		return errors.New(hash.FailureMessage)
+	}
+	if hash.SaveDir != "" {
+		if err := removeFiles(filepath.Join(hash.SaveDir, "*")); err != nil {
+			return err
+		}
+		if err := copyFile(hash.SaveFile, filepath.Join(hash.SaveDir, checksum), 0644); err != nil {
+			return err
+		}
+	}
+	if hash.Output != "" {
+		if err := os.WriteFile(hash.Output, []byte(checksum+"\n"), 0644); err != nil { //nolint:gosec
+			return errors.Wrapf(err, "failed to write %s", hash.Output)
+		}
+	}
+	return nil
+}
+
+// removeFiles removes the files matching a pattern, and the missing ones are ignored
+func removeFiles(pattern string) error {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove %s", f)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	content, err := os.ReadFile(src) //nolint:gosec
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", src)
+	}
+	return errors.Wrapf(os.WriteFile(dst, content, mode), "failed to write %s", dst)
+}
+
+// lockFile locks a file exclusively, waiting for the other holders
+func lockFile(name string) (func(), error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0644) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open the lock file %s", name)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "failed to lock %s", name)
+	}
+	return func() { _ = f.Close() }, nil
+}
+
+// Install copies the runner binary itself to a path, like an emptyDir volume shared with the executor, so that it's
+// installed by an init container running the image of the controller, which has no shell either
+func Install(dst string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return copyFile(self, dst, 0755)
+}
+
+// ExitCode is the exit code of the runner for the error of the steps, which is the one of the failed command, or 128
+// plus the signal which killed it like a shell
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 1
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	if exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
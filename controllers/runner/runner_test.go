@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunnerRun(t *testing.T) {
+	dir := t.TempDir()
+	file := func(name string) string { return filepath.Join(dir, name) }
+	if err := os.MkdirAll(file("plans"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file("plans/stale"), []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("plan\n"))
+	hash := hex.EncodeToString(sum[:])
+
+	var stdout bytes.Buffer
+	r := &Runner{Stdout: &stdout, Stderr: &stdout}
+	err := r.Run([]Step{
+		{WriteFile: &WriteFile{Path: file("override.tf"), Content: "terraform {}\n"}},
+		{Run: &Run{Args: []string{"echo", "init"}, Lock: file(".lock")}},
+		{Run: &Run{Args: []string{"echo", "plan"}, Stdout: file("plan.txt")}},
+		{Hash: &Hash{File: file("plan.txt"), Expected: hash, Output: file("termination-log"), SaveFile: file("plan.txt"),
+			SaveDir: file("plans")}},
+		{Run: &Run{Args: []string{"false"}, Unless: []string{"true"}}},
+		{IfExists: &IfExists{Path: file("override.tf"), Then: []Step{{Remove: file("*.tf")}}, Else: []Step{{Run: &Run{Args: []string{"false"}}}}}},
+		{IfExists: &IfExists{Path: file("override.tf"), Then: []Step{{Run: &Run{Args: []string{"false"}}}}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "init\n" {
+		t.Errorf("expected only the output of init logged, got %q", stdout.String())
+	}
+	if message, _ := os.ReadFile(file("termination-log")); string(message) != hash+"\n" {
+		t.Errorf("expected the hash written to the termination message, got %q", message)
+	}
+	// the saved plan replaces the previous ones
+	if saved, _ := filepath.Glob(file("plans/*")); len(saved) != 1 || saved[0] != file("plans/"+hash) {
+		t.Errorf("expected only the plan saved by its hash, got %v", saved)
+	}
+	if _, err := os.Stat(file("override.tf")); !os.IsNotExist(err) {
+		t.Errorf("expected the override file removed, got %v", err)
+	}
+
+	// the changed plan fails with the message which the controller tells the failure by
+	err = r.Run([]Step{{Hash: &Hash{File: file("plan.txt"), Expected: "abc", FailureMessage: "Error: The plan changed"}}})
+	if err == nil || err.Error() != "Error: The plan changed" || ExitCode(err) != 1 {
+		t.Errorf("expected the plan changed, got %v", err)
+	}
+	err = r.Run([]Step{{Run: &Run{Args: []string{"false"}, FailureMessage: "Error: Import failed"}}})
+	if err == nil || err.Error() != "Error: Import failed" {
+		t.Errorf("expected the import failed, got %v", err)
+	}
+	// the steps stop at the failed command, whose exit code is the one of the runner
+	err = r.Run([]Step{{Run: &Run{Args: []string{"false"}}}, {WriteFile: &WriteFile{Path: file("after"), Content: ""}}})
+	if ExitCode(err) != 1 {
+		t.Errorf("expected the exit code of the failed command, got %v", err)
+	}
+	if _, err := os.Stat(file("after")); !os.IsNotExist(err) {
+		t.Errorf("expected no steps after the failed one, got %v", err)
+	}
+	if ExitCode(nil) != 0 {
+		t.Error("expected the exit code 0 of the steps succeeded")
+	}
+}
+
+func TestRunnerForwardsSignals(t *testing.T) {
+	dir := t.TempDir()
+	signals := make(chan os.Signal, 1)
+	r := &Runner{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}, Signals: signals}
+
+	// SIGTERM of the eviction stops the running command, and no more steps run
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		signals <- syscall.SIGTERM
+	}()
+	start := time.Now()
+	err := r.Run([]Step{
+		{Run: &Run{Args: []string{"sleep", "30"}, FailureMessage: "Error: failed"}},
+		{WriteFile: &WriteFile{Path: filepath.Join(dir, "after"), Content: ""}},
+	})
+	if err == nil || time.Since(start) > 10*time.Second {
+		t.Fatalf("expected the command stopped by SIGTERM, got %v after %s", err, time.Since(start))
+	}
+	if strings.Contains(err.Error(), "Error: failed") || ExitCode(err) != 128+int(syscall.SIGTERM) {
+		t.Errorf("expected the command terminated rather than failed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "after")); !os.IsNotExist(err) {
+		t.Errorf("expected no steps after the termination, got %v", err)
+	}
+	if err := r.Run([]Step{{Run: &Run{Args: []string{"true"}}}}); err != ErrTerminated {
+		t.Errorf("expected no more steps run once terminated, got %v", err)
+	}
+}
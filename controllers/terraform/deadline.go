@@ -0,0 +1,50 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// jobDeadlineExceededReason is the reason of the failed condition of a Job which ran past its activeDeadlineSeconds
+const jobDeadlineExceededReason = "DeadlineExceeded"
+
+// IsJobDeadlineExceeded tells whether a Job was stopped by Kubernetes after its activeDeadlineSeconds
+func IsJobDeadlineExceeded(job batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == v1.ConditionTrue && condition.Reason == jobDeadlineExceededReason {
+			return true
+		}
+	}
+	return false
+}
+
+// DeadlineExceededMessage is the failure of a Job stopped after its deadline
+func DeadlineExceededMessage(job batchv1.Job) string {
+	if job.Spec.ActiveDeadlineSeconds == nil {
+		return JobDeadlineExceededMessage
+	}
+	deadline := time.Duration(*job.Spec.ActiveDeadlineSeconds) * time.Second
+	return fmt.Sprintf("%s of %s", JobDeadlineExceededMessage, deadline)
+}
+
+// getDeadlineExceeded returns the failure of a Job if it was stopped after its deadline, or empty otherwise
+func getDeadlineExceeded(ctx context.Context, client kubernetes.Interface, namespace, jobName string) (string, error) {
+	job, err := client.BatchV1().Jobs(namespace).Get(ctx, jobName, metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !IsJobDeadlineExceeded(*job) {
+		return "", nil
+	}
+	return DeadlineExceededMessage(*job), nil
+}
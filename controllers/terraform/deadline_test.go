@@ -0,0 +1,48 @@
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+func TestGetDeadlineExceeded(t *testing.T) {
+	ctx := context.Background()
+	deadline := int64(1800)
+	timedOut := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss-apply", Namespace: "vela-system"},
+		Spec:       batchv1.JobSpec{ActiveDeadlineSeconds: &deadline},
+		Status: batchv1.JobStatus{Failed: 1, Conditions: []batchv1.JobCondition{{
+			Type:    batchv1.JobFailed,
+			Status:  v1.ConditionTrue,
+			Reason:  "DeadlineExceeded",
+			Message: "Job was active longer than specified deadline",
+		}}},
+	}
+	running := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss-destroy", Namespace: "vela-system"},
+		Spec:       batchv1.JobSpec{ActiveDeadlineSeconds: &deadline},
+		Status:     batchv1.JobStatus{Active: 1},
+	}
+	client := fake.NewSimpleClientset(timedOut, running)
+
+	errMsg, err := getDeadlineExceeded(ctx, client, "vela-system", "oss-apply")
+	if err != nil || errMsg != "Error: The Job was stopped after its deadline of 30m0s" {
+		t.Fatalf("expected the Job timed out, got %q, %v", errMsg, err)
+	}
+	if reason := ClassifyFailure(errMsg); reason != types.FailureReasonTimeout {
+		t.Errorf("expected the failure classified as %s, got %s", types.FailureReasonTimeout, reason)
+	}
+	if errMsg, err := getDeadlineExceeded(ctx, client, "vela-system", "oss-destroy"); err != nil || errMsg != "" {
+		t.Errorf("expected the running Job not timed out, got %q, %v", errMsg, err)
+	}
+	if errMsg, err := getDeadlineExceeded(ctx, client, "vela-system", "oss-missing"); err != nil || errMsg != "" {
+		t.Errorf("expected no failure without the Job, got %q, %v", errMsg, err)
+	}
+}
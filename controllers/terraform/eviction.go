@@ -0,0 +1,48 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrPodEvicted means the pods of a Job were evicted, e.g. by a node drain, before Terraform finished
+var ErrPodEvicted = errors.New("evicted before Terraform finished")
+
+// podDisruptionTarget is the condition of a pod which is being deleted by a disruption, like the eviction API
+const podDisruptionTarget v1.PodConditionType = "DisruptionTarget"
+
+// isPodEvicted tells whether a pod was evicted by the kubelet for the node pressure, or by the eviction API
+func isPodEvicted(pod v1.Pod) bool {
+	if pod.Status.Phase == v1.PodFailed && pod.Status.Reason == "Evicted" {
+		return true
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == podDisruptionTarget && condition.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// getEvictedPod returns an evicted pod of a Job if all of its pods were evicted, in which case there are no logs to
+// tell how far Terraform went
+func getEvictedPod(ctx context.Context, client kubernetes.Interface, namespace, jobName string) (*v1.Pod, error) {
+	label := fmt.Sprintf("job-name=%s", jobName)
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: label})
+	if err != nil {
+		return nil, err
+	}
+	var evicted *v1.Pod
+	for i, pod := range pods.Items {
+		if !isPodEvicted(pod) {
+			return nil, nil
+		}
+		evicted = &pods.Items[i]
+	}
+	return evicted, nil
+}
@@ -0,0 +1,37 @@
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetEvictedPod(t *testing.T) {
+	ctx := context.Background()
+	pod := func(name string, status v1.PodStatus) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "vela-system", Labels: map[string]string{"job-name": "oss-apply"}},
+			Status:     status,
+		}
+	}
+	evicted := pod("oss-apply-1", v1.PodStatus{Phase: v1.PodFailed, Reason: "Evicted"})
+	drained := pod("oss-apply-2", v1.PodStatus{Phase: v1.PodRunning, Conditions: []v1.PodCondition{
+		{Type: podDisruptionTarget, Status: v1.ConditionTrue},
+	}})
+
+	got, err := getEvictedPod(ctx, fake.NewSimpleClientset(evicted, drained), "vela-system", "oss-apply")
+	if err != nil || got == nil {
+		t.Fatalf("expected an evicted pod, got %v, %v", got, err)
+	}
+
+	client := fake.NewSimpleClientset(evicted, pod("oss-apply-3", v1.PodStatus{Phase: v1.PodRunning}))
+	if got, err := getEvictedPod(ctx, client, "vela-system", "oss-apply"); err != nil || got != nil {
+		t.Errorf("expected no evicted pod when another pod runs, got %v, %v", got, err)
+	}
+	if got, err := getEvictedPod(ctx, client, "vela-system", "oss-destroy"); err != nil || got != nil {
+		t.Errorf("expected no evicted pod without pods, got %v, %v", got, err)
+	}
+}
@@ -13,7 +13,19 @@ import (
 	"k8s.io/klog/v2"
 )
 
-func initClientSet() (*kubernetes.Clientset, error) {
+// clientSetKey is the key of the client set of the cluster which the Jobs run in
+type clientSetKey struct{}
+
+// WithClientSet returns a context whose pods of the Jobs are read by the client set, like the one of the workload
+// cluster which the Jobs run in. The ones in the cluster of the controller are read otherwise.
+func WithClientSet(ctx context.Context, clientSet kubernetes.Interface) context.Context {
+	return context.WithValue(ctx, clientSetKey{}, clientSet)
+}
+
+func initClientSet(ctx context.Context) (kubernetes.Interface, error) {
+	if clientSet, ok := ctx.Value(clientSetKey{}).(kubernetes.Interface); ok {
+		return clientSet, nil
+	}
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, err
@@ -21,14 +33,31 @@ func initClientSet() (*kubernetes.Clientset, error) {
 	return kubernetes.NewForConfig(config)
 }
 
-func getPodLog(ctx context.Context, client *kubernetes.Clientset, namespace, jobName string) (string, error) {
+// GetTerraformLogs gets the logs of the pod of a Job
+func GetTerraformLogs(ctx context.Context, namespace, jobName string) (string, error) {
+	clientSet, err := initClientSet(ctx)
+	if err != nil {
+		klog.ErrorS(err, "failed to init clientSet")
+		return "", err
+	}
+	return getPodLog(ctx, clientSet, namespace, jobName)
+}
+
+func getPodLog(ctx context.Context, client kubernetes.Interface, namespace, jobName string) (string, error) {
 	label := fmt.Sprintf("job-name=%s", jobName)
 	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: label})
 	if err != nil || pods == nil || len(pods.Items) == 0 {
 		klog.InfoS("pods are not found", "Label", label)
 		return "", nil //nolint:nilerr
 	}
+	// the logs of an evicted pod are incomplete, or gone with its containers
 	pod := pods.Items[0]
+	for _, p := range pods.Items {
+		if !isPodEvicted(p) {
+			pod = p
+			break
+		}
+	}
 
 	req := client.CoreV1().Pods(namespace).GetLogs(pod.Name, &v1.PodLogOptions{})
 	logs, err := req.Stream(ctx)
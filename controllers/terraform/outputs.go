@@ -0,0 +1,42 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// GetTerraformOutputs gets the outputs which the container of a succeeded Job wrote to its termination message, in the
+// format of `terraform output -json`
+func GetTerraformOutputs(ctx context.Context, namespace, jobName, containerName string) ([]byte, error) {
+	clientSet, err := initClientSet(ctx)
+	if err != nil {
+		klog.ErrorS(err, "failed to init clientSet")
+		return nil, err
+	}
+	return getTerminationMessage(ctx, clientSet, namespace, jobName, containerName)
+}
+
+func getTerminationMessage(ctx context.Context, client kubernetes.Interface, namespace, jobName, containerName string) ([]byte, error) {
+	label := fmt.Sprintf("job-name=%s", jobName)
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: label})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the pods of Job %s", jobName)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodSucceeded {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == containerName && status.State.Terminated != nil {
+				return []byte(status.State.Terminated.Message), nil
+			}
+		}
+	}
+	return nil, errors.Errorf("no succeeded pod of Job %s is found", jobName)
+}
@@ -0,0 +1,67 @@
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetTerminationMessage(t *testing.T) {
+	ctx := context.Background()
+	pod := func(name string, phase v1.PodPhase, message string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "vela-system", Labels: map[string]string{"job-name": "oss-apply"}},
+			Status: v1.PodStatus{
+				Phase: phase,
+				ContainerStatuses: []v1.ContainerStatus{{
+					Name:  "terraform-executor",
+					State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Message: message}},
+				}},
+			},
+		}
+	}
+	outputs := `{"name":{"sensitive":false,"type":"string","value":"oss"}}`
+	client := fake.NewSimpleClientset(pod("oss-apply-1", v1.PodFailed, ""), pod("oss-apply-2", v1.PodSucceeded, outputs))
+
+	data, err := getTerminationMessage(ctx, client, "vela-system", "oss-apply", "terraform-executor")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != outputs {
+		t.Errorf("expected outputs %s, got %s", outputs, data)
+	}
+
+	if _, err := getTerminationMessage(ctx, client, "vela-system", "oss-destroy", "terraform-executor"); err == nil {
+		t.Error("expected an error without a succeeded pod")
+	}
+}
+
+func TestGetInitContainerFailure(t *testing.T) {
+	ctx := context.Background()
+	message := "Error: path modules/vpc doesn't exist or is empty in the repo, available directories: modules/oss"
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "oss-apply-1", Namespace: "vela-system", Labels: map[string]string{"job-name": "oss-apply"}},
+		Status: v1.PodStatus{
+			InitContainerStatuses: []v1.ContainerStatus{{
+				Name:                 "git-configuration",
+				State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Message: message + "\n"}},
+			}},
+		},
+	}
+	client := fake.NewSimpleClientset(pod)
+
+	errMsg, err := getInitContainerFailure(ctx, client, "vela-system", "oss-apply")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "init container git-configuration failed: " + message; errMsg != expected {
+		t.Errorf("expected %q, got %q", expected, errMsg)
+	}
+	if errMsg, err := getInitContainerFailure(ctx, client, "vela-system", "oss-destroy"); err != nil || errMsg != "" {
+		t.Errorf("expected no failure, got %q, %v", errMsg, err)
+	}
+}
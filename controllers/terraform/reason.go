@@ -0,0 +1,88 @@
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+// ImportFailedMessage is logged by the apply Job when a resource of spec.imports fails to be imported
+const ImportFailedMessage = "Error: Import failed"
+
+// PlanChangedMessage is logged by the apply Job when its plan differs from the approved one, which isn't applied
+const PlanChangedMessage = "Error: The plan changed after it was approved"
+
+// StalePlanMessage is logged by Terraform when the saved plan isn't applied, as the state has changed since it was made
+const StalePlanMessage = "Saved plan is stale"
+
+// JobDeadlineExceededMessage tells a Job was stopped by Kubernetes after its activeDeadlineSeconds, whose pods are
+// deleted with the logs
+const JobDeadlineExceededMessage = "Error: The Job was stopped after its deadline"
+
+// VerifyContainerName is the init container which verifies the signature of the source of the configuration
+const VerifyContainerName = "verify-configuration"
+
+// IsVerificationFailure tells whether a Job failed as the source of the configuration isn't signed by a trusted key
+func IsVerificationFailure(message string) bool {
+	return strings.Contains(message, fmt.Sprintf("init container %s failed", VerifyContainerName))
+}
+
+// failurePatterns recognize the failures in the logs of Terraform and OpenTofu, which are checked in order
+var failurePatterns = []struct {
+	reason  types.FailureReason
+	pattern *regexp.Regexp
+}{
+	{
+		reason:  types.FailureReasonTimeout,
+		pattern: regexp.MustCompile(regexp.QuoteMeta(JobDeadlineExceededMessage)),
+	},
+	{
+		reason:  types.FailureReasonImportFailed,
+		pattern: regexp.MustCompile(regexp.QuoteMeta(ImportFailedMessage)),
+	},
+	{
+		reason: types.FailureReasonInvalidConfiguration,
+		pattern: regexp.MustCompile(`Error: (Unsupported argument|Missing required argument|Unsupported block type|` +
+			`Unsupported attribute|Reference to undeclared|Invalid reference|Invalid expression|Invalid function argument|` +
+			`Call to unknown function|Argument or block definition required|Missing newline after argument|` +
+			`Unclosed configuration block|Incorrect attribute value type|Duplicate resource|No value for required variable|` +
+			`Invalid value for (input )?variable|path .* doesn't exist or is empty in the (repo|artifact))`),
+	},
+	{
+		reason: types.FailureReasonCredentialError,
+		pattern: regexp.MustCompile(`(InvalidAccessKeyId|SignatureDoesNotMatch|InvalidClientTokenId|UnrecognizedClientException|` +
+			`AuthFailure|ExpiredToken|IncompleteSignature|InvalidAccessKeySecret|Forbidden\.AccessKey|` +
+			`No valid credential sources|AADSTS\d+|invalid_client|StatusCode=401|401 Unauthorized|AccessDenied|` +
+			`not authorized to perform)`),
+	},
+	{
+		reason:  types.FailureReasonThrottled,
+		pattern: regexp.MustCompile(`(?i)(Throttling|Rate exceeded|TooManyRequests|Too Many Requests|RequestLimitExceeded|rate limit)`),
+	},
+	{
+		reason:  types.FailureReasonQuotaExceeded,
+		pattern: regexp.MustCompile(`(?i)(QuotaExceed|quota exceeded|exceeded .*quota|LimitExceeded|exceeds the limit)`),
+	},
+	{
+		reason: types.FailureReasonResourceAlreadyExists,
+		pattern: regexp.MustCompile(`(?i)(AlreadyExists|already exists|AlreadyOwnedByYou|ResourceExistsError|` +
+			`needs to be imported into the State)`),
+	},
+	{
+		reason:  types.FailureReasonDependencyViolation,
+		pattern: regexp.MustCompile(`(?i)(DependencyViolation|has a dependent object|DependentResource|resource ?is ?in ?use|InUseBy)`),
+	},
+}
+
+// ClassifyFailure classifies the failure in the logs of a Job, which is the message of the failed Configuration
+func ClassifyFailure(logs string) types.FailureReason {
+	logs = ansiEscapeRegexp.ReplaceAllString(logs, "")
+	for _, p := range failurePatterns {
+		if p.pattern.MatchString(logs) {
+			return p.reason
+		}
+	}
+	return types.FailureReasonUnknown
+}
@@ -0,0 +1,63 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	testcases := map[string]struct {
+		logs   string
+		reason types.FailureReason
+	}{
+		"hcl error": {
+			logs:   "\x1b[31m│\x1b[0m \x1b[1m\x1b[31mError: \x1b[0m\x1b[0m\x1b[1mUnsupported argument\x1b[0m",
+			reason: types.FailureReasonInvalidConfiguration,
+		},
+		"throttled": {
+			logs:   "Error: error creating VPC: Throttling.User: Request was denied due to user flow control.",
+			reason: types.FailureReasonThrottled,
+		},
+		"credential error": {
+			logs:   "Error: error configuring Terraform AWS Provider: InvalidClientTokenId: The security token included in the request is invalid.",
+			reason: types.FailureReasonCredentialError,
+		},
+		"quota exceeded": {
+			logs:   "Error: Error creating VPC: VpcLimitExceeded: The maximum number of VPCs has been reached.",
+			reason: types.FailureReasonQuotaExceeded,
+		},
+		"throttled rather than quota exceeded": {
+			logs:   "Error: RequestLimitExceeded: Request limit exceeded.",
+			reason: types.FailureReasonThrottled,
+		},
+		"resource already exists": {
+			logs:   "Error: creating S3 Bucket (oss): BucketAlreadyOwnedByYou: Your previous request to create the named bucket succeeded",
+			reason: types.FailureReasonResourceAlreadyExists,
+		},
+		"azure resource already exists": {
+			logs: `Error: A resource with the ID "/subscriptions/x/resourceGroups/rg" already exists - to be managed via Terraform ` +
+				`this resource needs to be imported into the State.`,
+			reason: types.FailureReasonResourceAlreadyExists,
+		},
+		"dependency violation": {
+			logs:   "Error: error deleting EC2 VPC (vpc-1): DependencyViolation: The vpc 'vpc-1' has dependencies and cannot be deleted.",
+			reason: types.FailureReasonDependencyViolation,
+		},
+		"import failed": {
+			logs:   "Error: Cannot import non-existent remote object\nError: Import failed: alicloud_vpc.main",
+			reason: types.FailureReasonImportFailed,
+		},
+		"unknown": {
+			logs:   "Error: something went wrong",
+			reason: types.FailureReasonUnknown,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			if reason := ClassifyFailure(tc.logs); reason != tc.reason {
+				t.Errorf("expected reason %s, got %s", tc.reason, reason)
+			}
+		})
+	}
+}
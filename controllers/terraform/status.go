@@ -2,39 +2,295 @@ package terraform
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
 )
 
-// GetTerraformStatus will get Terraform execution status
-func GetTerraformStatus(ctx context.Context, namespace, jobName string) error {
+// GetTerraformStatus will get Terraform execution status, along with the summary of the plan if it's found
+func GetTerraformStatus(ctx context.Context, namespace, jobName string) (*v1beta1.PlanSummary, error) {
 	klog.InfoS("checking Terraform execution status", "Namespace", namespace, "Job", jobName)
-	clientSet, err := initClientSet()
+	clientSet, err := initClientSet(ctx)
 	if err != nil {
 		klog.ErrorS(err, "failed to init clientSet")
-		return err
+		return nil, err
+	}
+
+	// the pods of a Job stopped after its deadline are deleted, and their logs can't tell why it failed
+	if errMsg, err := getDeadlineExceeded(ctx, clientSet, namespace, jobName); err != nil || errMsg != "" {
+		if err != nil {
+			klog.ErrorS(err, "failed to check the deadline of the Job")
+			return nil, err
+		}
+		return nil, errors.New(errMsg)
+	}
+
+	// the executor doesn't run when an init container fails, like when the remote git repo can't be prepared
+	if errMsg, err := getInitContainerFailure(ctx, clientSet, namespace, jobName); err != nil || errMsg != "" {
+		if err != nil {
+			klog.ErrorS(err, "failed to check the init containers")
+			return nil, err
+		}
+		return nil, errors.New(errMsg)
+	}
+
+	// the Job creates another pod for an evicted one, which is waited for
+	if pod, err := getEvictedPod(ctx, clientSet, namespace, jobName); err != nil || pod != nil {
+		if err != nil {
+			klog.ErrorS(err, "failed to check the evicted pods")
+			return nil, err
+		}
+		return nil, errors.Wrapf(ErrPodEvicted, "pod %s", pod.Name)
 	}
 
 	logs, err := getPodLog(ctx, clientSet, namespace, jobName)
 	if err != nil {
 		klog.ErrorS(err, "failed to get pod logs")
-		return err
+		return nil, err
 	}
 
-	success, errMsg := analyzeTerraformLog(logs)
+	summary, success, errMsg := analyzeLogs(logs)
 	if success {
-		return nil
+		return summary, nil
+	}
+
+	return summary, errors.New(errMsg)
+}
+
+// analyzeLogs finds the summary of the plan and the error in the logs of a Job. The machine-readable messages of the
+// commands run with -json are parsed, while the ones which don't support it, like init, log plain text.
+func analyzeLogs(logs string) (*v1beta1.PlanSummary, bool, string) {
+	messages, plain := splitJSONLogs(logs)
+	plain = stripTerraformLogs(plain)
+	summary := analyzeJSONPlanSummary(messages)
+	if summary == nil {
+		summary = analyzePlanSummary(plain)
+	}
+	if errMsg := analyzeJSONDiagnostics(messages); errMsg != "" {
+		return summary, false, errMsg
+	}
+	success, errMsg := analyzeTerraformLog(plain)
+	return summary, success, errMsg
+}
+
+// GetApplyProgress reads the logs of a running apply Job for how many of the planned changes it has made. The total is
+// 0 if the plan isn't found in the logs yet, or isn't logged at all, like when an approved plan is applied.
+func GetApplyProgress(ctx context.Context, namespace, jobName string) (int, int, error) {
+	clientSet, err := initClientSet(ctx)
+	if err != nil {
+		klog.ErrorS(err, "failed to init clientSet")
+		return 0, 0, err
+	}
+	logs, err := getPodLog(ctx, clientSet, namespace, jobName)
+	if err != nil {
+		return 0, 0, err
+	}
+	completed, total := analyzeApplyProgress(logs)
+	return completed, total, nil
+}
+
+// completedChangeRegexp matches the plain text of a resource which has been created, changed or destroyed, like
+// `aws_s3_bucket.bucket: Creation complete after 2s [id=bucket]`, while the data sources read aren't counted
+var completedChangeRegexp = regexp.MustCompile(`: (Creation|Modifications|Destruction) complete after `)
+
+// analyzeApplyProgress counts the changes made in the logs of an apply, and the changes planned
+func analyzeApplyProgress(logs string) (int, int) {
+	messages, plain := splitJSONLogs(logs)
+	summary := analyzeJSONPlanSummary(messages)
+	if summary == nil {
+		summary = analyzePlanSummary(plain)
+	}
+	var completed, total int
+	if summary != nil {
+		total = summary.Add + summary.Change + summary.Destroy
+	}
+	for _, m := range messages {
+		if m.Type == "apply_complete" && m.Hook != nil && m.Hook.Action != "read" {
+			completed++
+		}
+	}
+	for _, line := range strings.Split(plain, "\n") {
+		if completedChangeRegexp.MatchString(ansiEscapeRegexp.ReplaceAllString(line, "")) {
+			completed++
+		}
+	}
+	return completed, total
+}
+
+// jsonMessage is a message of the machine-readable UI of Terraform and OpenTofu, logged by the commands run with -json
+type jsonMessage struct {
+	Level      string          `json:"@level"`
+	Message    string          `json:"@message"`
+	Type       string          `json:"type"`
+	Diagnostic *jsonDiagnostic `json:"diagnostic,omitempty"`
+	Changes    *struct {
+		Add       int    `json:"add"`
+		Change    int    `json:"change"`
+		Remove    int    `json:"remove"`
+		Operation string `json:"operation"`
+	} `json:"changes,omitempty"`
+	Hook *struct {
+		Action string `json:"action"`
+	} `json:"hook,omitempty"`
+}
+
+type jsonDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Address  string `json:"address"`
+	Range    *struct {
+		Filename string `json:"filename"`
+		Start    struct {
+			Line int `json:"line"`
+		} `json:"start"`
+	} `json:"range,omitempty"`
+}
+
+// splitJSONLogs separates the machine-readable messages from the plain text lines in the logs
+func splitJSONLogs(logs string) ([]jsonMessage, string) {
+	var (
+		messages []jsonMessage
+		plain    []string
+	)
+	for _, line := range strings.Split(logs, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, `{"@level"`) {
+			var m jsonMessage
+			if err := json.Unmarshal([]byte(trimmed), &m); err == nil {
+				messages = append(messages, m)
+				continue
+			}
+		}
+		plain = append(plain, line)
+	}
+	return messages, strings.Join(plain, "\n")
+}
+
+// analyzeJSONPlanSummary finds the summary of the changes of the plan in the machine-readable messages
+func analyzeJSONPlanSummary(messages []jsonMessage) *v1beta1.PlanSummary {
+	for _, m := range messages {
+		if m.Type != "change_summary" || m.Changes == nil || m.Changes.Operation != "plan" {
+			continue
+		}
+		c := m.Changes
+		if c.Add == 0 && c.Change == 0 && c.Remove == 0 {
+			return &v1beta1.PlanSummary{NoChanges: true}
+		}
+		return &v1beta1.PlanSummary{Add: c.Add, Change: c.Change, Destroy: c.Remove}
+	}
+	return nil
+}
+
+// analyzeJSONDiagnostics composes the error diagnostics in the machine-readable messages like the plain text of them,
+// so that the failures are classified in the same way
+func analyzeJSONDiagnostics(messages []jsonMessage) string {
+	var errs []string
+	for _, m := range messages {
+		d := m.Diagnostic
+		if m.Type != "diagnostic" || d == nil || d.Severity != "error" {
+			continue
+		}
+		errMsg := "Error: " + d.Summary
+		if d.Address != "" {
+			errMsg += fmt.Sprintf("\n\n  with %s", d.Address)
+		}
+		if d.Range != nil && d.Range.Filename != "" {
+			errMsg += fmt.Sprintf("\n  on %s line %d", d.Range.Filename, d.Range.Start.Line)
+		}
+		if d.Detail != "" {
+			errMsg += "\n\n" + d.Detail
+		}
+		errs = append(errs, errMsg)
+	}
+	return strings.Join(errs, "\n\n")
+}
+
+// getInitContainerFailure returns the termination message of the init container of the pods of a Job which failed
+func getInitContainerFailure(ctx context.Context, client kubernetes.Interface, namespace, jobName string) (string, error) {
+	label := fmt.Sprintf("job-name=%s", jobName)
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: label})
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.InitContainerStatuses {
+			// the init container could be restarting, whose failure is in the last state
+			for _, terminated := range []*v1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+				if terminated != nil && terminated.ExitCode != 0 && terminated.Message != "" {
+					return fmt.Sprintf("init container %s failed: %s", status.Name, strings.TrimSpace(terminated.Message)), nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+// planCountRegexps match the counts in the summary of the plan, which could also have `to import` in between
+var planCountRegexps = map[string]*regexp.Regexp{
+	"add":     regexp.MustCompile(`(\d+) to add`),
+	"change":  regexp.MustCompile(`(\d+) to change`),
+	"destroy": regexp.MustCompile(`(\d+) to destroy`),
+}
+
+// analyzePlanSummary finds the summary of the plan in the log, which is like `Plan: 1 to add, 0 to change, 0 to
+// destroy.`, or `No changes.` when the infrastructure matches the configuration
+func analyzePlanSummary(logs string) *v1beta1.PlanSummary {
+	for _, line := range strings.Split(logs, "\n") {
+		line = strings.TrimSpace(ansiEscapeRegexp.ReplaceAllString(line, ""))
+		if strings.HasPrefix(line, "No changes.") {
+			return &v1beta1.PlanSummary{NoChanges: true}
+		}
+		if !strings.HasPrefix(line, "Plan:") {
+			continue
+		}
+		counts := make(map[string]int)
+		for name, re := range planCountRegexps {
+			if m := re.FindStringSubmatch(line); m != nil {
+				counts[name], _ = strconv.Atoi(m[1])
+			}
+		}
+		return &v1beta1.PlanSummary{Add: counts["add"], Change: counts["change"], Destroy: counts["destroy"]}
 	}
+	return nil
+}
+
+// ansiEscapeRegexp matches the color codes in the output of Terraform/OpenTofu
+var ansiEscapeRegexp = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 
-	return errors.New(errMsg)
+// terraformLogRegexp matches a line logged by Terraform or OpenTofu when TF_LOG is set, like
+// `2023-05-04T08:09:10.123Z [DEBUG] provider: starting plugin`
+var terraformLogRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\S+ \[(TRACE|DEBUG|INFO|WARN|ERROR)\]`)
+
+// stripTerraformLogs leaves out the lines logged by Terraform for TF_LOG, so that they don't end up in the status
+func stripTerraformLogs(logs string) string {
+	lines := strings.Split(logs, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !terraformLogRegexp.MatchString(ansiEscapeRegexp.ReplaceAllString(line, "")) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
 }
 
+// analyzeTerraformLog finds the error in the log of Terraform or OpenTofu. Both mark an error with `Error:`, while
+// the line could be colored and prefixed with the diagnostic box-drawing character `│`.
 func analyzeTerraformLog(logs string) (bool, string) {
 	lines := strings.Split(logs, "\n")
 	for i, line := range lines {
-		if strings.Contains(line, "31mError:") {
+		line = strings.TrimSpace(ansiEscapeRegexp.ReplaceAllString(line, ""))
+		line = strings.TrimSpace(strings.TrimPrefix(line, "│"))
+		if strings.HasPrefix(line, "Error:") {
 			errMsg := strings.Join(lines[i:], "\n")
 			return false, errMsg
 		}
@@ -0,0 +1,145 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestAnalyzeTerraformLog(t *testing.T) {
+	testcases := map[string]struct {
+		logs    string
+		success bool
+		errMsg  string
+	}{
+		"terraform succeeded": {
+			logs:    "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+			success: true,
+		},
+		"terraform colored error": {
+			logs:    "Plan: 1 to add\n\x1b[31m│\x1b[0m \x1b[0m\x1b[1m\x1b[31mError: \x1b[0m\x1b[0m\x1b[1mInvalid region\n│ bad",
+			success: false,
+			errMsg:  "\x1b[31m│\x1b[0m \x1b[0m\x1b[1m\x1b[31mError: \x1b[0m\x1b[0m\x1b[1mInvalid region\n│ bad",
+		},
+		"opentofu plain error": {
+			logs:    "Initializing...\n╷\n│ Error: Unsupported argument\n╵",
+			success: false,
+			errMsg:  "│ Error: Unsupported argument\n╵",
+		},
+		"validate error": {
+			logs: "Initializing provider plugins...\nTerraform has been successfully initialized!\n\n" +
+				"Error: Unsupported argument\n\n  on main.tf line 3, in resource \"aws_vpc\" \"main\":\n   3:   cidr = \"10.0.0.0/16\"",
+			success: false,
+			errMsg:  "Error: Unsupported argument\n\n  on main.tf line 3, in resource \"aws_vpc\" \"main\":\n   3:   cidr = \"10.0.0.0/16\"",
+		},
+		"error word in a resource name": {
+			logs:    "alicloud_oss_bucket.Error: Creating...",
+			success: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			success, errMsg := analyzeTerraformLog(tc.logs)
+			if success != tc.success {
+				t.Errorf("expected success %v, got %v", tc.success, success)
+			}
+			if errMsg != tc.errMsg {
+				t.Errorf("expected error message %q, got %q", tc.errMsg, errMsg)
+			}
+		})
+	}
+}
+
+func TestAnalyzePlanSummary(t *testing.T) {
+	testcases := map[string]struct {
+		logs     string
+		expected *v1beta1.PlanSummary
+	}{
+		"colored plan": {
+			logs:     "Terraform will perform the following actions:\n\x1b[1mPlan:\x1b[0m 2 to add, 1 to change, 0 to destroy.\nApply complete!",
+			expected: &v1beta1.PlanSummary{Add: 2, Change: 1},
+		},
+		"plan with imports": {
+			logs:     "Plan: 1 to import, 0 to add, 0 to change, 3 to destroy.",
+			expected: &v1beta1.PlanSummary{Destroy: 3},
+		},
+		"no changes": {
+			logs:     "No changes. Your infrastructure matches the configuration.",
+			expected: &v1beta1.PlanSummary{NoChanges: true},
+		},
+		"no plan yet": {
+			logs: "Initializing the backend...",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			if got := analyzePlanSummary(tc.logs); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAnalyzeJSONLogs(t *testing.T) {
+	logs := "Initializing the backend...\nTerraform has been successfully initialized!\n" +
+		`{"@level":"info","@message":"Terraform 1.5.7","@module":"terraform.ui","type":"version","terraform":"1.5.7","ui":"1.1"}` + "\n" +
+		`{"@level":"info","@message":"aws_s3_bucket.b: Plan to create","type":"planned_change","change":{"action":"create"}}` + "\n" +
+		`{"@level":"info","@message":"Plan: 2 to add, 0 to change, 1 to destroy.","type":"change_summary","changes":{"add":2,"change":0,"import":0,"remove":1,"operation":"plan"}}` + "\n" +
+		`{"@level":"info","@message":"aws_s3_bucket.b: Creating...","type":"apply_start","hook":{"resource":{"addr":"aws_s3_bucket.b"},"action":"create"}}` + "\n" +
+		`{"@level":"error","@message":"Error: creating S3 Bucket (b): BucketAlreadyExists","type":"diagnostic","diagnostic":{"severity":"error","summary":"creating S3 Bucket (b): BucketAlreadyExists","detail":"status code: 409","address":"aws_s3_bucket.b","range":{"filename":"main.tf","start":{"line":3,"column":1,"byte":30}}}}` + "\n" +
+		`{"@level":"warn","@message":"Warning: Argument is deprecated","type":"diagnostic","diagnostic":{"severity":"warning","summary":"Argument is deprecated","detail":""}}`
+
+	summary, success, errMsg := analyzeLogs(logs)
+	if !reflect.DeepEqual(summary, &v1beta1.PlanSummary{Add: 2, Destroy: 1}) {
+		t.Errorf("expected the summary of the plan, got %v", summary)
+	}
+	expected := "Error: creating S3 Bucket (b): BucketAlreadyExists\n\n  with aws_s3_bucket.b\n  on main.tf line 3\n\nstatus code: 409"
+	if success || errMsg != expected {
+		t.Errorf("expected the error %q, got %v, %q", expected, success, errMsg)
+	}
+
+	// the commands without -json, like init, still log plain text
+	summary, success, errMsg = analyzeLogs("Initializing the backend...\n│ Error: Failed to query available provider packages")
+	if summary != nil || success || errMsg != "│ Error: Failed to query available provider packages" {
+		t.Errorf("expected the plain error of init, got %v, %v, %q", summary, success, errMsg)
+	}
+
+	// the logs of Terraform for TF_LOG are left out of the error
+	debugLogs := "2023-05-04T08:09:10.123Z [DEBUG] provider: starting plugin\n" +
+		"│ Error: Invalid provider configuration\n" +
+		"2023-05-04T08:09:10.456Z [TRACE] provider.stdio: waiting for stdio data\n" +
+		"│ region is required"
+	if _, success, errMsg := analyzeLogs(debugLogs); success || errMsg != "│ Error: Invalid provider configuration\n│ region is required" {
+		t.Errorf("expected the error without the logs of Terraform, got %v, %q", success, errMsg)
+	}
+
+	noChanges := `{"@level":"info","@message":"Plan: 0 to add, 0 to change, 0 to destroy.","type":"change_summary","changes":{"add":0,"change":0,"remove":0,"operation":"plan"}}`
+	if summary, success, _ := analyzeLogs(noChanges); !success || !reflect.DeepEqual(summary, &v1beta1.PlanSummary{NoChanges: true}) {
+		t.Errorf("expected no changes, got %v", summary)
+	}
+}
+
+func TestAnalyzeApplyProgress(t *testing.T) {
+	plain := "Plan: 2 to add, 1 to change, 0 to destroy.\n" +
+		"data.aws_caller_identity.current: Read complete after 0s [id=123456789012]\n" +
+		"aws_s3_bucket.a: Creating...\n" +
+		"aws_s3_bucket.a: Creation complete after 2s [id=a]\n" +
+		"\x1b[0m\x1b[1maws_iam_role.r: Modifications complete after 1s [id=r]\x1b[0m\n" +
+		"aws_s3_bucket.b: Creating..."
+	if completed, total := analyzeApplyProgress(plain); completed != 2 || total != 3 {
+		t.Errorf("expected 2 of 3 changes, got %d of %d", completed, total)
+	}
+
+	json := `{"@level":"info","@message":"Plan: 1 to add, 0 to change, 1 to destroy.","type":"change_summary","changes":{"add":1,"change":0,"remove":1,"operation":"plan"}}` + "\n" +
+		`{"@level":"info","@message":"data.aws_region.r: Read complete","type":"apply_complete","hook":{"resource":{"addr":"data.aws_region.r"},"action":"read"}}` + "\n" +
+		`{"@level":"info","@message":"aws_s3_bucket.b: Destruction complete after 1s","type":"apply_complete","hook":{"resource":{"addr":"aws_s3_bucket.b"},"action":"delete"}}`
+	if completed, total := analyzeApplyProgress(json); completed != 1 || total != 2 {
+		t.Errorf("expected 1 of 2 changes, got %d of %d", completed, total)
+	}
+
+	// the plan isn't logged when an approved plan is applied
+	if completed, total := analyzeApplyProgress("aws_s3_bucket.a: Creation complete after 2s [id=a]"); completed != 1 || total != 0 {
+		t.Errorf("expected 1 change of an unknown total, got %d of %d", completed, total)
+	}
+}
@@ -19,9 +19,13 @@ package util
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig/v3"
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/oam-dev/terraform-controller/api/v1beta1"
@@ -48,11 +52,133 @@ func RawExtension2Map(raw *runtime.RawExtension) (map[string]interface{}, error)
 		return nil, err
 	}
 	var ret map[string]interface{}
-	err = json.Unmarshal(data, &ret)
-	if err != nil {
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, explainJSONError(data, err)
+	}
+	// the numbers are kept as they are written, as float64 loses the precision of large integers
+	ret = nil
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&ret); err != nil {
 		return nil, err
 	}
-	return ret, err
+	return ret, nil
+}
+
+// explainJSONError points at where the JSON failed to be parsed, by the line, the column and the key, which locates
+// the mistake in a large object edited by hand
+func explainJSONError(data []byte, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field == "" {
+		return errors.Errorf("must be a JSON object, got a JSON %s", typeErr.Value)
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	end := int(syntaxErr.Offset) - 1
+	if end < 0 || end > len(data) {
+		end = len(data)
+	}
+	line, column := 1, 1
+	for _, c := range data[:end] {
+		if c == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	if key := jsonKeyAtError(data); key != "" {
+		return errors.Errorf("invalid JSON at line %d, column %d, in the value of key %s: %s", line, column, key, err.Error())
+	}
+	return errors.Errorf("invalid JSON at line %d, column %d: %s", line, column, err.Error())
+}
+
+// jsonKeyAtError returns the path of the key whose value is being read when the JSON fails to be parsed, like
+// `network.subnets[1].cidr`
+func jsonKeyAtError(data []byte) string {
+	type frame struct {
+		object    bool
+		expectKey bool
+		key       string
+		index     int
+	}
+	var stack []*frame
+	// valueDone moves the innermost object to its next key, or the innermost array to its next element
+	valueDone := func() {
+		if len(stack) == 0 {
+			return
+		}
+		if top := stack[len(stack)-1]; top.object {
+			top.expectKey = true
+		} else {
+			top.index++
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				stack = append(stack, &frame{object: t == '{', expectKey: t == '{'})
+			default:
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+		case string:
+			if top := len(stack) - 1; top >= 0 && stack[top].object && stack[top].expectKey {
+				stack[top].key, stack[top].expectKey = t, false
+			} else {
+				valueDone()
+			}
+		default:
+			valueDone()
+		}
+	}
+
+	var path strings.Builder
+	for _, f := range stack {
+		switch {
+		case f.object && f.key != "":
+			if path.Len() > 0 {
+				path.WriteString(".")
+			}
+			path.WriteString(f.key)
+		case !f.object:
+			fmt.Fprintf(&path, "[%d]", f.index)
+		}
+	}
+	return path.String()
+}
+
+// Interface2String converts a value, like the value of a Terraform output, to a string. Strings and numbers are kept as
+// they are, while complex values like lists, maps and objects are encoded in JSON so that they are still structured.
+func Interface2String(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
 }
 
 type backendVars struct {
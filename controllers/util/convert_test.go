@@ -0,0 +1,66 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestInterface2String(t *testing.T) {
+	testcases := map[string]struct {
+		value    interface{}
+		expected string
+	}{
+		"nil":    {value: nil, expected: ""},
+		"string": {value: "abc", expected: "abc"},
+		"number": {value: json.Number("12345678901234567890"), expected: "12345678901234567890"},
+		"float":  {value: 1.5, expected: "1.5"},
+		"bool":   {value: true, expected: "true"},
+		"list":   {value: []interface{}{"a", "b"}, expected: `["a","b"]`},
+		"map": {
+			value:    map[string]interface{}{"k": []interface{}{json.Number("1")}},
+			expected: `{"k":[1]}`,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Interface2String(tc.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRawExtension2MapError(t *testing.T) {
+	testcases := map[string]struct {
+		raw      string
+		expected string
+	}{
+		"not an object": {raw: `["a"]`, expected: "must be a JSON object, got a JSON array"},
+		"malformed":     {raw: `{"name": "bucket",}`, expected: "invalid JSON at line 1, column 19"},
+		"partially valid": {
+			raw:      "{\n  \"name\": \"bucket\",\n  \"network\": {\"subnets\": [{\"cidr\": \"10.0.0.0/24\"}, {\"cidr\": 10.0.1}]}\n}",
+			expected: "invalid JSON at line 3, column 65, in the value of key network.subnets[1].cidr",
+		},
+		"truncated": {raw: `{"tags": {"env": "prod"`, expected: "in the value of key tags.env: unexpected end of JSON input"},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			_, err := RawExtension2Map(&runtime.RawExtension{Raw: []byte(tc.raw)})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected an error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+
+	variables, err := RawExtension2Map(&runtime.RawExtension{Raw: []byte(`{"name": "bucket", "count": 2}`)})
+	if err != nil || variables["name"] != "bucket" {
+		t.Errorf("unexpected variables %v, error %v", variables, err)
+	}
+}
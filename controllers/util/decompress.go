@@ -22,3 +22,16 @@ func DecompressTerraformStateSecret(data string) ([]byte, error) {
 	}
 	return b.Bytes(), nil
 }
+
+// CompressData compresses the data with gzip
+func CompressData(data string) ([]byte, error) {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// remoteGitCheckInterval is the minimal interval to query the latest commit of the same git repo
+const remoteGitCheckInterval = time.Minute
+
+var gitHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+type remoteGitCommit struct {
+	commit    string
+	checkedAt time.Time
+}
+
+var (
+	remoteGitCommitCache = map[string]remoteGitCommit{}
+	remoteGitCommitLock  sync.Mutex
+)
+
+// GetRemoteGitHeadCommit gets the commit SHA of HEAD of a public git repo, which is what `git clone` checks out.
+// It speaks the smart HTTP protocol like `git ls-remote`, so only http(s) repos are supported. The result is cached for
+// a while as it's called on every reconciliation.
+func GetRemoteGitHeadCommit(ctx context.Context, repo string) (string, error) {
+	if !strings.HasPrefix(repo, "https://") && !strings.HasPrefix(repo, "http://") {
+		return "", fmt.Errorf("only http(s) git repos are supported to detect changes, got %s", repo)
+	}
+
+	remoteGitCommitLock.Lock()
+	cached, ok := remoteGitCommitCache[repo]
+	remoteGitCommitLock.Unlock()
+	if ok && time.Since(cached.checkedAt) < remoteGitCheckInterval {
+		return cached.commit, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(repo, "/")+"/info/refs?service=git-upload-pack", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := gitHTTPClient.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to query the refs of git repo %s", repo)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to query the refs of git repo %s: %s", repo, resp.Status)
+	}
+
+	commit, err := parseHeadCommit(resp.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the refs of git repo %s", repo)
+	}
+
+	remoteGitCommitLock.Lock()
+	remoteGitCommitCache[repo] = remoteGitCommit{commit: commit, checkedAt: time.Now()}
+	remoteGitCommitLock.Unlock()
+	return commit, nil
+}
+
+// parseHeadCommit parses the ref advertisement of the smart HTTP protocol, which is a sequence of pkt-lines like
+// `<sha> HEAD\x00<capabilities>\n`, and returns the commit of HEAD
+func parseHeadCommit(r io.Reader) (string, error) {
+	reader := bufio.NewReader(r)
+	for {
+		lengthHex := make([]byte, 4)
+		if _, err := io.ReadFull(reader, lengthHex); err != nil {
+			if err == io.EOF {
+				return "", errors.New("HEAD is not found")
+			}
+			return "", err
+		}
+		length, err := strconv.ParseUint(string(lengthHex), 16, 16)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid pkt-line length %q", lengthHex)
+		}
+		// flush-pkt
+		if length == 0 {
+			continue
+		}
+		if length < 4 {
+			return "", fmt.Errorf("invalid pkt-line length %d", length)
+		}
+		payload := make([]byte, length-4)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return "", err
+		}
+
+		line := strings.TrimSuffix(string(payload), "\n")
+		if i := strings.IndexByte(line, 0); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "HEAD" {
+			return fields[0], nil
+		}
+	}
+}
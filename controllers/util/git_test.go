@@ -0,0 +1,74 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const refsAdvertisement = "001e# service=git-upload-pack\n" +
+	"0000" +
+	"00a67a2d1b5e5a0b4e28f0e55a6f1a3f9f5ad5cc9c1e HEAD\x00multi_ack thin-pack side-band side-band-64k ofs-delta shallow no-progress include-tag symref=HEAD:refs/heads/master\n" +
+	"003f7a2d1b5e5a0b4e28f0e55a6f1a3f9f5ad5cc9c1e refs/heads/master\n" +
+	"0000"
+
+func TestParseHeadCommit(t *testing.T) {
+	testcases := map[string]struct {
+		refs    string
+		commit  string
+		wantErr bool
+	}{
+		"HEAD advertised": {
+			refs:   refsAdvertisement,
+			commit: "7a2d1b5e5a0b4e28f0e55a6f1a3f9f5ad5cc9c1e",
+		},
+		"empty repo": {
+			refs:    "001e# service=git-upload-pack\n0000",
+			wantErr: true,
+		},
+		"not a pkt-line": {
+			refs:    "<html>",
+			wantErr: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			commit, err := parseHeadCommit(strings.NewReader(tc.refs))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if commit != tc.commit {
+				t.Errorf("expected commit %q, got %q", tc.commit, commit)
+			}
+		})
+	}
+}
+
+func TestGetRemoteGitHeadCommit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repo.git/info/refs" || r.URL.Query().Get("service") != "git-upload-pack" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, refsAdvertisement)
+	}))
+	defer server.Close()
+
+	commit, err := GetRemoteGitHeadCommit(context.Background(), server.URL+"/repo.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit != "7a2d1b5e5a0b4e28f0e55a6f1a3f9f5ad5cc9c1e" {
+		t.Errorf("unexpected commit %s", commit)
+	}
+
+	if _, err := GetRemoteGitHeadCommit(context.Background(), server.URL+"/missing.git"); err == nil {
+		t.Error("expected an error for a missing repo")
+	}
+	if _, err := GetRemoteGitHeadCommit(context.Background(), "git@github.com:oam-dev/terraform-controller.git"); err == nil {
+		t.Error("expected an error for an ssh repo")
+	}
+}
@@ -6,11 +6,15 @@ import (
 
 	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/terraform-controller/api/types"
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
 	"github.com/oam-dev/terraform-controller/api/v1beta1"
 )
 
@@ -52,6 +56,7 @@ const (
 	envARMClientSecret   = "ARM_CLIENT_SECRET"
 	envARMSubscriptionID = "ARM_SUBSCRIPTION_ID"
 	envARMTenantID       = "ARM_TENANT_ID"
+	envARMAccessKey      = "ARM_ACCESS_KEY"
 
 	envVSphereUser               = "VSPHERE_USER"
 	envVSpherePassword           = "VSPHERE_PASSWORD"
@@ -88,6 +93,8 @@ type AzureCredentials struct {
 	ARMClientSecret   string `yaml:"armClientSecret"`
 	ARMSubscriptionID string `yaml:"armSubscriptionID"`
 	ARMTenantID       string `yaml:"armTenantID"`
+	// ARMAccessKey is the access key of the storage account of the azurerm backend
+	ARMAccessKey string `yaml:"armAccessKey,omitempty"`
 }
 
 // VSphereCredentials are credentials for VSphere
@@ -105,124 +112,231 @@ type ECCredentials struct {
 
 // GetProviderCredentials gets provider credentials by cloud provider name
 func GetProviderCredentials(ctx context.Context, k8sClient client.Client, providerNamespace, providerName string) (map[string]string, error) {
+	credentials, _, err := GetProviderCredentialsInRegion(ctx, k8sClient, providerNamespace, providerName, "")
+	return credentials, err
+}
+
+// GetProviderCredentialsInRegion gets the credentials of a Provider in a region, which overrides the region of the
+// Provider if it's not empty. It returns the effective region too.
+func GetProviderCredentialsInRegion(ctx context.Context, k8sClient client.Client, providerNamespace, providerName,
+	region string) (map[string]string, string, error) {
 	provider, err := GetProviderFromConfiguration(ctx, k8sClient, providerNamespace, providerName)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if provider.Status.State != types.ProviderIsReady {
 		err := fmt.Errorf("provider is not ready: %s/%s", provider.Namespace, provider.Name)
+		// the Provider is initializing until the Secret of its credentials is created, which is told apart from the
+		// invalid credentials
+		if InjectsCredentials(provider) {
+			if _, dataErr := credentialsData(ctx, k8sClient, provider); IsCredentialsNotFound(dataErr) {
+				err = errors.Wrapf(dataErr, "provider is not ready: %s/%s", provider.Namespace, provider.Name)
+			}
+		}
 		klog.ErrorS(err, "failed to get credential")
-		return nil, err
+		return nil, "", err
+	}
+	credentials, err := providerCredentials(ctx, k8sClient, provider)
+	if err != nil {
+		return nil, "", err
+	}
+	region, err = SetRegion(provider, credentials, region)
+	if err != nil {
+		return nil, "", err
 	}
+	return credentials, region, nil
+}
+
+// IsCredentialsNotFound tells whether the credentials of a Provider can't be read because the Provider or the Secret of
+// its credentials isn't found, which may be created later, rather than the credentials being invalid
+func IsCredentialsNotFound(err error) bool {
+	return err != nil && kerrors.IsNotFound(errors.Cause(err))
+}
 
+// SetRegion sets the region in the credentials of a Provider, which overrides the region of the Provider if it's not
+// empty, and returns the effective region
+func SetRegion(provider *v1beta1.Provider, credentials map[string]string, region string) (string, error) {
+	if region == "" {
+		return provider.Spec.Region, nil
+	}
+	env, ok := regionEnvs[CloudProvider(provider.Spec.Provider)]
+	if !ok {
+		return "", errors.Wrapf(ErrInvalidRegion, "the region of provider %s can't be set", provider.Spec.Provider)
+	}
+	if err := ValidateRegion(provider.Spec.Provider, region); err != nil {
+		return "", err
+	}
+	credentials[env] = region
+	return region, nil
+}
+
+// providerCredentials reads the credentials of a Provider, regardless of whether it's ready
+func providerCredentials(ctx context.Context, k8sClient client.Client, provider *v1beta1.Provider) (map[string]string, error) {
 	region := provider.Spec.Region
+	if !InjectsCredentials(provider) {
+		// only the region is set, and the Terraform provider authenticates by the identity of the pods of the Jobs
+		credentials := make(map[string]string)
+		if env, ok := regionEnvs[CloudProvider(provider.Spec.Provider)]; ok && region != "" {
+			credentials[env] = region
+		}
+		return credentials, nil
+	}
+	data, err := credentialsData(ctx, k8sClient, provider)
+	if err != nil {
+		return nil, err
+	}
+	switch provider.Spec.Provider {
+	case string(alibaba):
+		var ak AlibabaCloudCredentials
+		if err := yaml.Unmarshal(data, &ak); err != nil {
+			klog.ErrorS(err, errConvertCredentials, "Name", provider.Name, "Namespace", provider.Namespace)
+			return nil, errors.Wrap(err, errConvertCredentials)
+		}
+		return map[string]string{
+			envAlicloudAcessKey:  ak.AccessKeyID,
+			envAlicloudSecretKey: ak.AccessKeySecret,
+			envAlicloudRegion:    region,
+			envAliCloudStsToken:  ak.SecurityToken,
+		}, nil
+	case string(aws):
+		var ak AWSCredentials
+		if err := yaml.Unmarshal(data, &ak); err != nil {
+			klog.ErrorS(err, errConvertCredentials, "Name", provider.Name, "Namespace", provider.Namespace)
+			return nil, errors.Wrap(err, errConvertCredentials)
+		}
+		return map[string]string{
+			envAWSAccessKeyID:     ak.AWSAccessKeyID,
+			envAWSSecretAccessKey: ak.AWSSecretAccessKey,
+			envAWSSessionToken:    ak.AWSSessionToken,
+			envAWSDefaultRegion:   region,
+		}, nil
+	case string(gcp):
+		var ak GCPCredentials
+		if err := yaml.Unmarshal(data, &ak); err != nil {
+			klog.ErrorS(err, errConvertCredentials, "Name", provider.Name, "Namespace", provider.Namespace)
+			return nil, errors.Wrap(err, errConvertCredentials)
+		}
+		return map[string]string{
+			envGCPCredentialsJSON: ak.GCPCredentialsJSON,
+			envGCPProject:         ak.GCPProject,
+			envGCPRegion:          region,
+		}, nil
+	case string(azure):
+		var cred AzureCredentials
+		if err := yaml.Unmarshal(data, &cred); err != nil {
+			klog.ErrorS(err, errConvertCredentials, "Name", provider.Name, "Namespace", provider.Namespace)
+			return nil, errors.Wrap(err, errConvertCredentials)
+		}
+		credentials := map[string]string{
+			envARMClientID:       cred.ARMClientID,
+			envARMClientSecret:   cred.ARMClientSecret,
+			envARMSubscriptionID: cred.ARMSubscriptionID,
+			envARMTenantID:       cred.ARMTenantID,
+		}
+		if cred.ARMAccessKey != "" {
+			credentials[envARMAccessKey] = cred.ARMAccessKey
+		}
+		return credentials, nil
+	case string(vsphere):
+		var cred VSphereCredentials
+		if err := yaml.Unmarshal(data, &cred); err != nil {
+			klog.ErrorS(err, errConvertCredentials, "Name", provider.Name, "Namespace", provider.Namespace)
+			return nil, errors.Wrap(err, errConvertCredentials)
+		}
+		return map[string]string{
+			envVSphereUser:               cred.VSphereUser,
+			envVSpherePassword:           cred.VSpherePassword,
+			envVSphereServer:             cred.VSphereServer,
+			envVSphereAllowUnverifiedSSL: cred.VSphereAllowUnverifiedSSL,
+		}, nil
+	case string(ec):
+		var ak ECCredentials
+		if err := yaml.Unmarshal(data, &ak); err != nil {
+			klog.ErrorS(err, errConvertCredentials, "Name", provider.Name, "Namespace", provider.Namespace)
+			return nil, errors.Wrap(err, errConvertCredentials)
+		}
+		return map[string]string{
+			envECApiKey: ak.ECApiKey,
+		}, nil
+	}
+	return nil, nil
+}
+
+// credentialsData reads the credentials of a Provider from its source, which are parsed by the cloud of the Provider
+func credentialsData(ctx context.Context, k8sClient client.Client, provider *v1beta1.Provider) ([]byte, error) {
 	switch provider.Spec.Credentials.Source {
-	case "Secret":
+	case crossplane.CredentialsSourceSecret:
 		var secret v1.Secret
 		secretRef := provider.Spec.Credentials.SecretRef
+		if secretRef == nil {
+			return nil, fmt.Errorf("the secretRef of Provider %s/%s is not set", provider.Namespace, provider.Name)
+		}
 		if err := k8sClient.Get(ctx, client.ObjectKey{Name: secretRef.Name, Namespace: secretRef.Namespace}, &secret); err != nil {
 			errMsg := "failed to get the Secret from Provider"
 			klog.ErrorS(err, errMsg, "Name", secretRef.Name, "Namespace", secretRef.Namespace)
-			return nil, errors.Wrap(err, errMsg)
+			return nil, errors.Wrap(explainGetError(ctx, k8sClient, err, "", "secrets", secretRef.Namespace, secretRef.Name), errMsg)
 		}
-		switch provider.Spec.Provider {
-		case string(alibaba):
-			var ak AlibabaCloudCredentials
-			if err := yaml.Unmarshal(secret.Data[secretRef.Key], &ak); err != nil {
-				klog.ErrorS(err, errConvertCredentials, "Name", secretRef.Name, "Namespace", secretRef.Namespace)
-				return nil, errors.Wrap(err, errConvertCredentials)
-			}
-			return map[string]string{
-				envAlicloudAcessKey:  ak.AccessKeyID,
-				envAlicloudSecretKey: ak.AccessKeySecret,
-				envAlicloudRegion:    region,
-				envAliCloudStsToken:  ak.SecurityToken,
-			}, nil
-		case string(aws):
-			var ak AWSCredentials
-			if err := yaml.Unmarshal(secret.Data[secretRef.Key], &ak); err != nil {
-				klog.ErrorS(err, errConvertCredentials, "Name", secretRef.Name, "Namespace", secretRef.Namespace)
-				return nil, errors.Wrap(err, errConvertCredentials)
-			}
-			return map[string]string{
-				envAWSAccessKeyID:     ak.AWSAccessKeyID,
-				envAWSSecretAccessKey: ak.AWSSecretAccessKey,
-				envAWSSessionToken:    ak.AWSSessionToken,
-				envAWSDefaultRegion:   region,
-			}, nil
-		case string(gcp):
-			var ak GCPCredentials
-			if err := yaml.Unmarshal(secret.Data[secretRef.Key], &ak); err != nil {
-				klog.ErrorS(err, errConvertCredentials, "Name", secretRef.Name, "Namespace", secretRef.Namespace)
-				return nil, errors.Wrap(err, errConvertCredentials)
-			}
-			return map[string]string{
-				envGCPCredentialsJSON: ak.GCPCredentialsJSON,
-				envGCPProject:         ak.GCPProject,
-				envGCPRegion:          region,
-			}, nil
-		case string(azure):
-			var cred AzureCredentials
-			if err := yaml.Unmarshal(secret.Data[secretRef.Key], &cred); err != nil {
-				klog.ErrorS(err, errConvertCredentials, "Name", secretRef.Name, "Namespace", secretRef.Namespace)
-				return nil, errors.Wrap(err, errConvertCredentials)
-			}
-			return map[string]string{
-				envARMClientID:       cred.ARMClientID,
-				envARMClientSecret:   cred.ARMClientSecret,
-				envARMSubscriptionID: cred.ARMSubscriptionID,
-				envARMTenantID:       cred.ARMTenantID,
-			}, nil
-		case string(vsphere):
-			var cred VSphereCredentials
-			if err := yaml.Unmarshal(secret.Data[secretRef.Key], &cred); err != nil {
-				klog.ErrorS(err, errConvertCredentials, "Name", secretRef.Name, "Namespace", secretRef.Namespace)
-				return nil, errors.Wrap(err, errConvertCredentials)
-			}
-			return map[string]string{
-				envVSphereUser:               cred.VSphereUser,
-				envVSpherePassword:           cred.VSpherePassword,
-				envVSphereServer:             cred.VSphereServer,
-				envVSphereAllowUnverifiedSSL: cred.VSphereAllowUnverifiedSSL,
-			}, nil
-		case string(ec):
-			var ak ECCredentials
-			if err := yaml.Unmarshal(secret.Data[secretRef.Key], &ak); err != nil {
-				klog.ErrorS(err, errConvertCredentials, "Name", secretRef.Name, "Namespace", secretRef.Namespace)
-				return nil, errors.Wrap(err, errConvertCredentials)
-			}
-			return map[string]string{
-				envECApiKey: ak.ECApiKey,
-			}, nil
+		return secret.Data[secretRef.Key], nil
+	case v1beta1.CredentialsSourceSecretStore:
+		storeRef := provider.Spec.Credentials.SecretStoreRef
+		if storeRef == nil {
+			return nil, fmt.Errorf("the secretStoreRef of Provider %s/%s is not set", provider.Namespace, provider.Name)
 		}
+		data, err := fetchFromSecretStore(ctx, storeRef)
+		if err != nil {
+			errMsg := "failed to fetch the credentials from the secret store of Provider"
+			klog.ErrorS(err, errMsg, "Name", provider.Name, "Namespace", provider.Namespace, "Store", storeRef.Type)
+			return nil, errors.Wrap(err, errMsg)
+		}
+		return data, nil
 	default:
 		errMsg := "the credentials type is not supported."
 		err := errors.New(errMsg)
 		klog.ErrorS(err, "", "CredentialType", provider.Spec.Credentials.Source)
 		return nil, err
 	}
-	return nil, nil
 }
 
-// ValidateProviderCredentials validates provider credentials by cloud provider name
-func ValidateProviderCredentials(ctx context.Context, k8sClient client.Client, provider *v1beta1.Provider) error {
+// credentialEnvs are the environment variables of the credentials of all the clouds
+var credentialEnvs = sets.NewString(
+	envAlicloudAcessKey, envAlicloudSecretKey, envAlicloudRegion, envAliCloudStsToken,
+	envAWSAccessKeyID, envAWSSecretAccessKey, envAWSDefaultRegion, envAWSSessionToken,
+	envGCPCredentialsJSON, envGCPRegion, envGCPProject,
+	envARMClientID, envARMClientSecret, envARMSubscriptionID, envARMTenantID, envARMAccessKey,
+	envVSphereUser, envVSpherePassword, envVSphereServer, envVSphereAllowUnverifiedSSL,
+	envECApiKey,
+)
+
+// CredentialEnvs returns the environment variables of the credentials of all the clouds
+func CredentialEnvs() []string {
+	return credentialEnvs.List()
+}
+
+// IsCredentialEnv tells whether an environment variable is set by the credentials of Providers
+func IsCredentialEnv(name string) bool {
+	return credentialEnvs.Has(name)
+}
+
+// InjectsCredentials tells whether the credentials of a Provider are injected into the Terraform Jobs as environment
+// variables. The ones whose source is InjectedIdentity or None aren't, and rely on the identity of the pods of the
+// Jobs, like IRSA of AWS or Workload Identity of GCP, which is granted to spec.serviceAccountName of a Configuration.
+func InjectsCredentials(provider *v1beta1.Provider) bool {
 	switch provider.Spec.Credentials.Source {
-	case "Secret":
-		var secret v1.Secret
-		secretRef := provider.Spec.Credentials.SecretRef
-		if err := k8sClient.Get(ctx, client.ObjectKey{Name: secretRef.Name, Namespace: secretRef.Namespace}, &secret); err != nil {
-			errMsg := "failed to get the Secret from Provider"
-			klog.ErrorS(err, errMsg, "Name", secretRef.Name, "Namespace", secretRef.Namespace)
-			return errors.Wrap(err, errMsg)
-		}
+	case crossplane.CredentialsSourceInjectedIdentity, crossplane.CredentialsSourceNone:
+		return false
 	default:
-		errMsg := "the credentials type is not supported."
-		err := errors.New(errMsg)
-		klog.ErrorS(err, "", "CredentialType", provider.Spec.Credentials.Source)
-		return err
+		return true
 	}
-	return nil
+}
+
+// ValidateProviderCredentials validates provider credentials by cloud provider name
+func ValidateProviderCredentials(ctx context.Context, k8sClient client.Client, provider *v1beta1.Provider) error {
+	if !InjectsCredentials(provider) {
+		return nil
+	}
+	_, err := credentialsData(ctx, k8sClient, provider)
+	return err
 }
 
 // GetProviderFromConfiguration gets provider object from Configuration
@@ -230,8 +344,32 @@ func GetProviderFromConfiguration(ctx context.Context, k8sClient client.Client,
 	var provider = &v1beta1.Provider{}
 	if err := k8sClient.Get(ctx, client.ObjectKey{Name: providerName, Namespace: namespace}, provider); err != nil {
 		errMsg := "failed to get Provider object"
-		klog.ErrorS(err, errMsg, "Name", providerName)
-		return nil, errors.Wrap(err, errMsg)
+		klog.ErrorS(err, errMsg, "Name", providerName, "Namespace", namespace)
+		return nil, errors.Wrap(explainGetError(ctx, k8sClient, err, v1beta1.GroupVersion.Group, "providers", namespace, providerName), errMsg)
 	}
 	return provider, nil
 }
+
+// explainGetError checks whether the controller is allowed to get the object it failed to get. A Provider and its
+// Secret could be in any namespace, and the controller may not have the permission there.
+func explainGetError(ctx context.Context, k8sClient client.Client, err error, group, resource, namespace, name string) error {
+	if kerrors.IsNotFound(err) {
+		return err
+	}
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "get",
+				Group:     group,
+				Resource:  resource,
+				Name:      name,
+			},
+		},
+	}
+	if reviewErr := k8sClient.Create(ctx, review); reviewErr != nil || review.Status.Allowed {
+		return err
+	}
+	return fmt.Errorf("the controller is not allowed to get %s %s/%s, please grant it the permission in namespace %s",
+		resource, namespace, name, namespace)
+}
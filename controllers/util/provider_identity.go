@@ -0,0 +1,240 @@
+package util
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+// identityVerifyTimeout bounds a call to the cloud API to verify the credentials
+const identityVerifyTimeout = 10 * time.Second
+
+// identityVerifier calls the API of a cloud with the credentials of a Provider, and returns who they authenticate as
+type identityVerifier func(ctx context.Context, httpClient *http.Client, credentials map[string]string) (string, error)
+
+// identityVerifiers are the clouds whose credentials can be verified, by the equivalents of AWS STS GetCallerIdentity,
+// which needs no permission
+var identityVerifiers = map[CloudProvider]identityVerifier{
+	aws: func(ctx context.Context, httpClient *http.Client, credentials map[string]string) (string, error) {
+		return verifyAWSIdentity(ctx, httpClient, awsSTSEndpoint(credentials[envAWSDefaultRegion]), credentials)
+	},
+	alibaba: func(ctx context.Context, httpClient *http.Client, credentials map[string]string) (string, error) {
+		return verifyAlibabaIdentity(ctx, httpClient, "https://sts.aliyuncs.com", credentials)
+	},
+}
+
+// ErrIdentityVerificationNotSupported means the credentials of the cloud of a Provider can't be verified
+var ErrIdentityVerificationNotSupported = errors.New("verifying the credentials is not supported")
+
+// VerifyProviderIdentity verifies the credentials of a Provider by calling the API of its cloud, and returns the
+// identity they belong to, like the ARN of an AWS user or role
+func VerifyProviderIdentity(ctx context.Context, k8sClient client.Client, provider *v1beta1.Provider) (string, error) {
+	verify, ok := identityVerifiers[CloudProvider(provider.Spec.Provider)]
+	if !ok {
+		return "", errors.Wrapf(ErrIdentityVerificationNotSupported, "provider %s", provider.Spec.Provider)
+	}
+	if !InjectsCredentials(provider) {
+		// the identity is the one of the pods of the Jobs rather than the controller
+		return "", errors.Wrapf(ErrIdentityVerificationNotSupported, "credentials source %s", provider.Spec.Credentials.Source)
+	}
+	credentials, err := providerCredentials(ctx, k8sClient, provider)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, identityVerifyTimeout)
+	defer cancel()
+	return verify(ctx, &http.Client{}, credentials)
+}
+
+// awsSTSEndpoint returns the regional endpoint of AWS STS, which works in the opt-in regions too
+func awsSTSEndpoint(region string) string {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return awsEndpoint("sts", region)
+}
+
+// awsEndpoint returns the regional endpoint of an AWS service
+func awsEndpoint(service, region string) string {
+	if strings.HasPrefix(region, "cn-") {
+		return fmt.Sprintf("https://%s.%s.amazonaws.com.cn", service, region)
+	}
+	return fmt.Sprintf("https://%s.%s.amazonaws.com", service, region)
+}
+
+type awsGetCallerIdentityResponse struct {
+	Arn     string `xml:"GetCallerIdentityResult>Arn"`
+	Account string `xml:"GetCallerIdentityResult>Account"`
+}
+
+type awsErrorResponse struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// verifyAWSIdentity calls GetCallerIdentity of AWS STS, signed by Signature Version 4
+func verifyAWSIdentity(ctx context.Context, httpClient *http.Client, endpoint string, credentials map[string]string) (string, error) {
+	region := credentials[envAWSDefaultRegion]
+	if region == "" {
+		region = "us-east-1"
+	}
+	query := "Action=GetCallerIdentity&Version=2011-06-15"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/?"+query, nil)
+	if err != nil {
+		return "", err
+	}
+
+	headers := make(map[string]string)
+	if token := credentials[envAWSSessionToken]; token != "" {
+		headers["x-amz-security-token"] = token
+	}
+	signAWSRequest(req, query, region, "sts", headers, nil, credentials[envAWSAccessKeyID], credentials[envAWSSecretAccessKey], time.Now())
+
+	body, status, err := doHTTPRequest(httpClient, req)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		var errResp awsErrorResponse
+		if xml.Unmarshal(body, &errResp) == nil && errResp.Code != "" {
+			return "", errors.Errorf("AWS STS rejected the credentials: %s: %s", errResp.Code, errResp.Message)
+		}
+		return "", errors.Errorf("AWS STS rejected the credentials with status %d", status)
+	}
+	var resp awsGetCallerIdentityResponse
+	if err := xml.Unmarshal(body, &resp); err != nil {
+		return "", errors.Wrap(err, "failed to decode the response of AWS STS")
+	}
+	return resp.Arn, nil
+}
+
+// signAWSRequest signs a request by Signature Version 4 with the headers to sign besides host, and the payload which is
+// the body of the request
+func signAWSRequest(req *http.Request, query, region, service string, headers map[string]string, payload []byte,
+	accessKey, secretKey string, now time.Time) {
+	now = now.UTC()
+	amzDate, date := now.Format("20060102T150405Z"), now.Format("20060102")
+	signed := map[string]string{"host": req.URL.Host, "x-amz-date": amzDate}
+	for name, value := range headers {
+		signed[name] = value
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, signed[name])
+		if name != "host" {
+			req.Header.Set(name, signed[name])
+		}
+	}
+	signedHeaders := strings.Join(names, ";")
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{req.Method, "/", query, canonicalHeaders.String(), signedHeaders,
+		hex.EncodeToString(payloadHash[:])}, "\n")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(canonicalRequestHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, data := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, data)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+type alibabaGetCallerIdentityResponse struct {
+	Arn     string `json:"Arn"`
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+// verifyAlibabaIdentity calls GetCallerIdentity of Alibaba Cloud STS, signed by the RPC signature
+func verifyAlibabaIdentity(ctx context.Context, httpClient *http.Client, endpoint string, credentials map[string]string) (string, error) {
+	params := url.Values{
+		"Action":           {"GetCallerIdentity"},
+		"Format":           {"JSON"},
+		"Version":          {"2015-04-01"},
+		"AccessKeyId":      {credentials[envAlicloudAcessKey]},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureVersion": {"1.0"},
+		"SignatureNonce":   {strconv.FormatInt(time.Now().UnixNano(), 36)},
+		"Timestamp":        {time.Now().UTC().Format("2006-01-02T15:04:05Z")},
+	}
+	if token := credentials[envAliCloudStsToken]; token != "" {
+		params.Set("SecurityToken", token)
+	}
+	params.Set("Signature", alibabaSignature(params, credentials[envAlicloudSecretKey]))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	body, status, err := doHTTPRequest(httpClient, req)
+	if err != nil {
+		return "", err
+	}
+	var resp alibabaGetCallerIdentityResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", errors.Wrap(err, "failed to decode the response of Alibaba Cloud STS")
+	}
+	if status != http.StatusOK {
+		return "", errors.Errorf("Alibaba Cloud STS rejected the credentials: %s: %s", resp.Code, resp.Message)
+	}
+	return resp.Arn, nil
+}
+
+// alibabaSignature signs the parameters of a GET request by the RPC signature of Alibaba Cloud
+func alibabaSignature(params url.Values, secret string) string {
+	// url.Values.Encode sorts the parameters by their names
+	canonicalQuery := alibabaPercentEncode(params.Encode())
+	stringToSign := "GET&%2F&" + alibabaPercentEncode(url.QueryEscape(canonicalQuery))
+	h := hmac.New(sha1.New, []byte(secret+"&"))
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// alibabaPercentEncode turns the encoding of url.Values into the one of the RPC signature, which follows RFC 3986
+func alibabaPercentEncode(s string) string {
+	return strings.NewReplacer("+", "%20", "*", "%2A", "%7E", "~").Replace(s)
+}
+
+func doHTTPRequest(httpClient *http.Client, req *http.Request) ([]byte, int, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to call %s", req.URL.Host)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to read the response of %s", req.URL.Host)
+	}
+	return body, resp.StatusCode, nil
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAWSRequest(t *testing.T) {
+	// the example of Signature Version 4 in the documentation of AWS
+	query := "Action=ListUsers&Version=2010-05-08"
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := map[string]string{"content-type": "application/x-www-form-urlencoded; charset=utf-8"}
+	signAWSRequest(req, query, "us-east-1", "iam", headers, nil, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("expected Authorization %s, got %s", expected, got)
+	}
+}
+
+func TestAlibabaSignature(t *testing.T) {
+	// the example of the RPC signature in the documentation of Alibaba Cloud
+	params := url.Values{
+		"Format":           {"XML"},
+		"Version":          {"2014-05-26"},
+		"Action":           {"DescribeRegions"},
+		"AccessKeyId":      {"testid"},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {"3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf"},
+		"SignatureVersion": {"1.0"},
+		"Timestamp":        {"2016-02-23T12:46:24Z"},
+	}
+	if got := alibabaSignature(params, "testsecret"); got != "OLeaidS1JvxuMvnyHOwuJ+uX5qY=" {
+		t.Errorf("expected signature OLeaidS1JvxuMvnyHOwuJ+uX5qY=, got %s", got)
+	}
+}
+
+func TestVerifyAWSIdentity(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=ak/") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<ErrorResponse><Error><Code>InvalidClientTokenId</Code><Message>The security token included in the request is invalid.</Message></Error></ErrorResponse>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult><Arn>arn:aws:iam::123456789012:user/tf</Arn><Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`))
+	}))
+	defer server.Close()
+
+	identity, err := verifyAWSIdentity(ctx, server.Client(), server.URL, map[string]string{envAWSAccessKeyID: "ak", envAWSSecretAccessKey: "sk"})
+	if err != nil || identity != "arn:aws:iam::123456789012:user/tf" {
+		t.Errorf("expected the identity of the credentials, got %s, %v", identity, err)
+	}
+	_, err = verifyAWSIdentity(ctx, server.Client(), server.URL, map[string]string{envAWSAccessKeyID: "invalid", envAWSSecretAccessKey: "sk"})
+	if err == nil || !strings.Contains(err.Error(), "InvalidClientTokenId") {
+		t.Errorf("expected the credentials to be rejected, got %v", err)
+	}
+}
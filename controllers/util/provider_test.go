@@ -0,0 +1,183 @@
+package util
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+// forbiddenClient can't get the objects in the forbidden namespace, like the API server without RBAC there
+type forbiddenClient struct {
+	client.Client
+	forbiddenNamespace string
+}
+
+func (c *forbiddenClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if key.Namespace == c.forbiddenNamespace {
+		return kerrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, key.Name, nil)
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *forbiddenClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if review, ok := obj.(*authorizationv1.SelfSubjectAccessReview); ok {
+		review.Status.Allowed = review.Spec.ResourceAttributes.Namespace != c.forbiddenNamespace
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestGetProviderCredentialsCrossNamespace(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	// The Configuration lives in namespace "app", while the Provider and its Secret live elsewhere
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "prod"},
+		Spec: v1beta1.ProviderSpec{
+			Provider: "aws",
+			Region:   "us-east-1",
+			Credentials: v1beta1.ProviderCredentials{
+				Source: "Secret",
+				SecretRef: &crossplane.SecretKeySelector{
+					SecretReference: crossplane.SecretReference{Name: "aws-creds", Namespace: "vela-system"},
+					Key:             "credentials",
+				},
+			},
+		},
+		Status: v1beta1.ProviderStatus{State: types.ProviderIsReady},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws-creds", Namespace: "vela-system"},
+		Data:       map[string][]byte{"credentials": []byte("awsAccessKeyID: ak\nawsSecretAccessKey: sk\n")},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, provider, secret)
+
+	credentials, err := GetProviderCredentials(ctx, k8sClient, "prod", "aws")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if credentials[envAWSAccessKeyID] != "ak" || credentials[envAWSDefaultRegion] != "us-east-1" {
+		t.Errorf("unexpected credentials %v", credentials)
+	}
+
+	_, err = GetProviderCredentials(ctx, &forbiddenClient{Client: k8sClient, forbiddenNamespace: "vela-system"}, "prod", "aws")
+	if err == nil || !strings.Contains(err.Error(), "not allowed to get secrets vela-system/aws-creds") {
+		t.Errorf("expected an error about the permission, got %v", err)
+	}
+
+	_, err = GetProviderCredentials(ctx, k8sClient, "default", "aws")
+	if err == nil || !kerrors.IsNotFound(errors.Cause(err)) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestGetProviderCredentialsInjectedIdentity(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "default"},
+		Spec: v1beta1.ProviderSpec{
+			Provider:    "aws",
+			Region:      "us-east-1",
+			Credentials: v1beta1.ProviderCredentials{Source: crossplane.CredentialsSourceInjectedIdentity},
+		},
+		Status: v1beta1.ProviderStatus{State: types.ProviderIsReady},
+	}
+	k8sClient := fake.NewFakeClientWithScheme(s, provider)
+
+	if err := ValidateProviderCredentials(ctx, k8sClient, provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	credentials, region, err := GetProviderCredentialsInRegion(ctx, k8sClient, "default", "aws", "eu-west-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(credentials) != 1 || credentials[envAWSDefaultRegion] != "eu-west-1" || region != "eu-west-1" {
+		t.Errorf("expected only the region to be set, got %v", credentials)
+	}
+	if _, err := VerifyProviderIdentity(ctx, k8sClient, provider); !errors.Is(err, ErrIdentityVerificationNotSupported) {
+		t.Errorf("expected the verification not to be supported, got %v", err)
+	}
+}
+
+func TestSetRegion(t *testing.T) {
+	cases := map[string]struct {
+		provider string
+		region   string
+		want     string
+		wantErr  bool
+	}{
+		"the provider region by default":      {provider: "aws", want: "us-east-1"},
+		"the configuration region overrides":  {provider: "aws", region: "eu-central-1", want: "eu-central-1"},
+		"a GovCloud region":                   {provider: "aws", region: "us-gov-west-1", want: "us-gov-west-1"},
+		"an alibaba region":                   {provider: "alibaba", region: "cn-hangzhou", want: "cn-hangzhou"},
+		"a gcp region":                        {provider: "gcp", region: "europe-west1", want: "europe-west1"},
+		"a gcp zone isn't a region":           {provider: "gcp", region: "europe-west1-b", wantErr: true},
+		"an alibaba region isn't aws":         {provider: "aws", region: "cn-hangzhou", wantErr: true},
+		"the cloud without regions":           {provider: "azure", region: "eastus", wantErr: true},
+		"the cloud without regions unchanged": {provider: "azure", want: "us-east-1"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			provider := &v1beta1.Provider{Spec: v1beta1.ProviderSpec{Provider: tc.provider, Region: "us-east-1"}}
+			credentials := map[string]string{regionEnvs[CloudProvider(tc.provider)]: "us-east-1"}
+			got, err := SetRegion(provider, credentials, tc.region)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidRegion) {
+					t.Errorf("expected an invalid region error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected the effective region %s, got %s", tc.want, got)
+			}
+			if env, ok := regionEnvs[CloudProvider(tc.provider)]; ok && credentials[env] != tc.want {
+				t.Errorf("expected %s to be %s, got %s", env, tc.want, credentials[env])
+			}
+		})
+	}
+}
+
+func TestValidateRegion(t *testing.T) {
+	if err := ValidateRegion("aws", "us-west-2"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateRegion("azure", "eastus"); err != nil {
+		t.Errorf("expected the regions of azure not to be checked, got %v", err)
+	}
+	if err := ValidateRegion("alibaba", ""); err != nil {
+		t.Errorf("expected an empty region to pass, got %v", err)
+	}
+	err := ValidateRegion("aws", "us-wset-2")
+	if !errors.Is(err, ErrInvalidRegion) || !strings.Contains(err.Error(), "us-east-1, us-east-2, us-gov-east-1") {
+		t.Errorf("expected an invalid region error listing the valid regions, got %v", err)
+	}
+}
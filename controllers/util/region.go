@@ -0,0 +1,62 @@
+package util
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ErrInvalidRegion means a region is not valid for the cloud of a Provider
+var ErrInvalidRegion = errors.New("invalid region")
+
+// regionEnvs are the environment variables of the regions of the clouds
+var regionEnvs = map[CloudProvider]string{
+	alibaba: envAlicloudRegion,
+	aws:     envAWSDefaultRegion,
+	gcp:     envGCPRegion,
+}
+
+// regions are the known regions of the clouds, which a typo of a region is checked against before any Job runs.
+// A region launched by a cloud needs to be added here before it can be used.
+var regions = map[CloudProvider]sets.String{
+	alibaba: sets.NewString(
+		"cn-qingdao", "cn-beijing", "cn-zhangjiakou", "cn-huhehaote", "cn-wulanchabu", "cn-hangzhou", "cn-shanghai",
+		"cn-nanjing", "cn-fuzhou", "cn-shenzhen", "cn-heyuan", "cn-guangzhou", "cn-chengdu", "cn-hongkong",
+		"cn-hangzhou-finance", "cn-shanghai-finance-1", "cn-shenzhen-finance-1", "cn-beijing-finance-1",
+		"ap-northeast-1", "ap-northeast-2", "ap-southeast-1", "ap-southeast-2", "ap-southeast-3", "ap-southeast-5",
+		"ap-southeast-6", "ap-southeast-7", "ap-south-1", "us-east-1", "us-west-1", "eu-west-1", "eu-central-1",
+		"me-east-1", "me-central-1",
+	),
+	aws: sets.NewString(
+		"us-east-1", "us-east-2", "us-west-1", "us-west-2", "af-south-1", "ap-east-1", "ap-south-1", "ap-south-2",
+		"ap-southeast-1", "ap-southeast-2", "ap-southeast-3", "ap-southeast-4", "ap-southeast-5", "ap-southeast-7",
+		"ap-northeast-1", "ap-northeast-2", "ap-northeast-3", "ca-central-1", "ca-west-1", "eu-central-1",
+		"eu-central-2", "eu-west-1", "eu-west-2", "eu-west-3", "eu-south-1", "eu-south-2", "eu-north-1",
+		"il-central-1", "me-south-1", "me-central-1", "mx-central-1", "sa-east-1", "us-gov-east-1", "us-gov-west-1",
+		"cn-north-1", "cn-northwest-1",
+	),
+	gcp: sets.NewString(
+		"africa-south1", "asia-east1", "asia-east2", "asia-northeast1", "asia-northeast2", "asia-northeast3",
+		"asia-south1", "asia-south2", "asia-southeast1", "asia-southeast2", "australia-southeast1",
+		"australia-southeast2", "europe-central2", "europe-north1", "europe-north2", "europe-southwest1",
+		"europe-west1", "europe-west2", "europe-west3", "europe-west4", "europe-west6", "europe-west8", "europe-west9",
+		"europe-west10", "europe-west12", "me-central1", "me-central2", "me-west1", "northamerica-northeast1",
+		"northamerica-northeast2", "northamerica-south1", "southamerica-east1", "southamerica-west1", "us-central1",
+		"us-east1", "us-east4", "us-east5", "us-south1", "us-west1", "us-west2", "us-west3", "us-west4",
+	),
+}
+
+// ValidateRegion checks a region against the known regions of a cloud. An empty region, or a cloud whose regions
+// aren't known, passes.
+func ValidateRegion(provider, region string) error {
+	known, ok := regions[CloudProvider(provider)]
+	if !ok || region == "" || known.Has(region) {
+		return nil
+	}
+	valid := known.List()
+	sort.Strings(valid)
+	return errors.Wrapf(ErrInvalidRegion, "%s is not a region of provider %s, the valid regions are %s", region, provider,
+		strings.Join(valid, ", "))
+}
@@ -0,0 +1,367 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+const (
+	// secretStoreTimeout bounds fetching a secret from a secret store
+	secretStoreTimeout = 10 * time.Second
+	// secretStoreCacheTTL is how long a secret fetched from a secret store is used, which stops every reconciliation
+	// from calling the secret store, while the rotated credentials are used soon
+	secretStoreCacheTTL = 30 * time.Second
+)
+
+// SecretStore fetches the secrets from a secret store outside of Kubernetes
+type SecretStore interface {
+	// Fetch returns the content of a secret, which is a JSON object if the secret has keys, like the ones of Vault
+	Fetch(ctx context.Context, ref *v1beta1.SecretStoreReference) ([]byte, error)
+}
+
+var secretStores = map[v1beta1.SecretStoreType]SecretStore{
+	v1beta1.SecretStoreVault:             &vaultSecretStore{httpClient: &http.Client{}},
+	v1beta1.SecretStoreAWSSecretsManager: &awsSecretsManager{httpClient: &http.Client{}, endpoint: awsEndpoint},
+}
+
+// SecretStoreOptions configures the built-in secret stores by the flags of the controller. They aren't set by
+// Providers, as the token of the service account of the controller is sent to Vault, and the secrets of AWS Secrets
+// Manager are read with the credentials of the controller.
+type SecretStoreOptions struct {
+	// VaultAddress is the address of Vault, like https://vault.example.com:8200
+	VaultAddress string
+	// VaultAuthMountPath is the mount path of the Kubernetes auth method of Vault
+	VaultAuthMountPath string
+	// VaultRole is the role of the Kubernetes auth method bound to the service account of the controller
+	VaultRole string
+	// VaultAllowedPaths are the prefixes of the paths of Vault which Providers can read. No path can be read without
+	// them.
+	VaultAllowedPaths []string
+	// AWSRegion is the region of AWS Secrets Manager
+	AWSRegion string
+	// AWSAllowedSecrets are the prefixes of the names or the ARNs of the secrets of AWS Secrets Manager which Providers
+	// can read. No secret can be read without them.
+	AWSAllowedSecrets []string
+}
+
+// ConfigureSecretStores configures the built-in secret stores. It should be called before the controllers start.
+func ConfigureSecretStores(opts SecretStoreOptions) {
+	mountPath := opts.VaultAuthMountPath
+	if mountPath == "" {
+		mountPath = "kubernetes"
+	}
+	secretStores[v1beta1.SecretStoreVault] = &vaultSecretStore{
+		httpClient:   &http.Client{},
+		address:      strings.TrimSuffix(opts.VaultAddress, "/"),
+		mountPath:    strings.Trim(mountPath, "/"),
+		role:         opts.VaultRole,
+		allowedPaths: opts.VaultAllowedPaths,
+	}
+	secretStores[v1beta1.SecretStoreAWSSecretsManager] = &awsSecretsManager{
+		httpClient:     &http.Client{},
+		endpoint:       awsEndpoint,
+		region:         opts.AWSRegion,
+		allowedSecrets: opts.AWSAllowedSecrets,
+	}
+}
+
+// RegisterSecretStore registers the secret store of a type, which replaces the built-in one of the type. It should be
+// called before the controllers start.
+func RegisterSecretStore(storeType v1beta1.SecretStoreType, store SecretStore) {
+	secretStores[storeType] = store
+}
+
+type cachedSecret struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+var secretStoreCache = struct {
+	sync.Mutex
+	secrets map[string]cachedSecret
+}{secrets: make(map[string]cachedSecret)}
+
+// fetchFromSecretStore fetches the credentials referenced from a secret store, and caches them for a short while.
+// The errors are not cached, so a Provider is ready as soon as the secret store is fixed.
+func fetchFromSecretStore(ctx context.Context, ref *v1beta1.SecretStoreReference) ([]byte, error) {
+	store, ok := secretStores[ref.Type]
+	if !ok {
+		return nil, errors.Errorf("the secret store %q is not supported", ref.Type)
+	}
+	rawKey, err := json.Marshal(ref)
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := string(rawKey)
+
+	secretStoreCache.Lock()
+	cached, ok := secretStoreCache.secrets[cacheKey]
+	secretStoreCache.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, secretStoreTimeout)
+	defer cancel()
+	content, err := store.Fetch(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := selectSecretKey(content, ref.Key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the secret %s", ref.Path)
+	}
+
+	secretStoreCache.Lock()
+	secretStoreCache.secrets[cacheKey] = cachedSecret{data: data, expiresAt: time.Now().Add(secretStoreCacheTTL)}
+	secretStoreCache.Unlock()
+	return data, nil
+}
+
+// selectSecretKey returns the value of a key of a secret, or the whole secret if the key is empty
+func selectSecretKey(content []byte, key string) ([]byte, error) {
+	if key == "" {
+		return content, nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, errors.Wrapf(err, "the secret has no key %s as it isn't a JSON object", key)
+	}
+	value, ok := values[key]
+	if !ok {
+		return nil, errors.Errorf("the secret has no key %s", key)
+	}
+	if str, ok := value.(string); ok {
+		return []byte(str), nil
+	}
+	return json.Marshal(value)
+}
+
+// serviceAccountTokenFile is the token of the service account of the controller, which logs in to Vault
+var serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
+
+// vaultSecretStore reads the secrets of the KV secrets engines of Vault, logged in by the Kubernetes auth method, whose
+// paths start with the allowed prefixes
+type vaultSecretStore struct {
+	httpClient   *http.Client
+	address      string
+	mountPath    string
+	role         string
+	allowedPaths []string
+}
+
+type vaultResponse struct {
+	Auth struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []string                   `json:"errors"`
+}
+
+func (s *vaultSecretStore) Fetch(ctx context.Context, ref *v1beta1.SecretStoreReference) ([]byte, error) {
+	if s.address == "" || s.role == "" {
+		return nil, errors.New("Vault is not configured by --vault-address and --vault-role of the controller")
+	}
+	secretPath := strings.Trim(ref.Path, "/")
+	if !s.allowed(secretPath) {
+		return nil, errors.Errorf("the secret %s is not allowed by --vault-allowed-paths of the controller", ref.Path)
+	}
+	jwt, err := os.ReadFile(serviceAccountTokenFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the token of the service account to log in to Vault")
+	}
+	login, err := json.Marshal(map[string]string{"role": s.role, "jwt": strings.TrimSpace(string(jwt))})
+	if err != nil {
+		return nil, err
+	}
+	var loginResp vaultResponse
+	if err := s.do(ctx, http.MethodPost, fmt.Sprintf("%s/v1/auth/%s/login", s.address, s.mountPath), "",
+		login, &loginResp); err != nil {
+		return nil, errors.Wrap(err, "failed to log in to Vault")
+	}
+
+	var secretResp vaultResponse
+	if err := s.do(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s", s.address, secretPath),
+		loginResp.Auth.ClientToken, nil, &secretResp); err != nil {
+		return nil, errors.Wrapf(err, "failed to read the secret %s from Vault", ref.Path)
+	}
+	// the KV secrets engine version 2 wraps the secret with its metadata
+	if data, ok := secretResp.Data["data"]; ok && secretResp.Data["metadata"] != nil {
+		return data, nil
+	}
+	return json.Marshal(secretResp.Data)
+}
+
+// allowed tells whether a path starts with one of the allowed prefixes. The paths with the . or .. segments are never
+// allowed, as they could escape the prefixes.
+func (s *vaultSecretStore) allowed(secretPath string) bool {
+	if path.Clean("/"+secretPath) != "/"+secretPath {
+		return false
+	}
+	for _, prefix := range s.allowedPaths {
+		prefix = strings.TrimPrefix(prefix, "/")
+		if prefix != "" && strings.HasPrefix(secretPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *vaultSecretStore) do(ctx context.Context, method, address, token string, body []byte, resp *vaultResponse) error {
+	req, err := http.NewRequestWithContext(ctx, method, address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	respBody, status, err := doHTTPRequest(s.httpClient, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(respBody, resp); err != nil && status == http.StatusOK {
+		return errors.Wrap(err, "failed to decode the response of Vault")
+	}
+	if status != http.StatusOK {
+		return errors.Errorf("status %d: %s", status, strings.Join(resp.Errors, "; "))
+	}
+	return nil
+}
+
+// awsSecretsManager reads the secret strings of AWS Secrets Manager, whose names or ARNs start with the allowed
+// prefixes
+type awsSecretsManager struct {
+	httpClient     *http.Client
+	endpoint       func(service, region string) string
+	region         string
+	allowedSecrets []string
+}
+
+type awsAssumeRoleWithWebIdentityResponse struct {
+	AccessKeyID     string `xml:"AssumeRoleWithWebIdentityResult>Credentials>AccessKeyId"`
+	SecretAccessKey string `xml:"AssumeRoleWithWebIdentityResult>Credentials>SecretAccessKey"`
+	SessionToken    string `xml:"AssumeRoleWithWebIdentityResult>Credentials>SessionToken"`
+}
+
+type awsGetSecretValueResponse struct {
+	SecretString string `json:"SecretString"`
+	Type         string `json:"__type"`
+	Message      string `json:"Message"`
+	LowerMessage string `json:"message"`
+}
+
+func (s *awsSecretsManager) Fetch(ctx context.Context, ref *v1beta1.SecretStoreReference) ([]byte, error) {
+	if s.region == "" {
+		return nil, errors.New("AWS Secrets Manager is not configured by --aws-secrets-manager-region of the controller")
+	}
+	if !s.allowed(ref.Path) {
+		return nil, errors.Errorf("the secret %s is not allowed by --aws-secrets-manager-allowed-secrets of the controller", ref.Path)
+	}
+	region := s.region
+	credentials, err := s.credentials(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(map[string]string{"SecretId": ref.Path})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint("secretsmanager", region)+"/", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{
+		"content-type": "application/x-amz-json-1.1",
+		"x-amz-target": "secretsmanager.GetSecretValue",
+	}
+	if credentials.SessionToken != "" {
+		headers["x-amz-security-token"] = credentials.SessionToken
+	}
+	signAWSRequest(req, "", region, "secretsmanager", headers, payload, credentials.AccessKeyID, credentials.SecretAccessKey, time.Now())
+
+	body, status, err := doHTTPRequest(s.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	var resp awsGetSecretValueResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the response of AWS Secrets Manager")
+	}
+	if status != http.StatusOK {
+		if resp.Message == "" {
+			resp.Message = resp.LowerMessage
+		}
+		return nil, errors.Errorf("failed to get the secret %s from AWS Secrets Manager: %s: %s", ref.Path, resp.Type, resp.Message)
+	}
+	return []byte(resp.SecretString), nil
+}
+
+// allowed tells whether a secret starts with one of the allowed prefixes
+func (s *awsSecretsManager) allowed(secret string) bool {
+	for _, prefix := range s.allowedSecrets {
+		if prefix != "" && strings.HasPrefix(secret, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// credentials returns the AWS credentials in the environment of the controller, which are static, or assumed by the
+// web identity token of IAM roles for service accounts
+func (s *awsSecretsManager) credentials(ctx context.Context, region string) (*awsAssumeRoleWithWebIdentityResponse, error) {
+	if accessKeyID := os.Getenv(envAWSAccessKeyID); accessKeyID != "" {
+		return &awsAssumeRoleWithWebIdentityResponse{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: os.Getenv(envAWSSecretAccessKey),
+			SessionToken:    os.Getenv(envAWSSessionToken),
+		}, nil
+	}
+	roleARN, tokenFile := os.Getenv("AWS_ROLE_ARN"), os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if roleARN == "" || tokenFile == "" {
+		return nil, errors.New("no AWS credentials in the environment of the controller to call AWS Secrets Manager")
+	}
+	token, err := os.ReadFile(tokenFile) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the web identity token")
+	}
+	query := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {"terraform-controller"},
+		"Version":          {"2011-06-15"},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint("sts", region)+"/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, status, err := doHTTPRequest(s.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		var errResp awsErrorResponse
+		_ = xml.Unmarshal(body, &errResp)
+		return nil, errors.Errorf("failed to assume the role %s: %s: %s", roleARN, errResp.Code, errResp.Message)
+	}
+	var resp awsAssumeRoleWithWebIdentityResponse
+	if err := xml.Unmarshal(body, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the response of AWS STS")
+	}
+	return &resp, nil
+}
@@ -0,0 +1,166 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestVaultSecretStoreFetch(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("sa-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(file string) { serviceAccountTokenFile = file }(serviceAccountTokenFile)
+	serviceAccountTokenFile = tokenFile
+
+	var logins, reads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			logins++
+			var login map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&login); err != nil || login["role"] != "terraform" || login["jwt"] != "sa-token" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["invalid role or jwt"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"vault-token"}}`))
+		case "/v1/secret/data/terraform/aws":
+			reads++
+			if r.Header.Get("X-Vault-Token") != "vault-token" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"data":{"awsAccessKeyID":"a","awsSecretAccessKey":"b"},"metadata":{"version":1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	// the token isn't sent anywhere before Vault is configured
+	ref := &v1beta1.SecretStoreReference{Type: v1beta1.SecretStoreVault, Path: "secret/data/terraform/aws"}
+	if _, err := fetchFromSecretStore(context.Background(), ref); err == nil || !strings.Contains(err.Error(), "--vault-address") {
+		t.Errorf("expected the error of the unconfigured Vault, got %v", err)
+	}
+	defer func(store SecretStore) { secretStores[v1beta1.SecretStoreVault] = store }(secretStores[v1beta1.SecretStoreVault])
+	ConfigureSecretStores(SecretStoreOptions{VaultAddress: server.URL + "/", VaultRole: "terraform"})
+
+	// no path can be read without the allowed paths
+	if _, err := fetchFromSecretStore(context.Background(), ref); err == nil || !strings.Contains(err.Error(), "--vault-allowed-paths") {
+		t.Errorf("expected the path not allowed, got %v", err)
+	}
+	ConfigureSecretStores(SecretStoreOptions{VaultAddress: server.URL + "/", VaultRole: "terraform",
+		VaultAllowedPaths: []string{"secret/data/terraform/"}})
+
+	provider := &v1beta1.Provider{Spec: v1beta1.ProviderSpec{
+		Provider:    "aws",
+		Region:      "us-west-2",
+		Credentials: v1beta1.ProviderCredentials{Source: v1beta1.CredentialsSourceSecretStore, SecretStoreRef: ref},
+	}}
+	for i := 0; i < 2; i++ {
+		credentials, err := providerCredentials(context.Background(), nil, provider)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if credentials[envAWSAccessKeyID] != "a" || credentials[envAWSSecretAccessKey] != "b" || credentials[envAWSDefaultRegion] != "us-west-2" {
+			t.Errorf("unexpected credentials: %v", credentials)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("expected the secret to be read once and then cached, got %d reads", reads)
+	}
+
+	missing := *ref
+	missing.Path = "secret/data/terraform/missing"
+	if _, err := fetchFromSecretStore(context.Background(), &missing); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("expected the error of the missing secret, got %v", err)
+	}
+	cacheKey, _ := json.Marshal(&missing)
+	if _, ok := secretStoreCache.secrets[string(cacheKey)]; ok {
+		t.Error("expected the failure not to be cached")
+	}
+
+	// the paths other than the allowed ones aren't read, even if the role of the controller can, and the token isn't
+	// sent for them
+	loginsBefore := logins
+	for _, path := range []string{"secret/data/prod/database", "secret/data/terraform/../../prod/database", "secret/data/terraform"} {
+		denied := *ref
+		denied.Path = path
+		if _, err := fetchFromSecretStore(context.Background(), &denied); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("expected the path %s not allowed, got %v", path, err)
+		}
+	}
+	if logins != loginsBefore {
+		t.Errorf("expected no login for the paths not allowed, got %d", logins-loginsBefore)
+	}
+}
+
+func TestAWSSecretsManagerFetch(t *testing.T) {
+	for name, value := range map[string]string{envAWSAccessKeyID: "AKID", envAWSSecretAccessKey: "secret", envAWSSessionToken: ""} {
+		defer os.Setenv(name, os.Getenv(name)) //nolint:errcheck
+		os.Setenv(name, value)                 //nolint:errcheck
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req["SecretId"] != "terraform/alibaba" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","Message":"Secrets Manager can't find the specified secret."}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"SecretString":"{\"credentials\":\"accessKeyID: a\\naccessKeySecret: b\\n\"}"}`))
+	}))
+	defer server.Close()
+
+	store := &awsSecretsManager{
+		httpClient:     server.Client(),
+		endpoint:       func(string, string) string { return server.URL },
+		region:         "us-east-1",
+		allowedSecrets: []string{"terraform/"},
+	}
+	ref := &v1beta1.SecretStoreReference{Type: v1beta1.SecretStoreAWSSecretsManager, Path: "terraform/alibaba"}
+	content, err := store.Fetch(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := selectSecretKey(content, "credentials")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "accessKeyID: a\naccessKeySecret: b\n" {
+		t.Errorf("unexpected credentials: %q", data)
+	}
+
+	ref.Path = "terraform/missing"
+	if _, err := store.Fetch(context.Background(), ref); err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("expected the error of the missing secret, got %v", err)
+	}
+
+	// the secrets other than the allowed ones aren't read, even if the controller can
+	ref.Path = "prod/database"
+	if _, err := store.Fetch(context.Background(), ref); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected the secret not allowed, got %v", err)
+	}
+	store.allowedSecrets = nil
+	ref.Path = "terraform/alibaba"
+	if _, err := store.Fetch(context.Background(), ref); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected no secret allowed without the allowed prefixes, got %v", err)
+	}
+}
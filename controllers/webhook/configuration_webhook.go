@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers"
+	cfgvalidator "github.com/oam-dev/terraform-controller/controllers/configuration"
+)
+
+const (
+	// ValidateConfigurationPath is the path of the validating webhook of Configuration
+	ValidateConfigurationPath = "/validate-terraform-core-oam-dev-v1beta1-configuration"
+	// MutateConfigurationPath is the path of the mutating webhook of Configuration
+	MutateConfigurationPath = "/mutate-terraform-core-oam-dev-v1beta1-configuration"
+)
+
+// +kubebuilder:webhook:path=/validate-terraform-core-oam-dev-v1beta1-configuration,mutating=false,failurePolicy=fail,sideEffects=None,groups=terraform.core.oam.dev,resources=configurations,verbs=create;update,versions=v1beta1,name=vconfiguration.terraform.core.oam.dev,admissionReviewVersions={v1,v1beta1}
+
+// ConfigurationValidator validates Configurations when they're created or updated
+type ConfigurationValidator struct {
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &ConfigurationValidator{}
+var _ admission.DecoderInjector = &ConfigurationValidator{}
+
+// Handle implements admission.Handler
+func (v *ConfigurationValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	var configuration v1beta1.Configuration
+	if err := v.decoder.Decode(req, &configuration); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// Don't block removing the finalizer of a Configuration being deleted
+	if !configuration.DeletionTimestamp.IsZero() {
+		return admission.Allowed("")
+	}
+	if err := cfgvalidator.ValidateConfiguration(&configuration); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := append(controllers.ValidateExecutorVolumes(&configuration), controllers.ValidateEnv(&configuration)...).ToAggregate(); err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// InjectDecoder implements admission.DecoderInjector
+func (v *ConfigurationValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// +kubebuilder:webhook:path=/mutate-terraform-core-oam-dev-v1beta1-configuration,mutating=true,failurePolicy=fail,sideEffects=None,groups=terraform.core.oam.dev,resources=configurations,verbs=create;update,versions=v1beta1,name=mconfiguration.terraform.core.oam.dev,admissionReviewVersions={v1,v1beta1}
+
+// ConfigurationDefaulter persists the default values of Configurations, so that the stored spec reflects the values
+// the controller works with
+type ConfigurationDefaulter struct {
+	// DefaultProvider is the default Provider of the controller, see cfgvalidator.SetDefaultProvider
+	DefaultProvider string
+	decoder         *admission.Decoder
+}
+
+var _ admission.Handler = &ConfigurationDefaulter{}
+var _ admission.DecoderInjector = &ConfigurationDefaulter{}
+
+// Handle implements admission.Handler
+func (d *ConfigurationDefaulter) Handle(_ context.Context, req admission.Request) admission.Response {
+	var configuration v1beta1.Configuration
+	if err := d.decoder.Decode(req, &configuration); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !configuration.DeletionTimestamp.IsZero() {
+		return admission.Allowed("")
+	}
+	cfgvalidator.SetDefaultProvider(&configuration, d.DefaultProvider)
+	cfgvalidator.SetDefaults(&configuration)
+	marshaled, err := json.Marshal(&configuration)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// InjectDecoder implements admission.DecoderInjector
+func (d *ConfigurationDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// Register registers the webhooks of Configuration to the webhook server of the manager, which default the Provider of
+// the Configurations which don't reference any to defaultProvider
+func Register(mgr ctrl.Manager, defaultProvider string) {
+	server := mgr.GetWebhookServer()
+	server.Register(ValidateConfigurationPath, &webhook.Admission{Handler: &ConfigurationValidator{}})
+	server.Register(MutateConfigurationPath, &webhook.Admission{Handler: &ConfigurationDefaulter{DefaultProvider: defaultProvider}})
+}
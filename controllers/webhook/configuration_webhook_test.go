@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta1"
+)
+
+func TestConfigurationValidator(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := &ConfigurationValidator{}
+	if err := validator.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+
+	hcl := `resource "random_id" "server" {}`
+	deleting := metav1.Now()
+	testcases := map[string]struct {
+		configuration v1beta1.Configuration
+		allowed       bool
+		reason        string
+	}{
+		"valid": {
+			configuration: v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+				HCL:      hcl,
+				Variable: &runtime.RawExtension{Raw: []byte(`{"name": "abc"}`)},
+				Backend:  &v1beta1.Backend{SecretSuffix: "oss"},
+				PostApplyHooks: []v1beta1.Hook{
+					{Name: "notify", Image: "busybox"},
+				},
+			}},
+			allowed: true,
+		},
+		"neither hcl, json nor remote": {
+			allowed: false,
+			reason:  "spec.JSON, spec.HCL, spec.Remote or spec.OCI should be set",
+		},
+		"both hcl and remote": {
+			configuration: v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{HCL: hcl, Remote: "https://github.com/a/b"}},
+			reason:        "at the same time",
+		},
+		"variable is not an object": {
+			configuration: v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+				HCL:      hcl,
+				Variable: &runtime.RawExtension{Raw: []byte(`["abc"]`)},
+			}},
+			reason: "spec.variable",
+		},
+		"invalid backend secret suffix": {
+			configuration: v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+				HCL:     hcl,
+				Backend: &v1beta1.Backend{SecretSuffix: "OSS_bucket"},
+			}},
+			reason: "spec.backend.secretSuffix",
+		},
+		"duplicated hooks": {
+			configuration: v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+				HCL: hcl,
+				PostApplyHooks: []v1beta1.Hook{
+					{Name: "notify", Image: "busybox"},
+					{Name: "notify", Image: "busybox"},
+				},
+			}},
+			reason: "spec.postApplyHooks[1].name: Duplicate value",
+		},
+		"hook without image": {
+			configuration: v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+				HCL:            hcl,
+				PostApplyHooks: []v1beta1.Hook{{Name: "notify"}},
+			}},
+			reason: "spec.postApplyHooks[0].image: Required value",
+		},
+		"negative destroy timeout": {
+			configuration: v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+				HCL:            hcl,
+				DestroyTimeout: &metav1.Duration{Duration: -time.Minute},
+			}},
+			reason: "spec.destroyTimeout",
+		},
+		"invalid service account name": {
+			configuration: v1beta1.Configuration{Spec: v1beta1.ConfigurationSpec{
+				HCL:                hcl,
+				ServiceAccountName: "Terraform_SA",
+			}},
+			reason: "spec.serviceAccountName",
+		},
+		"invalid but being deleted": {
+			configuration: v1beta1.Configuration{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleting}},
+			allowed:       true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			tc.configuration.APIVersion = v1beta1.GroupVersion.String()
+			tc.configuration.Kind = "Configuration"
+			raw, err := json.Marshal(tc.configuration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := validator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Operation: admissionv1beta1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			}})
+			if resp.Allowed != tc.allowed {
+				t.Fatalf("expected allowed %v, got %v: %v", tc.allowed, resp.Allowed, resp.Result)
+			}
+			if tc.reason != "" && !strings.Contains(string(resp.Result.Reason), tc.reason) {
+				t.Errorf("expected reason containing %q, got %q", tc.reason, resp.Result.Reason)
+			}
+		})
+	}
+}
+
+func TestConfigurationDefaulter(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaulter := &ConfigurationDefaulter{}
+	if err := defaulter.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+
+	configuration := v1beta1.Configuration{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1beta1.GroupVersion.String(), Kind: "Configuration"},
+		ObjectMeta: metav1.ObjectMeta{Name: "oss", Namespace: "default"},
+		Spec:       v1beta1.ConfigurationSpec{HCL: `resource "random_id" "server" {}`, Engine: "tofu"},
+	}
+	raw, err := json.Marshal(configuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := defaulter.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: admissionv1beta1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}})
+	if !resp.Allowed {
+		t.Fatalf("expected allowed, got %v", resp.Result)
+	}
+	patched := map[string]interface{}{}
+	for _, p := range resp.Patches {
+		patched[p.Path] = p.Value
+	}
+	expected := map[string]interface{}{
+		"/spec/providerRef":    map[string]interface{}{"name": "default", "namespace": "default"},
+		"/spec/backend":        map[string]interface{}{"secretSuffix": "oss", "inClusterConfig": true},
+		"/spec/applyTimeout":   "1h0m0s",
+		"/spec/destroyTimeout": "1h0m0s",
+	}
+	if !reflect.DeepEqual(patched, expected) {
+		t.Errorf("expected patches %v, got %v", expected, patched)
+	}
+
+	// the default Provider of the controller is persisted for the Configurations which don't reference any
+	defaulter.DefaultProvider = "aws"
+	resp = defaulter.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: admissionv1beta1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}})
+	for _, p := range resp.Patches {
+		if p.Path == "/spec/providerRef" && !reflect.DeepEqual(p.Value, map[string]interface{}{"name": "aws", "namespace": "default"}) {
+			t.Errorf("expected the default Provider of the controller, got %v", p.Value)
+		}
+	}
+}
@@ -1,5 +1,9 @@
 #!/bin/bash
 
 echo "armClientID: ${ARM_CLIENT_ID}\narmClientSecret: ${ARM_CLIENT_SECRET}\narmSubscriptionID: ${ARM_SUBSCRIPTION_ID}\narmTenantID: ${ARM_TENANT_ID}" > azure-credentials.conf
+# The access key of the storage account is only needed by the azurerm backend
+if [ -n "${ARM_ACCESS_KEY}" ]; then
+  echo "armAccessKey: ${ARM_ACCESS_KEY}" >> azure-credentials.conf
+fi
 kubectl create secret generic azure-account-creds -n vela-system --from-file=credentials=azure-credentials.conf
 rm -f azure-credentials.conf
@@ -18,16 +18,25 @@ package main
 
 import (
 	"flag"
+	"net/url"
 	"os"
+	"path"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	terraformv1beta1 "github.com/oam-dev/terraform-controller/api/v1beta1"
 	"github.com/oam-dev/terraform-controller/controllers"
+	"github.com/oam-dev/terraform-controller/controllers/util"
+	"github.com/oam-dev/terraform-controller/controllers/webhook"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -45,47 +54,272 @@ func init() {
 
 func main() {
 	var metricsAddr string
+	var healthProbeAddr string
 	var enableLeaderElection bool
 	var syncPeriod time.Duration
+	var orphanCollectInterval time.Duration
+	var outputsAPIAddr string
+	var outputsAPICertDir string
+	var enableWebhook bool
+	var jobTTLSecondsAfterFinished int
+	var enableStateSurgery bool
+	var workingVolumeMedium string
+	var workingVolumeSizeLimit string
+	var verifyProviderCredentials bool
+	var providerCheckInterval time.Duration
+	var providerVerifyInterval time.Duration
+	var pluginCachePVC string
+	var pluginCacheHostPath string
+	var planArtifactsPVC string
+	var planExpiry time.Duration
+	var cliConfigConfigMap string
+	var cliConfigSecret string
+	var terraformImage string
+	var openTofuImage string
+	var runnerImage string
+	var terraformJSONLogs bool
+	var applyProgressInterval time.Duration
+	var credentialsGracePeriod time.Duration
+	var finalizerGracePeriod time.Duration
+	var reconcileTimeout time.Duration
+	var reconcileStallTimeout time.Duration
+	var executionNamespaces string
+	var notificationWebhooks string
+	var defaultProvider string
+	var secretStoreOpts util.SecretStoreOptions
+	var vaultAllowedPaths string
+	var awsAllowedSecrets string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":38080", "The address the metric endpoint binds to.")
+	flag.StringVar(&healthProbeAddr, "health-probe-addr", ":38081",
+		"The address the liveness and readiness probes of /healthz and /readyz bind to, empty disables them.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.DurationVar(&syncPeriod, "informer-re-sync-interval", 10*time.Second,
 		"controller shared informer lister full re-sync period")
+	flag.DurationVar(&orphanCollectInterval, "orphan-collect-interval", 10*time.Minute,
+		"the interval to delete sub-resources whose Configuration no longer exists, 0 disables it")
+	flag.StringVar(&outputsAPIAddr, "outputs-api-addr", "",
+		"The address the HTTPS API of Configuration outputs binds to, empty disables it.")
+	flag.StringVar(&outputsAPICertDir, "outputs-api-cert-dir", "",
+		"The directory of the serving certificate tls.crt and its key tls.key of the outputs API, which is required by --outputs-api-addr.")
+	flag.BoolVar(&enableWebhook, "enable-webhook", false,
+		"Enable the admission webhooks of Configuration, which requires the serving certificates of the webhook server.")
+	flag.IntVar(&jobTTLSecondsAfterFinished, "job-ttl-seconds-after-finished", -1,
+		"The seconds after which the finished apply and destroy Jobs are cleaned up, negative keeps them until the Configuration is deleted.")
+	flag.BoolVar(&enableStateSurgery, "enable-state-surgery", false,
+		"Enable removing resources from the state without destroying them by the annotation "+controllers.StateRemoveAnnotation+" of Configurations.")
+	flag.StringVar(&workingVolumeMedium, "working-volume-medium", "",
+		"The default medium of the emptyDir volumes of the Terraform executor, Memory or empty for the storage of the node.")
+	flag.StringVar(&workingVolumeSizeLimit, "working-volume-size-limit", "",
+		"The default size limit of the emptyDir volumes of the Terraform executor, e.g. 1Gi, empty means unlimited.")
+	flag.BoolVar(&verifyProviderCredentials, "verify-provider-credentials", false,
+		"Verify the credentials of Providers by the APIs of AWS and Alibaba Cloud, which needs the egress to them.")
+	flag.DurationVar(&providerCheckInterval, "provider-check-interval", 5*time.Minute,
+		"The interval to check the credentials of Providers again, 0 disables it.")
+	flag.DurationVar(&providerVerifyInterval, "provider-verify-interval", time.Hour,
+		"The minimum interval to verify the credentials of a Provider by the cloud again, when --verify-provider-credentials is enabled.")
+	flag.StringVar(&pluginCachePVC, "plugin-cache-pvc", "",
+		"The PersistentVolumeClaim in the namespace of the controller and the execution namespaces shared by the Terraform Jobs as the plugin cache, which needs to be ReadWriteMany when the Jobs run on multiple nodes.")
+	flag.StringVar(&pluginCacheHostPath, "plugin-cache-host-path", "",
+		"The directory of the nodes shared by the Terraform Jobs on a node as the plugin cache, empty disables it.")
+	flag.StringVar(&planArtifactsPVC, "plan-artifacts-pvc", "",
+		"The PersistentVolumeClaim in the namespace of the controller and the execution namespaces where the plans of Configurations requiring approval are saved, so that the approved plan is applied as it is, empty plans again before the apply.")
+	flag.DurationVar(&planExpiry, "plan-expiry", time.Hour,
+		"How long a saved plan could wait to be applied before it's planned again, when --plan-artifacts-pvc is set, 0 disables it.")
+	flag.StringVar(&cliConfigConfigMap, "cli-config-configmap", "",
+		"The ConfigMap in the namespace of the controller and the execution namespaces whose key "+controllers.CLIConfigKey+" is the CLI configuration of Terraform, like a provider network mirror.")
+	flag.StringVar(&cliConfigSecret, "cli-config-secret", "",
+		"The Secret in the namespace of the controller and the execution namespaces whose key "+controllers.CLIConfigKey+" is the CLI configuration of Terraform, like the one with the credentials of a private registry.")
+	flag.StringVar(&terraformImage, "terraform-image", "",
+		"The image of the executor of the Configurations run by Terraform, which only needs the terraform binary and could be distroless, empty uses the built-in one.")
+	flag.StringVar(&openTofuImage, "opentofu-image", "",
+		"The image of the executor of the Configurations run by OpenTofu, which only needs the tofu binary and could be distroless, empty uses the built-in one.")
+	flag.StringVar(&runnerImage, "runner-image", "",
+		"The image of the controller, whose runner binary is installed to the Terraform Jobs to run their steps without a shell, empty uses the built-in one.")
+	flag.BoolVar(&terraformJSONLogs, "terraform-json-logs", false,
+		"Run plan, apply and destroy with -json, whose machine-readable logs are parsed for the status, which needs Terraform 0.15.3 or later.")
+	flag.DurationVar(&applyProgressInterval, "apply-progress-interval", 15*time.Second,
+		"The minimum interval to read the logs of a running apply Job for its progress, 0 disables it.")
+	flag.DurationVar(&credentialsGracePeriod, "provider-credentials-grace-period", 2*time.Minute,
+		"How long a Provider or the Secret of its credentials which isn't found is waited for before Configurations turn ProviderNotReady, 0 disables it.")
+	flag.DurationVar(&finalizerGracePeriod, "finalizer-grace-period", 0,
+		"How long the finalizer of a deleted Configuration is held after its cloud resources are destroyed, 0 removes it right away.")
+	flag.DurationVar(&reconcileTimeout, "reconcile-timeout", 5*time.Minute,
+		"How long a reconciliation of a Configuration could take, like reading a slow backend, before it's cancelled and retried, 0 disables it.")
+	flag.DurationVar(&reconcileStallTimeout, "reconcile-stall-timeout", 15*time.Minute,
+		"How long Configurations could wait to be reconciled while no reconciliation finishes before the liveness probe fails, 0 disables it.")
+	flag.StringVar(&executionNamespaces, "execution-namespaces", "",
+		"The comma-separated namespaces which the Terraform Jobs and the other sub-resources of Configurations can be routed to by the annotation "+controllers.ExecutionNamespaceAnnotation+".")
+	flag.StringVar(&notificationWebhooks, "notification-webhooks", "",
+		"The comma-separated URLs of the webhooks, like the incoming webhooks of Slack, which are POSTed when Configurations become available, or their apply or destroy fails or finishes.")
+	flag.StringVar(&defaultProvider, "default-provider", "",
+		"The name of the Provider in the namespace default which Configurations not referencing any use, which takes precedence over the Provider default.")
+	flag.StringVar(&secretStoreOpts.VaultAddress, "vault-address", "",
+		"The address of Vault, like https://vault.example.com:8200, which Providers read the credentials from by secretStoreRef.")
+	flag.StringVar(&secretStoreOpts.VaultAuthMountPath, "vault-auth-mount-path", "kubernetes",
+		"The mount path of the Kubernetes auth method of Vault, which the controller logs in with its service account.")
+	flag.StringVar(&secretStoreOpts.VaultRole, "vault-role", "",
+		"The role of the Kubernetes auth method of Vault bound to the service account of the controller.")
+	flag.StringVar(&vaultAllowedPaths, "vault-allowed-paths", "",
+		"The comma-separated prefixes of the paths of Vault, like secret/data/terraform/, which Providers can read.")
+	flag.StringVar(&secretStoreOpts.AWSRegion, "aws-secrets-manager-region", "",
+		"The region of AWS Secrets Manager, which Providers read the credentials from by secretStoreRef.")
+	flag.StringVar(&awsAllowedSecrets, "aws-secrets-manager-allowed-secrets", "",
+		"The comma-separated prefixes of the names or the ARNs of the secrets of AWS Secrets Manager which Providers can read.")
 	flag.Parse()
 
+	workingVolume, err := newWorkingVolume(workingVolumeMedium, workingVolumeSizeLimit)
+	if err != nil {
+		setupLog.Error(err, "invalid working volume")
+		os.Exit(1)
+	}
+	pluginCache, err := newPluginCache(pluginCachePVC, pluginCacheHostPath)
+	if err != nil {
+		setupLog.Error(err, "invalid plugin cache")
+		os.Exit(1)
+	}
+	cliConfig, err := newCLIConfig(cliConfigConfigMap, cliConfigSecret)
+	if err != nil {
+		setupLog.Error(err, "invalid CLI configuration")
+		os.Exit(1)
+	}
+	namespaces, err := parseNamespaces(executionNamespaces)
+	if err != nil {
+		setupLog.Error(err, "invalid execution namespaces")
+		os.Exit(1)
+	}
+	if errs := validation.IsDNS1123Subdomain(defaultProvider); defaultProvider != "" && len(errs) > 0 {
+		setupLog.Error(errors.New(strings.Join(errs, ", ")), "invalid default provider", "Provider", defaultProvider)
+		os.Exit(1)
+	}
+	webhooks, err := parseWebhooks(notificationWebhooks)
+	if err != nil {
+		setupLog.Error(err, "invalid notification webhooks")
+		os.Exit(1)
+	}
+	if u, err := url.Parse(secretStoreOpts.VaultAddress); secretStoreOpts.VaultAddress != "" &&
+		(err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+		setupLog.Error(errors.New("not an http or https URL"), "invalid Vault address", "Address", secretStoreOpts.VaultAddress)
+		os.Exit(1)
+	}
+	for _, prefix := range strings.Split(vaultAllowedPaths, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			secretStoreOpts.VaultAllowedPaths = append(secretStoreOpts.VaultAllowedPaths, prefix)
+		}
+	}
+	for _, prefix := range strings.Split(awsAllowedSecrets, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			secretStoreOpts.AWSAllowedSecrets = append(secretStoreOpts.AWSAllowedSecrets, prefix)
+		}
+	}
+	util.ConfigureSecretStores(secretStoreOpts)
+
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "ce329a9c.core.oam.dev",
-		SyncPeriod:         &syncPeriod,
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: healthProbeAddr,
+		Port:                   9443,
+		LeaderElection:         enableLeaderElection,
+		LeaderElectionID:       "ce329a9c.core.oam.dev",
+		SyncPeriod:             &syncPeriod,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	health := controllers.NewReconcileHealth(mgr.GetAPIReader(), reconcileStallTimeout)
+	if err = mgr.AddHealthzCheck("reconcile", health.CheckReconcile); err != nil {
+		setupLog.Error(err, "unable to add liveness check")
+		os.Exit(1)
+	}
+	if err = mgr.AddReadyzCheck("apiserver", health.CheckAPIServer); err != nil {
+		setupLog.Error(err, "unable to add readiness check")
+		os.Exit(1)
+	}
+	if err = mgr.AddMetricsExtraHandler("/reconcile-health", health); err != nil {
+		setupLog.Error(err, "unable to add reconcile health report")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.ConfigurationReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Configuration"),
-		Scheme: mgr.GetScheme(),
+		Client:                     mgr.GetClient(),
+		Log:                        ctrl.Log.WithName("controllers").WithName("Configuration"),
+		Scheme:                     mgr.GetScheme(),
+		Recorder:                   mgr.GetEventRecorderFor("configuration-controller"),
+		ProviderName:               defaultProvider,
+		JobTTLSecondsAfterFinished: jobTTL(jobTTLSecondsAfterFinished),
+		EnableStateSurgery:         enableStateSurgery,
+		WorkingVolume:              workingVolume,
+		PluginCache:                pluginCache,
+		PlanArtifacts:              newPlanArtifacts(planArtifactsPVC),
+		PlanExpiry:                 planExpiry,
+		CLIConfig:                  cliConfig,
+		TerraformImage:             terraformImage,
+		OpenTofuImage:              openTofuImage,
+		RunnerImage:                runnerImage,
+		JSONLogs:                   terraformJSONLogs,
+		ApplyProgressInterval:      applyProgressInterval,
+		CredentialsGracePeriod:     credentialsGracePeriod,
+		FinalizerGracePeriod:       finalizerGracePeriod,
+		ReconcileTimeout:           reconcileTimeout,
+		Health:                     health,
+		ExecutionNamespaces:        namespaces,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Configuration")
 		os.Exit(1)
 	}
 	if err = (&controllers.ProviderReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Provider"),
-		Scheme: mgr.GetScheme(),
+		Client:            mgr.GetClient(),
+		Log:               ctrl.Log.WithName("controllers").WithName("Provider"),
+		Scheme:            mgr.GetScheme(),
+		VerifyCredentials: verifyProviderCredentials,
+		CheckInterval:     providerCheckInterval,
+		VerifyInterval:    providerVerifyInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Provider")
 		os.Exit(1)
 	}
+	if orphanCollectInterval > 0 {
+		if err = mgr.Add(&controllers.OrphanCollector{
+			Client:     mgr.GetClient(),
+			Interval:   orphanCollectInterval,
+			Namespaces: namespaces,
+		}); err != nil {
+			setupLog.Error(err, "unable to add orphan collector")
+			os.Exit(1)
+		}
+	}
+	if enableWebhook {
+		webhook.Register(mgr, defaultProvider)
+	}
+	if outputsAPIAddr != "" {
+		if outputsAPICertDir == "" {
+			setupLog.Error(errors.New("--outputs-api-cert-dir is required"), "the outputs API is only served over TLS")
+			os.Exit(1)
+		}
+		if err = mgr.Add(&controllers.OutputsServer{
+			Client:   mgr.GetClient(),
+			Addr:     outputsAPIAddr,
+			CertFile: path.Join(outputsAPICertDir, "tls.crt"),
+			KeyFile:  path.Join(outputsAPICertDir, "tls.key"),
+		}); err != nil {
+			setupLog.Error(err, "unable to add outputs server")
+			os.Exit(1)
+		}
+	}
+	if len(webhooks) > 0 {
+		if err = mgr.Add(&controllers.Notifier{
+			Cache: mgr.GetCache(),
+			URLs:  webhooks,
+		}); err != nil {
+			setupLog.Error(err, "unable to add notifier")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -94,3 +328,107 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newWorkingVolume returns the default settings of the emptyDir volumes of the Terraform executor
+func newWorkingVolume(medium, sizeLimit string) (terraformv1beta1.WorkingVolume, error) {
+	v := terraformv1beta1.WorkingVolume{Medium: v1.StorageMedium(medium)}
+	if v.Medium != v1.StorageMediumDefault && v.Medium != v1.StorageMediumMemory {
+		return v, errors.Errorf("unsupported medium %s", medium)
+	}
+	if sizeLimit != "" {
+		q, err := resource.ParseQuantity(sizeLimit)
+		if err != nil {
+			return v, errors.Wrapf(err, "invalid size limit %s", sizeLimit)
+		}
+		if q.Sign() <= 0 {
+			return v, errors.Errorf("size limit %s must be positive", sizeLimit)
+		}
+		v.SizeLimit = &q
+	}
+	return v, nil
+}
+
+// newPluginCache returns the volume of the plugin cache shared by the Terraform Jobs, which is nil if it's disabled
+func newPluginCache(pvc, hostPath string) (*v1.VolumeSource, error) {
+	switch {
+	case pvc != "" && hostPath != "":
+		return nil, errors.New("only one of the PersistentVolumeClaim and the hostPath can be the plugin cache")
+	case pvc != "":
+		return &v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: pvc}}, nil
+	case hostPath != "":
+		if !path.IsAbs(hostPath) {
+			return nil, errors.Errorf("the hostPath %s of the plugin cache must be absolute", hostPath)
+		}
+		hostPathType := v1.HostPathDirectoryOrCreate
+		return &v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: hostPath, Type: &hostPathType}}, nil
+	}
+	return nil, nil
+}
+
+// newPlanArtifacts returns the volume of the saved plans, which is nil if it's disabled
+func newPlanArtifacts(pvc string) *v1.VolumeSource {
+	if pvc == "" {
+		return nil
+	}
+	return &v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: pvc}}
+}
+
+// newCLIConfig returns the volume of the CLI configuration of Terraform, which is nil if it's not set
+func newCLIConfig(configMap, secret string) (*v1.VolumeSource, error) {
+	items := []v1.KeyToPath{{Key: controllers.CLIConfigKey, Path: controllers.CLIConfigKey}}
+	switch {
+	case configMap != "" && secret != "":
+		return nil, errors.New("only one of the ConfigMap and the Secret can be the CLI configuration")
+	case configMap != "":
+		return &v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+			LocalObjectReference: v1.LocalObjectReference{Name: configMap},
+			Items:                items,
+		}}, nil
+	case secret != "":
+		return &v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: secret, Items: items}}, nil
+	}
+	return nil, nil
+}
+
+// parseNamespaces returns the namespaces in a comma-separated list
+func parseNamespaces(list string) ([]string, error) {
+	var namespaces []string
+	for _, ns := range strings.Split(list, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return nil, errors.Errorf("invalid namespace %s: %s", ns, strings.Join(errs, ", "))
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, nil
+}
+
+// parseWebhooks parses the comma-separated URLs of the notification webhooks, which aren't told by the errors as they
+// could be credentials
+func parseWebhooks(list string) ([]string, error) {
+	var webhooks []string
+	for i, raw := range strings.Split(list, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.Errorf("the webhook #%d isn't an http or https URL", i+1)
+		}
+		webhooks = append(webhooks, raw)
+	}
+	return webhooks, nil
+}
+
+// jobTTL returns the ttlSecondsAfterFinished of Jobs, which is unset when it's negative
+func jobTTL(seconds int) *int32 {
+	if seconds < 0 {
+		return nil
+	}
+	ttl := int32(seconds)
+	return &ttl
+}